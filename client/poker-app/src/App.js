@@ -3,6 +3,7 @@ import React, { useContext } from 'react'
 import { appStore } from './appStore'
 import Game from './routes/Game'
 import Join from './routes/Join'
+import Lobby from './routes/Lobby'
 
 
 function App() {
@@ -13,6 +14,10 @@ function App() {
     return <Game />
   }
 
+  if (appState.token) {
+    return <Lobby />
+  }
+
   return <Join />
 }
 
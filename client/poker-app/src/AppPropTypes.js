@@ -6,7 +6,9 @@ const actions = PropTypes.arrayOf(PropTypes.oneOf([
   Event.CALL,
   Event.CHECK,
   Event.FOLD,
+  Event.MUCK_HAND,
   Event.RAISE,
+  Event.SHOW_HAND,
 ]))
 
 const card = PropTypes.shape({
@@ -15,9 +17,18 @@ const card = PropTypes.shape({
 })
 
 const player = PropTypes.shape({
+  bounty: PropTypes.number,
   chips: PropTypes.number,
   chipsInPot: PropTypes.number,
   holeCards: PropTypes.arrayOf(card),
+  hud: PropTypes.shape({
+    '3b': PropTypes.number,
+    af: PropTypes.number,
+    hands: PropTypes.number,
+    pfr: PropTypes.number,
+    vpip: PropTypes.number,
+    wtsd: PropTypes.number,
+  }),
   hasFolded: PropTypes.bool,
   isActive: PropTypes.bool,
   isDealer: PropTypes.bool,
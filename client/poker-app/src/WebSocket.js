@@ -4,6 +4,7 @@ import React, { createContext, useContext, useState, useEffect } from 'react'
 import { w3cwebsocket} from "websocket"
 
 import {
+  changeSeat,
   error,
   newMessage,
   onHoleCards,
@@ -32,14 +33,27 @@ const WebSocketProvider = ({ children }) => {
   const appContext = useContext(appStore)
   const { appState, dispatch } = appContext
 
+  // Only connect once the user has logged in and chosen a table in the lobby. The server
+  // checks the session token before accepting the connection.
+  const { tableCode, tableName, token } = appState
+
   useEffect(() => {
-    const _client = w3cwebsocket(BASE_WS_URL)
+    if (!token || !tableName) {
+      return
+    }
+
+    let url = `${BASE_WS_URL}?token=${encodeURIComponent(token)}&table=${encodeURIComponent(tableName)}`
+    if (tableCode) {
+      url += `&code=${encodeURIComponent(tableCode)}`
+    }
+    const _client = w3cwebsocket(url)
     _client.onerror = function() {
       error(dispatch, {error: 'Could not connect to the server.'})
     }
 
     _client.onopen = function() {
       console.log('WebSocket client connected')
+      joinGame(_client)
     }
 
     _client.onclose = function() {
@@ -47,7 +61,7 @@ const WebSocketProvider = ({ children }) => {
     }
 
     setClient(_client)
-  }, [dispatch])
+  }, [dispatch, tableCode, tableName, token])
 
   if (client) {
     client.onmessage = (payload) => {
@@ -73,9 +87,9 @@ const WebSocketProvider = ({ children }) => {
   }
 
   ws = {
+    changeSeat: partial(changeSeat, client),
     client,
-    joinGame: partial(joinGame, client),
-    sendMessage: partial(sendMessage, client, appState.username),
+    sendMessage: partial(sendMessage, client),
     sendMuteVideo: partial(sendMuteVideo, client),
     sendPlayerAction: partial(sendPlayerAction, client),
     takeSeat: partial(takeSeat, client),
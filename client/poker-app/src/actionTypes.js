@@ -9,8 +9,12 @@ const actionNames = [
   'CHAT.NEW_MESSAGE',
   'GAME.ON_HOLE_CARDS',
   'GAME.UPDATE',
+  'LOBBY.CHOOSE_TABLE',
+  'LOBBY.SET',
+  'LOBBY.UPDATE',
   'SERVER.ERROR',
   'SERVER.ON_JOIN',
+  'SERVER.ON_LOGIN',
   'SERVER.ON_TAKE_SEAT',
   'WEBRTC.REMOVE_STREAM',
   'WEBRTC.SET_STREAM',
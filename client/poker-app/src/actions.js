@@ -1,4 +1,4 @@
-import { find, forEach, keys, omit } from 'lodash'
+import { find, forEach, keyBy, keys, omit } from 'lodash'
 import Peer from 'simple-peer'
 import { v4 as uuidv4 } from 'uuid'
 
@@ -116,14 +116,108 @@ const sendSignal = (client, peerID, streamID, signalData) => {
   }))
 }
 
+// Accounts
+
+const BASE_API_URL = process.env.REACT_APP_API_URL
+
+const login = (dispatch, username, password, isRegistering = false) => {
+  return fetch(`${BASE_API_URL}/${isRegistering ? 'register' : 'login'}`, {
+    method: 'POST',
+    headers: {'Content-Type': 'application/json'},
+    body: JSON.stringify({username, password}),
+  })
+  .then(response => response.json().then(body => ({ok: response.ok, body})))
+  .then(({ok, body}) => {
+    if (!ok) {
+      error(dispatch, body)
+      return false
+    }
+    dispatch({
+      type: actionTypes.SERVER.ON_LOGIN,
+      token: body.token,
+    })
+    return true
+  })
+  .catch(() => {
+    error(dispatch, {error: 'Could not connect to the server.'})
+    return false
+  })
+}
+
+// Lobby
+
+// The table code is the invite code or password of a private table
+const chooseTable = (dispatch, tableName, tableCode = null) => {
+  dispatch({
+    type: actionTypes.LOBBY.CHOOSE_TABLE,
+    tableCode,
+    tableName,
+  })
+}
+
+const createPrivateTable = (dispatch, token, name, password) => {
+  return fetch(`${BASE_API_URL}/tables`, {
+    method: 'POST',
+    headers: {'Authorization': `Bearer ${token}`},
+    body: JSON.stringify({name, password}),
+  })
+  .then(response => response.json().then(body => ({ok: response.ok, body})))
+  .then(({ok, body}) => {
+    if (ok) {
+      chooseTable(dispatch, body.name, body.inviteCode)
+    } else {
+      error(dispatch, body)
+    }
+    return ok
+  })
+  .catch(() => {
+    error(dispatch, {error: 'Could not connect to the server.'})
+    return false
+  })
+}
+
+const onLobby = (dispatch, params) => {
+  dispatch({
+    type: actionTypes.LOBBY.SET,
+    tables: keyBy(params.tables, 'name'),
+    tournaments: keyBy(params.tournaments, 'name'),
+  })
+}
+
+const registerTournament = (dispatch, token, name) => {
+  return fetch(`${BASE_API_URL}/tournaments/${encodeURIComponent(name)}/register`, {
+    method: 'POST',
+    headers: {'Authorization': `Bearer ${token}`},
+  })
+  .then(response => response.json().then(body => ({ok: response.ok, body})))
+  .then(({ok, body}) => {
+    if (!ok) {
+      error(dispatch, body)
+    }
+    return ok
+  })
+  .catch(() => {
+    error(dispatch, {error: 'Could not connect to the server.'})
+    return false
+  })
+}
+
+const updateLobby = (dispatch, params) => {
+  dispatch({
+    type: actionTypes.LOBBY.UPDATE,
+    removedTables: params.removedTables,
+    removedTournaments: params.removedTournaments,
+    tables: keyBy(params.tables, 'name'),
+    tournaments: keyBy(params.tournaments, 'name'),
+  })
+}
+
 // Game
 
-const joinGame = (client, username) => {
+const joinGame = (client) => {
   client.send(JSON.stringify({
     action: Event.JOIN,
-    params: {
-      username,
-    },
+    params: {},
   }))
 }
 
@@ -185,11 +279,10 @@ const onTakeSeat = (dispatch, params, ws, appState) => {
   .catch(() => {}) // TODO Handle error
 }
 
-const sendMessage = (client, username, message) => {
+const sendMessage = (client, message) => {
   client.send(JSON.stringify({
     action: Event.SEND_MESSAGE,
     params: {
-      username,
       message,
     },
   }))
@@ -212,6 +305,15 @@ const sendPlayerAction = (client, action, params = {}) => {
   }))
 }
 
+const changeSeat = (client, seatID) => {
+  client.send(JSON.stringify({
+    action: Event.CHANGE_SEAT,
+    params: {
+      seatID,
+    },
+  }))
+}
+
 const takeSeat = (client, seatID) => {
   client.send(JSON.stringify({
     action: Event.TAKE_SEAT,
@@ -249,7 +351,18 @@ export {
   // WebRTC
   onReceiveSignal,
 
+  // Accounts
+  login,
+
+  // Lobby
+  chooseTable,
+  createPrivateTable,
+  onLobby,
+  registerTournament,
+  updateLobby,
+
   // Game
+  changeSeat,
   joinGame,
   newMessage,
   onHoleCards,
@@ -19,9 +19,16 @@ const initialState = {
   },
   error: null,
   gameState: null,
+  lobby: {
+    tables: {},
+    tournaments: {},
+  },
   seatID: null,
   streams: {},
   streamSeatMap: {},
+  tableCode: null,
+  tableName: null,
+  token: null,
   userHoleCards: [null, null],
   userID: null,
   username: null,
@@ -56,6 +63,35 @@ const AppStateProvider = ({ children }) => {
           ...state,
           gameState: action.gameState,
         }
+      case actionTypes.LOBBY.CHOOSE_TABLE:
+        return {
+          ...state,
+          error: null,
+          tableCode: action.tableCode,
+          tableName: action.tableName,
+        }
+      case actionTypes.LOBBY.SET:
+        return {
+          ...state,
+          lobby: {
+            tables: action.tables,
+            tournaments: action.tournaments,
+          },
+        }
+      case actionTypes.LOBBY.UPDATE:
+        return {
+          ...state,
+          lobby: {
+            tables: update(state.lobby.tables, {
+              $unset: action.removedTables,
+              $merge: action.tables,
+            }),
+            tournaments: update(state.lobby.tournaments, {
+              $unset: action.removedTournaments,
+              $merge: action.tournaments,
+            }),
+          },
+        }
       case actionTypes.SERVER.ERROR:
         return {
           ...state,
@@ -67,6 +103,12 @@ const AppStateProvider = ({ children }) => {
           userID: action.userID,
           username: action.username,
         }
+      case actionTypes.SERVER.ON_LOGIN:
+        return {
+          ...state,
+          error: null,
+          token: action.token,
+        }
       case actionTypes.SERVER.ON_TAKE_SEAT:
         return {
           ...state,
@@ -129,6 +129,14 @@ const ActionBar = ({
         )
       }
 
+      if (action === Event.SHOW_HAND || action === Event.MUCK_HAND) {
+        return (
+          <button key={action} className={buttonCss} onClick={() => onAction(action)}>
+            {action === Event.SHOW_HAND ? 'SHOW' : 'MUCK'}
+          </button>
+        )
+      }
+
       if (action === Event.CALL) {
         return (
           <button key={action} className={buttonCss} onClick={() => onAction(action)}>
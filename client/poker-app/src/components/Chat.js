@@ -3,6 +3,8 @@ import { noop } from 'lodash'
 import PropTypes from 'prop-types'
 import React, { useState, useEffect, useRef } from 'react'
 
+import { ChatMode, Event } from '../enums'
+
 const chatLogCss = classNames(
   'overflow-y-auto',
 
@@ -19,8 +21,25 @@ const chatLogCss = classNames(
   'md:text-sm',
 )
 
-const Chat = ({messages, onSend}) => {
+const smallButtonCss = classNames(
+  'bg-gray-700',
+  'hover:bg-gray-800',
+  'text-gray-50',
+
+  // Spacing
+  'ml-1',
+  'px-2',
+  'py-1',
+)
+
+// Longest message the server accepts
+const MAX_MESSAGE_LENGTH = 280
+
+const SYSTEM_USERNAME = 'System'
+
+const Chat = ({canModerate, chatMode, ignored, messages, muted, onAction, onSend, username}) => {
   const [message, setMessage] = useState('')
+  const [selected, setSelected] = useState(null)
   const scrollRef = useRef()
 
   useEffect(() => {
@@ -42,19 +61,64 @@ const Chat = ({messages, onSend}) => {
     }
   }
 
+  const handleSelect = (messageUsername) => {
+    if (messageUsername !== SYSTEM_USERNAME && messageUsername !== username) {
+      setSelected(messageUsername === selected ? null : messageUsername)
+    }
+  }
+
+  const isIgnored = selected && ignored.includes(selected)
+  // Muted players are listed by their lowercase username
+  const isMuted = selected && muted.includes(selected.toLowerCase())
+
   return (
     <>
+      {canModerate &&
+        <div className="flex items-center justify-between p-1 text-xs">
+          <span>{chatMode === ChatMode.PLAYERS ? 'Only players can chat' : 'Everyone can chat'}</span>
+          <button
+            className={smallButtonCss}
+            onClick={() => onAction(Event.CHANGE_CHAT_MODE, {
+              mode: chatMode === ChatMode.PLAYERS ? ChatMode.EVERYONE : ChatMode.PLAYERS,
+            })}
+          >
+            {chatMode === ChatMode.PLAYERS ? 'Let everyone chat' : 'Players only'}
+          </button>
+        </div>
+      }
       <div className={chatLogCss}>
         {messages.map(message => (
           <div key={message.id} className="pb-1">
-            <strong>{message.username}</strong>: {message.message}
+            <strong className="cursor-pointer" onClick={() => handleSelect(message.username)}>
+              {message.username}
+            </strong>: {message.message}
           </div>
         ))}
         <div ref={scrollRef}></div>
       </div>
+      {selected &&
+        <div className="flex items-center p-1 text-xs">
+          <span className="flex-1">{selected}{isMuted && ' (muted)'}</span>
+          <button
+            className={smallButtonCss}
+            onClick={() => onAction(Event.IGNORE_PLAYER, {ignored: !isIgnored, username: selected})}
+          >
+            {isIgnored ? 'Unignore' : 'Ignore'}
+          </button>
+          {canModerate &&
+            <button
+              className={smallButtonCss}
+              onClick={() => onAction(Event.MUTE_PLAYER, {muted: !isMuted, username: selected})}
+            >
+              {isMuted ? 'Unmute' : 'Mute'}
+            </button>
+          }
+        </div>
+      }
       <div className="flex">
         <input
           className="border p-3 w-full text-xs md:text-sm"
+          maxLength={MAX_MESSAGE_LENGTH}
           name="name"
           onChange={(e) => setMessage(e.target.value)}
           onKeyUp={handleKeyPress}
@@ -68,10 +132,19 @@ const Chat = ({messages, onSend}) => {
 }
 
 Chat.defaultProps = {
+  canModerate: false,
+  chatMode: ChatMode.EVERYONE,
+  ignored: [],
+  muted: [],
+  onAction: noop,
   onSend: noop,
+  username: null,
 }
 
 Chat.propTypes = {
+  canModerate: PropTypes.bool,
+  chatMode: PropTypes.oneOf(Object.values(ChatMode)),
+  ignored: PropTypes.arrayOf(PropTypes.string),
   messages: PropTypes.arrayOf(
     PropTypes.shape({
       id: PropTypes.string.isRequired,
@@ -79,7 +152,10 @@ Chat.propTypes = {
       username: PropTypes.string.isRequired,
     }).isRequired,
   ),
+  muted: PropTypes.arrayOf(PropTypes.string),
+  onAction: PropTypes.func,
   onSend: PropTypes.func,
+  username: PropTypes.string,
 }
 
 export default Chat
@@ -0,0 +1,77 @@
+import classNames from 'classnames'
+import { noop } from 'lodash'
+import PropTypes from 'prop-types'
+import React from 'react'
+
+import { Event } from '../enums'
+
+const buttonCss = classNames(
+  'flex-1',
+
+  'bg-blue-600',
+  'hover:bg-blue-700',
+
+  // spacing
+  'm-1',
+  'p-1',
+  'md:p-2',
+
+  // text
+  'font-medium',
+  'text-center',
+
+  'text-xs',
+  'md:text-sm',
+
+  'text-white',
+)
+
+const DealBar = ({deal, onAction, username}) => {
+  if (!deal) {
+    return (
+      <div className="flex bg-gray-800">
+        <button className={buttonCss} onClick={() => onAction(Event.PROPOSE_DEAL, {type: 'icm'})}>
+          PROPOSE<br />ICM DEAL
+        </button>
+        <button className={buttonCss} onClick={() => onAction(Event.PROPOSE_DEAL, {type: 'chip-chop'})}>
+          PROPOSE<br />CHIP CHOP
+        </button>
+      </div>
+    )
+  }
+
+  if (deal.accepted[username]) {
+    return (
+      <div className="flex bg-gray-800 p-2 text-xs md:text-sm text-center text-white">
+        <div className="flex-1">Waiting for the other players to decide on the deal</div>
+      </div>
+    )
+  }
+
+  return (
+    <div className="flex bg-gray-800">
+      <button className={buttonCss} onClick={() => onAction(Event.ACCEPT_DEAL)}>
+        ACCEPT DEAL<br />ℝ{deal.payouts[username]}
+      </button>
+      <button className={buttonCss} onClick={() => onAction(Event.DECLINE_DEAL)}>
+        DECLINE<br />DEAL
+      </button>
+    </div>
+  )
+}
+
+DealBar.defaultProps = {
+  deal: null,
+  onAction: noop,
+}
+
+DealBar.propTypes = {
+  deal: PropTypes.shape({
+    accepted: PropTypes.object.isRequired,
+    payouts: PropTypes.object.isRequired,
+  }),
+  onAction: PropTypes.func,
+  username: PropTypes.string.isRequired,
+}
+
+export default DealBar
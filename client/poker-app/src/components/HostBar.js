@@ -0,0 +1,92 @@
+import classNames from 'classnames'
+import { noop } from 'lodash'
+import PropTypes from 'prop-types'
+import React, { useState } from 'react'
+
+import AppPropTypes from '../AppPropTypes'
+import { Event, PlayerStatus } from '../enums'
+
+const optionButtonCss = classNames(
+  'flex-1',
+
+  'bg-gray-800',
+  'hover:bg-gray-900',
+  'text-gray-50',
+
+  // Spacing
+  'p-2',
+)
+
+const smallButtonCss = classNames(
+  'bg-gray-700',
+  'hover:bg-gray-800',
+  'text-gray-50',
+
+  // Spacing
+  'px-2',
+  'py-1',
+)
+
+const HostBar = ({bigBlind, inviteCode, isPaused, onAction, players, requests, username}) => {
+  const [newBigBlind, setNewBigBlind] = useState(bigBlind)
+  const kickablePlayers = players.filter(p => p.status !== PlayerStatus.VACATED && p.name !== username)
+
+  return (
+    <div className="flex flex-col text-xs">
+      <div className="p-2 text-center text-gray-500">Invite code: {inviteCode}</div>
+      {requests.map(request => (
+        <div className="flex items-center justify-between p-1" key={request}>
+          <span>{request} wants to sit</span>
+          <button className={smallButtonCss} onClick={() => onAction(Event.APPROVE_PLAYER, {username: request})}>
+            Approve
+          </button>
+        </div>
+      ))}
+      {kickablePlayers.map(player => (
+        <div className="flex items-center justify-between p-1" key={player.id}>
+          <span>{player.name}</span>
+          <button className={smallButtonCss} onClick={() => onAction(Event.KICK_PLAYER, {seatID: player.id})}>
+            Kick
+          </button>
+        </div>
+      ))}
+      <div className="flex items-center p-1">
+        <span className="mr-2">Big blind</span>
+        <input
+          className="border flex-1 mr-2 p-1"
+          min={2}
+          onChange={e => setNewBigBlind(parseInt(e.target.value, 10))}
+          step={2}
+          type="number"
+          value={newBigBlind}
+        />
+        <button className={smallButtonCss} onClick={() => onAction(Event.CHANGE_BLINDS, {bigBlind: newBigBlind})}>
+          Change blinds
+        </button>
+      </div>
+      <div className="flex">
+        {isPaused ?
+          <button className={optionButtonCss} onClick={() => onAction(Event.RESUME_TABLE)}>Resume</button> :
+          <button className={optionButtonCss} onClick={() => onAction(Event.PAUSE_TABLE)}>Pause</button>
+        }
+        <button className={optionButtonCss} onClick={() => onAction(Event.CLOSE_TABLE)}>Close table</button>
+      </div>
+    </div>
+  )
+}
+
+HostBar.defaultProps = {
+  onAction: noop,
+}
+
+HostBar.propTypes = {
+  bigBlind: PropTypes.number.isRequired,
+  inviteCode: PropTypes.string.isRequired,
+  isPaused: PropTypes.bool.isRequired,
+  onAction: PropTypes.func,
+  players: PropTypes.arrayOf(AppPropTypes.player).isRequired,
+  requests: PropTypes.arrayOf(PropTypes.string).isRequired,
+  username: PropTypes.string.isRequired,
+}
+
+export default HostBar
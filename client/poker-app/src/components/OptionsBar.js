@@ -5,6 +5,8 @@ import { noop } from 'lodash'
 import PropTypes from 'prop-types'
 import React from 'react'
 
+import { Event } from '../enums'
+
 const getMicButtonCss = (muted) => (
   classNames(
     {
@@ -23,24 +25,58 @@ const getMicButtonCss = (muted) => (
 )
 
 
-const OptionsBar = ({muted, onMuteVideo}) => {
+const optionButtonCss = classNames(
+  'flex-1',
+
+  'bg-gray-800',
+  'hover:bg-gray-900',
+  'text-gray-50',
+
+  // Spacing
+  'p-2',
+)
+
+const OptionsBar = ({autoMuck, canShowCards, muted, onAction, onMuteVideo}) => {
   const icon = muted ? faMicrophoneSlash : faMicrophone
   const label = muted ? 'Unmute' : 'Mute'
+  const autoMuckLabel = autoMuck ? 'Show losing hands' : 'Muck losing hands'
   return (
-    <div className="flex">
-      <button className={getMicButtonCss(muted)} onClick={() => onMuteVideo(!muted)}>
-        <FontAwesomeIcon icon={icon} /> {label}
-      </button>
+    <div className="flex flex-col">
+      {canShowCards &&
+        <div className="flex">
+          <button className={optionButtonCss} onClick={() => onAction(Event.SHOW_CARDS, {cards: [0]})}>
+            Show left
+          </button>
+          <button className={optionButtonCss} onClick={() => onAction(Event.SHOW_CARDS, {cards: [1]})}>
+            Show right
+          </button>
+          <button className={optionButtonCss} onClick={() => onAction(Event.SHOW_CARDS, {cards: [0, 1]})}>
+            Show both
+          </button>
+        </div>
+      }
+      <div className="flex">
+        <button className={optionButtonCss} onClick={() => onAction(Event.AUTO_MUCK, {autoMuck: !autoMuck})}>
+          {autoMuckLabel}
+        </button>
+        <button className={getMicButtonCss(muted)} onClick={() => onMuteVideo(!muted)}>
+          <FontAwesomeIcon icon={icon} /> {label}
+        </button>
+      </div>
     </div>
   )
 }
 
 OptionsBar.defaultProps = {
+  onAction: noop,
   onMuteVideo: noop,
 }
 
 OptionsBar.propTypes = {
+  autoMuck: PropTypes.bool.isRequired,
+  canShowCards: PropTypes.bool.isRequired,
   muted: PropTypes.bool.isRequired,
+  onAction: PropTypes.func,
   onMuteVideo: PropTypes.func,
 }
 
@@ -0,0 +1,53 @@
+import classNames from 'classnames'
+import { noop, range } from 'lodash'
+import PropTypes from 'prop-types'
+import React from 'react'
+
+import { Event } from '../enums'
+
+const buttonCss = classNames(
+  'flex-1',
+
+  'bg-blue-600',
+  'hover:bg-blue-700',
+
+  // spacing
+  'm-1',
+  'p-1',
+  'md:p-2',
+
+  // text
+  'font-medium',
+  'text-center',
+
+  'text-xs',
+  'md:text-sm',
+
+  'text-white',
+)
+
+const TIMES_LABELS = ['', 'ONCE', 'TWICE']
+
+const RunItBar = ({maxTimes, onAction}) => {
+  const buttons = range(1, maxTimes + 1).map(times => (
+    <button key={times} className={buttonCss} onClick={() => onAction(Event.RUN_IT, {times})}>
+      RUN IT<br />{TIMES_LABELS[times] || `${times} TIMES`}
+    </button>
+  ))
+  return (
+    <div className="flex bg-gray-800">
+      {buttons}
+    </div>
+  )
+}
+
+RunItBar.defaultProps = {
+  onAction: noop,
+}
+
+RunItBar.propTypes = {
+  maxTimes: PropTypes.number.isRequired,
+  onAction: PropTypes.func,
+}
+
+export default RunItBar
@@ -65,6 +65,21 @@ const chipsInfoCss = classNames(
   'text-xs',
 )
 
+const hudCss = classNames(
+  'bg-gray-900',
+  'bg-opacity-60',
+  'rounded',
+
+  // spacing
+  'mx-1',
+  'px-1',
+
+  // text
+  'text-center',
+  'text-gray-300',
+  'text-xs',
+)
+
 const getWrapCss = (location) => (
   classNames(
     {
@@ -133,13 +148,13 @@ const getNameOverlayCss = (player) => (
 )
 
 
-const getButtonCss = (seatID) => (
+const getButtonCss = (disabled) => (
   classNames(
     {
-      'cursor-default': seatID !== null,
-      'hover:bg-gray-800': seatID === null,
-      'hover:bg-opacity-50': seatID === null,
-      'hover:text-gray-200': seatID === null,
+      'cursor-default': disabled,
+      'hover:bg-gray-800': !disabled,
+      'hover:bg-opacity-50': !disabled,
+      'hover:text-gray-200': !disabled,
     },
 
     'flex-1',
@@ -210,9 +225,23 @@ const getPlayerStatusMessage = (player) => {
   return `ℝ${player.chips}`
 }
 
+// getHUDMessage formats the player's stats as VPIP/PFR/3-bet/AF with the number of hands
+const getHUDMessage = (hud) => (
+  `${Math.round(hud.vpip)}/${Math.round(hud.pfr)}/${Math.round(hud['3b'])}/${hud.af.toFixed(1)} (${hud.hands})`
+)
+
+const getOpenSeatLabel = (heldFor, seatID) => {
+  if (heldFor) {
+    return `Held for ${heldFor}`
+  }
+  return seatID === null ? 'Take Seat' : 'Change Seat'
+}
+
 const Seat = ({
   dealDelay,
+  heldFor,
   location,
+  onChangeSeat,
   onTakeSeat,
   player,
   seatID,
@@ -265,11 +294,11 @@ const Seat = ({
       <div className={getWrapCss(location)}>
         <div className={getDealerCss(player)}>D</div>
         <button
-          className={getButtonCss(seatID)}
-          disabled={seatID !== null}
-          onClick={() => onTakeSeat(player.id)}
+          className={getButtonCss(Boolean(heldFor))}
+          disabled={Boolean(heldFor)}
+          onClick={() => seatID === null ? onTakeSeat(player.id) : onChangeSeat(player.id)}
         >
-          {seatID === null ? 'Take Seat' : 'Open Seat'}
+          {getOpenSeatLabel(heldFor, seatID)}
         </button>
       </div>
     )
@@ -292,6 +321,12 @@ const Seat = ({
           </div>
           <div className={getCardWrapCss(location)}>
             <p className={chipsInfoCss}>{getPlayerStatusMessage(player)}</p>
+            {player.bounty !== null && player.bounty !== undefined && (
+              <p className={hudCss} title="Bounty">Bounty ℝ{player.bounty}</p>
+            )}
+            {player.hud && (
+              <p className={hudCss} title="VPIP/PFR/3-Bet/AF (Hands)">{getHUDMessage(player.hud)}</p>
+            )}
             <div className="flex justify-center items-end">
               <motion.div animate={card1Anim} className={getCardCss(player)} variants={CARD_ANIM_VARIANTS}>
                 <img alt="Card" className="max-h-20" src={getCardImage(player.holeCards[0])} />
@@ -307,13 +342,17 @@ const Seat = ({
 }
 
 Seat.defaultProps = {
+  heldFor: null,
   location: PlayerLocation.BOTTOM,
+  onChangeSeat: noop,
   onTakeSeat: noop,
 }
 
 Seat.propTypes = {
   dealDelay: PropTypes.arrayOf(PropTypes.number),
+  heldFor: PropTypes.string,
   location: PropTypes.oneOf([PlayerLocation.BOTTOM, PlayerLocation.TOP]),
+  onChangeSeat: PropTypes.func,
   onTakeSeat: PropTypes.func,
   player: AppPropTypes.player.isRequired,
   seatID: PropTypes.string,
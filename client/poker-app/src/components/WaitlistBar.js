@@ -0,0 +1,85 @@
+import classNames from 'classnames'
+import { noop } from 'lodash'
+import PropTypes from 'prop-types'
+import React from 'react'
+
+import { Event } from '../enums'
+
+const optionButtonCss = classNames(
+  'flex-1',
+
+  'bg-gray-800',
+  'hover:bg-gray-900',
+  'text-gray-50',
+
+  // Spacing
+  'p-2',
+)
+
+const takeSeatButtonCss = classNames(
+  'flex-1',
+
+  'bg-blue-600',
+  'hover:bg-blue-700',
+  'text-white',
+
+  // Spacing
+  'p-2',
+)
+
+const WaitlistBar = ({onAction, onTakeSeat, seatOffer, username, waitlist}) => {
+  if (seatOffer) {
+    return (
+      <div className="flex">
+        <button className={takeSeatButtonCss} onClick={() => onTakeSeat(seatOffer.seatID)}>
+          Take your seat
+        </button>
+        <button className={optionButtonCss} onClick={() => onAction(Event.LEAVE_WAITLIST)}>
+          Pass
+        </button>
+      </div>
+    )
+  }
+
+  const position = waitlist.indexOf(username)
+  return (
+    <div className="flex flex-col">
+      {waitlist.length > 0 &&
+        <div className="p-2 text-xs text-center text-gray-500">
+          {position >= 0 ? `You are #${position + 1} on the waitlist` : `${waitlist.length} waiting`}
+        </div>
+      }
+      <div className="flex">
+        {position >= 0 ?
+          <button className={optionButtonCss} onClick={() => onAction(Event.LEAVE_WAITLIST)}>
+            Leave waitlist
+          </button> :
+          <button className={optionButtonCss} onClick={() => onAction(Event.JOIN_WAITLIST)}>
+            Join waitlist
+          </button>
+        }
+      </div>
+    </div>
+  )
+}
+
+WaitlistBar.defaultProps = {
+  onAction: noop,
+  onTakeSeat: noop,
+  seatOffer: null,
+  username: null,
+}
+
+WaitlistBar.propTypes = {
+  onAction: PropTypes.func,
+  onTakeSeat: PropTypes.func,
+  seatOffer: PropTypes.shape({
+    expiresAt: PropTypes.string.isRequired,
+    seatID: PropTypes.string.isRequired,
+    username: PropTypes.string.isRequired,
+  }),
+  username: PropTypes.string,
+  waitlist: PropTypes.arrayOf(PropTypes.string).isRequired,
+}
+
+export default WaitlistBar
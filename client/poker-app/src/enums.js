@@ -6,23 +6,56 @@ export const PlayerLocation = deepFreeze({
 })
 
 export const Event = deepFreeze({
+  ACCEPT_DEAL: 'accept-deal',
+  APPROVE_PLAYER: 'approve-player',
+  AUTO_MUCK: 'auto-muck',
   CALL: 'call',
+  CHANGE_BLINDS: 'change-blinds',
+  CHANGE_CHAT_MODE: 'change-chat-mode',
+  CHANGE_SEAT: 'change-seat',
+  CLOSE_TABLE: 'close-table',
   CHECK: 'check',
+  DECLINE_DEAL: 'decline-deal',
   ERROR: 'error',
   FOLD: 'fold',
+  IGNORE_PLAYER: 'ignore-player',
   JOIN: 'join',
+  JOIN_WAITLIST: 'join-waitlist',
+  KICK_PLAYER: 'kick-player',
+  LEAVE_WAITLIST: 'leave-waitlist',
+  MUCK_HAND: 'muck-hand',
+  MUTE_PLAYER: 'mute-player',
   MUTE_VIDEO: 'mute-video',
   NEW_MESSAGE: 'new-message',
   NEW_PEER: 'new-peer',
   ON_HOLE_CARDS: 'on-hole-cards',
   ON_JOIN: 'on-join',
+  ON_LOBBY: 'on-lobby',
   ON_RECEIVE_SIGNAL: 'on-receive-signal',
   ON_TAKE_SEAT: 'on-take-seat',
+  PAUSE_TABLE: 'pause-table',
+  PROPOSE_DEAL: 'propose-deal',
   RAISE: 'raise',
+  RESUME_TABLE: 'resume-table',
+  RUN_IT: 'run-it',
   SEND_MESSAGE: 'send-message',
   SEND_SIGNAL: 'send-signal',
+  SHOW_CARDS: 'show-cards',
+  SHOW_HAND: 'show-hand',
   TAKE_SEAT: 'take-seat',
   UPDATE_GAME: 'update-game',
+  UPDATE_LOBBY: 'update-lobby',
+})
+
+export const ChatMode = deepFreeze({
+  EVERYONE: 'everyone',
+  PLAYERS: 'players',
+})
+
+export const LobbyGame = deepFreeze({
+  CASH: 'cash',
+  MULTI_TABLE: 'multi-table',
+  SIT_AND_GO: 'sit-and-go',
 })
 
 export const PlayerStatus = deepFreeze({
@@ -31,6 +64,12 @@ export const PlayerStatus = deepFreeze({
   VACATED: 'vacated',
 })
 
+export const Role = deepFreeze({
+  ADMIN: 'admin',
+  PLAYER: 'player',
+  SPECTATOR: 'spectator',
+})
+
 export const Stage = deepFreeze({
   WAITING: 'Waiting',
   PREFLOP: 'Preflop',
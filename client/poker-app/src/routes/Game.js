@@ -5,10 +5,14 @@ import { appStore } from '../appStore'
 import ActionBar from '../components/ActionBar'
 import Chat from '../components/Chat'
 import CommunityCards from '../components/CommunityCards'
+import DealBar from '../components/DealBar'
+import HostBar from '../components/HostBar'
 import OptionsBar from '../components/OptionsBar'
 import Seat from '../components/Seat'
 import Pot from '../components/Pot'
-import { PlayerLocation, Stage } from '../enums'
+import RunItBar from '../components/RunItBar'
+import WaitlistBar from '../components/WaitlistBar'
+import { Event, PlayerLocation, Role, Stage } from '../enums'
 import { WebSocketContext } from '../WebSocket'
 
 const DELAY_INCREMENT = .15
@@ -40,12 +44,45 @@ const getStream = (userID, player, streamSeatMap, streams, userStream) => {
   return null
 }
 
+// getHeldFor gets who an open seat is being held for, unless it is being held for the user
+const getHeldFor = (seatOffers, player, username) => {
+  const offer = find(seatOffers, o => o.seatID === player.id)
+  if (!offer || offer.username === username) {
+    return null
+  }
+  return offer.username
+}
+
+const getTournamentMessage = (tournament) => {
+  if (tournament.isFinished) {
+    return 'The tournament has finished'
+  }
+  if (!tournament.isRunning) {
+    return `Waiting for players (${tournament.players.length}/${tournament.numPlayers}) - ℝ${tournament.buyIn} buy-in`
+  }
+  let message = `Level ${tournament.level}: ℝ${tournament.smallBlind}/ℝ${tournament.bigBlind}`
+  if (tournament.ante > 0) {
+    message += ` ante ℝ${tournament.ante}`
+  }
+  if (tournament.bounty > 0) {
+    message += tournament.progressive ? ' - progressive knockout' : ' - knockout'
+  }
+  if (tournament.secondsToNextLevel !== null) {
+    const minutes = Math.floor(tournament.secondsToNextLevel / 60)
+    const seconds = String(tournament.secondsToNextLevel % 60).padStart(2, '0')
+    message += ` - next level in ${minutes}:${seconds}`
+  } else if (tournament.handsToNextLevel !== null) {
+    message += ` - next level in ${tournament.handsToNextLevel} hands`
+  }
+  return message
+}
+
 const Game = () => {
   const ws = useContext(WebSocketContext)
 
   const appContext = useContext(appStore)
   const { appState } = appContext
-  const { chat, error, gameState, seatID, streams, streamSeatMap, userID, userStream } = appState
+  const { chat, error, gameState, seatID, streams, streamSeatMap, userID, username, userStream } = appState
 
   const [cardDelay, setCardDelay] = useState(DEFAULT_CARD_DELAY)
   const [newDeal, setNewDeal] = useState(true)
@@ -88,8 +125,15 @@ const Game = () => {
   if (!gameState) {
     return <div className="container-fluid"></div>
   }
-  const showActionBar = ![Stage.WAITING, Stage.SHOWDOWN].includes(stage) && seatID === gameState.actionBar.seatID
+  // At showdown, the action bar is only shown to the player choosing to show or muck
+  const isChoosingToShow = stage === Stage.SHOWDOWN && (gameState.actionBar.actions || []).includes(Event.SHOW_HAND)
+  const showActionBar = (isChoosingToShow || ![Stage.WAITING, Stage.SHOWDOWN].includes(stage)) && seatID === gameState.actionBar.seatID
   const userPlayer = players.find(p => p.id === seatID)
+  const showRunItBar = gameState.runItVote && gameState.runItVote.votes[seatID] === 0
+  const tournament = gameState.tournament
+  const showDealBar = tournament && tournament.isRunning && userPlayer && !tournament.eliminated.includes(userPlayer.name)
+  const privateTable = gameState.private
+  const isHost = privateTable && privateTable.host === username
 
   return (
     <div className="container-fluid">
@@ -104,6 +148,8 @@ const Game = () => {
             <div className="flex">
               <Seat
                 dealDelay={cardDelay[0]}
+                heldFor={getHeldFor(gameState.seatOffers, gameState.players[0], username)}
+                onChangeSeat={ws.changeSeat}
                 onTakeSeat={ws.takeSeat}
                 player={gameState.players[0]}
                 location={PlayerLocation.TOP}
@@ -118,6 +164,8 @@ const Game = () => {
               />
               <Seat
                 dealDelay={cardDelay[1]}
+                heldFor={getHeldFor(gameState.seatOffers, gameState.players[1], username)}
+                onChangeSeat={ws.changeSeat}
                 onTakeSeat={ws.takeSeat}
                 player={gameState.players[1]}
                 location={PlayerLocation.TOP}
@@ -132,6 +180,8 @@ const Game = () => {
               />
               <Seat
                 dealDelay={cardDelay[2]}
+                heldFor={getHeldFor(gameState.seatOffers, gameState.players[2], username)}
+                onChangeSeat={ws.changeSeat}
                 onTakeSeat={ws.takeSeat}
                 player={gameState.players[2]}
                 location={PlayerLocation.TOP}
@@ -160,6 +210,8 @@ const Game = () => {
               <div className="flex">
                 <Seat
                   dealDelay={cardDelay[5]}
+                  heldFor={getHeldFor(gameState.seatOffers, gameState.players[5], username)}
+                  onChangeSeat={ws.changeSeat}
                   onTakeSeat={ws.takeSeat}
                   player={gameState.players[5]}
                   seatID={seatID}
@@ -173,6 +225,8 @@ const Game = () => {
                 />
                 <Seat
                   dealDelay={cardDelay[4]}
+                  heldFor={getHeldFor(gameState.seatOffers, gameState.players[4], username)}
+                  onChangeSeat={ws.changeSeat}
                   onTakeSeat={ws.takeSeat}
                   player={gameState.players[4]}
                   seatID={seatID}
@@ -186,6 +240,8 @@ const Game = () => {
                 />
                 <Seat
                   dealDelay={cardDelay[3]}
+                  heldFor={getHeldFor(gameState.seatOffers, gameState.players[3], username)}
+                  onChangeSeat={ws.changeSeat}
                   onTakeSeat={ws.takeSeat}
                   player={gameState.players[3]}
                   seatID={seatID}
@@ -201,7 +257,18 @@ const Game = () => {
             </div>
 
             <div className="flex-1 flex flex-col-reverse">
-            {showActionBar && <ActionBar
+            {showDealBar && <DealBar
+                deal={tournament.deal}
+                onAction={ws.sendPlayerAction}
+                username={userPlayer.name}
+              />
+            }
+            {showRunItBar && <RunItBar
+                maxTimes={gameState.runItVote.maxTimes}
+                onAction={ws.sendPlayerAction}
+              />
+            }
+            {showActionBar && !gameState.runItVote && <ActionBar
                 actions={gameState.actionBar.actions}
                 callAmount={gameState.actionBar.callAmount}
                 chipsInPot={gameState.actionBar.chipsInPot}
@@ -218,8 +285,62 @@ const Game = () => {
           </div>
 
         <div className="hidden sm:flex flex-col w-1/4 bg-gray-50">
-          <Chat messages={chat.messages} onSend={ws.sendMessage} />
-          {userPlayer && <OptionsBar muted={userPlayer.muted} onMuteVideo={ws.sendMuteVideo} />}
+          {gameState.spectators &&
+            <div className="p-2 text-xs text-center text-gray-500">
+              {gameState.spectators.count} watching
+              {gameState.spectators.delay > 0 && ` (${gameState.spectators.delay}s delay)`}
+            </div>
+          }
+          {gameState.tournament &&
+            <div className="p-2 text-xs text-center text-gray-500">
+              {getTournamentMessage(gameState.tournament)}
+            </div>
+          }
+          {privateTable &&
+            <div className="p-2 text-xs text-center text-gray-500">
+              Hosted by {privateTable.host} - ℝ{privateTable.bigBlind / 2}/ℝ{privateTable.bigBlind}
+              {privateTable.isPaused && ' - paused'}
+            </div>
+          }
+          <Chat
+            canModerate={isHost || gameState.role === Role.ADMIN}
+            chatMode={gameState.chat.mode}
+            ignored={gameState.chat.ignored}
+            messages={chat.messages}
+            muted={gameState.chat.muted}
+            onAction={ws.sendPlayerAction}
+            onSend={ws.sendMessage}
+            username={username}
+          />
+          {isHost &&
+            <HostBar
+              bigBlind={privateTable.bigBlind}
+              inviteCode={privateTable.inviteCode}
+              isPaused={privateTable.isPaused}
+              onAction={ws.sendPlayerAction}
+              players={players}
+              requests={privateTable.requests}
+              username={username}
+            />
+          }
+          {!userPlayer && !tournament &&
+            <WaitlistBar
+              onAction={ws.sendPlayerAction}
+              onTakeSeat={ws.takeSeat}
+              seatOffer={find(gameState.seatOffers, o => o.username === username)}
+              username={username}
+              waitlist={gameState.waitlist}
+            />
+          }
+          {userPlayer &&
+            <OptionsBar
+              autoMuck={userPlayer.autoMuck}
+              canShowCards={gameState.showCardsSeat === seatID}
+              muted={userPlayer.muted}
+              onAction={ws.sendPlayerAction}
+              onMuteVideo={ws.sendMuteVideo}
+            />
+          }
         </div>
       </div>
     </div>
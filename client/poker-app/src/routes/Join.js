@@ -1,24 +1,32 @@
 import React, { useContext, useState } from 'react'
 
-import { WebSocketContext } from '../WebSocket'
+import { login } from '../actions'
+import { appStore } from '../appStore'
 
 const Join = () => {
-  const ws = useContext(WebSocketContext)
+  const appContext = useContext(appStore)
+  const { appState, dispatch } = appContext
 
   const [isJoining, setJoining] = useState(false)
+  const [password, setPassword] = useState('')
   const [username, setUsername] = useState('')
 
-  const handleJoinGame = (username) => {
+  const handleJoinGame = (isRegistering) => {
     const trimmedUsername = username.trim()
-    if (trimmedUsername) {
+    if (trimmedUsername && password) {
       setJoining(true)
-      ws.joinGame(username)
+      login(dispatch, trimmedUsername, password, isRegistering)
+        .then(ok => {
+          if (!ok) {
+            setJoining(false)
+          }
+        })
     }
   }
 
   const handleKeyPress = (e) => {
     if (e.code === 'Enter') {
-      handleJoinGame(e.target.value)
+      handleJoinGame(false)
     }
   }
 
@@ -26,6 +34,9 @@ const Join = () => {
     <div className="container mx-auto">
       <div className="flex h-screen">
         <div className="border shadow-lg m-auto p-10 font-bold text-black">
+          {appState.error &&
+            <div className="bg-red-500 mb-4 p-2 text-center text-gray-50">{appState.error}</div>
+          }
           <label className="sr-only" htmlFor="name">Name</label>
           <input
             autoFocus
@@ -37,14 +48,31 @@ const Join = () => {
             type="text"
             value={username}
           />
+          <label className="sr-only" htmlFor="password">Password</label>
+          <input
+            className="border rounded-sm mr-2 p-3"
+            name="password"
+            onChange={(e) => setPassword(e.target.value)}
+            onKeyUp={handleKeyPress}
+            placeholder="Password"
+            type="password"
+            value={password}
+          />
+          <button
+            className="bg-blue-700 mr-2 px-6 py-3 font-bold text-white"
+            disabled={isJoining}
+            onClick={() => handleJoinGame(false)}
+          >
+            Log in
+          </button>
           <button
-            className="bg-blue-700 px-6 py-3 font-bold text-white"
+            className="bg-gray-700 px-6 py-3 font-bold text-white"
             disabled={isJoining}
-            onClick={() => handleJoinGame(username)}
+            onClick={() => handleJoinGame(true)}
           >
-            Join
+            Register
           </button>
-	      </div>
+        </div>
       </div>
     </div>
   )
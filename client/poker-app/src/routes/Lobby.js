@@ -0,0 +1,188 @@
+import { sortBy, values } from 'lodash'
+import React, { useContext, useEffect, useState } from 'react'
+import { w3cwebsocket } from 'websocket'
+
+import { chooseTable, createPrivateTable, error, onLobby, registerTournament, updateLobby } from '../actions'
+import { appStore } from '../appStore'
+import { Event, LobbyGame } from '../enums'
+
+const BASE_WS_URL = process.env.REACT_APP_WEBSOCKET_URL
+
+const GAME_LABELS = {
+  [LobbyGame.CASH]: 'Cash game',
+  [LobbyGame.MULTI_TABLE]: 'Tournament',
+  [LobbyGame.SIT_AND_GO]: 'Sit and go',
+}
+
+const formatStakes = (table) => {
+  let stakes = `ℝ${table.smallBlind}/ℝ${table.bigBlind}`
+  if (table.ante > 0) {
+    stakes += ` ante ℝ${table.ante}`
+  }
+  return stakes
+}
+
+const formatTournamentStatus = (tournament) => {
+  if (tournament.status === 'registering' && tournament.startAt) {
+    return `Starts ${new Date(tournament.startAt).toLocaleString()}`
+  }
+  if (tournament.status === 'running') {
+    let status = `Level ${tournament.level} - ${tournament.numRemaining} left`
+    if (tournament.isLateRegistrationOpen) {
+      status += ' - late registration'
+    }
+    return status
+  }
+  return tournament.status.charAt(0).toUpperCase() + tournament.status.slice(1)
+}
+
+// Private tables need the invite code or password to join
+const openTable = (dispatch, table) => {
+  if (!table.isPrivate) {
+    chooseTable(dispatch, table.name)
+    return
+  }
+  const code = window.prompt('Enter the invite code or password for this table')
+  if (code) {
+    chooseTable(dispatch, table.name, code)
+  }
+}
+
+const Lobby = () => {
+  const appContext = useContext(appStore)
+  const { appState, dispatch } = appContext
+  const { lobby, token } = appState
+
+  const [privateTableName, setPrivateTableName] = useState('')
+  const [privateTablePassword, setPrivateTablePassword] = useState('')
+
+  const onCreatePrivateTable = (event) => {
+    event.preventDefault()
+    createPrivateTable(dispatch, token, privateTableName, privateTablePassword)
+  }
+
+  // The lobby has its own connection that is closed once the player chooses a table
+  useEffect(() => {
+    const client = w3cwebsocket(`${BASE_WS_URL}/lobby?token=${encodeURIComponent(token)}`)
+    client.onerror = () => {
+      error(dispatch, {error: 'Could not connect to the lobby.'})
+    }
+    client.onmessage = (payload) => {
+      const event = JSON.parse(payload.data)
+      if (event.action === Event.ON_LOBBY) {
+        onLobby(dispatch, event.params)
+      } else if (event.action === Event.UPDATE_LOBBY) {
+        updateLobby(dispatch, event.params)
+      }
+    }
+    return () => client.close()
+  }, [dispatch, token])
+
+  const tables = sortBy(values(lobby.tables), 'name')
+  const tournaments = sortBy(values(lobby.tournaments), 'name')
+
+  return (
+    <div className="container mx-auto p-10">
+      {appState.error &&
+        <div className="bg-red-500 mb-4 p-2 text-center text-gray-50">{appState.error}</div>
+      }
+      <h1 className="font-bold mb-4 text-2xl">Tables</h1>
+      <table className="mb-10 text-left w-full">
+        <thead>
+          <tr className="border-b">
+            <th className="p-2">Table</th>
+            <th className="p-2">Game</th>
+            <th className="p-2">Stakes</th>
+            <th className="p-2">Players</th>
+            <th className="p-2">Waiting</th>
+            <th className="p-2">Watching</th>
+            <th className="p-2">Average pot</th>
+            <th className="p-2"></th>
+          </tr>
+        </thead>
+        <tbody>
+          {tables.map(table => (
+            <tr className="border-b" key={table.name}>
+              <td className="p-2">{table.name}{table.isPrivate && ' (private)'}</td>
+              <td className="p-2">{table.variant} - {GAME_LABELS[table.game]}</td>
+              <td className="p-2">{formatStakes(table)}</td>
+              <td className="p-2">{table.numSeated}/{table.numSeats}</td>
+              <td className="p-2">{table.numWaiting}</td>
+              <td className="p-2">{table.numWatching}</td>
+              <td className="p-2">ℝ{table.averagePot}</td>
+              <td className="p-2">
+                <button
+                  className="bg-blue-700 px-4 py-2 font-bold text-white"
+                  onClick={() => openTable(dispatch, table)}
+                >
+                  Open
+                </button>
+              </td>
+            </tr>
+          ))}
+        </tbody>
+      </table>
+
+      <form className="flex mb-10" onSubmit={onCreatePrivateTable}>
+        <input
+          className="border flex-1 mr-2 p-2"
+          onChange={e => setPrivateTableName(e.target.value)}
+          placeholder="Table name"
+          type="text"
+          value={privateTableName}
+        />
+        <input
+          className="border flex-1 mr-2 p-2"
+          onChange={e => setPrivateTablePassword(e.target.value)}
+          placeholder="Password (optional)"
+          type="password"
+          value={privateTablePassword}
+        />
+        <button className="bg-gray-700 px-4 py-2 font-bold text-white" type="submit">
+          Host a private table
+        </button>
+      </form>
+
+      {tournaments.length > 0 &&
+        <>
+          <h1 className="font-bold mb-4 text-2xl">Tournaments</h1>
+          <table className="text-left w-full">
+            <thead>
+              <tr className="border-b">
+                <th className="p-2">Tournament</th>
+                <th className="p-2">Buy-in</th>
+                <th className="p-2">Players</th>
+                <th className="p-2">Prize pool</th>
+                <th className="p-2">Status</th>
+                <th className="p-2"></th>
+              </tr>
+            </thead>
+            <tbody>
+              {tournaments.map(tournament => (
+                <tr className="border-b" key={tournament.name}>
+                  <td className="p-2">{tournament.name}</td>
+                  <td className="p-2">ℝ{tournament.buyIn}</td>
+                  <td className="p-2">{tournament.numRegistered}/{tournament.numPlayers}</td>
+                  <td className="p-2">ℝ{tournament.prizePool}</td>
+                  <td className="p-2">{formatTournamentStatus(tournament)}</td>
+                  <td className="p-2">
+                    {(tournament.status === 'registering' || tournament.isLateRegistrationOpen) &&
+                      <button
+                        className="bg-gray-700 px-4 py-2 font-bold text-white"
+                        onClick={() => registerTournament(dispatch, token, tournament.name)}
+                      >
+                        Register
+                      </button>
+                    }
+                  </td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        </>
+      }
+    </div>
+  )
+}
+
+export default Lobby
@@ -1,15 +1,26 @@
 package main
 
 import (
+	"context"
+	"log"
 	"math/rand"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/richard-to/go-poker/pkg/auth"
 	"github.com/richard-to/go-poker/pkg/server"
+	"github.com/richard-to/go-poker/pkg/storage"
 )
 
+// App Engine gives instances 30 seconds to shut down, so leave a little time to close connections
+const defaultShutdownTimeout = 20 * time.Second
+
 func main() {
 	// Set a random seed to get random card shuffle
 	rand.Seed(time.Now().UnixNano())
@@ -27,20 +38,214 @@ func main() {
 	godotenv.Load(".env." + env)
 	godotenv.Load()
 
-	// Start websocket hub and game state manager
-	hub := server.NewHub()
-	gameState := server.NewGameState()
+	// How long to wait for the hand in progress to finish when shutting down
+	shutdownTimeout := defaultShutdownTimeout
+	if timeout := os.Getenv("POKER_SHUTDOWN_TIMEOUT"); timeout != "" {
+		var err error
+		shutdownTimeout, err = time.ParseDuration(timeout)
+		if err != nil {
+			log.Fatalf("invalid shutdown timeout: %v", err)
+		}
+	}
+
+	tableConfig, err := server.LoadTableConfig()
+	if err != nil {
+		log.Fatalf("invalid table config: %v", err)
+	}
+
+	// Everything is kept in memory unless a database file is set
+	var store storage.Store = storage.NewMemoryStore()
+	if dbPath := os.Getenv("POKER_DB_PATH"); dbPath != "" {
+		store, err = storage.OpenBoltStore(dbPath)
+		if err != nil {
+			log.Fatalf("could not open database: %v", err)
+		}
+	}
+	defer store.Close()
+
+	if err := store.SaveTableConfig(tableConfig.Name, tableConfig); err != nil {
+		log.Fatalf("could not save table config: %v", err)
+	}
 
+	// Player accounts. If no secret is set, players will need to log in again after a restart.
+	// Admins in POKER_ADMINS need to register before the server starts to get admin access.
+	accounts, err := auth.NewAccounts(
+		store.Users(),
+		[]byte(os.Getenv("POKER_AUTH_SECRET")),
+		strings.Split(os.Getenv("POKER_ADMINS"), ","),
+	)
+	if err != nil {
+		log.Fatalf("could not set up accounts: %v", err)
+	}
+
+	// Players' IP addresses are only taken from the proxy headers behind a trusted proxy
+	if err := server.LoadTrustedProxies(); err != nil {
+		log.Fatalf("invalid trusted proxies: %v", err)
+	}
+
+	// Banned accounts and IP addresses can't use the API or connect to the tables
+	bans, err := server.NewBanList(store)
+	if err != nil {
+		log.Fatalf("could not load bans: %v", err)
+	}
+
+	// The deploy only sends traffic once the tables have been restored
+	health := server.NewHealth(store)
+
+	// Start websocket hub and game state manager. The hand that was in progress when the
+	// server stopped is resumed.
+	hub := server.NewHub()
 	go hub.Run()
 
+	gameState, err := server.RecoverGameState(hub, tableConfig, store)
+	if err != nil {
+		log.Fatalf("could not recover game state: %v", err)
+	}
+
+	// Tables are looked up by name. Multi-table tournaments add their tables once they start.
+	registry := server.NewTableRegistry()
+	registry.AddTable(hub, gameState)
+
+	// Private tables stay open until their host closes them
+	if err := server.RestorePrivateTables(registry, store); err != nil {
+		log.Fatalf("could not restore private tables: %v", err)
+	}
+
+	// Scheduled tournaments keep their registrations across restarts
+	if err := server.RestoreScheduledTournaments(registry, store); err != nil {
+		log.Fatalf("could not restore scheduled tournaments: %v", err)
+	}
+
+	mttConfig, err := server.LoadMultiTableTournamentConfig()
+	if err != nil {
+		log.Fatalf("invalid multi-table tournament config: %v", err)
+	}
+	if mttConfig != nil && mttConfig.StartAt.IsZero() == false {
+		// The tournament was already restored if it was scheduled before the restart
+		if registry.GetTournament(mttConfig.Name) == nil {
+			if _, err := server.ScheduleTournament(*mttConfig, registry, store); err != nil {
+				log.Printf("could not schedule multi-table tournament: %v", err)
+			}
+		}
+	} else if mttConfig != nil {
+		mtt, err := server.NewMultiTableTournament(*mttConfig, registry, store)
+		if err != nil {
+			log.Fatalf("could not create multi-table tournament: %v", err)
+		}
+		if err := registry.AddTournament(mtt); err != nil {
+			log.Fatalf("could not create multi-table tournament: %v", err)
+		}
+	}
+
+	// The lobby lists the tables and tournaments for players who are choosing where to play
+	lobby := server.NewLobby(registry, time.Second)
+	go lobby.Run()
+
 	if env == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 	r := gin.Default()
 
+	// Health endpoints. App Engine reserves paths ending in z, so /healthz can't be used.
+	r.GET("/api/health", func(c *gin.Context) {
+		server.ServeHealth(c.Writer, c.Request)
+	})
+	r.GET("/api/ready", func(c *gin.Context) {
+		server.ServeReady(health, c.Writer, c.Request)
+	})
+
+	r.Use(func(c *gin.Context) {
+		if err := server.CheckBan(bans, accounts, c.Request); err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		}
+	})
+
 	// Websocket endpoint
 	r.GET("/ws", func(c *gin.Context) {
-		server.ServeWs(hub, gameState, c.Writer, c.Request)
+		server.ServeTableWs(registry, accounts, c.Writer, c.Request)
+	})
+	r.GET("/ws/lobby", func(c *gin.Context) {
+		server.ServeLobbyWs(lobby, accounts, c.Writer, c.Request)
+	})
+
+	// Account endpoints
+	r.POST("/api/register", func(c *gin.Context) {
+		server.ServeRegister(accounts, c.Writer, c.Request)
+	})
+	r.POST("/api/login", func(c *gin.Context) {
+		server.ServeLogin(accounts, c.Writer, c.Request)
+	})
+
+	r.GET("/api/bankroll", func(c *gin.Context) {
+		server.ServeBankroll(store, accounts, c.Writer, c.Request)
+	})
+
+	// Hand history endpoints
+	r.GET("/api/hands", func(c *gin.Context) {
+		server.ServeHandList(store, accounts, c.Writer, c.Request)
+	})
+	r.GET("/api/hands/:id/replay", func(c *gin.Context) {
+		server.ServeHandReplay(store, accounts, c.Writer, c.Request, c.Param("id"))
+	})
+
+	r.GET("/api/stats", func(c *gin.Context) {
+		server.ServeStats(store, accounts, c.Writer, c.Request)
+	})
+	r.GET("/api/graph", func(c *gin.Context) {
+		server.ServeGraph(store, accounts, c.Writer, c.Request)
+	})
+
+	// Rating endpoints
+	r.GET("/api/leaderboard", func(c *gin.Context) {
+		server.ServeLeaderboard(store, accounts, c.Writer, c.Request)
+	})
+	r.GET("/api/ratings/history", func(c *gin.Context) {
+		server.ServeRatingHistory(store, accounts, c.Writer, c.Request)
+	})
+
+	// Private table endpoints
+	r.POST("/api/tables", func(c *gin.Context) {
+		server.ServeCreatePrivateTable(registry, store, accounts, c.Writer, c.Request)
+	})
+
+	// Tournament endpoints
+	r.GET("/api/tournaments", func(c *gin.Context) {
+		server.ServeTournamentList(registry, accounts, c.Writer, c.Request)
+	})
+	r.GET("/api/tournaments/:name", func(c *gin.Context) {
+		server.ServeTournament(registry, accounts, c.Writer, c.Request, c.Param("name"))
+	})
+	r.POST("/api/tournaments/:name/register", func(c *gin.Context) {
+		server.ServeTournamentRegister(registry, accounts, c.Writer, c.Request, c.Param("name"))
+	})
+	r.POST("/api/tournaments/:name/unregister", func(c *gin.Context) {
+		server.ServeTournamentUnregister(registry, accounts, c.Writer, c.Request, c.Param("name"))
+	})
+	r.POST("/api/tournaments/:name/rebuy", func(c *gin.Context) {
+		server.ServeTournamentRebuy(registry, accounts, c.Writer, c.Request, c.Param("name"))
+	})
+
+	// Admin endpoints
+	r.GET("/api/admin/tables", func(c *gin.Context) {
+		server.ServeAdminTables(registry, accounts, c.Writer, c.Request)
+	})
+	r.GET("/api/admin/tables/:name", func(c *gin.Context) {
+		server.ServeAdminTable(registry, accounts, c.Writer, c.Request, c.Param("name"))
+	})
+	r.POST("/api/admin/tables/:name/end-hand", func(c *gin.Context) {
+		server.ServeAdminEndHand(registry, accounts, c.Writer, c.Request, c.Param("name"))
+	})
+	r.POST("/api/admin/players/:username/chips", func(c *gin.Context) {
+		server.ServeAdminAdjustChips(store, accounts, c.Writer, c.Request, c.Param("username"))
+	})
+	r.GET("/api/admin/bans", func(c *gin.Context) {
+		server.ServeAdminBans(bans, accounts, c.Writer, c.Request)
+	})
+	r.POST("/api/admin/bans", func(c *gin.Context) {
+		server.ServeAdminBan(bans, registry, accounts, c.Writer, c.Request)
+	})
+	r.DELETE("/api/admin/bans/:kind/:value", func(c *gin.Context) {
+		server.ServeAdminUnban(bans, accounts, c.Writer, c.Request, c.Param("kind"), c.Param("value"))
 	})
 
 	// Serve static react build directory
@@ -53,5 +258,31 @@ func main() {
 	// Google App Engine will set the port automatically
 	port := os.Getenv("PORT")
 
-	r.Run(":" + port)
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: r,
+	}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("could not start server: %v", err)
+		}
+	}()
+	health.SetReady(true)
+
+	// Wait for a redeploy or Ctrl+C. The hands in progress at every table are finished before exiting.
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Println("shutting down")
+	health.SetReady(false)
+
+	// No new websocket connections are accepted while the tables are closed. Shutdown does not
+	// wait for the open websocket connections, which are closed by the tables.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("could not shut down server: %v", err)
+	}
+
+	server.ShutdownTables(registry, shutdownTimeout)
 }
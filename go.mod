@@ -10,5 +10,7 @@ require (
 	github.com/kr/pretty v0.1.0 // indirect
 	github.com/onsi/ginkgo v1.15.0
 	github.com/onsi/gomega v1.10.5
+	go.etcd.io/bbolt v1.3.5
+	golang.org/x/crypto v0.0.0-20210220033148-5ea612d1eb83
 	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
 )
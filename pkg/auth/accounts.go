@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const defaultTokenTTL time.Duration = 24 * time.Hour
+
+const minPasswordLength int = 8
+const maxPasswordLength int = 72 // bcrypt ignores anything past 72 bytes
+const minUsernameLength int = 3
+const maxUsernameLength int = 20
+
+// Usernames used by the server that players can't register
+var reservedUsernames = []string{"system", "admin"}
+
+// Accounts registers and logs in players.
+type Accounts struct {
+	admins   map[string]bool // Lower case usernames of admins
+	tokens   *TokenSigner
+	TokenTTL time.Duration
+	users    UserStore
+}
+
+// NewAccounts creates an account service.
+//
+// Users in the admins list are given admin access when they log in. Admins must already be
+// registered, so that no one can claim admin access by registering an admin's username first.
+// Removing a user from the list takes away their admin access once their token expires. If the
+// secret is empty, a random one is generated, so tokens will not survive a restart.
+func NewAccounts(users UserStore, secret []byte, admins []string) (*Accounts, error) {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+	}
+	a := &Accounts{
+		admins:   make(map[string]bool),
+		tokens:   NewTokenSigner(secret),
+		TokenTTL: defaultTokenTTL,
+		users:    users,
+	}
+	for _, username := range admins {
+		username = strings.TrimSpace(username)
+		if username == "" {
+			continue
+		}
+		if _, err := users.Get(username); err != nil {
+			log.Printf("Admin %s is not registered, so they will not get admin access", username)
+			continue
+		}
+		a.admins[strings.ToLower(username)] = true
+	}
+	return a, nil
+}
+
+// Register creates a new account and returns a session token.
+func (a *Accounts) Register(username string, password string) (string, error) {
+	username = strings.TrimSpace(username)
+	if err := validateUsername(username); err != nil {
+		return "", err
+	}
+	if len(password) < minPasswordLength {
+		return "", fmt.Errorf("Password must be at least %d characters", minPasswordLength)
+	}
+	if len(password) > maxPasswordLength {
+		return "", fmt.Errorf("Password must be at most %d characters", maxPasswordLength)
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	u := &User{
+		CreatedAt:    time.Now().UTC(),
+		PasswordHash: passwordHash,
+		Username:     username,
+	}
+	if err := a.users.Create(u); err != nil {
+		return "", err
+	}
+	return a.createToken(u)
+}
+
+// Login checks the password and returns a session token.
+func (a *Accounts) Login(username string, password string) (string, error) {
+	u, err := a.users.Get(strings.TrimSpace(username))
+	if err != nil {
+		// Hash anyway so that the response time does not reveal which usernames exist
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return "", ErrInvalidLogin
+	}
+	if err := bcrypt.CompareHashAndPassword(u.PasswordHash, []byte(password)); err != nil {
+		return "", ErrInvalidLogin
+	}
+	return a.createToken(u)
+}
+
+// Verify checks a session token and returns the identity stored in it.
+func (a *Accounts) Verify(token string) (Claims, error) {
+	return a.tokens.Verify(token)
+}
+
+func (a *Accounts) createToken(u *User) (string, error) {
+	return a.tokens.Sign(Claims{
+		ExpiresAt: time.Now().Add(a.TokenTTL).Unix(),
+		IsAdmin:   a.admins[strings.ToLower(u.Username)],
+		Username:  u.Username,
+	})
+}
+
+func validateUsername(username string) error {
+	if len(username) < minUsernameLength || len(username) > maxUsernameLength {
+		return fmt.Errorf("Username must be between %d and %d characters", minUsernameLength, maxUsernameLength)
+	}
+	for _, r := range username {
+		if unicode.IsLetter(r) == false && unicode.IsDigit(r) == false && r != '_' && r != '-' {
+			return fmt.Errorf("Username can only contain letters, numbers, underscores and dashes")
+		}
+	}
+	for _, reserved := range reservedUsernames {
+		if strings.EqualFold(username, reserved) {
+			return ErrUsernameTaken
+		}
+	}
+	return nil
+}
+
+// ErrInvalidLogin is returned when the username or password is wrong.
+var ErrInvalidLogin = fmt.Errorf("Invalid username or password")
+
+// Hash of a random password used to keep failed logins for unknown users slow
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
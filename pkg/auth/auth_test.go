@@ -0,0 +1,147 @@
+package auth_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+var _ = Describe("Accounts", func() {
+	var accounts *auth.Accounts
+	var users *auth.MemoryUserStore
+
+	BeforeEach(func() {
+		var err error
+		users = auth.NewMemoryUserStore()
+		accounts, err = auth.NewAccounts(users, []byte("secret"), []string{})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("registers a player and returns a session token", func() {
+		token, err := accounts.Register(" alice ", "password123")
+		Expect(err).NotTo(HaveOccurred())
+
+		claims, err := accounts.Verify(token)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claims.Username).To(Equal("alice"))
+		Expect(claims.IsAdmin).To(BeFalse())
+
+		u, err := users.Get("alice")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(u.PasswordHash)).NotTo(ContainSubstring("password123"))
+	})
+
+	It("does not allow the same username twice", func() {
+		_, err := accounts.Register("alice", "password123")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = accounts.Register("ALICE", "password123")
+		Expect(err).To(Equal(auth.ErrUsernameTaken))
+	})
+
+	It("rejects invalid usernames and short passwords", func() {
+		_, err := accounts.Register("al", "password123")
+		Expect(err).To(HaveOccurred())
+		_, err = accounts.Register("alice bob", "password123")
+		Expect(err).To(HaveOccurred())
+		_, err = accounts.Register("System", "password123")
+		Expect(err).To(Equal(auth.ErrUsernameTaken))
+		_, err = accounts.Register(storage.HouseAccount, "password123")
+		Expect(err).To(HaveOccurred())
+		_, err = accounts.Register("alice", "short")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("logs in with the correct password", func() {
+		_, err := accounts.Register("alice", "password123")
+		Expect(err).NotTo(HaveOccurred())
+
+		token, err := accounts.Login("alice", "password123")
+		Expect(err).NotTo(HaveOccurred())
+		claims, err := accounts.Verify(token)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claims.Username).To(Equal("alice"))
+
+		_, err = accounts.Login("alice", "wrong-password")
+		Expect(err).To(Equal(auth.ErrInvalidLogin))
+		_, err = accounts.Login("bob", "password123")
+		Expect(err).To(Equal(auth.ErrInvalidLogin))
+	})
+
+	It("gives admin access to configured admins", func() {
+		_, err := accounts.Register("boss", "password123")
+		Expect(err).NotTo(HaveOccurred())
+
+		withAdmins, err := auth.NewAccounts(users, []byte("secret"), []string{"Boss"})
+		Expect(err).NotTo(HaveOccurred())
+		token, err := withAdmins.Login("boss", "password123")
+		Expect(err).NotTo(HaveOccurred())
+		claims, err := withAdmins.Verify(token)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claims.IsAdmin).To(BeTrue())
+
+		// Admin access is taken away once the player is removed from the list
+		token, err = accounts.Login("boss", "password123")
+		Expect(err).NotTo(HaveOccurred())
+		claims, err = accounts.Verify(token)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claims.IsAdmin).To(BeFalse())
+	})
+
+	It("does not give admin access to players who register an admin's username", func() {
+		withAdmins, err := auth.NewAccounts(users, []byte("secret"), []string{"Boss"})
+		Expect(err).NotTo(HaveOccurred())
+		token, err := withAdmins.Register("boss", "password123")
+		Expect(err).NotTo(HaveOccurred())
+		claims, err := withAdmins.Verify(token)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claims.IsAdmin).To(BeFalse())
+
+		token, err = withAdmins.Login("boss", "password123")
+		Expect(err).NotTo(HaveOccurred())
+		claims, err = withAdmins.Verify(token)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claims.IsAdmin).To(BeFalse())
+	})
+})
+
+var _ = Describe("TokenSigner", func() {
+	signer := auth.NewTokenSigner([]byte("secret"))
+
+	It("verifies signed tokens", func() {
+		token, err := signer.Sign(auth.Claims{Username: "alice", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+		Expect(err).NotTo(HaveOccurred())
+		claims, err := signer.Verify(token)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claims.Username).To(Equal("alice"))
+	})
+
+	It("rejects tampered tokens", func() {
+		token, err := signer.Sign(auth.Claims{Username: "alice", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+		Expect(err).NotTo(HaveOccurred())
+		forged, err := signer.Sign(auth.Claims{Username: "bob", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+		Expect(err).NotTo(HaveOccurred())
+
+		parts := strings.Split(token, ".")
+		forgedParts := strings.Split(forged, ".")
+		_, err = signer.Verify(forgedParts[0] + "." + parts[1])
+		Expect(err).To(Equal(auth.ErrInvalidToken))
+
+		_, err = auth.NewTokenSigner([]byte("other-secret")).Verify(token)
+		Expect(err).To(Equal(auth.ErrInvalidToken))
+
+		_, err = signer.Verify("not-a-token")
+		Expect(err).To(Equal(auth.ErrInvalidToken))
+	})
+
+	It("rejects expired tokens", func() {
+		token, err := signer.Sign(auth.Claims{Username: "alice", ExpiresAt: time.Now().Add(-time.Minute).Unix()})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = signer.Verify(token)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Claims is the identity stored in a session token.
+type Claims struct {
+	ExpiresAt int64  `json:"exp"` // Unix time
+	IsAdmin   bool   `json:"admin"`
+	Username  string `json:"sub"`
+}
+
+// TokenSigner signs and verifies session tokens.
+//
+// A token is the base64 encoded JSON claims followed by an HMAC-SHA256 signature of
+// the claims. Tokens can't be revoked, so they should expire fairly quickly.
+type TokenSigner struct {
+	secret []byte
+}
+
+// NewTokenSigner creates a token signer with the given secret.
+func NewTokenSigner(secret []byte) *TokenSigner {
+	return &TokenSigner{secret: secret}
+}
+
+// Sign creates a signed token for the claims.
+func (s *TokenSigner) Sign(claims Claims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
+	return encodedPayload + "." + base64.RawURLEncoding.EncodeToString(s.sign(encodedPayload)), nil
+}
+
+// Verify checks the token signature and expiry and returns the claims.
+func (s *TokenSigner) Verify(token string) (Claims, error) {
+	var claims Claims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return claims, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || hmac.Equal(signature, s.sign(parts[0])) == false {
+		return claims, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return claims, ErrInvalidToken
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, ErrInvalidToken
+	}
+
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return claims, fmt.Errorf("Session has expired")
+	}
+	return claims, nil
+}
+
+func (s *TokenSigner) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// ErrInvalidToken is returned when a token is malformed or has a bad signature.
+var ErrInvalidToken = fmt.Errorf("Invalid session token")
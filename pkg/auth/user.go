@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// User is a registered player account.
+type User struct {
+	CreatedAt    time.Time `json:"createdAt"`
+	PasswordHash []byte    `json:"passwordHash"`
+	Username     string    `json:"username"`
+}
+
+// UserStore stores player accounts.
+type UserStore interface {
+	Create(u *User) error
+	Get(username string) (*User, error)
+}
+
+// MemoryUserStore keeps player accounts in memory.
+type MemoryUserStore struct {
+	mu    sync.RWMutex
+	users map[string]*User // Keyed by the lower case username
+}
+
+// NewMemoryUserStore creates an empty in-memory user store.
+func NewMemoryUserStore() *MemoryUserStore {
+	return &MemoryUserStore{
+		users: make(map[string]*User),
+	}
+}
+
+// Create adds a new user. Usernames are unique regardless of case.
+func (s *MemoryUserStore) Create(u *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := strings.ToLower(u.Username)
+	if _, ok := s.users[key]; ok {
+		return ErrUsernameTaken
+	}
+	s.users[key] = u
+	return nil
+}
+
+// Get gets a user by username.
+func (s *MemoryUserStore) Get(username string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.users[strings.ToLower(username)]
+	if ok == false {
+		return nil, ErrUserNotFound
+	}
+	return u, nil
+}
+
+// ErrUsernameTaken is returned when registering a username that already exists.
+var ErrUsernameTaken = fmt.Errorf("Username has already been taken")
+
+// ErrUserNotFound is returned when a user does not exist.
+var ErrUserNotFound = fmt.Errorf("User not found")
@@ -0,0 +1,302 @@
+package history
+
+import (
+	"time"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+// Street is a round of betting in a hand.
+type Street string
+
+// Streets
+const (
+	StreetPreflop  Street = "Preflop"
+	StreetFlop     Street = "Flop"
+	StreetTurn     Street = "Turn"
+	StreetRiver    Street = "River"
+	StreetShowdown Street = "Showdown"
+)
+
+// ActionType is the type of action a player took.
+type ActionType string
+
+// Action types
+const (
+	ActionPostSmallBlind ActionType = "post-sb"
+	ActionPostBigBlind   ActionType = "post-bb"
+	ActionPostAnte       ActionType = "post-ante"
+	ActionFold           ActionType = "fold"
+	ActionCheck          ActionType = "check"
+	ActionCall           ActionType = "call"
+	ActionBet            ActionType = "bet"
+	ActionRaise          ActionType = "raise"
+	ActionShow           ActionType = "show"
+	ActionMuck           ActionType = "muck"
+)
+
+// HandHistory is a record of everything that happened in a hand.
+type HandHistory struct {
+	ID          string        `json:"id"`
+	Seed        int64         `json:"seed,omitempty"` // Only kept for admins. The deck can be shuffled again from it.
+	TableName   string        `json:"tableName"`
+	TableSize   int           `json:"tableSize"`
+	StartedAt   time.Time     `json:"startedAt"`
+	EndedAt     time.Time     `json:"endedAt"`
+	SmallBlind  int           `json:"smallBlind"`
+	BigBlind    int           `json:"bigBlind"`
+	Ante        int           `json:"ante,omitempty"`
+	DealerSeat  int           `json:"dealerSeat"`
+	Seats       []Seat        `json:"seats"`
+	Actions     []Action      `json:"actions"`
+	Board       poker.Board   `json:"board"`
+	Boards      []poker.Board `json:"boards,omitempty"` // Only used when the board is run more than once
+	UncalledBet *UncalledBet  `json:"uncalledBet,omitempty"`
+	Pots        []Pot         `json:"pots"`
+	Rake        int           `json:"rake"`
+	Bounties    []Bounty      `json:"bounties,omitempty"` // Only used in knockout tournaments
+}
+
+// Seat is a player who was dealt into the hand.
+type Seat struct {
+	Number    int            `json:"number"` // Starts at 1
+	PlayerID  string         `json:"playerID"`
+	Name      string         `json:"name"`
+	Chips     int            `json:"chips"` // Chips at the start of the hand
+	HoleCards [2]*poker.Card `json:"holeCards"`
+}
+
+// Action is an action taken by a player.
+//
+// The amount is the number of chips the player added to the pot with the action. For
+// raises, the raise to amount is the total amount the player has bet on the street.
+type Action struct {
+	Street   Street        `json:"street"`
+	PlayerID string        `json:"playerID"`
+	Type     ActionType    `json:"type"`
+	Amount   int           `json:"amount,omitempty"`
+	RaiseTo  int           `json:"raiseTo,omitempty"`
+	IsAllIn  bool          `json:"isAllIn,omitempty"`
+	Cards    []*poker.Card `json:"cards,omitempty"`
+}
+
+// IsForced checks if the action was a blind or ante that the player had to post.
+func (a Action) IsForced() bool {
+	return a.Type == ActionPostSmallBlind || a.Type == ActionPostBigBlind || a.Type == ActionPostAnte
+}
+
+// UncalledBet is the part of a bet that no other player matched. It is returned to the player.
+type UncalledBet struct {
+	PlayerID string `json:"playerID"`
+	Amount   int    `json:"amount"`
+}
+
+// Pot is a main pot or side pot that was awarded at the end of the hand.
+type Pot struct {
+	Number  int      `json:"number"` // The main pot is 0
+	Amount  int      `json:"amount"` // Total chips in the pot, including the rake
+	Rake    int      `json:"rake"`
+	Winners []Winner `json:"winners"`
+}
+
+// Winner is a player who won chips from a pot.
+type Winner struct {
+	PlayerID  string   `json:"playerID"`
+	Amount    int      `json:"amount"`
+	HandRank  string   `json:"handRank,omitempty"`  // Empty if the player won without a showdown or the board was run more than once
+	HandRanks []string `json:"handRanks,omitempty"` // Hand on each board when the board is run more than once. Empty for boards the player did not win.
+}
+
+// Bounty is a bounty that a player won for knocking out another player.
+//
+// In progressive knockout tournaments, part of the bounty is added to the winner's own bounty
+// instead of being paid out.
+type Bounty struct {
+	PlayerID     string `json:"playerID"`
+	EliminatedID string `json:"eliminatedID"`
+	Amount       int    `json:"amount"`             // Paid to the player
+	Increase     int    `json:"increase,omitempty"` // Added to the player's own bounty
+	Total        int    `json:"total,omitempty"`    // The player's own bounty after the increase
+}
+
+// NewHandHistory creates a hand history for a hand that is about to be dealt.
+//
+// Hole cards are recorded for all players. Use ForViewer to hide the hole cards that a
+// viewer is not allowed to see.
+func NewHandHistory(id string, seed int64, tableName string, t *poker.Table) *HandHistory {
+	h := &HandHistory{
+		ID:         id,
+		Seed:       seed,
+		TableName:  tableName,
+		TableSize:  t.Seats.Len(),
+		StartedAt:  time.Now().UTC(),
+		SmallBlind: t.MinBet / 2,
+		BigBlind:   t.MinBet,
+		Ante:       t.Ante,
+		Seats:      make([]Seat, 0),
+		Actions:    make([]Action, 0),
+		Pots:       make([]Pot, 0),
+	}
+
+	seat := t.Seats
+	for i := 0; i < seat.Len(); i++ {
+		if seat == t.Dealer {
+			h.DealerSeat = i + 1
+		}
+		if seat.Player.Status == poker.PlayerActive {
+			h.Seats = append(h.Seats, Seat{
+				Number:    i + 1,
+				PlayerID:  seat.Player.ID,
+				Name:      seat.Player.Name,
+				Chips:     seat.Player.Chips,
+				HoleCards: seat.Player.HoleCards,
+			})
+		}
+		seat = seat.Next()
+	}
+
+	return h
+}
+
+// AddAction records an action taken by a player.
+func (h *HandHistory) AddAction(a Action) {
+	h.Actions = append(h.Actions, a)
+}
+
+// GetSeat gets the seat of a player in the hand.
+func (h *HandHistory) GetSeat(playerID string) *Seat {
+	for i := range h.Seats {
+		if h.Seats[i].PlayerID == playerID {
+			return &h.Seats[i]
+		}
+	}
+	return nil
+}
+
+// GetSeatByName gets the seat of a player by name. Nil is returned if the player was not in the hand.
+func (h *HandHistory) GetSeatByName(name string) *Seat {
+	if name == "" {
+		return nil
+	}
+	for i := range h.Seats {
+		if h.Seats[i].Name == name {
+			return &h.Seats[i]
+		}
+	}
+	return nil
+}
+
+// HasPlayer checks if a player with the given name was dealt into the hand.
+func (h *HandHistory) HasPlayer(name string) bool {
+	for _, seat := range h.Seats {
+		if seat.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GetShownCards gets the hole cards a player showed at showdown or after the hand was over.
+// Nil is returned if the player did not show any cards.
+func (h *HandHistory) GetShownCards(playerID string) []*poker.Card {
+	var cards []*poker.Card
+	for _, a := range h.Actions {
+		if a.PlayerID == playerID && a.Type == ActionShow {
+			cards = append(cards, a.Cards...)
+		}
+	}
+	return cards
+}
+
+// GetActionsByStreet gets the actions that were taken on a street.
+func (h *HandHistory) GetActionsByStreet(street Street) []Action {
+	actions := make([]Action, 0)
+	for _, a := range h.Actions {
+		if a.Street == street {
+			actions = append(actions, a)
+		}
+	}
+	return actions
+}
+
+// GetTotalPot gets the total number of chips that were awarded, including the rake.
+func (h *HandHistory) GetTotalPot() int {
+	total := 0
+	for _, pot := range h.Pots {
+		total += pot.Amount
+	}
+	return total
+}
+
+// GetWinnings gets the number of chips a player won.
+func (h *HandHistory) GetWinnings(playerID string) int {
+	winnings := 0
+	for _, pot := range h.Pots {
+		for _, winner := range pot.Winners {
+			if winner.PlayerID == playerID {
+				winnings += winner.Amount
+			}
+		}
+	}
+	return winnings
+}
+
+// GetChipsPutIn gets the number of chips a player put into the pot, not counting an uncalled bet.
+func (h *HandHistory) GetChipsPutIn(playerID string) int {
+	chips := 0
+	for _, a := range h.Actions {
+		if a.PlayerID == playerID {
+			chips += a.Amount
+		}
+	}
+	if h.UncalledBet != nil && h.UncalledBet.PlayerID == playerID {
+		chips -= h.UncalledBet.Amount
+	}
+	return chips
+}
+
+// GetNetWinnings gets the number of chips a player won or lost in the hand.
+func (h *HandHistory) GetNetWinnings(playerID string) int {
+	return h.GetWinnings(playerID) - h.GetChipsPutIn(playerID)
+}
+
+// ForViewer creates a copy of the hand history that only contains the hole cards the
+// viewer is allowed to see.
+//
+// Viewers can see their own hole cards and the hole cards that were shown at showdown.
+// If showAll is set, then all hole cards are kept. Otherwise the seed is removed too,
+// since the whole deck can be shuffled again from it.
+func (h *HandHistory) ForViewer(viewerName string, showAll bool) *HandHistory {
+	redacted := *h
+	if showAll == false {
+		redacted.Seed = 0
+	}
+	redacted.Seats = make([]Seat, len(h.Seats))
+	for i, seat := range h.Seats {
+		redacted.Seats[i] = seat
+		if showAll == false && seat.Name != viewerName {
+			redacted.Seats[i].HoleCards = getShownHoleCards(seat.HoleCards, h.GetShownCards(seat.PlayerID))
+		}
+	}
+	return &redacted
+}
+
+// getShownHoleCards keeps the hole cards that were shown. Players can show one of their cards.
+func getShownHoleCards(holeCards [2]*poker.Card, shown []*poker.Card) [2]*poker.Card {
+	kept := [2]*poker.Card{}
+	for i, holeCard := range holeCards {
+		if holeCard != nil && containsCard(shown, holeCard) {
+			kept[i] = holeCard
+		}
+	}
+	return kept
+}
+
+func containsCard(cards []*poker.Card, card *poker.Card) bool {
+	for _, c := range cards {
+		if c.Rank == card.Rank && c.Suit == card.Suit {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,308 @@
+package history_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/history"
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+// newTestHandHistory creates a hand where Player 1 raises preflop and wins on the turn
+func newTestHandHistory() *history.HandHistory {
+	ps := []*poker.Player{
+		{
+			ID:     "1",
+			Name:   "Player 1",
+			Chips:  100,
+			Status: poker.PlayerActive,
+			HoleCards: [2]*poker.Card{
+				{Rank: poker.Ace, Suit: poker.Hearts},
+				{Rank: poker.King, Suit: poker.Diamonds},
+			},
+		},
+		{
+			ID:     "2",
+			Name:   "Player 2",
+			Chips:  80,
+			Status: poker.PlayerActive,
+			HoleCards: [2]*poker.Card{
+				{Rank: poker.Seven, Suit: poker.Clubs},
+				{Rank: poker.Two, Suit: poker.Spades},
+			},
+		},
+		{
+			ID:     "3",
+			Name:   "Player 3",
+			Chips:  120,
+			Status: poker.PlayerActive,
+			HoleCards: [2]*poker.Card{
+				{Rank: poker.Queen, Suit: poker.Clubs},
+				{Rank: poker.Queen, Suit: poker.Hearts},
+			},
+		},
+		{
+			ID:     "4",
+			Status: poker.PlayerVacated,
+		},
+	}
+
+	seats := poker.NewSeat(len(ps))
+	for _, p := range ps {
+		seats.Player = p
+		seats = seats.Next()
+	}
+
+	t := &poker.Table{
+		Dealer: seats,
+		MinBet: 2,
+		Pot:    poker.NewPot(),
+		Seats:  seats,
+	}
+
+	h := history.NewHandHistory("1001", 42, "Main", t)
+	h.AddAction(history.Action{Street: history.StreetPreflop, PlayerID: "2", Type: history.ActionPostSmallBlind, Amount: 1})
+	h.AddAction(history.Action{Street: history.StreetPreflop, PlayerID: "3", Type: history.ActionPostBigBlind, Amount: 2})
+	h.AddAction(history.Action{Street: history.StreetPreflop, PlayerID: "1", Type: history.ActionRaise, Amount: 6, RaiseTo: 6})
+	h.AddAction(history.Action{Street: history.StreetPreflop, PlayerID: "2", Type: history.ActionFold})
+	h.AddAction(history.Action{Street: history.StreetPreflop, PlayerID: "3", Type: history.ActionCall, Amount: 4})
+	h.AddAction(history.Action{Street: history.StreetFlop, PlayerID: "3", Type: history.ActionCheck})
+	h.AddAction(history.Action{Street: history.StreetFlop, PlayerID: "1", Type: history.ActionBet, Amount: 10, RaiseTo: 10})
+	h.AddAction(history.Action{Street: history.StreetFlop, PlayerID: "3", Type: history.ActionCall, Amount: 10})
+	h.AddAction(history.Action{Street: history.StreetTurn, PlayerID: "3", Type: history.ActionCheck})
+	h.AddAction(history.Action{Street: history.StreetTurn, PlayerID: "1", Type: history.ActionBet, Amount: 20, RaiseTo: 20})
+	h.AddAction(history.Action{Street: history.StreetTurn, PlayerID: "3", Type: history.ActionFold})
+
+	h.Board = poker.Board{
+		Flop: [3]*poker.Card{
+			{Rank: poker.Two, Suit: poker.Clubs},
+			{Rank: poker.Seven, Suit: poker.Diamonds},
+			{Rank: poker.Nine, Suit: poker.Hearts},
+		},
+		Turn: &poker.Card{Rank: poker.Jack, Suit: poker.Spades},
+	}
+	h.UncalledBet = &history.UncalledBet{PlayerID: "1", Amount: 20}
+	h.Pots = []history.Pot{
+		{
+			Number:  0,
+			Amount:  33,
+			Winners: []history.Winner{{PlayerID: "1", Amount: 33}},
+		},
+	}
+	return h
+}
+
+var _ = Describe("HandHistory", func() {
+	var h *history.HandHistory
+
+	BeforeEach(func() {
+		h = newTestHandHistory()
+	})
+
+	Describe("NewHandHistory", func() {
+		It("records the players dealt into the hand", func() {
+			Expect(h.TableSize).To(Equal(4))
+			Expect(h.DealerSeat).To(Equal(1))
+			Expect(h.SmallBlind).To(Equal(1))
+			Expect(h.BigBlind).To(Equal(2))
+			Expect(h.Seats).To(HaveLen(3))
+			Expect(h.Seats[1].Number).To(Equal(2))
+			Expect(h.Seats[1].Name).To(Equal("Player 2"))
+			Expect(h.Seats[1].Chips).To(Equal(80))
+			Expect(h.Seats[1].HoleCards[0].Rank).To(Equal(poker.Seven))
+		})
+	})
+
+	Describe("GetWinnings", func() {
+		It("sums the chips won from each pot", func() {
+			Expect(h.GetWinnings("1")).To(Equal(33))
+			Expect(h.GetWinnings("3")).To(Equal(0))
+		})
+	})
+
+	Describe("GetNetWinnings", func() {
+		It("does not count the uncalled bet as chips put in", func() {
+			Expect(h.GetChipsPutIn("1")).To(Equal(16))
+			Expect(h.GetNetWinnings("1")).To(Equal(17))
+			Expect(h.GetNetWinnings("2")).To(Equal(-1))
+			Expect(h.GetNetWinnings("3")).To(Equal(-16))
+		})
+	})
+
+	Describe("ForViewer", func() {
+		It("only keeps the viewer's hole cards", func() {
+			redacted := h.ForViewer("Player 1", false)
+			Expect(redacted.Seats[0].HoleCards[0]).NotTo(BeNil())
+			Expect(redacted.Seats[1].HoleCards[0]).To(BeNil())
+			Expect(redacted.Seats[2].HoleCards[0]).To(BeNil())
+			Expect(h.Seats[1].HoleCards[0]).NotTo(BeNil())
+		})
+
+		It("removes the seed", func() {
+			Expect(h.ForViewer("Player 1", false).Seed).To(BeZero())
+			Expect(h.ForViewer("", true).Seed).To(Equal(int64(42)))
+			Expect(h.Seed).To(Equal(int64(42)))
+		})
+
+		It("keeps hole cards that were shown", func() {
+			h.AddAction(history.Action{
+				Street:   history.StreetShowdown,
+				PlayerID: "3",
+				Type:     history.ActionShow,
+				Cards:    h.Seats[2].HoleCards[:],
+			})
+			redacted := h.ForViewer("", false)
+			Expect(redacted.Seats[0].HoleCards[0]).To(BeNil())
+			Expect(redacted.Seats[2].HoleCards[0]).NotTo(BeNil())
+		})
+
+		It("only keeps the hole card that was shown when one card was shown", func() {
+			h.AddAction(history.Action{
+				Street:   history.StreetShowdown,
+				PlayerID: "3",
+				Type:     history.ActionShow,
+				Cards:    h.Seats[2].HoleCards[1:],
+			})
+			redacted := h.ForViewer("", false)
+			Expect(redacted.Seats[2].HoleCards[0]).To(BeNil())
+			Expect(redacted.Seats[2].HoleCards[1]).To(Equal(h.Seats[2].HoleCards[1]))
+		})
+
+		It("keeps all hole cards if requested", func() {
+			redacted := h.ForViewer("", true)
+			for _, seat := range redacted.Seats {
+				Expect(seat.HoleCards[0]).NotTo(BeNil())
+			}
+		})
+	})
+
+	Describe("ExportPokerStars", func() {
+		It("exports the hand in the PokerStars format", func() {
+			text := history.ExportPokerStars(h, "Player 1")
+			Expect(text).To(HavePrefix("PokerStars Hand #1001: Hold'em No Limit (1/2) - "))
+			Expect(text).To(ContainSubstring("Table 'Main' 4-max Seat #1 is the button\n"))
+			Expect(text).To(ContainSubstring("Seat 2: Player 2 (80 in chips)\n"))
+			Expect(text).To(ContainSubstring("Player 2: posts small blind 1\nPlayer 3: posts big blind 2\n*** HOLE CARDS ***\n"))
+			Expect(text).To(ContainSubstring("Dealt to Player 1 [Ah Kd]\n"))
+			Expect(text).To(ContainSubstring("Player 1: raises 4 to 6\n"))
+			Expect(text).To(ContainSubstring("*** FLOP *** [2c 7d 9h]\n"))
+			Expect(text).To(ContainSubstring("Player 1: bets 10\n"))
+			Expect(text).To(ContainSubstring("*** TURN *** [2c 7d 9h] [Js]\n"))
+			Expect(text).To(ContainSubstring("Uncalled bet (20) returned to Player 1\n"))
+			Expect(text).To(ContainSubstring("Player 1 collected 33 from pot\n"))
+			Expect(text).To(ContainSubstring("Total pot 33 | Rake 0\n"))
+			Expect(text).To(ContainSubstring("Board [2c 7d 9h Js]\n"))
+			Expect(text).To(ContainSubstring("Seat 1: Player 1 (button) collected (33)\n"))
+			Expect(text).To(ContainSubstring("Seat 2: Player 2 (small blind) folded before Flop\n"))
+			Expect(text).To(ContainSubstring("Seat 3: Player 3 (big blind) folded on the Turn\n"))
+			Expect(text).NotTo(ContainSubstring("Qc"))
+			Expect(text).NotTo(ContainSubstring("*** SHOW DOWN ***"))
+		})
+
+		It("exports the showdown", func() {
+			h.Actions = h.Actions[:len(h.Actions)-1]
+			h.AddAction(history.Action{Street: history.StreetTurn, PlayerID: "3", Type: history.ActionCall, Amount: 20})
+			h.AddAction(history.Action{Street: history.StreetShowdown, PlayerID: "1", Type: history.ActionShow, Cards: h.Seats[0].HoleCards[:]})
+			h.AddAction(history.Action{Street: history.StreetShowdown, PlayerID: "3", Type: history.ActionMuck})
+			h.UncalledBet = nil
+			h.Pots[0].Amount = 73
+			h.Pots[0].Winners[0].Amount = 73
+
+			text := history.ExportPokerStars(h, "")
+			Expect(text).To(ContainSubstring("*** SHOW DOWN ***\nPlayer 1: shows [Ah Kd]\nPlayer 3: mucks hand\n"))
+			Expect(text).To(ContainSubstring("Seat 1: Player 1 (button) showed [Ah Kd] and won (73)\n"))
+			Expect(text).To(ContainSubstring("Seat 3: Player 3 (big blind) mucked\n"))
+			Expect(text).NotTo(ContainSubstring("Dealt to"))
+		})
+
+		It("exports a big blind that was posted short", func() {
+			h.Actions[1].Amount = 1
+			h.Actions[1].IsAllIn = true
+			text := history.ExportPokerStars(h, "")
+			Expect(text).To(ContainSubstring("Player 3: posts big blind 1 and is all-in\n"))
+			Expect(text).To(ContainSubstring("Player 1: raises 4 to 6\n"))
+		})
+
+		It("exports side pots", func() {
+			h.Pots = []history.Pot{
+				{Number: 0, Amount: 20, Winners: []history.Winner{{PlayerID: "1", Amount: 20}}},
+				{Number: 1, Amount: 13, Winners: []history.Winner{{PlayerID: "3", Amount: 13}}},
+			}
+			text := history.ExportPokerStars(h, "")
+			Expect(text).To(ContainSubstring("Player 1 collected 20 from main pot\n"))
+			Expect(text).To(ContainSubstring("Player 3 collected 13 from side pot-1\n"))
+			Expect(text).To(ContainSubstring("Total pot 33 Main pot 20. Side pot-1 13. | Rake 0\n"))
+		})
+
+		It("exports bounties", func() {
+			h.Bounties = []history.Bounty{
+				{PlayerID: "1", EliminatedID: "2", Amount: 5},
+				{PlayerID: "3", EliminatedID: "2", Amount: 2, Increase: 3, Total: 13},
+			}
+			text := history.ExportPokerStars(h, "")
+			Expect(text).To(ContainSubstring("Player 1 wins 5 for eliminating Player 2\n"))
+			Expect(text).To(ContainSubstring(
+				"Player 3 wins 2 for eliminating Player 2 and their own bounty increases by 3 to 13\n",
+			))
+		})
+	})
+
+	Describe("ExportOHH", func() {
+		It("exports the hand in the Open Hand History format", func() {
+			data, err := history.ExportOHH(h, "Player 1")
+			Expect(err).NotTo(HaveOccurred())
+
+			var ohh history.OHH
+			Expect(json.Unmarshal(data, &ohh)).To(Succeed())
+
+			Expect(ohh.OHH.GameNumber).To(Equal("1001"))
+			Expect(ohh.OHH.GameType).To(Equal("Holdem"))
+			Expect(ohh.OHH.BetLimit.BetType).To(Equal("NL"))
+			Expect(ohh.OHH.HeroPlayerID).To(Equal(1))
+			Expect(ohh.OHH.Players).To(HaveLen(3))
+			Expect(ohh.OHH.Players[2].StartingStack).To(Equal(120))
+
+			Expect(ohh.OHH.Rounds).To(HaveLen(3))
+			preflop := ohh.OHH.Rounds[0]
+			Expect(preflop.Street).To(Equal("Preflop"))
+			Expect(preflop.Actions[0].Action).To(Equal("Post SB"))
+			Expect(preflop.Actions[1].Action).To(Equal("Post BB"))
+			Expect(preflop.Actions[2].Action).To(Equal("Dealt Cards"))
+			Expect(preflop.Actions[2].Cards).To(Equal([]string{"Ah", "Kd"}))
+			Expect(preflop.Actions[3].Action).To(Equal("Raise"))
+			Expect(preflop.Actions[3].Amount).To(Equal(6))
+			Expect(ohh.OHH.Rounds[1].Cards).To(Equal([]string{"2c", "7d", "9h"}))
+			Expect(ohh.OHH.Rounds[2].Cards).To(Equal([]string{"Js"}))
+			Expect(ohh.OHH.Rounds[2].Actions[2].ActionNumber).To(Equal(12))
+
+			Expect(ohh.OHH.Pots).To(HaveLen(1))
+			Expect(ohh.OHH.Pots[0].Amount).To(Equal(33))
+			Expect(ohh.OHH.Pots[0].PlayerWins[0].PlayerID).To(Equal(1))
+		})
+	})
+})
+
+var _ = Describe("MemoryStore", func() {
+	It("saves and finds hand histories", func() {
+		store := history.NewMemoryStore()
+		h := newTestHandHistory()
+		Expect(store.Save(h)).To(Succeed())
+
+		saved, err := store.Get("1001")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(saved).To(Equal(h))
+
+		_, err = store.Get("1002")
+		Expect(err).To(HaveOccurred())
+
+		histories, err := store.ListByPlayer("Player 2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(histories).To(HaveLen(1))
+
+		histories, err = store.ListByPlayer("Player 5")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(histories).To(BeEmpty())
+	})
+})
@@ -0,0 +1,233 @@
+package history
+
+import (
+	"encoding/json"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+// Version of the Open Hand History standard that is exported
+const ohhSpecVersion string = "1.4.6"
+const ohhSiteName string = "go-poker"
+
+// OHH action names
+var ohhActions = map[ActionType]string{
+	ActionPostSmallBlind: "Post SB",
+	ActionPostBigBlind:   "Post BB",
+	ActionPostAnte:       "Post Ante",
+	ActionFold:           "Fold",
+	ActionCheck:          "Check",
+	ActionCall:           "Call",
+	ActionBet:            "Bet",
+	ActionRaise:          "Raise",
+	ActionShow:           "Shows Cards",
+	ActionMuck:           "Mucks Cards",
+}
+
+// OHH is the root object of an Open Hand History (https://hh-specs.handhistory.org).
+type OHH struct {
+	OHH OHHHand `json:"ohh"`
+}
+
+// OHHHand is a hand in the Open Hand History format.
+type OHHHand struct {
+	SpecVersion      string      `json:"spec_version"`
+	SiteName         string      `json:"site_name"`
+	GameNumber       string      `json:"game_number"`
+	StartDateUTC     string      `json:"start_date_utc"`
+	TableName        string      `json:"table_name"`
+	GameType         string      `json:"game_type"`
+	BetLimit         OHHBetLimit `json:"bet_limit"`
+	TableSize        int         `json:"table_size"`
+	DealerSeat       int         `json:"dealer_seat"`
+	SmallBlindAmount int         `json:"small_blind_amount"`
+	BigBlindAmount   int         `json:"big_blind_amount"`
+	AnteAmount       int         `json:"ante_amount"`
+	HeroPlayerID     int         `json:"hero_player_id,omitempty"`
+	Players          []OHHPlayer `json:"players"`
+	Rounds           []OHHRound  `json:"rounds"`
+	Pots             []OHHPot    `json:"pots"`
+}
+
+// OHHBetLimit is the betting structure of the hand.
+type OHHBetLimit struct {
+	BetType string `json:"bet_type"`
+}
+
+// OHHPlayer is a player dealt into the hand. Player IDs are the seat numbers.
+type OHHPlayer struct {
+	ID            int    `json:"id"`
+	Seat          int    `json:"seat"`
+	Name          string `json:"name"`
+	StartingStack int    `json:"starting_stack"`
+}
+
+// OHHRound is a street of the hand.
+type OHHRound struct {
+	ID      int         `json:"id"`
+	Street  string      `json:"street"`
+	Cards   []string    `json:"cards,omitempty"`
+	Actions []OHHAction `json:"actions"`
+}
+
+// OHHAction is an action in a round.
+type OHHAction struct {
+	ActionNumber int      `json:"action_number"`
+	PlayerID     int      `json:"player_id"`
+	Action       string   `json:"action"`
+	Amount       int      `json:"amount,omitempty"`
+	IsAllIn      bool     `json:"is_allin,omitempty"`
+	Cards        []string `json:"cards,omitempty"`
+}
+
+// OHHPot is a pot that was awarded.
+type OHHPot struct {
+	Number     int            `json:"number"`
+	Amount     int            `json:"amount"`
+	Rake       int            `json:"rake"`
+	PlayerWins []OHHPlayerWin `json:"player_wins"`
+}
+
+// OHHPlayerWin is the amount a player won from a pot.
+type OHHPlayerWin struct {
+	PlayerID  int `json:"player_id"`
+	WinAmount int `json:"win_amount"`
+}
+
+// ExportOHH exports the hand history in the Open Hand History JSON format.
+//
+// The hero's hole cards are included as dealt cards. The hero can be left empty. Only the
+// first board is exported when the board was run more than once, but the pots include
+// the winnings from every board.
+func ExportOHH(h *HandHistory, heroName string) ([]byte, error) {
+	hand := OHHHand{
+		SpecVersion:      ohhSpecVersion,
+		SiteName:         ohhSiteName,
+		GameNumber:       h.ID,
+		StartDateUTC:     h.StartedAt.UTC().Format("2006-01-02T15:04:05Z"),
+		TableName:        h.TableName,
+		GameType:         "Holdem",
+		BetLimit:         OHHBetLimit{BetType: "NL"},
+		TableSize:        h.TableSize,
+		DealerSeat:       h.DealerSeat,
+		SmallBlindAmount: h.SmallBlind,
+		BigBlindAmount:   h.BigBlind,
+		AnteAmount:       h.Ante,
+		Players:          make([]OHHPlayer, 0),
+		Rounds:           make([]OHHRound, 0),
+		Pots:             make([]OHHPot, 0),
+	}
+
+	for _, seat := range h.Seats {
+		hand.Players = append(hand.Players, OHHPlayer{
+			ID:            seat.Number,
+			Seat:          seat.Number,
+			Name:          seat.Name,
+			StartingStack: seat.Chips,
+		})
+	}
+
+	actionNumber := 0
+	rounds := []struct {
+		street Street
+		cards  []*poker.Card
+	}{
+		{StreetPreflop, nil},
+		{StreetFlop, h.Board.Flop[:]},
+		{StreetTurn, []*poker.Card{h.Board.Turn}},
+		{StreetRiver, []*poker.Card{h.Board.River}},
+		{StreetShowdown, nil},
+	}
+	for _, r := range rounds {
+		actions := h.GetActionsByStreet(r.street)
+		if r.street != StreetPreflop && r.street != StreetShowdown && isStreetDealt(h.Board, r.street) == false {
+			break
+		}
+		if r.street == StreetShowdown && len(actions) == 0 {
+			break
+		}
+
+		round := OHHRound{
+			ID:      len(hand.Rounds),
+			Street:  string(r.street),
+			Cards:   formatOHHCards(r.cards),
+			Actions: make([]OHHAction, 0),
+		}
+
+		for _, a := range actions {
+			// Hole cards are dealt after the blinds are posted
+			if r.street == StreetPreflop && a.IsForced() == false {
+				actionNumber = addOHHDealtCards(h, &hand, &round, heroName, actionNumber)
+			}
+			actionNumber++
+			round.Actions = append(round.Actions, OHHAction{
+				ActionNumber: actionNumber,
+				PlayerID:     getOHHPlayerID(h, a.PlayerID),
+				Action:       ohhActions[a.Type],
+				Amount:       a.Amount,
+				IsAllIn:      a.IsAllIn,
+				Cards:        formatOHHCards(a.Cards),
+			})
+		}
+		if r.street == StreetPreflop {
+			actionNumber = addOHHDealtCards(h, &hand, &round, heroName, actionNumber)
+		}
+		hand.Rounds = append(hand.Rounds, round)
+	}
+
+	for _, pot := range h.Pots {
+		ohhPot := OHHPot{
+			Number:     pot.Number,
+			Amount:     pot.Amount,
+			Rake:       pot.Rake,
+			PlayerWins: make([]OHHPlayerWin, 0),
+		}
+		for _, winner := range pot.Winners {
+			ohhPot.PlayerWins = append(ohhPot.PlayerWins, OHHPlayerWin{
+				PlayerID:  getOHHPlayerID(h, winner.PlayerID),
+				WinAmount: winner.Amount,
+			})
+		}
+		hand.Pots = append(hand.Pots, ohhPot)
+	}
+
+	return json.MarshalIndent(OHH{OHH: hand}, "", "  ")
+}
+
+// addOHHDealtCards adds the hero's dealt cards to the round if they have not been added yet
+func addOHHDealtCards(h *HandHistory, hand *OHHHand, round *OHHRound, heroName string, actionNumber int) int {
+	hero := h.GetSeatByName(heroName)
+	if hero == nil || hero.HoleCards[0] == nil || hand.HeroPlayerID != 0 {
+		return actionNumber
+	}
+	hand.HeroPlayerID = hero.Number
+	actionNumber++
+	round.Actions = append(round.Actions, OHHAction{
+		ActionNumber: actionNumber,
+		PlayerID:     hero.Number,
+		Action:       "Dealt Cards",
+		Cards:        formatOHHCards(hero.HoleCards[:]),
+	})
+	return actionNumber
+}
+
+func getOHHPlayerID(h *HandHistory, playerID string) int {
+	seat := h.GetSeat(playerID)
+	if seat == nil {
+		return 0
+	}
+	return seat.Number
+}
+
+func formatOHHCards(cs []*poker.Card) []string {
+	cards := make([]string, 0)
+	for _, c := range cs {
+		if c != nil {
+			cards = append(cards, FormatPokerStarsCard(c))
+		}
+	}
+	if len(cards) == 0 {
+		return nil
+	}
+	return cards
+}
@@ -0,0 +1,561 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+var (
+	pokerStarsHandIDRegex    = regexp.MustCompile(`Hand #([\w-]+)`)
+	pokerStarsBlindsRegex    = regexp.MustCompile(`\(([^()/]+)/([^()/ ]+)(?: [A-Z]{3})?\)`)
+	pokerStarsDateRegex      = regexp.MustCompile(`(\d{4}/\d{2}/\d{2} \d{1,2}:\d{2}:\d{2})(?: ([A-Z]+))?`)
+	pokerStarsTableRegex     = regexp.MustCompile(`^Table '(.*)' (\d+)-max.* Seat #(\d+) is the button`)
+	pokerStarsSeatRegex      = regexp.MustCompile(`^Seat (\d+): (.+) \(([^()]+) in chips[^()]*\)(.*)$`)
+	pokerStarsCardsRegex     = regexp.MustCompile(`\[([^\[\]]+)\]`)
+	pokerStarsRaiseRegex     = regexp.MustCompile(`^raises (\S+) to (\S+)`)
+	pokerStarsUncalledRegex  = regexp.MustCompile(`^Uncalled bet \((\S+)\) returned to (.+)$`)
+	pokerStarsCollectedRegex = regexp.MustCompile(`^(.+) collected (\S+) from (pot|main pot|side pot(?:-(\d+))?)$`)
+	pokerStarsBountyRegex    = regexp.MustCompile(`^(.+) wins (\S+) for eliminating (.+?)(?: and their own bounty increases by (\S+) to (\S+))?$`)
+	pokerStarsRakeRegex      = regexp.MustCompile(`\| Rake (\S+)`)
+	pokerStarsTotalPotRegex  = regexp.MustCompile(`^Total pot (\S+)`)
+	pokerStarsPotAmountRegex = regexp.MustCompile(`(Main pot|Side pot(?:-(\d+))?) (\S+?)\.(?:\s|$)`)
+	pokerStarsBoardsRegex    = regexp.MustCompile(`^[A-Z]+ Board \[([^\[\]]+)\]`)
+	pokerStarsStreetRegex    = regexp.MustCompile(`^\*\*\* (FLOP|TURN|RIVER) \*\*\*`)
+)
+
+// ParseJSON parses hand histories that were saved by this server.
+//
+// The data can either be a single hand history or a list of hand histories.
+func ParseJSON(data []byte) ([]*HandHistory, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		histories := make([]*HandHistory, 0)
+		if err := json.Unmarshal(data, &histories); err != nil {
+			return nil, err
+		}
+		return histories, nil
+	}
+	h := &HandHistory{}
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, err
+	}
+	return []*HandHistory{h}, nil
+}
+
+// ParsePokerStars parses hands in the PokerStars text format.
+//
+// Only No Limit Hold'em hands are supported. Player IDs are set to the
+// player names since PokerStars does not include player IDs. Amounts are converted to
+// cents if the blinds are not whole numbers (e.g. $0.01/$0.02).
+func ParsePokerStars(text string) ([]*HandHistory, error) {
+	histories := make([]*HandHistory, 0)
+	for _, handText := range splitPokerStarsHands(text) {
+		h, err := parsePokerStarsHand(handText)
+		if err != nil {
+			return nil, err
+		}
+		histories = append(histories, h)
+	}
+	return histories, nil
+}
+
+// splitPokerStarsHands splits a file that contains multiple hands into one string per hand
+func splitPokerStarsHands(text string) []string {
+	hands := make([]string, 0)
+	var current []string
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if strings.HasPrefix(line, "PokerStars ") && strings.Contains(line, "Hand #") {
+			if len(current) > 0 {
+				hands = append(hands, strings.Join(current, "\n"))
+			}
+			current = make([]string, 0)
+		}
+		if current != nil && line != "" {
+			current = append(current, line)
+		}
+	}
+	if len(current) > 0 {
+		hands = append(hands, strings.Join(current, "\n"))
+	}
+	return hands
+}
+
+// pokerStarsParser keeps track of the state needed to parse a hand
+type pokerStarsParser struct {
+	h            *HandHistory
+	names        []string // Sorted by length so that longer names are matched first
+	pots         map[int]*Pot
+	scale        float64
+	street       Street
+	streetBets   map[string]int
+	isSummary    bool
+	hasHoleCards bool
+}
+
+func parsePokerStarsHand(text string) (*HandHistory, error) {
+	lines := strings.Split(text, "\n")
+
+	header := lines[0]
+	if strings.Contains(header, "Hold'em No Limit") == false {
+		return nil, fmt.Errorf("Only No Limit Hold'em hands are supported: %s", header)
+	}
+
+	p := &pokerStarsParser{
+		h: &HandHistory{
+			Seats:   make([]Seat, 0),
+			Actions: make([]Action, 0),
+			Pots:    make([]Pot, 0),
+		},
+		pots:       make(map[int]*Pot),
+		scale:      1,
+		street:     StreetPreflop,
+		streetBets: make(map[string]int),
+	}
+
+	if err := p.parseHeader(header); err != nil {
+		return nil, err
+	}
+
+	for _, line := range lines[1:] {
+		if err := p.parseLine(line); err != nil {
+			return nil, fmt.Errorf("Hand #%s: %s", p.h.ID, err)
+		}
+	}
+
+	numbers := make([]int, 0)
+	for number := range p.pots {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	for _, number := range numbers {
+		p.h.Pots = append(p.h.Pots, *p.pots[number])
+	}
+	if len(p.h.Pots) == 1 {
+		p.h.Pots[0].Rake = p.h.Rake
+	}
+
+	return p.h, nil
+}
+
+func (p *pokerStarsParser) parseHeader(header string) error {
+	var err error
+
+	match := pokerStarsHandIDRegex.FindStringSubmatch(header)
+	if match == nil {
+		return fmt.Errorf("Hand number not found: %s", header)
+	}
+	p.h.ID = match[1]
+
+	match = pokerStarsBlindsRegex.FindStringSubmatch(header)
+	if match == nil {
+		return fmt.Errorf("Blinds not found: %s", header)
+	}
+	if strings.Contains(match[1]+match[2], ".") || strings.ContainsAny(match[1]+match[2], "$€£") {
+		p.scale = 100
+	}
+	if p.h.SmallBlind, err = p.parseAmount(match[1]); err != nil {
+		return err
+	}
+	if p.h.BigBlind, err = p.parseAmount(match[2]); err != nil {
+		return err
+	}
+
+	match = pokerStarsDateRegex.FindStringSubmatch(header)
+	if match != nil {
+		location := time.UTC
+		if match[2] == "ET" {
+			if newYork, err := time.LoadLocation("America/New_York"); err == nil {
+				location = newYork
+			}
+		}
+		startedAt, err := time.ParseInLocation("2006/01/02 15:04:05", match[1], location)
+		if err != nil {
+			return err
+		}
+		p.h.StartedAt = startedAt.UTC()
+	}
+
+	return nil
+}
+
+func (p *pokerStarsParser) parseLine(line string) error {
+	if match := pokerStarsTableRegex.FindStringSubmatch(line); match != nil {
+		p.h.TableName = match[1]
+		p.h.TableSize, _ = strconv.Atoi(match[2])
+		p.h.DealerSeat, _ = strconv.Atoi(match[3])
+		return nil
+	}
+
+	if strings.HasPrefix(line, "*** SUMMARY ***") {
+		p.isSummary = true
+		return nil
+	}
+
+	if p.isSummary {
+		return p.parseSummaryLine(line)
+	}
+
+	if match := pokerStarsSeatRegex.FindStringSubmatch(line); match != nil && p.hasHoleCards == false {
+		if strings.Contains(match[4], "sitting out") {
+			return nil
+		}
+		number, _ := strconv.Atoi(match[1])
+		chips, err := p.parseAmount(match[3])
+		if err != nil {
+			return err
+		}
+		p.h.Seats = append(p.h.Seats, Seat{
+			Number:   number,
+			PlayerID: match[2],
+			Name:     match[2],
+			Chips:    chips,
+		})
+		p.names = append(p.names, match[2])
+		sort.Slice(p.names, func(i, j int) bool { return len(p.names[i]) > len(p.names[j]) })
+		return nil
+	}
+
+	if strings.HasPrefix(line, "*** HOLE CARDS ***") {
+		p.hasHoleCards = true
+		return nil
+	}
+
+	if match := pokerStarsStreetRegex.FindStringSubmatch(line); match != nil {
+		cards, err := parseLastPokerStarsCards(line)
+		if err != nil {
+			return err
+		}
+		p.streetBets = make(map[string]int)
+		switch match[1] {
+		case "FLOP":
+			p.street = StreetFlop
+			if len(cards) != 3 {
+				return fmt.Errorf("Invalid flop: %s", line)
+			}
+			copy(p.h.Board.Flop[:], cards)
+		case "TURN":
+			p.street = StreetTurn
+			if len(cards) != 1 {
+				return fmt.Errorf("Invalid turn: %s", line)
+			}
+			p.h.Board.Turn = cards[0]
+		case "RIVER":
+			p.street = StreetRiver
+			if len(cards) != 1 {
+				return fmt.Errorf("Invalid river: %s", line)
+			}
+			p.h.Board.River = cards[0]
+		}
+		return nil
+	}
+
+	if strings.HasPrefix(line, "*** SHOW DOWN ***") {
+		p.street = StreetShowdown
+		return nil
+	}
+
+	if strings.HasPrefix(line, "Dealt to ") {
+		name := p.findName(strings.TrimPrefix(line, "Dealt to "), " ")
+		cards, err := parseLastPokerStarsCards(line)
+		if name == "" || err != nil {
+			return nil
+		}
+		seat := p.h.GetSeat(name)
+		if seat == nil {
+			return fmt.Errorf("Seat not found for %s", name)
+		}
+		copy(seat.HoleCards[:], cards)
+		return nil
+	}
+
+	if match := pokerStarsUncalledRegex.FindStringSubmatch(line); match != nil {
+		amount, err := p.parseAmount(match[1])
+		if err != nil {
+			return err
+		}
+		p.h.UncalledBet = &UncalledBet{PlayerID: match[2], Amount: amount}
+		return nil
+	}
+
+	if match := pokerStarsCollectedRegex.FindStringSubmatch(line); match != nil {
+		amount, err := p.parseAmount(match[2])
+		if err != nil {
+			return err
+		}
+		number := 0
+		if strings.HasPrefix(match[3], "side pot") {
+			number = 1
+			if match[4] != "" {
+				number, _ = strconv.Atoi(match[4])
+			}
+		}
+		pot := p.getPot(number)
+		for i := range pot.Winners {
+			// Players can collect from the same pot multiple times when the board is run more than once
+			if pot.Winners[i].PlayerID == match[1] {
+				pot.Winners[i].Amount += amount
+				return nil
+			}
+		}
+		pot.Winners = append(pot.Winners, Winner{PlayerID: match[1], Amount: amount})
+		return nil
+	}
+
+	if match := pokerStarsBountyRegex.FindStringSubmatch(line); match != nil {
+		bounty := Bounty{PlayerID: match[1], EliminatedID: match[3]}
+		var err error
+		if bounty.Amount, err = p.parseAmount(match[2]); err != nil {
+			return err
+		}
+		if match[4] != "" {
+			if bounty.Increase, err = p.parseAmount(match[4]); err != nil {
+				return err
+			}
+			if bounty.Total, err = p.parseAmount(match[5]); err != nil {
+				return err
+			}
+		}
+		p.h.Bounties = append(p.h.Bounties, bounty)
+		return nil
+	}
+
+	name := p.findName(line, ": ")
+	if name == "" {
+		// Chat messages, players joining the table, etc. are not part of the hand
+		return nil
+	}
+	return p.parseAction(name, strings.TrimPrefix(line, name+": "))
+}
+
+func (p *pokerStarsParser) parseAction(name string, text string) error {
+	a := Action{
+		Street:   p.street,
+		PlayerID: name,
+		IsAllIn:  strings.HasSuffix(text, "and is all-in"),
+	}
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	// Amounts come after the action, such as "calls 10" or "posts big blind 20"
+	parseField := func(i int) (int, error) {
+		if i >= len(fields) {
+			return 0, fmt.Errorf("Amount not found: %s", text)
+		}
+		return p.parseAmount(fields[i])
+	}
+
+	var err error
+	switch {
+	case strings.HasPrefix(text, "posts small blind"):
+		a.Type = ActionPostSmallBlind
+		a.Amount, err = parseField(3)
+	case strings.HasPrefix(text, "posts big blind"):
+		a.Type = ActionPostBigBlind
+		a.Amount, err = parseField(3)
+	case strings.HasPrefix(text, "posts the ante"):
+		a.Type = ActionPostAnte
+		a.Amount, err = parseField(3)
+		if err == nil && a.Amount > p.h.Ante {
+			p.h.Ante = a.Amount
+		}
+	case strings.HasPrefix(text, "posts small & big blinds"):
+		return fmt.Errorf("Dead blinds are not supported")
+	case fields[0] == "folds":
+		a.Type = ActionFold
+	case fields[0] == "checks":
+		a.Type = ActionCheck
+	case fields[0] == "calls":
+		a.Type = ActionCall
+		a.Amount, err = parseField(1)
+	case fields[0] == "bets":
+		a.Type = ActionBet
+		a.Amount, err = parseField(1)
+		a.RaiseTo = p.streetBets[name] + a.Amount
+	case fields[0] == "raises":
+		match := pokerStarsRaiseRegex.FindStringSubmatch(text)
+		if match == nil {
+			return fmt.Errorf("Invalid raise: %s", text)
+		}
+		a.Type = ActionRaise
+		a.RaiseTo, err = p.parseAmount(match[2])
+		a.Amount = a.RaiseTo - p.streetBets[name]
+	case fields[0] == "shows":
+		a.Type = ActionShow
+		a.Street = StreetShowdown
+		a.Cards, err = parseFirstPokerStarsCards(text)
+		if err != nil {
+			break
+		}
+		seat := p.h.GetSeat(name)
+		if seat == nil {
+			return fmt.Errorf("Seat not found for %s", name)
+		}
+		copy(seat.HoleCards[:], a.Cards)
+	case fields[0] == "mucks":
+		a.Type = ActionMuck
+		a.Street = StreetShowdown
+	default:
+		// Other lines such as "doesn't show hand" do not change the outcome of the hand
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Antes are dead money, so they are not part of the player's bet for the street
+	if a.Type != ActionPostAnte {
+		p.streetBets[name] += a.Amount
+	}
+	p.h.AddAction(a)
+	return nil
+}
+
+func (p *pokerStarsParser) parseSummaryLine(line string) error {
+	if match := pokerStarsTotalPotRegex.FindStringSubmatch(line); match != nil {
+		total, err := p.parseAmount(match[1])
+		if err != nil {
+			return err
+		}
+		if match := pokerStarsRakeRegex.FindStringSubmatch(line); match != nil {
+			if p.h.Rake, err = p.parseAmount(match[1]); err != nil {
+				return err
+			}
+		}
+		potAmounts := pokerStarsPotAmountRegex.FindAllStringSubmatch(line, -1)
+		if len(potAmounts) == 0 {
+			p.getPot(0).Amount = total
+			return nil
+		}
+		for _, potAmount := range potAmounts {
+			number := 0
+			if strings.HasPrefix(potAmount[1], "Side pot") {
+				number = 1
+				if potAmount[2] != "" {
+					number, _ = strconv.Atoi(potAmount[2])
+				}
+			}
+			if p.getPot(number).Amount, err = p.parseAmount(potAmount[3]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if match := pokerStarsBoardsRegex.FindStringSubmatch(line); match != nil {
+		cards, err := parsePokerStarsCards(match[1])
+		if err != nil {
+			return err
+		}
+		board := poker.Board{}
+		copy(board.Flop[:], cards)
+		if len(cards) > 3 {
+			board.Turn = cards[3]
+		}
+		if len(cards) > 4 {
+			board.River = cards[4]
+		}
+		p.h.Boards = append(p.h.Boards, board)
+		return nil
+	}
+
+	// Hole cards of players who mucked are sometimes listed in the summary
+	if strings.HasPrefix(line, "Seat ") && strings.Contains(line, "mucked [") {
+		cards, err := parseLastPokerStarsCards(line)
+		if err != nil {
+			return err
+		}
+		for i, seat := range p.h.Seats {
+			if strings.HasPrefix(line, fmt.Sprintf("Seat %d: %s ", seat.Number, seat.Name)) {
+				copy(p.h.Seats[i].HoleCards[:], cards)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *pokerStarsParser) getPot(number int) *Pot {
+	if _, ok := p.pots[number]; ok == false {
+		p.pots[number] = &Pot{
+			Number:  number,
+			Winners: make([]Winner, 0),
+		}
+	}
+	return p.pots[number]
+}
+
+// findName finds the name of the player at the start of the line
+func (p *pokerStarsParser) findName(line string, separator string) string {
+	for _, name := range p.names {
+		if strings.HasPrefix(line, name+separator) {
+			return name
+		}
+	}
+	return ""
+}
+
+// parseAmount parses an amount such as 100, $0.25, or 1,500
+func (p *pokerStarsParser) parseAmount(s string) (int, error) {
+	s = strings.Trim(s, "$€£")
+	s = strings.ReplaceAll(s, ",", "")
+	amount, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid amount: %s", s)
+	}
+	return int(math.Round(amount * p.scale)), nil
+}
+
+func parseFirstPokerStarsCards(line string) ([]*poker.Card, error) {
+	match := pokerStarsCardsRegex.FindStringSubmatch(line)
+	if match == nil {
+		return nil, fmt.Errorf("Cards not found: %s", line)
+	}
+	return parsePokerStarsCards(match[1])
+}
+
+func parseLastPokerStarsCards(line string) ([]*poker.Card, error) {
+	matches := pokerStarsCardsRegex.FindAllStringSubmatch(line, -1)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("Cards not found: %s", line)
+	}
+	return parsePokerStarsCards(matches[len(matches)-1][1])
+}
+
+// parsePokerStarsCards parses a space separated list of cards (e.g. Ah Kd)
+func parsePokerStarsCards(s string) ([]*poker.Card, error) {
+	cards := make([]*poker.Card, 0)
+	for _, field := range strings.Fields(s) {
+		card, err := ParsePokerStarsCard(field)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+	return cards, nil
+}
+
+// ParsePokerStarsCard parses a card in the PokerStars format (e.g. Ah).
+func ParsePokerStarsCard(s string) (*poker.Card, error) {
+	if len(s) != 2 {
+		return nil, fmt.Errorf("Invalid card: %s", s)
+	}
+	rank := strings.IndexByte(pokerStarsRanks, s[0])
+	suit := strings.IndexByte(pokerStarsSuits, s[1])
+	if rank < 0 || suit < 0 {
+		return nil, fmt.Errorf("Invalid card: %s", s)
+	}
+	return &poker.Card{Rank: poker.CardRank(rank), Suit: poker.CardSuit(suit)}, nil
+}
@@ -0,0 +1,304 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+// PokerStars uses a one character abbreviation for ranks and suits (e.g. Ah, Td)
+const pokerStarsRanks string = "23456789TJQKA"
+const pokerStarsSuits string = "cdhs"
+
+// Ordinals used to label boards when a hand is run more than once
+var pokerStarsOrdinals = []string{"FIRST", "SECOND", "THIRD", "FOURTH", "FIFTH"}
+
+// FormatPokerStarsCard formats a card in the PokerStars format (e.g. Ah).
+func FormatPokerStarsCard(c *poker.Card) string {
+	return fmt.Sprintf("%c%c", pokerStarsRanks[c.Rank], pokerStarsSuits[c.Suit])
+}
+
+// ExportPokerStars exports the hand history in the PokerStars text format.
+//
+// The hero's hole cards are listed as "Dealt to" cards. The hero can be left empty.
+// Other hole cards only appear if they were shown.
+func ExportPokerStars(h *HandHistory, heroName string) string {
+	var b strings.Builder
+
+	fmt.Fprintf(
+		&b,
+		"PokerStars Hand #%s: Hold'em No Limit (%d/%d) - %s UTC\n",
+		h.ID, h.SmallBlind, h.BigBlind, h.StartedAt.UTC().Format("2006/01/02 15:04:05"),
+	)
+	fmt.Fprintf(&b, "Table '%s' %d-max Seat #%d is the button\n", h.TableName, h.TableSize, h.DealerSeat)
+	for _, seat := range h.Seats {
+		fmt.Fprintf(&b, "Seat %d: %s (%d in chips)\n", seat.Number, seat.Name, seat.Chips)
+	}
+
+	callAmount := 0
+	for _, a := range h.GetActionsByStreet(StreetPreflop) {
+		if a.IsForced() == false {
+			continue
+		}
+		writePokerStarsAction(&b, h, a, &callAmount)
+	}
+
+	b.WriteString("*** HOLE CARDS ***\n")
+	if hero := h.GetSeatByName(heroName); hero != nil && hero.HoleCards[0] != nil {
+		fmt.Fprintf(&b, "Dealt to %s [%s]\n", hero.Name, formatPokerStarsCards(hero.HoleCards[:]))
+	}
+	for _, a := range h.GetActionsByStreet(StreetPreflop) {
+		if a.IsForced() {
+			continue
+		}
+		writePokerStarsAction(&b, h, a, &callAmount)
+	}
+
+	streets := []struct {
+		street Street
+		header string
+		cards  func(board poker.Board) string
+	}{
+		{StreetFlop, "FLOP", func(board poker.Board) string {
+			return fmt.Sprintf("[%s]", formatPokerStarsCards(board.Flop[:]))
+		}},
+		{StreetTurn, "TURN", func(board poker.Board) string {
+			return fmt.Sprintf("[%s] [%s]", formatPokerStarsCards(board.Flop[:]), FormatPokerStarsCard(board.Turn))
+		}},
+		{StreetRiver, "RIVER", func(board poker.Board) string {
+			return fmt.Sprintf(
+				"[%s %s] [%s]",
+				formatPokerStarsCards(board.Flop[:]),
+				FormatPokerStarsCard(board.Turn),
+				FormatPokerStarsCard(board.River),
+			)
+		}},
+	}
+	for i, s := range streets {
+		if isStreetDealt(h.Board, s.street) == false {
+			break
+		}
+		fmt.Fprintf(&b, "*** %s *** %s\n", s.header, s.cards(h.Board))
+		callAmount = 0
+		for _, a := range h.GetActionsByStreet(s.street) {
+			writePokerStarsAction(&b, h, a, &callAmount)
+		}
+		// Show the extra boards once the last street has been dealt
+		if i == len(streets)-1 || isStreetDealt(h.Board, streets[i+1].street) == false {
+			for j, board := range h.Boards {
+				if j == 0 {
+					continue
+				}
+				fmt.Fprintf(&b, "*** %s %s *** %s\n", getPokerStarsOrdinal(j), s.header, s.cards(board))
+			}
+		}
+	}
+
+	if h.UncalledBet != nil {
+		fmt.Fprintf(
+			&b,
+			"Uncalled bet (%d) returned to %s\n",
+			h.UncalledBet.Amount,
+			h.getPlayerName(h.UncalledBet.PlayerID),
+		)
+	}
+
+	showdownActions := h.GetActionsByStreet(StreetShowdown)
+	if len(showdownActions) > 0 {
+		b.WriteString("*** SHOW DOWN ***\n")
+		for _, a := range showdownActions {
+			writePokerStarsAction(&b, h, a, &callAmount)
+		}
+	}
+
+	for _, pot := range h.Pots {
+		for _, winner := range pot.Winners {
+			fmt.Fprintf(
+				&b,
+				"%s collected %d from %s\n",
+				h.getPlayerName(winner.PlayerID),
+				winner.Amount,
+				getPokerStarsPotName(pot.Number, len(h.Pots)),
+			)
+		}
+	}
+
+	for _, bounty := range h.Bounties {
+		fmt.Fprintf(&b, "%s wins %d for eliminating %s", h.getPlayerName(bounty.PlayerID), bounty.Amount, h.getPlayerName(bounty.EliminatedID))
+		if bounty.Increase > 0 {
+			fmt.Fprintf(&b, " and their own bounty increases by %d to %d", bounty.Increase, bounty.Total)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("*** SUMMARY ***\n")
+	b.WriteString(formatPokerStarsTotalPot(h))
+	if len(h.Boards) > 1 {
+		fmt.Fprintf(&b, "Hand was run %s\n", formatRunTimes(len(h.Boards)))
+		for i, board := range h.Boards {
+			fmt.Fprintf(&b, "%s Board [%s]\n", getPokerStarsOrdinal(i), formatPokerStarsBoard(board))
+		}
+	} else if h.Board.Flop[0] != nil {
+		fmt.Fprintf(&b, "Board [%s]\n", formatPokerStarsBoard(h.Board))
+	}
+	for _, seat := range h.Seats {
+		fmt.Fprintf(&b, "Seat %d: %s%s %s\n", seat.Number, seat.Name, h.getPokerStarsPosition(seat), h.getPokerStarsResult(seat))
+	}
+
+	return b.String()
+}
+
+// writePokerStarsAction writes an action in the PokerStars format
+func writePokerStarsAction(b *strings.Builder, h *HandHistory, a Action, callAmount *int) {
+	name := h.getPlayerName(a.PlayerID)
+	allIn := ""
+	if a.IsAllIn {
+		allIn = " and is all-in"
+	}
+
+	switch a.Type {
+	case ActionPostSmallBlind:
+		fmt.Fprintf(b, "%s: posts small blind %d%s\n", name, a.Amount, allIn)
+	case ActionPostBigBlind:
+		fmt.Fprintf(b, "%s: posts big blind %d%s\n", name, a.Amount, allIn)
+		// A short stacked big blind does not lower the amount the other players have to call
+		*callAmount = h.BigBlind
+	case ActionPostAnte:
+		fmt.Fprintf(b, "%s: posts the ante %d%s\n", name, a.Amount, allIn)
+	case ActionFold:
+		fmt.Fprintf(b, "%s: folds\n", name)
+	case ActionCheck:
+		fmt.Fprintf(b, "%s: checks\n", name)
+	case ActionCall:
+		fmt.Fprintf(b, "%s: calls %d%s\n", name, a.Amount, allIn)
+	case ActionBet:
+		fmt.Fprintf(b, "%s: bets %d%s\n", name, a.Amount, allIn)
+		*callAmount = a.RaiseTo
+	case ActionRaise:
+		fmt.Fprintf(b, "%s: raises %d to %d%s\n", name, a.RaiseTo-*callAmount, a.RaiseTo, allIn)
+		*callAmount = a.RaiseTo
+	case ActionShow:
+		fmt.Fprintf(b, "%s: shows [%s]\n", name, formatPokerStarsCards(a.Cards))
+	case ActionMuck:
+		fmt.Fprintf(b, "%s: mucks hand\n", name)
+	}
+}
+
+func (h *HandHistory) getPlayerName(playerID string) string {
+	seat := h.GetSeat(playerID)
+	if seat == nil {
+		return ""
+	}
+	return seat.Name
+}
+
+// getPokerStarsPosition gets the position labels used in the summary section
+func (h *HandHistory) getPokerStarsPosition(seat Seat) string {
+	position := ""
+	if seat.Number == h.DealerSeat {
+		position += " (button)"
+	}
+	for _, a := range h.GetActionsByStreet(StreetPreflop) {
+		if a.PlayerID != seat.PlayerID {
+			continue
+		}
+		if a.Type == ActionPostSmallBlind {
+			position += " (small blind)"
+		} else if a.Type == ActionPostBigBlind {
+			position += " (big blind)"
+		}
+	}
+	return position
+}
+
+// getPokerStarsResult gets the result of the hand for a player used in the summary section
+func (h *HandHistory) getPokerStarsResult(seat Seat) string {
+	winnings := h.GetWinnings(seat.PlayerID)
+	for _, a := range h.Actions {
+		if a.PlayerID != seat.PlayerID {
+			continue
+		}
+		if a.Type == ActionFold {
+			if a.Street == StreetPreflop {
+				return "folded before Flop"
+			}
+			return fmt.Sprintf("folded on the %s", a.Street)
+		}
+		if a.Type == ActionMuck {
+			return "mucked"
+		}
+		if a.Type == ActionShow {
+			if winnings > 0 {
+				return fmt.Sprintf("showed [%s] and won (%d)", formatPokerStarsCards(a.Cards), winnings)
+			}
+			return fmt.Sprintf("showed [%s] and lost", formatPokerStarsCards(a.Cards))
+		}
+	}
+	if winnings > 0 {
+		return fmt.Sprintf("collected (%d)", winnings)
+	}
+	return "lost"
+}
+
+func formatPokerStarsTotalPot(h *HandHistory) string {
+	if len(h.Pots) <= 1 {
+		return fmt.Sprintf("Total pot %d | Rake %d\n", h.GetTotalPot(), h.Rake)
+	}
+	pots := make([]string, 0)
+	for _, pot := range h.Pots {
+		potName := getPokerStarsPotName(pot.Number, len(h.Pots))
+		pots = append(pots, fmt.Sprintf("%s%s %d.", strings.ToUpper(potName[:1]), potName[1:], pot.Amount))
+	}
+	return fmt.Sprintf("Total pot %d %s | Rake %d\n", h.GetTotalPot(), strings.Join(pots, " "), h.Rake)
+}
+
+func getPokerStarsPotName(number int, numPots int) string {
+	if numPots <= 1 {
+		return "pot"
+	}
+	if number == 0 {
+		return "main pot"
+	}
+	return fmt.Sprintf("side pot-%d", number)
+}
+
+func getPokerStarsOrdinal(i int) string {
+	if i < len(pokerStarsOrdinals) {
+		return pokerStarsOrdinals[i]
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
+
+func formatRunTimes(times int) string {
+	if times == 2 {
+		return "twice"
+	}
+	return fmt.Sprintf("%d times", times)
+}
+
+func formatPokerStarsCards(cs []*poker.Card) string {
+	cards := make([]string, 0)
+	for _, c := range cs {
+		if c != nil {
+			cards = append(cards, FormatPokerStarsCard(c))
+		}
+	}
+	return strings.Join(cards, " ")
+}
+
+func formatPokerStarsBoard(board poker.Board) string {
+	return formatPokerStarsCards([]*poker.Card{board.Flop[0], board.Flop[1], board.Flop[2], board.Turn, board.River})
+}
+
+func isStreetDealt(board poker.Board, street Street) bool {
+	switch street {
+	case StreetFlop:
+		return board.Flop[0] != nil
+	case StreetTurn:
+		return board.Turn != nil
+	case StreetRiver:
+		return board.River != nil
+	}
+	return false
+}
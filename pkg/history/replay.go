@@ -0,0 +1,381 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+// Replay replays a hand history through the poker engine one action at a time.
+//
+// The engine validates every action and awards the pot at the end of the hand, so
+// replays can be used to check that the engine reaches the same result as the history.
+type Replay struct {
+	BettingRound *poker.BettingRound
+	History      *HandHistory
+	Street       Street
+	Table        poker.Table
+
+	deck       poker.Deck
+	isDone     bool
+	mucked     map[string]bool
+	nextAction int
+	playerMap  map[string]*poker.Player
+	winnings   map[string]int
+}
+
+// NewReplay sets up the table as it was at the start of the hand.
+//
+// The cards are dealt from a stacked deck, so only the board cards need to be known.
+// Hole cards are only needed for the players who showed their hands.
+func NewReplay(h *HandHistory) (*Replay, error) {
+	if h.TableSize < 2 || len(h.Seats) < 2 {
+		return nil, fmt.Errorf("A hand needs at least two players")
+	}
+	if h.TableSize < len(h.Seats) {
+		return nil, fmt.Errorf("The table is too small for the number of players")
+	}
+
+	r := &Replay{
+		History:   h,
+		Street:    StreetPreflop,
+		mucked:    make(map[string]bool),
+		playerMap: make(map[string]*poker.Player),
+		winnings:  make(map[string]int),
+	}
+
+	seats := poker.NewSeat(h.TableSize)
+	for i := 0; i < seats.Len(); i++ {
+		seats.Player = &poker.Player{
+			ID:     fmt.Sprintf("empty-seat-%d", i+1),
+			Status: poker.PlayerVacated,
+		}
+		if seat := getSeatByNumber(h, i+1); seat != nil {
+			seats.Player = &poker.Player{
+				Chips:     seat.Chips,
+				HoleCards: seat.HoleCards,
+				ID:        seat.PlayerID,
+				IsHuman:   true,
+				Name:      seat.Name,
+				Status:    poker.PlayerActive,
+			}
+			r.playerMap[seat.PlayerID] = seats.Player
+		}
+		if i+1 == h.DealerSeat {
+			r.Table.Dealer = seats
+		}
+		seats = seats.Next()
+	}
+	if r.Table.Dealer == nil {
+		return nil, fmt.Errorf("The dealer seat (%d) is not at the table", h.DealerSeat)
+	}
+
+	r.Table.MinBet = h.BigBlind
+	r.Table.Pot = poker.NewPot()
+	r.Table.Seats = seats
+	if h.Rake > 0 {
+		// The rake rules are not recorded, so take the recorded amount
+		r.Table.Rake = &poker.Rake{Percent: 100, Cap: h.Rake}
+	}
+
+	r.deck = newBoardDeck(h)
+
+	startSeat, err := poker.GetNextActiveSeat(r.Table.Dealer)
+	if err != nil {
+		return nil, err
+	}
+	r.BettingRound, err = poker.NewBettingRound(startSeat, 0, h.BigBlind)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// IsDone checks if the hand has been replayed to the end.
+func (r *Replay) IsDone() bool {
+	return r.isDone
+}
+
+// GetNextAction gets the action that will be applied next. Nil is returned if there are
+// no more actions and only the pot is left to award.
+func (r *Replay) GetNextAction() *Action {
+	if r.nextAction >= len(r.History.Actions) {
+		return nil
+	}
+	return &r.History.Actions[r.nextAction]
+}
+
+// Next applies the next action.
+//
+// After the last action, the remaining board cards are dealt and the pot is awarded.
+func (r *Replay) Next() error {
+	if r.isDone {
+		return fmt.Errorf("The hand has already been replayed")
+	}
+
+	if r.nextAction >= len(r.History.Actions) {
+		return r.finish()
+	}
+
+	a := r.History.Actions[r.nextAction]
+	p := r.playerMap[a.PlayerID]
+	if p == nil {
+		return fmt.Errorf("%s is not in the hand", a.PlayerID)
+	}
+
+	if a.Street != StreetShowdown && a.Street != r.Street {
+		if err := r.dealStreet(a.Street); err != nil {
+			return err
+		}
+	}
+
+	if err := r.applyAction(p, a); err != nil {
+		return fmt.Errorf("Action %d: %s", r.nextAction+1, err)
+	}
+	r.nextAction++
+	return nil
+}
+
+// Run replays the rest of the hand.
+func (r *Replay) Run() error {
+	for r.isDone == false {
+		if err := r.Next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetWinnings gets the chips each player won according to the engine.
+//
+// Uncalled bets are not included.
+func (r *Replay) GetWinnings() map[string]int {
+	return r.winnings
+}
+
+// Verify replays the rest of the hand and checks that the engine awarded the same chips
+// to each player as the hand history.
+func (r *Replay) Verify() error {
+	if err := r.Run(); err != nil {
+		return err
+	}
+
+	mismatches := make([]string, 0)
+	for _, seat := range r.History.Seats {
+		expected := r.History.GetWinnings(seat.PlayerID)
+		actual := r.winnings[seat.PlayerID]
+		if expected != actual {
+			mismatches = append(mismatches, fmt.Sprintf("%s won %d, but the engine awarded %d", seat.Name, expected, actual))
+		}
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("Hand #%s: %s", r.History.ID, strings.Join(mismatches, "; "))
+	}
+	return nil
+}
+
+// applyAction applies an action to the table using the engine
+func (r *Replay) applyAction(p *poker.Player, a Action) error {
+	t := &r.Table
+	b := r.BettingRound
+	chips := p.Chips
+
+	var err error
+	switch a.Type {
+	case ActionPostSmallBlind:
+		t.SmallBlind = getSeatByPlayer(t, p)
+		postBlind(t, b, p, a.Amount)
+	case ActionPostBigBlind:
+		t.BigBlind = getSeatByPlayer(t, p)
+		postBlind(t, b, p, a.Amount)
+		// A short stacked big blind does not lower the amount the other players have to call
+		b.CallAmount = t.MinBet
+		b.RaiseByAmount = t.MinBet
+	case ActionPostAnte:
+		// Antes are dead money, so they do not count towards calling the big blind
+		amount := a.Amount
+		if amount > p.Chips {
+			amount = p.Chips
+		}
+		p.Chips -= amount
+		t.Pot.Bets[p] += amount
+	case ActionFold:
+		err = p.Fold(b)
+	case ActionCheck:
+		err = p.Check(b)
+	case ActionCall:
+		err = p.Call(t, b)
+	case ActionBet, ActionRaise:
+		err = p.Raise(t, b, a.RaiseTo)
+	case ActionShow:
+		if p.HoleCards[0] == nil && p.HoleCards[1] == nil && len(a.Cards) == 2 {
+			copy(p.HoleCards[:], a.Cards)
+		}
+		for i, holeCard := range p.HoleCards {
+			if holeCard != nil && containsCard(a.Cards, holeCard) {
+				p.ShownCards[i] = true
+			}
+		}
+	case ActionMuck:
+		r.mucked[p.ID] = true
+	default:
+		err = fmt.Errorf("Unknown action: %s", a.Type)
+	}
+	if err != nil {
+		return err
+	}
+
+	if chips-p.Chips != a.Amount {
+		return fmt.Errorf("%s added %d chips, but the engine added %d", p.Name, a.Amount, chips-p.Chips)
+	}
+	return nil
+}
+
+// dealStreet deals the board cards up to the given street and starts a new betting round
+func (r *Replay) dealStreet(street Street) error {
+	t := &r.Table
+	for r.Street != street {
+		switch r.Street {
+		case StreetPreflop:
+			poker.DealFlop(&r.deck, t)
+			r.Street = StreetFlop
+		case StreetFlop:
+			poker.DealTurn(&r.deck, t)
+			r.Street = StreetTurn
+		case StreetTurn:
+			poker.DealRiver(&r.deck, t)
+			r.Street = StreetRiver
+		default:
+			return fmt.Errorf("Cannot deal the %s after the %s", street, r.Street)
+		}
+		if isStreetDealt(t.GetBoard(), r.Street) == false {
+			return fmt.Errorf("The %s is missing from the board", r.Street)
+		}
+	}
+
+	startSeat, err := poker.GetNextActiveSeat(t.Dealer)
+	if err != nil {
+		return err
+	}
+	r.BettingRound, err = poker.NewBettingRound(startSeat, 0, t.MinBet)
+	return err
+}
+
+// finish runs out the board if needed and awards the pot
+func (r *Replay) finish() error {
+	t := &r.Table
+
+	players := make([]*poker.Player, 0)
+	for _, p := range r.playerMap {
+		if p.HasFolded == false {
+			players = append(players, p)
+		}
+	}
+
+	// The uncalled bet is part of the last side pot, but it is not really won
+	uncalledBet := t.Pot.GetUncalledBet()
+	if uncalledBet > 0 {
+		r.winnings[getLargestBettor(t.Pot).ID] -= uncalledBet
+	}
+
+	if len(players) == 1 {
+		r.winnings[players[0].ID] += poker.AwardPot(t, players[0])
+		r.isDone = true
+		return nil
+	}
+
+	if r.Street != StreetRiver {
+		if err := r.dealStreet(StreetRiver); err != nil {
+			return err
+		}
+	}
+
+	// Players who mucked or never showed their cards can't win, which is the same as folding
+	numShown := 0
+	for _, p := range players {
+		if r.mucked[p.ID] || p.HoleCards[0] == nil {
+			p.HasFolded = true
+		} else {
+			numShown++
+		}
+	}
+	if numShown == 0 {
+		return fmt.Errorf("None of the players at showdown showed their cards")
+	}
+
+	if len(r.History.Boards) > 1 {
+		for _, allWinningHands := range poker.DetermineWinnersByBoard(t, r.History.Boards) {
+			r.addWinningHands(allWinningHands)
+		}
+	} else {
+		r.addWinningHands(poker.DetermineWinners(t))
+	}
+	r.isDone = true
+	return nil
+}
+
+func (r *Replay) addWinningHands(allWinningHands [][]poker.PlayerHand) {
+	for _, winningHands := range allWinningHands {
+		for _, ph := range winningHands {
+			r.winnings[ph.Player.ID] += ph.ChipsWon
+		}
+	}
+}
+
+// postBlind posts a blind. Players who do not have enough chips post what they have.
+func postBlind(t *poker.Table, b *poker.BettingRound, p *poker.Player, amount int) {
+	if amount > p.Chips {
+		amount = p.Chips
+	}
+	p.Chips -= amount
+	t.Pot.Bets[p] += amount
+	b.Bets[p.ID] += amount
+}
+
+// newBoardDeck creates a deck that deals the board cards in order
+func newBoardDeck(h *HandHistory) poker.Deck {
+	board := h.Board
+	if len(h.Boards) > 0 {
+		board = h.Boards[0]
+	}
+	cards := make([]poker.Card, 0)
+	for _, c := range []*poker.Card{board.Flop[0], board.Flop[1], board.Flop[2], board.Turn, board.River} {
+		if c == nil {
+			break
+		}
+		cards = append(cards, *c)
+	}
+	return poker.NewStackedDeck(cards)
+}
+
+func getSeatByNumber(h *HandHistory, number int) *Seat {
+	for i := range h.Seats {
+		if h.Seats[i].Number == number {
+			return &h.Seats[i]
+		}
+	}
+	return nil
+}
+
+func getSeatByPlayer(t *poker.Table, p *poker.Player) *poker.Seat {
+	seat := t.Seats
+	for i := 0; i < seat.Len(); i++ {
+		if seat.Player == p {
+			return seat
+		}
+		seat = seat.Next()
+	}
+	return nil
+}
+
+func getLargestBettor(pot *poker.Pot) *poker.Player {
+	var largestBettor *poker.Player
+	for p, betAmount := range pot.Bets {
+		if largestBettor == nil || betAmount > pot.Bets[largestBettor] {
+			largestBettor = p
+		}
+	}
+	return largestBettor
+}
@@ -0,0 +1,272 @@
+package history_test
+
+import (
+	"encoding/json"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/history"
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+const pokerStarsShowdownHand = `PokerStars Hand #222222222: Hold'em No Limit ($0.01/$0.02 USD) - 2020/03/04 20:15:33 ET
+Table 'Aenna III' 6-max Seat #2 is the button
+Seat 1: alice ($2 in chips)
+Seat 2: bob ($1.50 in chips)
+Seat 3: carol ($2.34 in chips)
+Seat 5: dave ($0.80 in chips) is sitting out
+carol: posts small blind $0.01
+alice: posts big blind $0.02
+*** HOLE CARDS ***
+Dealt to bob [Ah Ad]
+bob: raises $0.04 to $0.06
+carol: calls $0.05
+alice: folds
+*** FLOP *** [Kd 8s 3c]
+carol: checks
+bob: bets $0.10
+carol: raises $0.20 to $0.30
+bob: raises $1.14 to $1.44 and is all-in
+carol: calls $1.14
+*** TURN *** [Kd 8s 3c] [2h]
+*** RIVER *** [Kd 8s 3c 2h] [7d]
+*** SHOW DOWN ***
+carol: shows [Kh Qc] (a pair of Kings)
+bob: shows [Ah Ad] (a pair of Aces)
+bob collected $2.87 from pot
+*** SUMMARY ***
+Total pot $3.02 | Rake $0.15
+Board [Kd 8s 3c 2h 7d]
+Seat 1: alice (big blind) folded before Flop
+Seat 2: bob (button) showed [Ah Ad] and won ($2.87) with a pair of Aces
+Seat 3: carol (small blind) showed [Kh Qc] and lost with a pair of Kings
+
+PokerStars Hand #222222223: Hold'em No Limit ($0.01/$0.02 USD) - 2020/03/04 20:16:40 ET
+Table 'Aenna III' 6-max Seat #3 is the button
+Seat 1: alice ($2.02 in chips)
+Seat 2: bob ($2.87 in chips)
+Seat 3: carol ($0.84 in chips)
+alice: posts small blind $0.01
+bob: posts big blind $0.02
+*** HOLE CARDS ***
+Dealt to bob [7c 2d]
+carol said, "nh"
+carol: folds
+alice: calls $0.01
+bob: checks
+*** FLOP *** [Jd Td 4s]
+alice: bets $0.04
+bob: folds
+Uncalled bet ($0.04) returned to alice
+alice collected $0.04 from pot
+alice: doesn't show hand
+*** SUMMARY ***
+Total pot $0.04 | Rake $0
+Board [Jd Td 4s]
+Seat 1: alice (small blind) collected ($0.04)
+Seat 2: bob (big blind) folded on the Flop
+Seat 3: carol (button) folded before Flop (didn't bet)
+`
+
+var _ = Describe("ParsePokerStars", func() {
+	It("parses each hand in the file", func() {
+		histories, err := history.ParsePokerStars(pokerStarsShowdownHand)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(histories).To(HaveLen(2))
+
+		h := histories[0]
+		Expect(h.ID).To(Equal("222222222"))
+		Expect(h.TableName).To(Equal("Aenna III"))
+		Expect(h.TableSize).To(Equal(6))
+		Expect(h.DealerSeat).To(Equal(2))
+		Expect(h.SmallBlind).To(Equal(1))
+		Expect(h.BigBlind).To(Equal(2))
+		Expect(h.Rake).To(Equal(15))
+
+		Expect(h.Seats).To(HaveLen(3))
+		Expect(h.Seats[0].Chips).To(Equal(200))
+		Expect(h.Seats[2].Name).To(Equal("carol"))
+		Expect(*h.Seats[1].HoleCards[0]).To(Equal(poker.Card{Rank: poker.Ace, Suit: poker.Hearts}))
+		Expect(*h.Seats[2].HoleCards[0]).To(Equal(poker.Card{Rank: poker.King, Suit: poker.Hearts}))
+
+		flop := h.GetActionsByStreet(history.StreetFlop)
+		Expect(flop).To(HaveLen(5))
+		Expect(flop[2].Type).To(Equal(history.ActionRaise))
+		Expect(flop[2].Amount).To(Equal(30))
+		Expect(flop[2].RaiseTo).To(Equal(30))
+		Expect(flop[3].Amount).To(Equal(134))
+		Expect(flop[3].RaiseTo).To(Equal(144))
+		Expect(flop[3].IsAllIn).To(BeTrue())
+
+		Expect(*h.Board.River).To(Equal(poker.Card{Rank: poker.Seven, Suit: poker.Diamonds}))
+		Expect(h.Pots).To(HaveLen(1))
+		Expect(h.Pots[0].Amount).To(Equal(302))
+		Expect(h.GetWinnings("bob")).To(Equal(287))
+
+		h = histories[1]
+		Expect(h.UncalledBet).To(Equal(&history.UncalledBet{PlayerID: "alice", Amount: 4}))
+		Expect(h.GetWinnings("alice")).To(Equal(4))
+		Expect(h.GetActionsByStreet(history.StreetPreflop)).To(HaveLen(5))
+	})
+
+	It("parses hands that were exported by this server", func() {
+		exported := newTestHandHistory()
+		exported.ID = "0b6c7e0a-3f5d-4a8e-9c1d-2e4f6a8b0c1d"
+		histories, err := history.ParsePokerStars(history.ExportPokerStars(exported, "Player 1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(histories).To(HaveLen(1))
+
+		h := histories[0]
+		Expect(h.ID).To(Equal("0b6c7e0a-3f5d-4a8e-9c1d-2e4f6a8b0c1d"))
+		Expect(h.Actions).To(HaveLen(11))
+		Expect(h.Actions[2].RaiseTo).To(Equal(6))
+		Expect(h.UncalledBet.Amount).To(Equal(20))
+		Expect(h.GetWinnings("Player 1")).To(Equal(33))
+	})
+
+	It("parses antes", func() {
+		h := newTestHandHistory()
+		antes := make([]history.Action, 0)
+		for _, id := range []string{"2", "3", "1"} {
+			antes = append(antes, history.Action{Street: history.StreetPreflop, PlayerID: id, Type: history.ActionPostAnte, Amount: 1})
+		}
+		h.Actions = append(antes, h.Actions...)
+		h.Ante = 1
+		h.Pots[0].Amount = 36
+		h.Pots[0].Winners[0].Amount = 36
+
+		histories, err := history.ParsePokerStars(history.ExportPokerStars(h, "Player 1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(histories[0].Ante).To(Equal(1))
+		Expect(histories[0].Actions).To(HaveLen(14))
+		Expect(histories[0].Actions[0].Type).To(Equal(history.ActionPostAnte))
+		Expect(histories[0].Actions[5].RaiseTo).To(Equal(6))
+
+		r, err := history.NewReplay(histories[0])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Verify()).To(Succeed())
+	})
+
+	It("parses bounties", func() {
+		h := newTestHandHistory()
+		h.Bounties = []history.Bounty{{PlayerID: "1", EliminatedID: "2", Amount: 5, Increase: 5, Total: 15}}
+
+		histories, err := history.ParsePokerStars(history.ExportPokerStars(h, "Player 1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(histories[0].Bounties).To(Equal([]history.Bounty{
+			{PlayerID: "Player 1", EliminatedID: "Player 2", Amount: 5, Increase: 5, Total: 15},
+		}))
+	})
+
+	It("does not support other games", func() {
+		_, err := history.ParsePokerStars("PokerStars Hand #1: Omaha Pot Limit ($0.01/$0.02 USD) - 2020/03/04 20:15:33 ET\n")
+		Expect(err).To(HaveOccurred())
+	})
+
+	DescribeTable("returns an error for malformed lines",
+		func(line string, message string) {
+			text := strings.Join([]string{
+				"PokerStars Hand #1: Hold'em No Limit (1/2) - 2020/03/04 20:15:33 ET",
+				"Table 'Main' 6-max Seat #1 is the button",
+				"Seat 1: alice (100 in chips)",
+				"Seat 2: bob (100 in chips)",
+				"*** HOLE CARDS ***",
+				line,
+			}, "\n")
+			_, err := history.ParsePokerStars(text)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("call without an amount", "alice: calls", "Amount not found: calls"),
+		Entry("bet without an amount", "alice: bets", "Amount not found: bets"),
+		Entry("blind without an amount", "alice: posts big blind", "Amount not found: posts big blind"),
+		Entry("ante without an amount", "alice: posts the ante", "Amount not found: posts the ante"),
+		Entry("invalid amount", "alice: calls ten", "Invalid amount: ten"),
+		Entry("raise without a total", "alice: raises 4", "Invalid raise: raises 4"),
+		Entry("show without cards", "alice: shows", "Cards not found: shows"),
+		Entry("turn without cards", "*** TURN ***", "Cards not found"),
+		Entry("turn with too many cards", "*** TURN *** [Kd 8s 3c]", "Invalid turn"),
+		Entry("river with too many cards", "*** RIVER *** [Kd 8s 3c 2h] [7d 6d]", "Invalid river"),
+	)
+})
+
+var _ = Describe("ParseJSON", func() {
+	It("parses hand histories saved by this server", func() {
+		data, err := json.Marshal([]*history.HandHistory{newTestHandHistory()})
+		Expect(err).NotTo(HaveOccurred())
+
+		histories, err := history.ParseJSON(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(histories).To(HaveLen(1))
+		Expect(histories[0].Seats[0].HoleCards).To(Equal(newTestHandHistory().Seats[0].HoleCards))
+		Expect(histories[0].Actions).To(Equal(newTestHandHistory().Actions))
+	})
+})
+
+var _ = Describe("Replay", func() {
+	It("replays a hand that ends with everyone folding", func() {
+		r, err := history.NewReplay(newTestHandHistory())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Verify()).To(Succeed())
+		Expect(r.GetWinnings()["1"]).To(Equal(33))
+		Expect(r.IsDone()).To(BeTrue())
+	})
+
+	It("replays real hands step by step", func() {
+		histories, err := history.ParsePokerStars(pokerStarsShowdownHand)
+		Expect(err).NotTo(HaveOccurred())
+
+		r, err := history.NewReplay(histories[0])
+		Expect(err).NotTo(HaveOccurred())
+		for i := 0; i < 7; i++ {
+			Expect(r.Next()).To(Succeed())
+		}
+		Expect(r.Street).To(Equal(history.StreetFlop))
+		Expect(r.Table.Pot.GetTotal()).To(Equal(24))
+		Expect(r.Verify()).To(Succeed())
+		Expect(r.Table.River).NotTo(BeNil())
+		Expect(r.Table.Pot.Rake).To(Equal(15))
+
+		for _, h := range histories {
+			r, err := history.NewReplay(h)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(r.Verify()).To(Succeed())
+		}
+	})
+
+	It("needs at least two players", func() {
+		_, err := history.NewReplay(&history.HandHistory{})
+		Expect(err).To(MatchError("A hand needs at least two players"))
+
+		h := newTestHandHistory()
+		h.Seats = h.Seats[:1]
+		_, err = history.NewReplay(h)
+		Expect(err).To(MatchError("A hand needs at least two players"))
+	})
+
+	It("reports when the engine awards a different amount", func() {
+		h := newTestHandHistory()
+		h.Pots[0].Winners[0].Amount = 30
+		r, err := history.NewReplay(h)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Verify()).To(MatchError("Hand #1001: Player 1 won 30, but the engine awarded 33"))
+	})
+
+	It("reports actions the engine does not allow", func() {
+		h := newTestHandHistory()
+		h.Actions[4] = history.Action{Street: history.StreetPreflop, PlayerID: "3", Type: history.ActionCheck}
+		r, err := history.NewReplay(h)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Run()).To(HaveOccurred())
+	})
+
+	It("reports bets that do not match the engine", func() {
+		h := newTestHandHistory()
+		h.Actions[4].Amount = 5
+		r, err := history.NewReplay(h)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Run()).To(MatchError("Action 5: Player 3 added 5 chips, but the engine added 4"))
+	})
+})
@@ -0,0 +1,83 @@
+package history
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Store saves hand histories so they can be looked up after the hand is over.
+type Store interface {
+	Save(h *HandHistory) error
+	AddAction(id string, a Action) error
+	Get(id string) (*HandHistory, error)
+	ListByPlayer(name string) ([]*HandHistory, error)
+}
+
+// MemoryStore keeps hand histories in memory.
+//
+// Hand histories are lost when the server restarts.
+type MemoryStore struct {
+	histories []*HandHistory
+	mu        sync.RWMutex
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		histories: make([]*HandHistory, 0),
+	}
+}
+
+// Save saves a hand history.
+func (s *MemoryStore) Save(h *HandHistory) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.histories = append(s.histories, h)
+	return nil
+}
+
+// AddAction records an action taken after the hand history was saved, such as cards shown
+// once the hand is over.
+//
+// The hand history is replaced with a copy, since the saved one may be read at the same time.
+func (s *MemoryStore) AddAction(id string, a Action) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, h := range s.histories {
+		if h.ID == id {
+			updated := *h
+			updated.Actions = append(append(make([]Action, 0, len(h.Actions)+1), h.Actions...), a)
+			s.histories[i] = &updated
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// Get gets a hand history by ID.
+func (s *MemoryStore) Get(id string) (*HandHistory, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, h := range s.histories {
+		if h.ID == id {
+			return h, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// ListByPlayer gets the hand histories of the hands a player was dealt into, oldest first.
+func (s *MemoryStore) ListByPlayer(name string) ([]*HandHistory, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	histories := make([]*HandHistory, 0)
+	for _, h := range s.histories {
+		if h.HasPlayer(name) {
+			histories = append(histories, h)
+		}
+	}
+	return histories, nil
+}
+
+// ErrNotFound is returned when a hand history does not exist.
+var ErrNotFound = fmt.Errorf("Hand history not found")
@@ -93,7 +93,7 @@ type Deck struct {
 // GetNextCard gets the next card from the deck. An error will occur if there
 // are no more cards in the deck.
 func (d *Deck) GetNextCard() (*Card, error) {
-	if d.currentCardIndex >= DeckSize {
+	if d.currentCardIndex >= len(d.cards) {
 		return nil, fmt.Errorf("No more cards left in deck")
 	}
 	card := d.cards[d.currentCardIndex]
@@ -103,6 +103,24 @@ func (d *Deck) GetNextCard() (*Card, error) {
 
 // NewDeck creates a shuffled deck of cards.
 func NewDeck() Deck {
+	return newDeck(rand.Shuffle)
+}
+
+// NewDeckFromSeed creates a deck of cards that is shuffled using the given seed.
+//
+// The same seed will always create the same deck, which allows hands to be replayed.
+func NewDeckFromSeed(seed int64) Deck {
+	return newDeck(rand.New(rand.NewSource(seed)).Shuffle)
+}
+
+// NewStackedDeck creates a deck that deals the given cards in order.
+//
+// This is used to replay hands where the cards that were dealt are already known.
+func NewStackedDeck(cards []Card) Deck {
+	return Deck{cards: cards, currentCardIndex: 0}
+}
+
+func newDeck(shuffle func(n int, swap func(i, j int))) Deck {
 	suits := []CardSuit{Clubs, Diamonds, Hearts, Spades}
 	ranks := []CardRank{Two, Three, Four, Five, Six, Seven, Eight, Nine, Ten, Jack, Queen, King, Ace}
 	cards := make([]Card, DeckSize)
@@ -119,7 +137,7 @@ func NewDeck() Deck {
 		}
 	}
 
-	rand.Shuffle(len(cards), func(i, j int) { cards[i], cards[j] = cards[j], cards[i] })
+	shuffle(len(cards), func(i, j int) { cards[i], cards[j] = cards[j], cards[i] })
 
 	return Deck{cards: cards, currentCardIndex: 0}
 }
@@ -81,4 +81,31 @@ var _ = Describe("Deck", func() {
 			}
 		})
 	})
+
+	Describe("NewDeckFromSeed", func() {
+		It("creates the same deck from the same seed", func() {
+			deck1 := poker.NewDeckFromSeed(42)
+			deck2 := poker.NewDeckFromSeed(42)
+			for i := 0; i < poker.DeckSize; i++ {
+				card1, _ := deck1.GetNextCard()
+				card2, _ := deck2.GetNextCard()
+				Expect(card1).To(Equal(card2))
+			}
+		})
+	})
+
+	Describe("NewStackedDeck", func() {
+		It("deals the given cards in order", func() {
+			deck := poker.NewStackedDeck([]poker.Card{
+				{Rank: poker.Ace, Suit: poker.Spades},
+				{Rank: poker.Two, Suit: poker.Clubs},
+			})
+			card, _ := deck.GetNextCard()
+			Expect(*card).To(Equal(poker.Card{Rank: poker.Ace, Suit: poker.Spades}))
+			card, _ = deck.GetNextCard()
+			Expect(*card).To(Equal(poker.Card{Rank: poker.Two, Suit: poker.Clubs}))
+			_, err := deck.GetNextCard()
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
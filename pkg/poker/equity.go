@@ -0,0 +1,108 @@
+package poker
+
+import (
+	"math/rand"
+)
+
+// equitySamples is the number of random boards that are dealt when there are too many
+// boards to check them all
+const equitySamples = 2000
+
+// CalculateEquity calculates each hand's share of the pot if the rest of the board is dealt.
+//
+// Every possible board is checked when the flop has been dealt. Preflop there are too many
+// boards, so random boards are dealt instead using the given random number generator.
+// Ties split the pot, so the equities always add up to 1.
+func CalculateEquity(holeCards [][2]*Card, board Board, r *rand.Rand) []float64 {
+	known := make([]*Card, 0)
+	for _, cards := range holeCards {
+		known = append(known, cards[0], cards[1])
+	}
+	known = append(known, getBoardCards(board)...)
+
+	// The cards that can still be dealt
+	remaining := make([]Card, 0)
+	unshuffled := newDeck(func(n int, swap func(i, j int)) {})
+	for _, c := range unshuffled.cards {
+		isKnown := false
+		for _, k := range known {
+			if k.Rank == c.Rank && k.Suit == c.Suit {
+				isKnown = true
+				break
+			}
+		}
+		if isKnown == false {
+			remaining = append(remaining, c)
+		}
+	}
+
+	numMissing := 5 - len(getBoardCards(board))
+	players := make([]*Player, len(holeCards))
+	for i := range holeCards {
+		players[i] = &Player{HoleCards: holeCards[i]}
+	}
+
+	equities := make([]float64, len(holeCards))
+	numBoards := 0
+	addBoard := func(cards []Card) {
+		t := &Table{}
+		t.SetBoard(completeBoard(board, cards))
+		winners := FindWinningHands(players, t)
+		for _, w := range winners {
+			for i, p := range players {
+				if w.Player == p {
+					equities[i] += 1 / float64(len(winners))
+				}
+			}
+		}
+		numBoards++
+	}
+
+	if numMissing == 0 {
+		addBoard(nil)
+	} else if numMissing <= 2 {
+		for _, cards := range FindCardCombinations(0, len(remaining)-numMissing+1, remaining) {
+			addBoard(cards[:numMissing])
+		}
+	} else {
+		for i := 0; i < equitySamples; i++ {
+			r.Shuffle(len(remaining), func(i, j int) { remaining[i], remaining[j] = remaining[j], remaining[i] })
+			addBoard(remaining[:numMissing])
+		}
+	}
+
+	for i := range equities {
+		equities[i] /= float64(numBoards)
+	}
+	return equities
+}
+
+// getBoardCards gets the community cards that have been dealt
+func getBoardCards(b Board) []*Card {
+	cards := make([]*Card, 0)
+	for _, c := range []*Card{b.Flop[0], b.Flop[1], b.Flop[2], b.Turn, b.River} {
+		if c != nil {
+			cards = append(cards, c)
+		}
+	}
+	return cards
+}
+
+// completeBoard fills in the community cards that have not been dealt yet
+func completeBoard(b Board, cards []Card) Board {
+	next := 0
+	deal := func(c *Card) *Card {
+		if c != nil {
+			return c
+		}
+		card := cards[next]
+		next++
+		return &card
+	}
+	for i := range b.Flop {
+		b.Flop[i] = deal(b.Flop[i])
+	}
+	b.Turn = deal(b.Turn)
+	b.River = deal(b.River)
+	return b
+}
@@ -0,0 +1,65 @@
+package poker_test
+
+import (
+	"math/rand"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+var _ = Describe("CalculateEquity", func() {
+	aces := [2]*poker.Card{
+		{Rank: poker.Ace, Suit: poker.Clubs},
+		{Rank: poker.Ace, Suit: poker.Hearts},
+	}
+	kings := [2]*poker.Card{
+		{Rank: poker.King, Suit: poker.Clubs},
+		{Rank: poker.King, Suit: poker.Hearts},
+	}
+	flop := [3]*poker.Card{
+		{Rank: poker.Two, Suit: poker.Diamonds},
+		{Rank: poker.Seven, Suit: poker.Spades},
+		{Rank: poker.Nine, Suit: poker.Clubs},
+	}
+	turn := &poker.Card{Rank: poker.Jack, Suit: poker.Diamonds}
+	river := &poker.Card{Rank: poker.Four, Suit: poker.Spades}
+
+	Context("when the board has been dealt", func() {
+		It("gives all the equity to the winning hand", func() {
+			board := poker.Board{Flop: flop, Turn: turn, River: river}
+			equities := poker.CalculateEquity([][2]*poker.Card{aces, kings}, board, nil)
+			Expect(equities).To(Equal([]float64{1, 0}))
+		})
+	})
+
+	Context("when the river has not been dealt", func() {
+		It("checks every river", func() {
+			board := poker.Board{Flop: flop, Turn: turn}
+			equities := poker.CalculateEquity([][2]*poker.Card{aces, kings}, board, nil)
+			Expect(equities[1]).To(BeNumerically("~", 2.0/44.0, 0.0001))
+			Expect(equities[0] + equities[1]).To(BeNumerically("~", 1, 0.0001))
+		})
+	})
+
+	Context("when players have the same hand", func() {
+		It("splits the equity", func() {
+			board := poker.Board{Flop: flop}
+			equities := poker.CalculateEquity([][2]*poker.Card{
+				{{Rank: poker.Ace, Suit: poker.Spades}, {Rank: poker.King, Suit: poker.Diamonds}},
+				{{Rank: poker.Ace, Suit: poker.Diamonds}, {Rank: poker.King, Suit: poker.Spades}},
+			}, board, nil)
+			Expect(equities[0]).To(BeNumerically("~", 0.5, 0.05))
+			Expect(equities[0] + equities[1]).To(BeNumerically("~", 1, 0.0001))
+		})
+	})
+
+	Context("when the flop has not been dealt", func() {
+		It("deals random boards", func() {
+			equities := poker.CalculateEquity([][2]*poker.Card{aces, kings}, poker.Board{}, rand.New(rand.NewSource(1)))
+			Expect(equities[0]).To(BeNumerically("~", 0.82, 0.03))
+			Expect(equities[0] + equities[1]).To(BeNumerically("~", 1, 0.0001))
+		})
+	})
+})
@@ -0,0 +1,102 @@
+package poker
+
+import (
+	"math/bits"
+)
+
+// MaxICMPlayers is the most players that ICM is calculated for. The time taken doubles with
+// each player, so the prize money is split by chip count when there are more players.
+const MaxICMPlayers = 12
+
+// CalculateICM calculates each player's share of the prize money using the Independent Chip Model.
+//
+// Prizes are listed in order of finish, starting with first place. A player's chance of
+// finishing first is their share of the chips. Their chance of finishing in a lower place is
+// their share of the chips that are left once the places above have been taken, which is
+// added up over every group of players who could have taken those places. The time taken
+// doubles with each player, but ten players only takes about a millisecond.
+//
+// Players without chips get nothing. The chip chop is used instead if there are more than
+// MaxICMPlayers players.
+func CalculateICM(stacks []int, prizes []int) []float64 {
+	if len(stacks) > MaxICMPlayers {
+		return CalculateChipChop(stacks, prizes)
+	}
+
+	evs := make([]float64, len(stacks))
+	total := 0
+	for _, stack := range stacks {
+		total += stack
+	}
+	if total == 0 {
+		return evs
+	}
+
+	numPlaces := len(prizes)
+	if numPlaces > len(stacks) {
+		numPlaces = len(stacks)
+	}
+
+	// Chance that the players in each group took the top places. Groups are bit masks of
+	// players, so a group is always checked after every smaller group that it contains.
+	chances := make([]float64, 1<<len(stacks))
+	chances[0] = 1
+	for group := range chances {
+		place := bits.OnesCount(uint(group))
+		if chances[group] == 0 || place >= numPlaces {
+			continue
+		}
+
+		chipsLeft := total
+		for i, stack := range stacks {
+			if group&(1<<i) != 0 {
+				chipsLeft -= stack
+			}
+		}
+		if chipsLeft == 0 {
+			continue
+		}
+
+		for i, stack := range stacks {
+			if group&(1<<i) != 0 || stack == 0 {
+				continue
+			}
+			chance := chances[group] * float64(stack) / float64(chipsLeft)
+			evs[i] += chance * float64(prizes[place])
+			chances[group|1<<i] += chance
+		}
+	}
+	return evs
+}
+
+// CalculateChipChop splits the prize money by chip count.
+//
+// Every player is guaranteed the smallest prize that is left. The rest of the prize money is
+// split by each player's share of the chips.
+func CalculateChipChop(stacks []int, prizes []int) []float64 {
+	evs := make([]float64, len(stacks))
+	total := 0
+	for _, stack := range stacks {
+		total += stack
+	}
+	if total == 0 {
+		return evs
+	}
+
+	// Only the places that the players can still finish in are paid
+	prizeMoney := 0
+	minPrize := 0
+	for place := 0; place < len(stacks); place++ {
+		minPrize = 0
+		if place < len(prizes) {
+			minPrize = prizes[place]
+		}
+		prizeMoney += minPrize
+	}
+
+	rest := float64(prizeMoney - minPrize*len(stacks))
+	for i, stack := range stacks {
+		evs[i] = float64(minPrize) + rest*float64(stack)/float64(total)
+	}
+	return evs
+}
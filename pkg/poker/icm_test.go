@@ -0,0 +1,83 @@
+package poker_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+var _ = Describe("CalculateICM", func() {
+	It("splits the prize money evenly between equal stacks", func() {
+		evs := poker.CalculateICM([]int{1000, 1000, 1000}, []int{50, 30, 20})
+		for _, ev := range evs {
+			Expect(ev).To(BeNumerically("~", 100.0/3.0, 0.0001))
+		}
+	})
+
+	It("gives the chip leader less than their share of the chips", func() {
+		evs := poker.CalculateICM([]int{5000, 3000, 2000}, []int{50, 30, 20})
+		Expect(evs[0]).To(BeNumerically("~", 38.3929, 0.0001))
+		Expect(evs[1]).To(BeNumerically("~", 32.75, 0.0001))
+		Expect(evs[2]).To(BeNumerically("~", 28.8571, 0.0001))
+		Expect(evs[0] + evs[1] + evs[2]).To(BeNumerically("~", 100, 0.0001))
+	})
+
+	It("only pays the places that have prizes", func() {
+		evs := poker.CalculateICM([]int{2000, 1000, 1000}, []int{100})
+		Expect(evs).To(Equal([]float64{50, 25, 25}))
+	})
+
+	It("gives nothing to players without chips", func() {
+		evs := poker.CalculateICM([]int{3000, 0}, []int{70, 30})
+		Expect(evs).To(Equal([]float64{70, 0}))
+	})
+
+	It("handles a full final table", func() {
+		stacks := []int{9000, 8000, 7000, 6000, 5000, 4000, 3000, 2000, 1000, 500}
+		evs := poker.CalculateICM(stacks, []int{30, 20, 15, 10, 8, 6, 5, 4, 2})
+		total := 0.0
+		for i, ev := range evs {
+			total += ev
+			if i > 0 {
+				Expect(ev).To(BeNumerically("<", evs[i-1]))
+			}
+		}
+		Expect(total).To(BeNumerically("~", 100, 0.0001))
+	})
+
+	It("splits the prize money by chip count when there are too many players", func() {
+		stacks := make([]int, poker.MaxICMPlayers)
+		prizes := make([]int, poker.MaxICMPlayers)
+		for i := range stacks {
+			stacks[i] = 1000 * (i + 1)
+			prizes[i] = 100 - i
+		}
+		Expect(poker.CalculateICM(stacks, prizes)).NotTo(Equal(poker.CalculateChipChop(stacks, prizes)))
+
+		stacks = append(stacks, 500)
+		prizes = append(prizes, 50)
+		Expect(poker.CalculateICM(stacks, prizes)).To(Equal(poker.CalculateChipChop(stacks, prizes)))
+
+		// A large field does not try to check every group of players
+		stacks = make([]int, 64)
+		for i := range stacks {
+			stacks[i] = 1000
+		}
+		evs := poker.CalculateICM(stacks, []int{50, 30, 20})
+		Expect(evs).To(HaveLen(64))
+		Expect(evs[0]).To(BeNumerically("~", 100.0/64, 0.0001))
+	})
+})
+
+var _ = Describe("CalculateChipChop", func() {
+	It("guarantees the smallest prize and splits the rest by chip count", func() {
+		evs := poker.CalculateChipChop([]int{6000, 3000, 1000}, []int{50, 30, 20})
+		Expect(evs).To(Equal([]float64{44, 32, 24}))
+	})
+
+	It("only includes the places that are left", func() {
+		evs := poker.CalculateChipChop([]int{3000, 1000}, []int{50, 30, 20})
+		Expect(evs).To(Equal([]float64{45, 35}))
+	})
+})
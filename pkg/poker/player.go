@@ -23,13 +23,14 @@ func (p PlayerStatus) String() string {
 // of poker, which encompasses all betting rounds from preflop, flop, turn, and
 // river.
 type Player struct {
-	Chips     int
-	HasFolded bool
-	HoleCards [2]*Card
-	ID        string
-	Name      string
-	Status    PlayerStatus
-	IsHuman   bool
+	Chips      int
+	HasFolded  bool
+	HoleCards  [2]*Card
+	ID         string
+	Name       string
+	ShownCards [2]bool // Hole cards that have been revealed to the table
+	Status     PlayerStatus
+	IsHuman    bool
 }
 
 // PrintHoleCards gets the player's hand in abbreviated format.
@@ -41,6 +42,11 @@ func (p *Player) PrintHoleCards() (string, error) {
 	return hand, nil
 }
 
+// ShowHoleCards reveals the player's hole cards to the table.
+func (p *Player) ShowHoleCards() {
+	p.ShownCards = [2]bool{true, true}
+}
+
 // CanFold checks if the player can fold.
 func (p *Player) CanFold(b *BettingRound) bool {
 	return (p.Status == PlayerActive &&
@@ -187,6 +193,7 @@ func (p *Player) Raise(t *Table, b *BettingRound, raiseAmount int) error {
 		b.RaiseByAmount = raiseAmount - b.CallAmount
 	}
 
+	b.Aggressor = p
 	b.CallAmount = raiseAmount
 	b.Raiser = p
 	t.Pot.Bets[p] += chipsNeeded
@@ -13,7 +13,9 @@ type Table struct {
 	SmallBlind *Seat // Start at small blind seat
 	BigBlind   *Seat // Start at big blind seat
 	MinBet     int
+	Ante       int // Optional. Posted by every player before the blinds.
 	Pot        *Pot
+	Rake       *Rake // Optional. No rake is taken if nil.
 	Flop       [3]*Card
 	Turn       *Card
 	River      *Card
@@ -58,7 +60,9 @@ func NewSeat(n int) *Seat {
 
 // Pot represents the amount of chips in play
 type Pot struct {
-	Bets map[*Player]int
+	Bets     map[*Player]int
+	Rake     int        // Chips taken by the house once the pot has been awarded
+	SidePots []*SidePot // Side pots, minus the rake, once the pot has been awarded
 }
 
 // SidePot represents a side pot
@@ -66,6 +70,7 @@ type SidePot struct {
 	Players []*Player
 	Total   int
 	MaxBet  int
+	Rake    int
 }
 
 // NewPot creates a new pot
@@ -84,6 +89,21 @@ func (p *Pot) GetTotal() int {
 	return total
 }
 
+// GetUncalledBet gets the amount of the largest bet that no other player matched.
+func (p *Pot) GetUncalledBet() int {
+	largestBet := 0
+	secondLargestBet := 0
+	for _, betAmount := range p.Bets {
+		if betAmount > largestBet {
+			secondLargestBet = largestBet
+			largestBet = betAmount
+		} else if betAmount > secondLargestBet {
+			secondLargestBet = betAmount
+		}
+	}
+	return largestBet - secondLargestBet
+}
+
 // GetSidePots splits the pot into multiple pots
 func (p *Pot) GetSidePots() []*SidePot {
 	activePlayerBets := make(ByPlayerBet, 0)
@@ -173,6 +193,7 @@ func (p ByPlayerBet) Less(i, j int) bool {
 
 // BettingRound keeps track of chips/bets/raisers for a round of betting (preflop, flop, turn, river).
 type BettingRound struct {
+	Aggressor     *Player // Last player to bet/raise. Nil if no one has bet/raised.
 	Bets          map[string]int
 	CallAmount    int
 	Raiser        *Player
@@ -221,14 +242,41 @@ func DealHands(d *Deck, t *Table) {
 	}
 }
 
+// TakeAntes takes the ante from every active player and adds it to the pot.
+//
+// Antes are dead money, so they do not count towards the player's bet in the betting round.
+// Players who do not have enough chips post what they have and are all in. The amount
+// posted by each player is returned.
+func TakeAntes(t *Table) map[*Player]int {
+	antes := make(map[*Player]int)
+	if t.Ante <= 0 {
+		return antes
+	}
+	for _, p := range GetActivePlayers(t) {
+		ante := t.Ante
+		if p.Chips < ante {
+			ante = p.Chips
+		}
+		p.Chips -= ante
+		t.Pot.Bets[p] += ante
+		antes[p] = ante
+	}
+	return antes
+}
+
 // TakeSmallBlind takes the small blind and adds it to the pot.
+//
+// If the player does not have enough chips, they post what they have and are all in.
 func TakeSmallBlind(t *Table, b *BettingRound) error {
 	p := t.SmallBlind.Player
-	if p.Chips < t.MinBet {
+	if p.Chips <= 0 {
 		return fmt.Errorf("%s does not have enough chips to play", p.Name)
 	}
 
 	smallBlind := (t.MinBet / 2)
+	if p.Chips < smallBlind {
+		smallBlind = p.Chips
+	}
 	p.Chips -= smallBlind
 	t.Pot.Bets[p] += smallBlind
 	b.Bets[p.ID] = smallBlind
@@ -237,15 +285,22 @@ func TakeSmallBlind(t *Table, b *BettingRound) error {
 }
 
 // TakeBigBlind takes the big blind and adds it to the pot.
+//
+// If the player does not have enough chips, they post what they have and are all in. The
+// other players still need to call the full big blind.
 func TakeBigBlind(t *Table, b *BettingRound) error {
 	p := t.BigBlind.Player
-	if p.Chips < t.MinBet {
+	if p.Chips <= 0 {
 		return fmt.Errorf("%s does not have enough chips to play", p.Name)
 	}
 
-	p.Chips -= t.MinBet
-	t.Pot.Bets[p] += t.MinBet
-	b.Bets[p.ID] = t.MinBet
+	bigBlind := t.MinBet
+	if p.Chips < bigBlind {
+		bigBlind = p.Chips
+	}
+	p.Chips -= bigBlind
+	t.Pot.Bets[p] += bigBlind
+	b.Bets[p.ID] = bigBlind
 	b.CallAmount = t.MinBet
 	b.RaiseByAmount = t.MinBet
 
@@ -327,38 +382,45 @@ func GetNextActiveSeat(currentSeat *Seat) (*Seat, error) {
 // DetermineWinners determines the winners of the hand
 func DetermineWinners(t *Table) [][]PlayerHand {
 	subPots := t.Pot.GetSidePots()
+	TakeRake(t, subPots)
+	t.Pot.SidePots = subPots
 	numSubPots := len(subPots)
 	allWinningHands := make([][]PlayerHand, numSubPots)
 
 	// Multiple pots can be created if there are players who are all in with
 	// different chip stacks.
 	for i, subPot := range subPots {
-		winningHands := FindWinningHands(subPot.Players, t)
-
-		// In the case of a tie, divide the pot amongst the winners
-		chipsWon := subPot.Total / len(winningHands)
-		remainderChipsWon := subPot.Total % len(winningHands)
-
-		// If the pot can be split evenly among all winners, then
-		// we will distribute one leftover chip to each player until
-		// there are no more chips
-		for j := range winningHands {
-			playerChipsWon := chipsWon
-			if remainderChipsWon > 0 {
-				remainderChipsWon--
-				playerChipsWon++
-			}
-			// Keep track of the chips won for logging purposes, such as displaying to chat
-			winningHands[j].ChipsWon = playerChipsWon
-			// Award winning chips to player
-			winningHands[j].Player.Chips += playerChipsWon
-		}
-		allWinningHands[i] = winningHands
+		allWinningHands[i] = awardSidePot(t, subPot.Players, subPot.Total)
 	}
 
 	return allWinningHands
 }
 
+// awardSidePot awards the chips in a side pot to the players with the best hands.
+func awardSidePot(t *Table, players []*Player, total int) []PlayerHand {
+	winningHands := FindWinningHands(players, t)
+
+	// In the case of a tie, divide the pot amongst the winners
+	chipsWon := total / len(winningHands)
+	remainderChipsWon := total % len(winningHands)
+
+	// If the pot can be split evenly among all winners, then
+	// we will distribute one leftover chip to each player until
+	// there are no more chips
+	for j := range winningHands {
+		playerChipsWon := chipsWon
+		if remainderChipsWon > 0 {
+			remainderChipsWon--
+			playerChipsWon++
+		}
+		// Keep track of the chips won for logging purposes, such as displaying to chat
+		winningHands[j].ChipsWon = playerChipsWon
+		// Award winning chips to player
+		winningHands[j].Player.Chips += playerChipsWon
+	}
+	return winningHands
+}
+
 // DetermineWinnerByFold checks if a player has won the hand by making everyone fold
 func DetermineWinnerByFold(currentSeat *Seat) *Player {
 	var activePlayer *Player
@@ -376,10 +438,14 @@ func DetermineWinnerByFold(currentSeat *Seat) *Player {
 	return activePlayer
 }
 
-// AwardPot awards the entire pot to a player. This is used when all players have folded
-// and there is no need to determine the best hand.
-func AwardPot(t *Table, p *Player) {
-	p.Chips += t.Pot.GetTotal()
+// AwardPot awards the entire pot, minus the rake, to a player. This is used when all players
+// have folded and there is no need to determine the best hand.
+func AwardPot(t *Table, p *Player) int {
+	t.Pot.SidePots = t.Pot.GetSidePots()
+	TakeRake(t, t.Pot.SidePots)
+	chipsWon := t.Pot.GetTotal() - t.Pot.Rake
+	p.Chips += chipsWon
+	return chipsWon
 }
 
 // SkipToShowdown checks if we should skip to the showdown.
@@ -310,3 +310,67 @@ var _ = Describe("Pot - GetSidePots", func() {
 		})
 	})
 })
+
+var _ = Describe("Blinds and antes", func() {
+	var t poker.Table
+	var b *poker.BettingRound
+	var ps []*poker.Player
+
+	BeforeEach(func() {
+		ps = []*poker.Player{
+			{ID: "1", Name: "Player 1", Chips: 100, Status: poker.PlayerActive},
+			{ID: "2", Name: "Player 2", Chips: 3, Status: poker.PlayerActive},
+			{ID: "3", Name: "Player 3", Chips: 12, Status: poker.PlayerActive},
+		}
+		seats := poker.NewSeat(len(ps))
+		for _, p := range ps {
+			seats.Player = p
+			seats = seats.Next()
+		}
+		t = poker.Table{
+			Ante:       2,
+			BigBlind:   seats.Move(2),
+			Dealer:     seats,
+			MinBet:     20,
+			Pot:        poker.NewPot(),
+			Seats:      seats,
+			SmallBlind: seats.Next(),
+		}
+		var err error
+		b, err = poker.NewBettingRound(seats, 0, t.MinBet)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Context("when players have enough chips", func() {
+		It("takes the ante from every active player as dead money", func() {
+			ps[2].Status = poker.PlayerSittingOut
+			antes := poker.TakeAntes(&t)
+			Expect(antes).To(HaveLen(2))
+			Expect(ps[0].Chips).To(Equal(98))
+			Expect(ps[1].Chips).To(Equal(1))
+			Expect(ps[2].Chips).To(Equal(12))
+			Expect(t.Pot.GetTotal()).To(Equal(4))
+			Expect(b.Bets[ps[0].ID]).To(Equal(0))
+		})
+	})
+
+	Context("when players do not have enough chips for the blinds", func() {
+		It("puts the players all in", func() {
+			poker.TakeAntes(&t)
+			Expect(poker.TakeSmallBlind(&t, b)).To(Succeed())
+			Expect(poker.TakeBigBlind(&t, b)).To(Succeed())
+
+			Expect(ps[1].Chips).To(Equal(0))
+			Expect(b.Bets[ps[1].ID]).To(Equal(1))
+			Expect(ps[2].Chips).To(Equal(0))
+			Expect(b.Bets[ps[2].ID]).To(Equal(10))
+			Expect(b.CallAmount).To(Equal(20))
+			Expect(t.Pot.GetTotal()).To(Equal(2 + 3 + 12))
+		})
+
+		It("cannot take a blind from a player without chips", func() {
+			ps[1].Chips = 0
+			Expect(poker.TakeSmallBlind(&t, b)).NotTo(Succeed())
+		})
+	})
+})
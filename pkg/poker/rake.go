@@ -0,0 +1,111 @@
+package poker
+
+import (
+	"math"
+	"sort"
+)
+
+// Rake is the house's cut of a pot.
+//
+// - The percentage is taken from the total pot and rounded down to the nearest chip
+// - The cap can vary by the number of players dealt into the hand
+// - If NoFlopNoDrop is set, no rake is taken when the hand ends before the flop
+type Rake struct {
+	Percent      float64
+	Cap          int         // Zero means that there is no cap
+	CapByPlayers map[int]int // Overrides Cap when at least N players were dealt in
+	NoFlopNoDrop bool
+}
+
+// GetCap gets the rake cap for the number of players dealt into the hand.
+//
+// The cap with the largest player count that does not exceed numPlayers is
+// used. If no caps match, the default cap is used.
+func (r *Rake) GetCap(numPlayers int) int {
+	capPlayers := make([]int, 0, len(r.CapByPlayers))
+	for n := range r.CapByPlayers {
+		capPlayers = append(capPlayers, n)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(capPlayers)))
+	for _, n := range capPlayers {
+		if n <= numPlayers {
+			return r.CapByPlayers[n]
+		}
+	}
+	return r.Cap
+}
+
+// Calculate calculates the rake for a pot.
+func (r *Rake) Calculate(potTotal int, numPlayers int, sawFlop bool) int {
+	if r.NoFlopNoDrop && sawFlop == false {
+		return 0
+	}
+	rake := int(math.Floor(float64(potTotal) * r.Percent / 100))
+	rakeCap := r.GetCap(numPlayers)
+	if rakeCap > 0 && rake > rakeCap {
+		rake = rakeCap
+	}
+	if rake < 0 {
+		return 0
+	}
+	return rake
+}
+
+// TakeRake takes the rake from the side pots and records the total on the pot.
+//
+// The rake is taken proportionally from each side pot. Chips that are lost to
+// rounding are taken from the earliest pots first, starting with the main pot.
+// Uncalled bets are not raked.
+func TakeRake(t *Table, sidePots []*SidePot) int {
+	t.Pot.Rake = 0
+	if t.Rake == nil || len(sidePots) == 0 {
+		return 0
+	}
+
+	// Uncalled chips can only be in the last side pot since it contains the largest bets
+	rakeableAmounts := make([]int, len(sidePots))
+	rakeableTotal := 0
+	for i, sidePot := range sidePots {
+		rakeableAmounts[i] = sidePot.Total
+		if i == len(sidePots)-1 {
+			rakeableAmounts[i] -= t.Pot.GetUncalledBet()
+		}
+		rakeableTotal += rakeableAmounts[i]
+	}
+
+	rake := t.Rake.Calculate(rakeableTotal, countPlayersDealtIn(t), t.Flop[0] != nil)
+	if rake == 0 {
+		return 0
+	}
+
+	remainingRake := rake
+	for i, sidePot := range sidePots {
+		sidePot.Rake = rake * rakeableAmounts[i] / rakeableTotal
+		remainingRake -= sidePot.Rake
+	}
+	for i, sidePot := range sidePots {
+		if remainingRake == 0 {
+			break
+		}
+		if sidePot.Rake < rakeableAmounts[i] {
+			sidePot.Rake++
+			remainingRake--
+		}
+	}
+	for _, sidePot := range sidePots {
+		sidePot.Total -= sidePot.Rake
+	}
+
+	t.Pot.Rake = rake
+	return rake
+}
+
+// countPlayersDealtIn counts the players that were dealt into the hand.
+//
+// If the table has no seats, then players with chips in the pot are counted instead.
+func countPlayersDealtIn(t *Table) int {
+	if t.Seats != nil {
+		return CountSeatsByPlayerStatus(t.Seats, PlayerActive)
+	}
+	return len(t.Pot.Bets)
+}
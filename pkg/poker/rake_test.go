@@ -0,0 +1,140 @@
+package poker_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+var _ = Describe("Rake - GetCap", func() {
+	rake := poker.Rake{
+		Percent:      5,
+		Cap:          3,
+		CapByPlayers: map[int]int{2: 1, 4: 2},
+	}
+
+	Context("when there is a cap for fewer players", func() {
+		It("uses the closest cap", func() {
+			Expect(rake.GetCap(3)).To(Equal(1))
+			Expect(rake.GetCap(5)).To(Equal(2))
+		})
+	})
+
+	Context("when there is no cap for the number of players", func() {
+		It("uses the default cap", func() {
+			Expect(rake.GetCap(1)).To(Equal(3))
+		})
+	})
+})
+
+var _ = Describe("Rake - Calculate", func() {
+	Context("when the rake is less than the cap", func() {
+		It("rounds the rake down", func() {
+			rake := poker.Rake{Percent: 5, Cap: 10}
+			Expect(rake.Calculate(59, 2, true)).To(Equal(2))
+		})
+	})
+
+	Context("when the rake is more than the cap", func() {
+		It("takes the cap", func() {
+			rake := poker.Rake{Percent: 5, Cap: 10}
+			Expect(rake.Calculate(1000, 2, true)).To(Equal(10))
+		})
+	})
+
+	Context("when the hand ended before the flop", func() {
+		It("takes no rake with no flop no drop", func() {
+			rake := poker.Rake{Percent: 5, NoFlopNoDrop: true}
+			Expect(rake.Calculate(100, 2, false)).To(Equal(0))
+		})
+
+		It("takes the rake without no flop no drop", func() {
+			rake := poker.Rake{Percent: 5}
+			Expect(rake.Calculate(100, 2, false)).To(Equal(5))
+		})
+	})
+})
+
+var _ = Describe("TakeRake", func() {
+	var ps []*poker.Player
+
+	BeforeEach(func() {
+		ps = []*poker.Player{
+			{ID: "1", Name: "Player 1"},
+			{ID: "2", Name: "Player 2"},
+			{ID: "3", Name: "Player 3"},
+		}
+	})
+
+	Context("when there is no rake", func() {
+		It("takes nothing", func() {
+			t := poker.Table{Pot: poker.NewPot()}
+			t.Pot.Bets[ps[0]] = 20
+			t.Pot.Bets[ps[1]] = 20
+			sidePots := t.Pot.GetSidePots()
+			Expect(poker.TakeRake(&t, sidePots)).To(Equal(0))
+			Expect(sidePots[0].Total).To(Equal(40))
+		})
+	})
+
+	Context("when there are side pots", func() {
+		It("takes the rake proportionally", func() {
+			t := poker.Table{
+				Pot:  poker.NewPot(),
+				Flop: [3]*poker.Card{{Rank: poker.Two}, {Rank: poker.Three}, {Rank: poker.Four}},
+				Rake: &poker.Rake{Percent: 10},
+			}
+			t.Pot.Bets[ps[0]] = 10
+			t.Pot.Bets[ps[1]] = 30
+			t.Pot.Bets[ps[2]] = 30
+			sidePots := t.Pot.GetSidePots()
+
+			Expect(poker.TakeRake(&t, sidePots)).To(Equal(7))
+			Expect(t.Pot.Rake).To(Equal(7))
+			Expect(sidePots[0].Rake).To(Equal(3))
+			Expect(sidePots[0].Total).To(Equal(27))
+			Expect(sidePots[1].Rake).To(Equal(4))
+			Expect(sidePots[1].Total).To(Equal(36))
+		})
+	})
+
+	Context("when there is an uncalled bet", func() {
+		It("does not rake the uncalled chips", func() {
+			ps[1].HasFolded = true
+			t := poker.Table{
+				Pot:  poker.NewPot(),
+				Flop: [3]*poker.Card{{Rank: poker.Two}, {Rank: poker.Three}, {Rank: poker.Four}},
+				Rake: &poker.Rake{Percent: 10},
+			}
+			t.Pot.Bets[ps[0]] = 100
+			t.Pot.Bets[ps[1]] = 20
+			sidePots := t.Pot.GetSidePots()
+
+			Expect(poker.TakeRake(&t, sidePots)).To(Equal(4))
+			Expect(sidePots[0].Total).To(Equal(116))
+		})
+	})
+})
+
+var _ = Describe("AwardPot", func() {
+	Context("when there is a rake", func() {
+		It("awards the pot minus the rake", func() {
+			ps := []*poker.Player{
+				{ID: "1", Name: "Player 1", Chips: 0},
+				{ID: "2", Name: "Player 2", HasFolded: true},
+			}
+			t := poker.Table{
+				Pot:  poker.NewPot(),
+				Flop: [3]*poker.Card{{Rank: poker.Two}, {Rank: poker.Three}, {Rank: poker.Four}},
+				Rake: &poker.Rake{Percent: 10, Cap: 1},
+			}
+			t.Pot.Bets[ps[0]] = 40
+			t.Pot.Bets[ps[1]] = 20
+
+			Expect(poker.AwardPot(&t, ps[0])).To(Equal(59))
+			Expect(ps[0].Chips).To(Equal(59))
+			Expect(t.Pot.Rake).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,89 @@
+package poker
+
+// Board is a set of community cards.
+type Board struct {
+	Flop  [3]*Card `json:"flop"`
+	Turn  *Card    `json:"turn"`
+	River *Card    `json:"river"`
+}
+
+// GetBoard gets the community cards that are currently on the table.
+func (t *Table) GetBoard() Board {
+	return Board{
+		Flop:  t.Flop,
+		Turn:  t.Turn,
+		River: t.River,
+	}
+}
+
+// SetBoard puts the community cards on the table.
+func (t *Table) SetBoard(b Board) {
+	t.Flop = b.Flop
+	t.Turn = b.Turn
+	t.River = b.River
+}
+
+// DealBoards deals the remaining community cards multiple times from the same deck.
+//
+// This is used when players who are all in agree to run it more than once. Cards
+// that have already been dealt are shared by every board. The table is left with
+// the first board. An error will occur if the deck runs out of cards.
+func DealBoards(d *Deck, t *Table, numBoards int) ([]Board, error) {
+	sharedBoard := t.GetBoard()
+	boards := make([]Board, numBoards)
+	for i := range boards {
+		board := sharedBoard
+		cards := make([]**Card, 0, 5)
+		if board.Flop[0] == nil {
+			for j := range board.Flop {
+				cards = append(cards, &board.Flop[j])
+			}
+		}
+		if board.Turn == nil {
+			cards = append(cards, &board.Turn)
+		}
+		if board.River == nil {
+			cards = append(cards, &board.River)
+		}
+		for _, card := range cards {
+			next, err := d.GetNextCard()
+			if err != nil {
+				t.SetBoard(sharedBoard)
+				return nil, err
+			}
+			*card = next
+		}
+		boards[i] = board
+	}
+	t.SetBoard(boards[0])
+	return boards, nil
+}
+
+// DetermineWinnersByBoard determines the winners of the hand when it has been run out
+// on multiple boards.
+//
+// Each side pot is split evenly between the boards. If a pot cannot be split evenly,
+// then the leftover chips go to the earliest boards. The winning hands are returned by
+// board and then by side pot.
+func DetermineWinnersByBoard(t *Table, boards []Board) [][][]PlayerHand {
+	subPots := t.Pot.GetSidePots()
+	TakeRake(t, subPots)
+	t.Pot.SidePots = subPots
+
+	numBoards := len(boards)
+	allWinningHands := make([][][]PlayerHand, numBoards)
+	for i, board := range boards {
+		t.SetBoard(board)
+		allWinningHands[i] = make([][]PlayerHand, len(subPots))
+		for j, subPot := range subPots {
+			total := subPot.Total / numBoards
+			if i < subPot.Total%numBoards {
+				total++
+			}
+			allWinningHands[i][j] = awardSidePot(t, subPot.Players, total)
+		}
+	}
+	t.SetBoard(boards[0])
+
+	return allWinningHands
+}
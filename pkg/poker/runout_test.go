@@ -0,0 +1,98 @@
+package poker_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+var _ = Describe("DealBoards", func() {
+	Context("when the flop has been dealt", func() {
+		It("shares the flop and deals a different turn and river for each board", func() {
+			deck := poker.NewDeck()
+			t := poker.Table{Pot: poker.NewPot()}
+			poker.DealFlop(&deck, &t)
+			flop := t.Flop
+
+			boards, err := poker.DealBoards(&deck, &t, 2)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(boards).To(HaveLen(2))
+			Expect(boards[0].Flop).To(Equal(flop))
+			Expect(boards[1].Flop).To(Equal(flop))
+			Expect(*boards[0].Turn).NotTo(Equal(*boards[1].Turn))
+			Expect(*boards[0].River).NotTo(Equal(*boards[1].River))
+			Expect(t.GetBoard()).To(Equal(boards[0]))
+		})
+	})
+
+	Context("when the deck runs out", func() {
+		It("returns an error and leaves the board alone", func() {
+			deck := poker.NewDeck()
+			t := poker.Table{Pot: poker.NewPot()}
+
+			boards, err := poker.DealBoards(&deck, &t, 11)
+
+			Expect(err).To(HaveOccurred())
+			Expect(boards).To(BeNil())
+			Expect(t.GetBoard()).To(Equal(poker.Board{}))
+		})
+	})
+})
+
+var _ = Describe("DetermineWinnersByBoard", func() {
+	Context("when each player wins one board", func() {
+		It("splits the pot between the boards", func() {
+			ps := []*poker.Player{
+				{
+					ID:   "1",
+					Name: "Player 1",
+					HoleCards: [2]*poker.Card{
+						{Rank: poker.Ace, Suit: poker.Clubs},
+						{Rank: poker.Ace, Suit: poker.Hearts},
+					},
+				},
+				{
+					ID:   "2",
+					Name: "Player 2",
+					HoleCards: [2]*poker.Card{
+						{Rank: poker.King, Suit: poker.Clubs},
+						{Rank: poker.King, Suit: poker.Hearts},
+					},
+				},
+			}
+			flop := [3]*poker.Card{
+				{Rank: poker.Two, Suit: poker.Spades},
+				{Rank: poker.Seven, Suit: poker.Diamonds},
+				{Rank: poker.Nine, Suit: poker.Clubs},
+			}
+			boards := []poker.Board{
+				{
+					Flop:  flop,
+					Turn:  &poker.Card{Rank: poker.Three, Suit: poker.Spades},
+					River: &poker.Card{Rank: poker.Four, Suit: poker.Spades},
+				},
+				{
+					Flop:  flop,
+					Turn:  &poker.Card{Rank: poker.King, Suit: poker.Spades},
+					River: &poker.Card{Rank: poker.Four, Suit: poker.Diamonds},
+				},
+			}
+
+			t := poker.Table{Pot: poker.NewPot()}
+			t.Pot.Bets[ps[0]] = 51
+			t.Pot.Bets[ps[1]] = 50
+
+			winningHands := poker.DetermineWinnersByBoard(&t, boards)
+
+			Expect(winningHands).To(HaveLen(2))
+			Expect(winningHands[0][0][0].Player).To(Equal(ps[0]))
+			Expect(winningHands[0][0][0].ChipsWon).To(Equal(50))
+			Expect(winningHands[1][0][0].Player).To(Equal(ps[1]))
+			Expect(winningHands[1][0][0].ChipsWon).To(Equal(50))
+			Expect(ps[0].Chips).To(Equal(51))
+			Expect(ps[1].Chips).To(Equal(50))
+		})
+	})
+})
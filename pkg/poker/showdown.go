@@ -0,0 +1,85 @@
+package poker
+
+// GetShowdownOrder gets the order that players reveal their hands at showdown.
+//
+// The last player to bet or raise on the final betting round shows first. If everyone
+// checked, then the first player left of the dealer shows first.
+func GetShowdownOrder(t *Table, lastAggressor *Player) []*Player {
+	startSeat := t.Dealer.Next()
+	if lastAggressor != nil {
+		seat := t.Seats
+		for i := 0; i < seat.Len(); i++ {
+			if seat.Player == lastAggressor {
+				startSeat = seat
+				break
+			}
+			seat = seat.Next()
+		}
+	}
+
+	players := make([]*Player, 0)
+	seat := startSeat
+	for i := 0; i < seat.Len(); i++ {
+		if seat.Player.Status == PlayerActive && seat.Player.HasFolded == false {
+			players = append(players, seat.Player)
+		}
+		seat = seat.Next()
+	}
+	return players
+}
+
+// RevealHands reveals the hands of the players at showdown in the given order.
+//
+// A player must show their hand if it can still win or tie one of the pots they are
+// eligible for. Otherwise they can choose to muck their hand. Hands that have already
+// been shown stay face up. The players who mucked their hands are returned.
+func RevealHands(t *Table, order []*Player, shouldMuck func(p *Player) bool) []*Player {
+	shown := make([]*Player, 0)
+	mucked := make([]*Player, 0)
+	for _, p := range order {
+		if CanMuck(t, shown, p) && shouldMuck(p) {
+			mucked = append(mucked, p)
+			continue
+		}
+		p.ShowHoleCards()
+		shown = append(shown, p)
+	}
+	return mucked
+}
+
+// CanMuck checks if a player can muck their hand at showdown after the given players have
+// shown theirs. A hand that can still win or tie a pot that the player is eligible for must
+// be shown, as must a hand that has already been shown.
+func CanMuck(t *Table, shown []*Player, p *Player) bool {
+	if p.ShownCards[0] && p.ShownCards[1] {
+		return false
+	}
+	hand := GetBestHand(p, t)
+
+	// Pots with only one eligible player do not need to be contested
+	for _, sidePot := range t.Pot.GetSidePots() {
+		if len(sidePot.Players) < 2 || containsPlayer(sidePot.Players, p) == false {
+			continue
+		}
+		isBeaten := false
+		for _, other := range shown {
+			if containsPlayer(sidePot.Players, other) && CompareHand(hand, GetBestHand(other, t)) == LessThan {
+				isBeaten = true
+				break
+			}
+		}
+		if isBeaten == false {
+			return false
+		}
+	}
+	return true
+}
+
+func containsPlayer(ps []*Player, p *Player) bool {
+	for _, player := range ps {
+		if player == p {
+			return true
+		}
+	}
+	return false
+}
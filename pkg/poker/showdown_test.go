@@ -0,0 +1,125 @@
+package poker_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+var _ = Describe("Showdown", func() {
+	var ps []*poker.Player
+	var t poker.Table
+
+	BeforeEach(func() {
+		ps = []*poker.Player{
+			{
+				ID:     "1",
+				Name:   "Player 1",
+				Status: poker.PlayerActive,
+				HoleCards: [2]*poker.Card{
+					{Rank: poker.King, Suit: poker.Clubs},
+					{Rank: poker.King, Suit: poker.Hearts},
+				},
+			},
+			{
+				ID:     "2",
+				Name:   "Player 2",
+				Status: poker.PlayerActive,
+				HoleCards: [2]*poker.Card{
+					{Rank: poker.Ace, Suit: poker.Clubs},
+					{Rank: poker.Ace, Suit: poker.Hearts},
+				},
+			},
+			{
+				ID:     "3",
+				Name:   "Player 3",
+				Status: poker.PlayerActive,
+				HoleCards: [2]*poker.Card{
+					{Rank: poker.Three, Suit: poker.Clubs},
+					{Rank: poker.Four, Suit: poker.Hearts},
+				},
+			},
+		}
+
+		seats := poker.NewSeat(len(ps))
+		for _, p := range ps {
+			seats.Player = p
+			seats = seats.Next()
+		}
+
+		t = poker.Table{
+			Dealer: seats,
+			Seats:  seats,
+			Pot:    poker.NewPot(),
+			Flop: [3]*poker.Card{
+				{Rank: poker.Two, Suit: poker.Spades},
+				{Rank: poker.Seven, Suit: poker.Diamonds},
+				{Rank: poker.Nine, Suit: poker.Clubs},
+			},
+			Turn:  &poker.Card{Rank: poker.Jack, Suit: poker.Spades},
+			River: &poker.Card{Rank: poker.Five, Suit: poker.Diamonds},
+		}
+		for _, p := range ps {
+			t.Pot.Bets[p] = 10
+		}
+	})
+
+	Describe("GetShowdownOrder", func() {
+		Context("when there was no aggressor", func() {
+			It("starts with the player left of the dealer", func() {
+				Expect(poker.GetShowdownOrder(&t, nil)).To(Equal([]*poker.Player{ps[1], ps[2], ps[0]}))
+			})
+		})
+
+		Context("when there was an aggressor", func() {
+			It("starts with the aggressor", func() {
+				Expect(poker.GetShowdownOrder(&t, ps[2])).To(Equal([]*poker.Player{ps[2], ps[0], ps[1]}))
+			})
+		})
+
+		Context("when a player has folded", func() {
+			It("skips the player", func() {
+				ps[1].HasFolded = true
+				Expect(poker.GetShowdownOrder(&t, nil)).To(Equal([]*poker.Player{ps[2], ps[0]}))
+			})
+		})
+	})
+
+	Describe("RevealHands", func() {
+		Context("when players want to muck losing hands", func() {
+			It("only shows hands that can win", func() {
+				order := poker.GetShowdownOrder(&t, ps[0])
+				mucked := poker.RevealHands(&t, order, func(p *poker.Player) bool { return true })
+
+				Expect(mucked).To(Equal([]*poker.Player{ps[2]}))
+				Expect(ps[0].ShownCards).To(Equal([2]bool{true, true}))
+				Expect(ps[1].ShownCards).To(Equal([2]bool{true, true}))
+				Expect(ps[2].ShownCards).To(Equal([2]bool{false, false}))
+			})
+		})
+
+		Context("when players do not want to muck losing hands", func() {
+			It("shows every hand", func() {
+				order := poker.GetShowdownOrder(&t, ps[0])
+				mucked := poker.RevealHands(&t, order, func(p *poker.Player) bool { return false })
+
+				Expect(mucked).To(BeEmpty())
+				Expect(ps[2].ShownCards).To(Equal([2]bool{true, true}))
+			})
+		})
+	})
+
+	Describe("CanMuck", func() {
+		It("makes players show hands that can still win", func() {
+			Expect(poker.CanMuck(&t, []*poker.Player{}, ps[2])).To(BeFalse())
+			Expect(poker.CanMuck(&t, []*poker.Player{ps[1]}, ps[0])).To(BeTrue())
+			Expect(poker.CanMuck(&t, []*poker.Player{ps[0]}, ps[1])).To(BeFalse())
+		})
+
+		It("keeps hands that were shown face up", func() {
+			ps[0].ShowHoleCards()
+			Expect(poker.CanMuck(&t, []*poker.Player{ps[1]}, ps[0])).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,246 @@
+package poker
+
+import "fmt"
+
+// TableSnapshot is a copy of a table that can be serialized.
+//
+// Seats form a ring and the pot is keyed by player pointers, so neither can be
+// serialized directly. Instead players are listed in seat order starting at the first
+// seat, seats are referenced by index and players are referenced by ID.
+type TableSnapshot struct {
+	Ante       int         `json:"ante"`
+	BigBlind   int         `json:"bigBlind"` // Seat index. -1 if not set.
+	Dealer     int         `json:"dealer"`   // Seat index. -1 if not set.
+	Flop       [3]*Card    `json:"flop"`
+	MinBet     int         `json:"minBet"`
+	Players    []Player    `json:"players"`
+	Pot        PotSnapshot `json:"pot"`
+	Rake       *Rake       `json:"rake"`
+	River      *Card       `json:"river"`
+	SmallBlind int         `json:"smallBlind"` // Seat index. -1 if not set.
+	Turn       *Card       `json:"turn"`
+}
+
+// PotSnapshot is a copy of a pot that can be serialized.
+type PotSnapshot struct {
+	Bets     map[string]int    `json:"bets"` // Keyed by player ID
+	Rake     int               `json:"rake"`
+	SidePots []SidePotSnapshot `json:"sidePots"`
+}
+
+// SidePotSnapshot is a copy of a side pot that can be serialized.
+type SidePotSnapshot struct {
+	MaxBet    int      `json:"maxBet"`
+	PlayerIDs []string `json:"playerIDs"`
+	Rake      int      `json:"rake"`
+	Total     int      `json:"total"`
+}
+
+// BettingRoundSnapshot is a copy of a betting round that can be serialized.
+type BettingRoundSnapshot struct {
+	Aggressor     string         `json:"aggressor"` // Player ID. Empty if no one has bet/raised.
+	Bets          map[string]int `json:"bets"`
+	CallAmount    int            `json:"callAmount"`
+	RaiseByAmount int            `json:"raiseByAmount"`
+	Raiser        string         `json:"raiser"` // Player ID
+}
+
+// DeckSnapshot is a copy of a deck that can be serialized.
+type DeckSnapshot struct {
+	Cards            []Card `json:"cards"`
+	CurrentCardIndex int    `json:"currentCardIndex"`
+}
+
+// Snapshot creates a serializable copy of the table.
+func (t *Table) Snapshot() TableSnapshot {
+	s := TableSnapshot{
+		Ante:       t.Ante,
+		BigBlind:   GetSeatIndex(t, t.BigBlind),
+		Dealer:     GetSeatIndex(t, t.Dealer),
+		Flop:       t.Flop,
+		MinBet:     t.MinBet,
+		Players:    make([]Player, 0),
+		Rake:       t.Rake,
+		River:      t.River,
+		SmallBlind: GetSeatIndex(t, t.SmallBlind),
+		Turn:       t.Turn,
+	}
+
+	seat := t.Seats
+	for i := 0; i < seat.Len(); i++ {
+		s.Players = append(s.Players, *seat.Player)
+		seat = seat.Next()
+	}
+
+	if t.Pot != nil {
+		s.Pot.Bets = make(map[string]int)
+		s.Pot.Rake = t.Pot.Rake
+		for p, betAmount := range t.Pot.Bets {
+			s.Pot.Bets[p.ID] = betAmount
+		}
+		for _, sidePot := range t.Pot.SidePots {
+			playerIDs := make([]string, 0)
+			for _, p := range sidePot.Players {
+				playerIDs = append(playerIDs, p.ID)
+			}
+			s.Pot.SidePots = append(s.Pot.SidePots, SidePotSnapshot{
+				MaxBet:    sidePot.MaxBet,
+				PlayerIDs: playerIDs,
+				Rake:      sidePot.Rake,
+				Total:     sidePot.Total,
+			})
+		}
+	}
+
+	return s
+}
+
+// RestoreTable rebuilds a table from a snapshot.
+func RestoreTable(s TableSnapshot) (Table, error) {
+	t := Table{
+		Ante:   s.Ante,
+		Flop:   s.Flop,
+		MinBet: s.MinBet,
+		Pot:    NewPot(),
+		Rake:   s.Rake,
+		River:  s.River,
+		Turn:   s.Turn,
+	}
+	if len(s.Players) == 0 {
+		return t, fmt.Errorf("The table snapshot does not have any seats")
+	}
+
+	seat := NewSeat(len(s.Players))
+	t.Seats = seat
+	for i := range s.Players {
+		p := s.Players[i]
+		seat.Player = &p
+		seat = seat.Next()
+	}
+
+	var err error
+	if t.Dealer, err = getSeatByIndex(&t, s.Dealer); err != nil {
+		return t, err
+	}
+	if t.SmallBlind, err = getSeatByIndex(&t, s.SmallBlind); err != nil {
+		return t, err
+	}
+	if t.BigBlind, err = getSeatByIndex(&t, s.BigBlind); err != nil {
+		return t, err
+	}
+
+	t.Pot.Rake = s.Pot.Rake
+	for playerID, betAmount := range s.Pot.Bets {
+		p := GetPlayerByID(&t, playerID)
+		if p == nil {
+			return t, fmt.Errorf("Player %s in the pot is not at the table", playerID)
+		}
+		t.Pot.Bets[p] = betAmount
+	}
+	for _, sidePot := range s.Pot.SidePots {
+		players := make([]*Player, 0)
+		for _, playerID := range sidePot.PlayerIDs {
+			p := GetPlayerByID(&t, playerID)
+			if p == nil {
+				return t, fmt.Errorf("Player %s in a side pot is not at the table", playerID)
+			}
+			players = append(players, p)
+		}
+		t.Pot.SidePots = append(t.Pot.SidePots, &SidePot{
+			MaxBet:  sidePot.MaxBet,
+			Players: players,
+			Rake:    sidePot.Rake,
+			Total:   sidePot.Total,
+		})
+	}
+
+	return t, nil
+}
+
+// Snapshot creates a serializable copy of the betting round.
+func (b *BettingRound) Snapshot() BettingRoundSnapshot {
+	s := BettingRoundSnapshot{
+		Bets:          make(map[string]int),
+		CallAmount:    b.CallAmount,
+		RaiseByAmount: b.RaiseByAmount,
+	}
+	for playerID, betAmount := range b.Bets {
+		s.Bets[playerID] = betAmount
+	}
+	if b.Aggressor != nil {
+		s.Aggressor = b.Aggressor.ID
+	}
+	if b.Raiser != nil {
+		s.Raiser = b.Raiser.ID
+	}
+	return s
+}
+
+// RestoreBettingRound rebuilds a betting round from a snapshot. The players must already be
+// seated at the table.
+func RestoreBettingRound(s BettingRoundSnapshot, t *Table) (*BettingRound, error) {
+	b := &BettingRound{
+		Bets:          make(map[string]int),
+		CallAmount:    s.CallAmount,
+		RaiseByAmount: s.RaiseByAmount,
+	}
+	for playerID, betAmount := range s.Bets {
+		b.Bets[playerID] = betAmount
+	}
+	if s.Aggressor != "" {
+		b.Aggressor = GetPlayerByID(t, s.Aggressor)
+		if b.Aggressor == nil {
+			return nil, fmt.Errorf("Aggressor %s is not at the table", s.Aggressor)
+		}
+	}
+	if s.Raiser != "" {
+		b.Raiser = GetPlayerByID(t, s.Raiser)
+		if b.Raiser == nil {
+			return nil, fmt.Errorf("Raiser %s is not at the table", s.Raiser)
+		}
+	}
+	return b, nil
+}
+
+// Snapshot creates a serializable copy of the deck, including the cards that have been dealt.
+func (d *Deck) Snapshot() DeckSnapshot {
+	cards := make([]Card, len(d.cards))
+	copy(cards, d.cards)
+	return DeckSnapshot{
+		Cards:            cards,
+		CurrentCardIndex: d.currentCardIndex,
+	}
+}
+
+// RestoreDeck rebuilds a deck from a snapshot.
+func RestoreDeck(s DeckSnapshot) Deck {
+	cards := make([]Card, len(s.Cards))
+	copy(cards, s.Cards)
+	return Deck{cards: cards, currentCardIndex: s.CurrentCardIndex}
+}
+
+// GetSeatIndex gets the position of the seat starting from the first seat. -1 is returned if
+// the seat is nil or not at the table.
+func GetSeatIndex(t *Table, s *Seat) int {
+	if s == nil {
+		return -1
+	}
+	seat := t.Seats
+	for i := 0; i < seat.Len(); i++ {
+		if seat == s {
+			return i
+		}
+		seat = seat.Next()
+	}
+	return -1
+}
+
+func getSeatByIndex(t *Table, i int) (*Seat, error) {
+	if i == -1 {
+		return nil, nil
+	}
+	if i < 0 || i >= t.Seats.Len() {
+		return nil, fmt.Errorf("Seat %d is not at the table", i)
+	}
+	return t.Seats.Move(i), nil
+}
@@ -0,0 +1,86 @@
+package poker_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+var _ = Describe("Snapshot", func() {
+	// newTable sets up a three handed table with the blinds posted and the flop dealt
+	newTable := func() (poker.Table, *poker.BettingRound, poker.Deck) {
+		deck := poker.NewDeckFromSeed(42)
+		seats := poker.NewSeat(4)
+		t := poker.Table{MinBet: 2, Pot: poker.NewPot(), Seats: seats}
+		for i, id := range []string{"1", "2", "3", "4"} {
+			seats.Player = &poker.Player{ID: id, Name: "Player " + id, Chips: 100, Status: poker.PlayerActive}
+			if i == 3 {
+				seats.Player.Status = poker.PlayerVacated
+			}
+			seats = seats.Next()
+		}
+		t.Dealer = t.Seats
+		t.SmallBlind = t.Seats.Next()
+		t.BigBlind = t.Seats.Move(2)
+
+		b, err := poker.NewBettingRound(t.Dealer, 0, t.MinBet)
+		Expect(err).NotTo(HaveOccurred())
+		poker.DealHands(&deck, &t)
+		Expect(poker.TakeSmallBlind(&t, b)).To(Succeed())
+		Expect(poker.TakeBigBlind(&t, b)).To(Succeed())
+		Expect(t.Dealer.Player.Raise(&t, b, 6)).To(Succeed())
+		poker.DealFlop(&deck, &t)
+		return t, b, deck
+	}
+
+	It("restores a table from JSON", func() {
+		t, b, deck := newTable()
+
+		data, err := json.Marshal(t.Snapshot())
+		Expect(err).NotTo(HaveOccurred())
+		var s poker.TableSnapshot
+		Expect(json.Unmarshal(data, &s)).To(Succeed())
+		restored, err := poker.RestoreTable(s)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(restored.Seats.Len()).To(Equal(4))
+		Expect(restored.Dealer.Player.ID).To(Equal("1"))
+		Expect(restored.SmallBlind.Player.ID).To(Equal("2"))
+		Expect(restored.BigBlind.Player.ID).To(Equal("3"))
+		Expect(restored.Dealer.Player).To(Equal(t.Dealer.Player))
+		Expect(restored.Dealer.Player).NotTo(BeIdenticalTo(t.Dealer.Player))
+		Expect(restored.GetBoard()).To(Equal(t.GetBoard()))
+		Expect(restored.Pot.GetTotal()).To(Equal(9))
+		Expect(restored.Pot.Bets[restored.Dealer.Player]).To(Equal(6))
+
+		restoredRound, err := poker.RestoreBettingRound(b.Snapshot(), &restored)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restoredRound.Bets).To(Equal(b.Bets))
+		Expect(restoredRound.Raiser).To(BeIdenticalTo(restored.Dealer.Player))
+
+		// The restored deck deals the same cards as the original
+		deckSnapshot := deck.Snapshot()
+		data, err = json.Marshal(deckSnapshot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(json.Unmarshal(data, &deckSnapshot)).To(Succeed())
+		restoredDeck := poker.RestoreDeck(deckSnapshot)
+		expectedCard, err := deck.GetNextCard()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restoredDeck.GetNextCard()).To(Equal(expectedCard))
+	})
+
+	It("keeps unset seats empty", func() {
+		t := poker.Table{MinBet: 2, Pot: poker.NewPot(), Seats: poker.NewSeat(2)}
+		t.Seats.Player = &poker.Player{ID: "1"}
+		t.Seats.Next().Player = &poker.Player{ID: "2"}
+
+		restored, err := poker.RestoreTable(t.Snapshot())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restored.Dealer).To(BeNil())
+		Expect(restored.SmallBlind).To(BeNil())
+		Expect(restored.BigBlind).To(BeNil())
+	})
+})
@@ -0,0 +1,127 @@
+package rating
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// DefaultRating is the rating of a player who has not been rated yet
+const DefaultRating float64 = 1500
+
+// kFactor is the most a rating can change from a single result
+const kFactor float64 = 32
+
+// Reasons for a rating change
+const (
+	ReasonSession    string = "session"
+	ReasonTournament string = "tournament"
+)
+
+// Rating is a player's skill rating.
+type Rating struct {
+	Games     int       `json:"games"`
+	Rating    float64   `json:"rating"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Username  string    `json:"username"`
+}
+
+// Change is a record of a player's rating changing after a session or tournament.
+type Change struct {
+	After     float64   `json:"after"`
+	Before    float64   `json:"before"`
+	CreatedAt time.Time `json:"createdAt"`
+	Reason    string    `json:"reason"`
+	Score     float64   `json:"score"` // Big blinds won in a session or the finishing place in a tournament
+	TableName string    `json:"tableName"`
+	Username  string    `json:"username"`
+}
+
+// Result is how a player did in a session or tournament. Higher scores are better.
+type Result struct {
+	Score    float64
+	Username string
+}
+
+// NewRating creates a rating for a player who has not been rated yet.
+func NewRating(username string) Rating {
+	return Rating{
+		Rating:   DefaultRating,
+		Username: username,
+	}
+}
+
+// Update calculates new ratings using a multiplayer version of Elo.
+//
+// Each player is treated as having played a game against every other player. The player
+// with the higher score wins the game, and equal scores are a draw. The change is divided by
+// the number of opponents so that bigger games do not move ratings more than heads up games.
+//
+// The ratings must include a rating for every player in the results.
+func Update(ratings map[string]Rating, results []Result, reason string, tableName string) ([]Rating, []Change) {
+	now := time.Now().UTC()
+	updated := make([]Rating, 0)
+	changes := make([]Change, 0)
+	if len(results) < 2 {
+		return updated, changes
+	}
+
+	numOpponents := float64(len(results) - 1)
+	for _, a := range results {
+		before := ratings[a.Username].Rating
+		delta := 0.0
+		for _, b := range results {
+			if a.Username == b.Username {
+				continue
+			}
+			delta += getActualScore(a.Score, b.Score) - getExpectedScore(before, ratings[b.Username].Rating)
+		}
+		after := math.Round((before+kFactor*delta/numOpponents)*10) / 10
+
+		r := ratings[a.Username]
+		r.Games++
+		r.Rating = after
+		r.UpdatedAt = now
+		updated = append(updated, r)
+		changes = append(changes, Change{
+			After:     after,
+			Before:    before,
+			CreatedAt: now,
+			Reason:    reason,
+			Score:     a.Score,
+			TableName: tableName,
+			Username:  a.Username,
+		})
+	}
+	return updated, changes
+}
+
+// PlacesToResults converts a tournament's finishing order to results. The winner is first.
+func PlacesToResults(usernames []string) []Result {
+	results := make([]Result, len(usernames))
+	for i, username := range usernames {
+		results[i] = Result{Score: -float64(i + 1), Username: username}
+	}
+	return results
+}
+
+// SortByRating sorts ratings from highest to lowest.
+func SortByRating(ratings []Rating) {
+	sort.SliceStable(ratings, func(i, j int) bool {
+		return ratings[i].Rating > ratings[j].Rating
+	})
+}
+
+// getExpectedScore gets the chance that a player with rating a beats a player with rating b
+func getExpectedScore(a float64, b float64) float64 {
+	return 1 / (1 + math.Pow(10, (b-a)/400))
+}
+
+func getActualScore(a float64, b float64) float64 {
+	if a > b {
+		return 1
+	} else if a < b {
+		return 0
+	}
+	return 0.5
+}
@@ -0,0 +1,84 @@
+package rating_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/rating"
+)
+
+var _ = Describe("Update", func() {
+	var ratings map[string]rating.Rating
+
+	BeforeEach(func() {
+		ratings = map[string]rating.Rating{
+			"alice": rating.NewRating("alice"),
+			"bob":   rating.NewRating("bob"),
+			"carol": rating.NewRating("carol"),
+		}
+	})
+
+	It("moves ratings towards the players with the best results", func() {
+		updated, changes := rating.Update(ratings, []rating.Result{
+			{Score: 50, Username: "alice"},
+			{Score: -20, Username: "bob"},
+			{Score: -30, Username: "carol"},
+		}, rating.ReasonSession, "Main")
+
+		Expect(updated).To(HaveLen(3))
+		Expect(updated[0].Rating).To(Equal(1516.0))
+		Expect(updated[0].Games).To(Equal(1))
+		Expect(updated[1].Rating).To(Equal(1500.0))
+		Expect(updated[2].Rating).To(Equal(1484.0))
+
+		Expect(changes[2].Before).To(Equal(1500.0))
+		Expect(changes[2].After).To(Equal(1484.0))
+		Expect(changes[2].Reason).To(Equal(rating.ReasonSession))
+		Expect(changes[2].TableName).To(Equal("Main"))
+	})
+
+	It("moves ratings less when a favourite wins", func() {
+		alice := ratings["alice"]
+		alice.Rating = 1800
+		ratings["alice"] = alice
+
+		updated, _ := rating.Update(ratings, []rating.Result{
+			{Score: 10, Username: "alice"},
+			{Score: -10, Username: "bob"},
+		}, rating.ReasonSession, "Main")
+		Expect(updated[0].Rating - 1800).To(BeNumerically("<", 16))
+		Expect(updated[0].Rating - 1800).To(BeNumerically(">", 0))
+	})
+
+	It("does not rate a player without opponents", func() {
+		updated, changes := rating.Update(ratings, []rating.Result{{Score: 10, Username: "alice"}}, rating.ReasonSession, "Main")
+		Expect(updated).To(BeEmpty())
+		Expect(changes).To(BeEmpty())
+	})
+
+	It("rates tournaments by finishing place", func() {
+		updated, _ := rating.Update(ratings, rating.PlacesToResults([]string{"carol", "alice", "bob"}), rating.ReasonTournament, "Main")
+		rating.SortByRating(updated)
+		Expect(updated[0].Username).To(Equal("carol"))
+		Expect(updated[2].Username).To(Equal("bob"))
+	})
+})
+
+var _ = Describe("MemoryStore", func() {
+	It("keeps ratings and their history", func() {
+		store := rating.NewMemoryStore()
+		r, err := store.Get("alice")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Rating).To(Equal(rating.DefaultRating))
+
+		r.Rating = 1510
+		Expect(store.Save([]rating.Rating{r}, []rating.Change{{Username: "alice", Before: 1500, After: 1510}})).To(Succeed())
+
+		r, _ = store.Get("alice")
+		Expect(r.Rating).To(Equal(1510.0))
+		changes, _ := store.ListChanges("alice")
+		Expect(changes).To(HaveLen(1))
+		ratings, _ := store.List()
+		Expect(ratings).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,77 @@
+package rating
+
+import (
+	"sync"
+)
+
+// Store persists ratings and rating history.
+type Store interface {
+	// Get gets a player's rating. A new rating is returned if the player has not been rated.
+	Get(username string) (Rating, error)
+	// List lists every rating.
+	List() ([]Rating, error)
+	// ListChanges lists the changes to a player's rating, oldest first.
+	ListChanges(username string) ([]Change, error)
+	// Save saves the new ratings and the changes that produced them.
+	Save(ratings []Rating, changes []Change) error
+}
+
+// MemoryStore keeps ratings in memory.
+type MemoryStore struct {
+	changes []Change
+	mu      sync.RWMutex
+	ratings map[string]Rating
+}
+
+// NewMemoryStore creates an empty in-memory rating store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		changes: make([]Change, 0),
+		ratings: make(map[string]Rating),
+	}
+}
+
+// Get gets a player's rating. A new rating is returned if the player has not been rated.
+func (s *MemoryStore) Get(username string) (Rating, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if r, ok := s.ratings[username]; ok {
+		return r, nil
+	}
+	return NewRating(username), nil
+}
+
+// List lists every rating.
+func (s *MemoryStore) List() ([]Rating, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ratings := make([]Rating, 0)
+	for _, r := range s.ratings {
+		ratings = append(ratings, r)
+	}
+	return ratings, nil
+}
+
+// ListChanges lists the changes to a player's rating, oldest first.
+func (s *MemoryStore) ListChanges(username string) ([]Change, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	changes := make([]Change, 0)
+	for _, c := range s.changes {
+		if c.Username == username {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+// Save saves the new ratings and the changes that produced them.
+func (s *MemoryStore) Save(ratings []Rating, changes []Change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range ratings {
+		s.ratings[r.Username] = r
+	}
+	s.changes = append(s.changes, changes...)
+	return nil
+}
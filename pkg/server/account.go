@@ -0,0 +1,102 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+// credentials is the request body used to register and log in.
+type credentials struct {
+	Password string `json:"password"`
+	Username string `json:"username"`
+}
+
+// ServeRegister creates a new account and returns a session token.
+func ServeRegister(accounts *auth.Accounts, w http.ResponseWriter, r *http.Request) {
+	var body credentials
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	token, err := accounts.Register(body.Username, body.Password)
+	if err == auth.ErrUsernameTaken {
+		writeJSONError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeSession(accounts, w, http.StatusCreated, token)
+}
+
+// ServeLogin checks the username and password and returns a session token.
+func ServeLogin(accounts *auth.Accounts, w http.ResponseWriter, r *http.Request) {
+	var body credentials
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	token, err := accounts.Login(body.Username, body.Password)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	writeSession(accounts, w, http.StatusOK, token)
+}
+
+// ServeBankroll sends the requester's bankroll and their buy-ins and cash-outs.
+func ServeBankroll(store storage.Store, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request) {
+	requester, err := authenticate(accounts, r)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+	bankroll, err := store.GetBankroll(requester.Username)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	transactions, err := store.ListTransactions(requester.Username)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"bankroll":     bankroll,
+		"transactions": transactions,
+	})
+}
+
+// authenticate finds who made the request from the session token.
+//
+// The token can be sent in the Authorization header or in the token query param. Browsers
+// can't set headers on websocket requests, so the query param is needed for ServeWs.
+func authenticate(accounts *auth.Accounts, r *http.Request) (auth.Claims, error) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	if token == "" {
+		return auth.Claims{}, auth.ErrInvalidToken
+	}
+	return accounts.Verify(token)
+}
+
+func writeSession(accounts *auth.Accounts, w http.ResponseWriter, status int, token string) {
+	claims, err := accounts.Verify(token)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, status, map[string]interface{}{
+		"expiresAt": claims.ExpiresAt,
+		"isAdmin":   claims.IsAdmin,
+		"token":     token,
+		"username":  claims.Username,
+	})
+}
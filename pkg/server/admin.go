@@ -0,0 +1,258 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+// AdminClient is a websocket connection to one of the tables as it is shown to admins.
+type AdminClient struct {
+	ID       string `json:"id"`
+	IP       string `json:"ip"`
+	IsAdmin  bool   `json:"isAdmin"`
+	PeerID   string `json:"peerID"`
+	SeatID   string `json:"seatID"`   // Empty for spectators
+	Username string `json:"username"` // Empty until the client joins the game
+}
+
+// AdminTable is a table as it is shown to admins.
+type AdminTable struct {
+	Clients    []AdminClient `json:"clients"`
+	Host       string        `json:"host,omitempty"` // Only set for private tables
+	Name       string        `json:"name"`
+	NumSeated  int           `json:"numSeated"`
+	Stage      string        `json:"stage"`
+	Tournament string        `json:"tournament,omitempty"` // Name of the multi-table tournament
+}
+
+type adjustChipsRequest struct {
+	Amount int    `json:"amount"`
+	Reason string `json:"reason"`
+}
+
+type banRequest struct {
+	Kind   string `json:"kind"`
+	Reason string `json:"reason"`
+	Value  string `json:"value"`
+}
+
+// ServeAdminTables lists the tables and the clients connected to them.
+func ServeAdminTables(registry *TableRegistry, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request) {
+	if _, ok := authenticateAdmin(accounts, w, r); ok == false {
+		return
+	}
+	tables := make([]AdminTable, 0)
+	for _, table := range registry.ListTables() {
+		tables = append(tables, createAdminTable(table, table.Hub.copyClients()))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tables": tables})
+}
+
+// ServeAdminTable sends the full state of a table, including every player's hole cards.
+func ServeAdminTable(registry *TableRegistry, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request, name string) {
+	if _, ok := authenticateAdmin(accounts, w, r); ok == false {
+		return
+	}
+	table := registry.GetTable(name)
+	if table == nil {
+		writeJSONError(w, http.StatusNotFound, "Table not found")
+		return
+	}
+	clients := table.Hub.copyClients()
+	table.GameState.lock()
+	state := ProjectGameState(table.GameState, createPeers(clients), Viewer{Role: ViewerAdmin})
+	table.GameState.unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"state": state.Params,
+		"table": createAdminTable(table, clients),
+	})
+}
+
+// ServeAdminEndHand cancels the hand in progress at a table. Each player's bets are returned
+// to them.
+func ServeAdminEndHand(registry *TableRegistry, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request, name string) {
+	admin, ok := authenticateAdmin(accounts, w, r)
+	if ok == false {
+		return
+	}
+	table := registry.GetTable(name)
+	if table == nil {
+		writeJSONError(w, http.StatusNotFound, "Table not found")
+		return
+	}
+	if err := EndHand(table); err != nil {
+		writeJSONError(w, http.StatusConflict, err.Error())
+		return
+	}
+	log.Printf("%s ended the hand at %s", admin.Username, name)
+
+	table.GameState.lock()
+	stage := table.GameState.Stage
+	table.GameState.unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"stage": stage.String()})
+}
+
+// ServeAdminAdjustChips adds chips to or removes chips from a player's bankroll.
+//
+// A reason must be given, since it is kept with the transaction for auditing.
+func ServeAdminAdjustChips(store storage.Store, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request, username string) {
+	admin, ok := authenticateAdmin(accounts, w, r)
+	if ok == false {
+		return
+	}
+	var req adjustChipsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	if req.Amount == 0 {
+		writeJSONError(w, http.StatusBadRequest, "The amount cannot be 0")
+		return
+	}
+	if req.Reason == "" {
+		writeJSONError(w, http.StatusBadRequest, "A reason is needed to adjust chips")
+		return
+	}
+	user, err := store.Users().Get(username)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, "Player not found")
+		return
+	}
+
+	note := fmt.Sprintf("%s (by %s)", req.Reason, admin.Username)
+	if err := store.AdjustBankroll(user.Username, req.Amount, note); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	log.Printf("%s adjusted the bankroll of %s by %d: %s", admin.Username, user.Username, req.Amount, req.Reason)
+
+	bankroll, err := store.GetBankroll(user.Username)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"bankroll": bankroll})
+}
+
+// ServeAdminBans lists the banned accounts and IP addresses.
+func ServeAdminBans(bans *BanList, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request) {
+	if _, ok := authenticateAdmin(accounts, w, r); ok == false {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"bans": bans.List()})
+}
+
+// ServeAdminBan bans an account or IP address. Matching clients are disconnected.
+func ServeAdminBan(bans *BanList, registry *TableRegistry, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request) {
+	admin, ok := authenticateAdmin(accounts, w, r)
+	if ok == false {
+		return
+	}
+	var req banRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	ban, err := bans.Add(Ban{
+		Admin:  admin.Username,
+		Kind:   req.Kind,
+		Reason: req.Reason,
+		Value:  req.Value,
+	})
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	log.Printf("%s banned %s %s: %s", admin.Username, ban.Kind, ban.Value, ban.Reason)
+	disconnectBanned(registry, ban)
+	writeJSON(w, http.StatusCreated, ban)
+}
+
+// ServeAdminUnban removes a ban.
+func ServeAdminUnban(bans *BanList, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request, kind string, value string) {
+	admin, ok := authenticateAdmin(accounts, w, r)
+	if ok == false {
+		return
+	}
+	if err := bans.Remove(kind, value); err != nil {
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	log.Printf("%s unbanned %s %s", admin.Username, kind, value)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// EndHand cancels the hand in progress at a table and deals the next one. Each player's bets
+// are returned to them.
+//
+// The table is locked, so the hand can't move on to the showdown while it is being cancelled.
+func EndHand(table *RegisteredTable) error {
+	g := table.GameState
+	g.lock()
+	defer g.unlock()
+
+	if g.isClosed {
+		return fmt.Errorf("The table is closed")
+	}
+	if g.Stage == Waiting {
+		return fmt.Errorf("No hand is being played")
+	}
+	if g.Stage == Showdown {
+		return fmt.Errorf("The hand is already being paid out")
+	}
+	c := newSystemClient(table.Hub, g, "")
+	refundHand(c)
+	offerOpenSeats(c)
+	sendHoleCardEvents(table.Hub)
+	broadcastAnnouncements(c)
+	return nil
+}
+
+// authenticateAdmin checks that the request was made by an admin. If not, an error is sent.
+func authenticateAdmin(accounts *auth.Accounts, w http.ResponseWriter, r *http.Request) (auth.Claims, bool) {
+	requester, err := authenticate(accounts, r)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, "Not authenticated")
+		return requester, false
+	}
+	if requester.IsAdmin == false {
+		writeJSONError(w, http.StatusForbidden, "Only admins can do this")
+		return requester, false
+	}
+	return requester, true
+}
+
+// createAdminTable creates the admin listing for a table. The listing is read from the summary
+// that the table publishes, so the table isn't locked.
+func createAdminTable(table *RegisteredTable, clients map[string]*Client) AdminTable {
+	summary := table.GameState.getSummary()
+	t := AdminTable{
+		Clients:    make([]AdminClient, 0, len(clients)),
+		Host:       table.GameState.Config.Host,
+		Name:       summary.lobby.Name,
+		NumSeated:  summary.lobby.NumSeated,
+		Stage:      summary.stage.String(),
+		Tournament: summary.lobby.Tournament,
+	}
+	for _, c := range clients {
+		seat := c.seat.Load().(clientSeat)
+		t.Clients = append(t.Clients, AdminClient{
+			ID:       c.id,
+			IP:       c.ip,
+			IsAdmin:  c.isAdmin,
+			PeerID:   c.peerID,
+			SeatID:   seat.seatID,
+			Username: seat.username,
+		})
+	}
+	sort.Slice(t.Clients, func(i, j int) bool {
+		return t.Clients[i].ID < t.Clients[j].ID
+	})
+	return t
+}
@@ -0,0 +1,250 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/poker"
+	"github.com/richard-to/go-poker/pkg/server"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+var _ = Describe("Admin API", func() {
+	var accounts *auth.Accounts
+	var adminToken string
+	var playerToken string
+	var registry *server.TableRegistry
+	var store *storage.MemoryStore
+	var table *server.RegisteredTable
+
+	newRequest := func(method string, target string, body string, token string) *http.Request {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		return r
+	}
+
+	decode := func(w *httptest.ResponseRecorder) map[string]interface{} {
+		var data map[string]interface{}
+		Expect(json.Unmarshal(w.Body.Bytes(), &data)).To(Succeed())
+		return data
+	}
+
+	BeforeEach(func() {
+		var err error
+		store = storage.NewMemoryStore()
+		accounts, err = auth.NewAccounts(store.Users(), []byte("secret"), []string{})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = accounts.Register("ops", "password")
+		Expect(err).NotTo(HaveOccurred())
+		playerToken, err = accounts.Register("alice", "password")
+		Expect(err).NotTo(HaveOccurred())
+
+		// Admins need to be registered before the server starts
+		accounts, err = auth.NewAccounts(store.Users(), []byte("secret"), []string{"ops"})
+		Expect(err).NotTo(HaveOccurred())
+		adminToken, err = accounts.Login("ops", "password")
+		Expect(err).NotTo(HaveOccurred())
+
+		hub := server.NewHub()
+		go hub.Run()
+		g := server.NewGameState(server.NewTableConfig(), store)
+		seat := g.Table.Seats
+		for i := 0; i < 3; i++ {
+			Expect(store.OpenBankroll(seat.Player.ID, 1000)).To(Succeed())
+			Expect(store.BuyIn(g.Config.Name, seat.Player.ID, 100)).To(Succeed())
+			seat.Player.Name = seat.Player.ID
+			seat.Player.Chips = 100
+			seat.Player.Status = poker.PlayerSittingOut
+			seat.Player.IsHuman = true
+			seat = seat.Next()
+		}
+		server.StartNewHand(g)
+		registry = server.NewTableRegistry()
+		table = registry.AddTable(hub, g)
+	})
+
+	It("only lets admins in", func() {
+		w := httptest.NewRecorder()
+		server.ServeAdminTables(registry, accounts, w, newRequest("GET", "/api/admin/tables", "", playerToken))
+		Expect(w.Code).To(Equal(http.StatusForbidden))
+
+		w = httptest.NewRecorder()
+		server.ServeAdminTables(registry, accounts, w, newRequest("GET", "/api/admin/tables", "", ""))
+		Expect(w.Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("lists the tables", func() {
+		w := httptest.NewRecorder()
+		server.ServeAdminTables(registry, accounts, w, newRequest("GET", "/api/admin/tables", "", adminToken))
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(MatchJSON(`{"tables": [
+			{"clients": [], "name": "Main", "numSeated": 3, "stage": "Preflop"}
+		]}`))
+	})
+
+	It("shows every player's hole cards", func() {
+		w := httptest.NewRecorder()
+		server.ServeAdminTable(registry, accounts, w, newRequest("GET", "/api/admin/tables/Main", "", adminToken), "Main")
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		players := decode(w)["state"].(map[string]interface{})["players"].([]interface{})
+		numHoleCards := 0
+		for _, p := range players {
+			for _, card := range p.(map[string]interface{})["holeCards"].([]interface{}) {
+				if card != nil {
+					numHoleCards++
+				}
+			}
+		}
+		Expect(numHoleCards).To(Equal(6))
+	})
+
+	It("ends the hand and returns the bets", func() {
+		handID := table.GameState.History.ID
+
+		w := httptest.NewRecorder()
+		server.ServeAdminEndHand(registry, accounts, w, newRequest("POST", "/api/admin/tables/Main/end-hand", "", adminToken), "Main")
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		Expect(table.GameState.History.ID).NotTo(Equal(handID))
+		stacks, err := store.GetStacks("Main")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stacks).To(HaveLen(3))
+		for _, chips := range stacks {
+			Expect(chips).To(Equal(100))
+		}
+
+		table.GameState.Stage = server.Waiting
+		w = httptest.NewRecorder()
+		server.ServeAdminEndHand(registry, accounts, w, newRequest("POST", "/api/admin/tables/Main/end-hand", "", adminToken), "Main")
+		Expect(w.Code).To(Equal(http.StatusConflict))
+	})
+
+	It("does not end the hand at a table that was closed", func() {
+		server.Shutdown(table.Hub, table.GameState, 0)
+		table.GameState.Stage = server.Preflop
+
+		w := httptest.NewRecorder()
+		server.ServeAdminEndHand(registry, accounts, w, newRequest("POST", "/api/admin/tables/Main/end-hand", "", adminToken), "Main")
+		Expect(w.Code).To(Equal(http.StatusConflict))
+		Expect(decode(w)["error"]).To(Equal("The table is closed"))
+	})
+
+	It("adjusts a player's bankroll with a reason", func() {
+		w := httptest.NewRecorder()
+		r := newRequest("POST", "/api/admin/players/ALICE/chips", `{"amount": 50}`, adminToken)
+		server.ServeAdminAdjustChips(store, accounts, w, r, "ALICE")
+		Expect(w.Code).To(Equal(http.StatusBadRequest))
+
+		w = httptest.NewRecorder()
+		r = newRequest("POST", "/api/admin/players/ALICE/chips", `{"amount": 50, "reason": "Lost connection"}`, adminToken)
+		server.ServeAdminAdjustChips(store, accounts, w, r, "ALICE")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(MatchJSON(`{"bankroll": 50}`))
+
+		transactions, err := store.ListTransactions("alice")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(transactions).To(HaveLen(1))
+		Expect(transactions[0].Reason).To(Equal(storage.ReasonAdjustment))
+		Expect(transactions[0].Note).To(Equal("Lost connection (by ops)"))
+
+		w = httptest.NewRecorder()
+		r = newRequest("POST", "/api/admin/players/zed/chips", `{"amount": 50, "reason": "Test"}`, adminToken)
+		server.ServeAdminAdjustChips(store, accounts, w, r, "zed")
+		Expect(w.Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("bans and unbans accounts and IP addresses", func() {
+		bans, err := server.NewBanList(store)
+		Expect(err).NotTo(HaveOccurred())
+
+		w := httptest.NewRecorder()
+		r := newRequest("POST", "/api/admin/bans", `{"kind": "account", "reason": "Collusion", "value": "Alice"}`, adminToken)
+		server.ServeAdminBan(bans, registry, accounts, w, r)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+
+		w = httptest.NewRecorder()
+		r = newRequest("POST", "/api/admin/bans", `{"kind": "ip", "value": "10.0.0.7"}`, adminToken)
+		server.ServeAdminBan(bans, registry, accounts, w, r)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+
+		Expect(server.CheckBan(bans, accounts, newRequest("GET", "/api/bankroll", "", playerToken))).To(Equal(server.ErrBanned))
+
+		// The proxy headers are ignored unless the server is behind a trusted proxy
+		defer func() {
+			os.Unsetenv("POKER_TRUSTED_PROXIES")
+			Expect(server.LoadTrustedProxies()).To(Succeed())
+		}()
+		r = newRequest("GET", "/api/admin/tables", "", "")
+		r.Header.Set("X-Forwarded-For", "10.0.0.7")
+		r.Header.Set("X-Appengine-User-IP", "10.0.0.7")
+		Expect(server.CheckBan(bans, accounts, r)).To(Succeed())
+
+		os.Setenv("POKER_TRUSTED_PROXIES", "1")
+		Expect(server.LoadTrustedProxies()).To(Succeed())
+		r = newRequest("GET", "/api/admin/tables", "", adminToken)
+		r.Header.Set("X-Forwarded-For", "10.0.0.1, 10.0.0.7")
+		Expect(server.CheckBan(bans, accounts, r)).To(Succeed())
+		r.Header.Set("Authorization", "")
+		Expect(server.CheckBan(bans, accounts, r)).To(Equal(server.ErrBanned))
+
+		// Only the addresses added by the proxies are trusted
+		r.Header.Set("X-Forwarded-For", "10.0.0.7, 10.0.0.1")
+		Expect(server.CheckBan(bans, accounts, r)).To(Succeed())
+		r.Header.Set("X-Appengine-User-IP", "10.0.0.7")
+		Expect(server.CheckBan(bans, accounts, r)).To(Succeed())
+
+		os.Setenv("POKER_TRUSTED_PROXIES", "2")
+		Expect(server.LoadTrustedProxies()).To(Succeed())
+		Expect(server.CheckBan(bans, accounts, r)).To(Equal(server.ErrBanned))
+
+		os.Setenv("POKER_TRUSTED_PROXIES", "appengine")
+		Expect(server.LoadTrustedProxies()).To(Succeed())
+		r.Header.Set("X-Forwarded-For", "10.0.0.1")
+		Expect(server.CheckBan(bans, accounts, r)).To(Equal(server.ErrBanned))
+
+		os.Setenv("POKER_TRUSTED_PROXIES", "some")
+		Expect(server.LoadTrustedProxies()).To(HaveOccurred())
+
+		// Bans are kept after a restart
+		bans, err = server.NewBanList(store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bans.List()).To(HaveLen(2))
+
+		w = httptest.NewRecorder()
+		server.ServeAdminUnban(bans, accounts, w, newRequest("DELETE", "/api/admin/bans/account/alice", "", adminToken), "account", "alice")
+		Expect(w.Code).To(Equal(http.StatusNoContent))
+		Expect(server.CheckBan(bans, accounts, newRequest("GET", "/api/bankroll", "", playerToken))).To(Succeed())
+
+		w = httptest.NewRecorder()
+		r = newRequest("POST", "/api/admin/bans", `{"kind": "ip", "value": "nowhere"}`, adminToken)
+		server.ServeAdminBan(bans, registry, accounts, w, r)
+		Expect(w.Code).To(Equal(http.StatusBadRequest))
+	})
+})
+
+var _ = Describe("Health", func() {
+	It("is only ready once the server says so", func() {
+		health := server.NewHealth(storage.NewMemoryStore())
+
+		w := httptest.NewRecorder()
+		server.ServeHealth(w, httptest.NewRequest("GET", "/api/health", nil))
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		w = httptest.NewRecorder()
+		server.ServeReady(health, w, httptest.NewRequest("GET", "/api/ready", nil))
+		Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+
+		health.SetReady(true)
+		w = httptest.NewRecorder()
+		server.ServeReady(health, w, httptest.NewRequest("GET", "/api/ready", nil))
+		Expect(w.Code).To(Equal(http.StatusOK))
+	})
+})
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+// HandSummary is a short summary of a recorded hand for the hand list.
+type HandSummary struct {
+	BigBlind   int       `json:"bigBlind"`
+	ID         string    `json:"id"`
+	Players    []string  `json:"players"`
+	SmallBlind int       `json:"smallBlind"`
+	StartedAt  time.Time `json:"startedAt"`
+	TableName  string    `json:"tableName"`
+	Winnings   int       `json:"winnings"` // Chips the player won in the hand
+}
+
+// ServeHandList lists the hands that the requester took part in.
+//
+// Every table saves its hands to the same store, so hands from private and tournament tables
+// are listed too. Admins can list the hands of any player using the player query param.
+func ServeHandList(store storage.Store, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request) {
+	requester, err := authenticate(accounts, r)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	playerName := requester.Username
+	if requester.IsAdmin {
+		playerName = r.URL.Query().Get("player")
+	}
+	if playerName == "" {
+		writeJSONError(w, http.StatusBadRequest, "No player was chosen")
+		return
+	}
+
+	histories, err := store.HandHistories().ListByPlayer(playerName)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	hands := make([]HandSummary, 0)
+	for _, h := range histories {
+		summary := HandSummary{
+			BigBlind:   h.BigBlind,
+			ID:         h.ID,
+			Players:    make([]string, 0),
+			SmallBlind: h.SmallBlind,
+			StartedAt:  h.StartedAt,
+			TableName:  h.TableName,
+		}
+		for _, seat := range h.Seats {
+			summary.Players = append(summary.Players, seat.Name)
+		}
+		if seat := h.GetSeatByName(playerName); seat != nil {
+			summary.Winnings = h.GetWinnings(seat.PlayerID)
+		}
+		hands = append(hands, summary)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"hands": hands})
+}
+
+// ServeHandReplay streams a recorded hand as update game frames.
+//
+// Each frame is sent as a line of JSON and flushed straight away, so long hands don't need to
+// be replayed in full before the first frame arrives. If the hand can't be replayed part way
+// through, the last line has the error instead of a frame.
+//
+// Players can only replay hands they took part in. Admins can replay any hand, no matter
+// which table it was played at.
+func ServeHandReplay(store storage.Store, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request, handID string) {
+	requester, err := authenticate(accounts, r)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	h, err := store.HandHistories().Get(handID)
+	if err != nil || (requester.IsAdmin == false && h.HasPlayer(requester.Username) == false) {
+		writeJSONError(w, http.StatusNotFound, "Hand not found")
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	isStreaming := false
+	err = StreamReplayFrames(h, requester.Username, requester.IsAdmin, func(frame Event) error {
+		if isStreaming == false {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.WriteHeader(http.StatusOK)
+			isStreaming = true
+		}
+		if err := encoder.Encode(frame); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil && isStreaming == false {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+	} else if err != nil {
+		encoder.Encode(map[string]string{"error": err.Error()})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,227 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+// Kinds of bans
+const banAccount string = "account"
+const banIP string = "ip"
+
+// ErrBanned is returned when a banned account or IP address makes a request.
+var ErrBanned = fmt.Errorf("You have been banned")
+
+// Ban stops an account or IP address from using the server.
+type Ban struct {
+	Admin     string    `json:"admin"` // Admin who added the ban
+	CreatedAt time.Time `json:"createdAt"`
+	Kind      string    `json:"kind"`
+	Reason    string    `json:"reason"`
+	Value     string    `json:"value"` // Lower case username or IP address
+}
+
+// BanList keeps track of the banned accounts and IP addresses.
+//
+// Bans are saved to the store, so they are kept after a restart.
+type BanList struct {
+	bans  map[string]Ban // Keyed by kind and value
+	mu    sync.RWMutex
+	store storage.Store
+}
+
+// NewBanList creates a ban list with the bans saved in the store.
+func NewBanList(store storage.Store) (*BanList, error) {
+	b := &BanList{
+		bans:  make(map[string]Ban),
+		store: store,
+	}
+	saved, err := store.ListBans()
+	if err != nil {
+		return nil, err
+	}
+	for _, data := range saved {
+		var ban Ban
+		if err := json.Unmarshal(data, &ban); err != nil {
+			return nil, err
+		}
+		b.bans[getBanKey(ban.Kind, ban.Value)] = ban
+	}
+	return b, nil
+}
+
+// Add bans an account or IP address.
+func (b *BanList) Add(ban Ban) (Ban, error) {
+	if ban.Kind != banAccount && ban.Kind != banIP {
+		return ban, fmt.Errorf("Bans must be for an account or an IP address")
+	}
+	ban.Value = normalizeBanValue(ban.Kind, ban.Value)
+	if ban.Value == "" {
+		return ban, fmt.Errorf("Nothing was chosen to ban")
+	}
+	if ban.Kind == banIP && net.ParseIP(ban.Value) == nil {
+		return ban, fmt.Errorf("%s is not a valid IP address", ban.Value)
+	}
+	ban.CreatedAt = time.Now().UTC()
+
+	key := getBanKey(ban.Kind, ban.Value)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.store.SaveBan(key, ban); err != nil {
+		return ban, err
+	}
+	b.bans[key] = ban
+	return ban, nil
+}
+
+// Remove unbans an account or IP address.
+func (b *BanList) Remove(kind string, value string) error {
+	key := getBanKey(kind, normalizeBanValue(kind, value))
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.bans[key]; ok == false {
+		return fmt.Errorf("%s is not banned", value)
+	}
+	if err := b.store.DeleteBan(key); err != nil {
+		return err
+	}
+	delete(b.bans, key)
+	return nil
+}
+
+// List lists the bans, newest first.
+func (b *BanList) List() []Ban {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	bans := make([]Ban, 0, len(b.bans))
+	for _, ban := range b.bans {
+		bans = append(bans, ban)
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].CreatedAt.After(bans[j].CreatedAt)
+	})
+	return bans
+}
+
+// IsBanned checks if the account or IP address is banned. Either can be empty.
+func (b *BanList) IsBanned(username string, ip string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if _, ok := b.bans[getBanKey(banAccount, normalizeBanValue(banAccount, username))]; ok && username != "" {
+		return true
+	}
+	_, ok := b.bans[getBanKey(banIP, normalizeBanValue(banIP, ip))]
+	return ok && ip != ""
+}
+
+// CheckBan checks that the request was not made by a banned account or IP address.
+//
+// Admins are never banned, so that an admin can't lock everyone out by banning a shared IP
+// address.
+func CheckBan(bans *BanList, accounts *auth.Accounts, r *http.Request) error {
+	username := ""
+	if account, err := authenticate(accounts, r); err == nil {
+		if account.IsAdmin {
+			return nil
+		}
+		username = account.Username
+	}
+	if bans.IsBanned(username, getRemoteIP(r)) {
+		return ErrBanned
+	}
+	return nil
+}
+
+// disconnectBanned closes the connections of the clients that match the ban
+func disconnectBanned(registry *TableRegistry, ban Ban) {
+	for _, table := range registry.ListTables() {
+		for _, c := range table.Hub.copyClients() {
+			isBanned := (ban.Kind == banAccount && strings.ToLower(c.account.Username) == ban.Value) ||
+				(ban.Kind == banIP && c.ip == ban.Value)
+			if isBanned && c.isAdmin == false && c.conn != nil {
+				c.conn.Close()
+			}
+		}
+	}
+}
+
+// trustedProxies says which headers set by the proxies in front of the server are trusted
+type trustedProxies struct {
+	appEngine bool // App Engine sets X-Appengine-User-IP to the player's address
+	hops      int  // Number of proxies that add the address they got the request from to X-Forwarded-For
+}
+
+var proxies trustedProxies
+
+// LoadTrustedProxies loads the proxies in front of the server from POKER_TRUSTED_PROXIES.
+//
+// Set it to "appengine" to use the X-Appengine-User-IP header that App Engine sets, or to the
+// number of proxies that add the address they got the request from to the end of
+// X-Forwarded-For. With a single load balancer this is 1, and the player's address is the
+// last entry. Entries before the ones added by the proxies come from the player, so they
+// can't be trusted. If it is not set, the headers are ignored and the address of the
+// connection is used.
+func LoadTrustedProxies() error {
+	value := os.Getenv("POKER_TRUSTED_PROXIES")
+	if value == "" {
+		proxies = trustedProxies{}
+		return nil
+	}
+	if value == "appengine" {
+		proxies = trustedProxies{appEngine: true}
+		return nil
+	}
+	hops, err := strconv.Atoi(value)
+	if err != nil || hops < 0 {
+		return fmt.Errorf("POKER_TRUSTED_PROXIES must be \"appengine\" or a number of proxies")
+	}
+	proxies = trustedProxies{hops: hops}
+	return nil
+}
+
+// getRemoteIP gets the IP address of the player who made the request.
+//
+// The proxy headers are only used if the server is behind a trusted proxy. Requests that
+// passed through fewer proxies than expected did not come through the proxy, so the address
+// of the connection is used.
+func getRemoteIP(r *http.Request) string {
+	if ip := r.Header.Get("X-Appengine-User-IP"); proxies.appEngine && ip != "" {
+		return strings.TrimSpace(ip)
+	}
+	if forwarded := r.Header.Get("X-Forwarded-For"); proxies.hops > 0 && forwarded != "" {
+		addresses := strings.Split(forwarded, ",")
+		if len(addresses) >= proxies.hops {
+			return strings.TrimSpace(addresses[len(addresses)-proxies.hops])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func getBanKey(kind string, value string) string {
+	return kind + ":" + value
+}
+
+// normalizeBanValue makes account bans case insensitive, since usernames are unique regardless
+// of case
+func normalizeBanValue(kind string, value string) string {
+	value = strings.TrimSpace(value)
+	if kind == banAccount {
+		return strings.ToLower(value)
+	}
+	return value
+}
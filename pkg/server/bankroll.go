@@ -0,0 +1,43 @@
+package server
+
+import (
+	"log"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+// Chips given to a player the first time they join
+const defaultBankroll int = 1000
+
+// settleHand saves the stacks at the table once the pot has been awarded.
+//
+// If the server stops during a hand, the stacks from the end of the previous hand are
+// used, so the chips bet in the unfinished hand are returned to the players.
+func settleHand(g *GameState) {
+	// Tournament chips are only paid out once the tournament has finished
+	if g.Tournament != nil {
+		return
+	}
+	stacks := make(map[string]int)
+	seat := g.Table.Seats
+	for i := 0; i < seat.Len(); i++ {
+		if seat.Player.Status > poker.PlayerVacated && seat.Player.Name != "" {
+			stacks[seat.Player.Name] = seat.Player.Chips
+		}
+		seat = seat.Next()
+	}
+	if err := g.Store.SettleHand(g.Config.Name, stacks, g.Table.Pot.Rake); err != nil {
+		log.Printf("could not settle hand: %v", err)
+	}
+}
+
+// cashOut returns a player's stack to their bankroll when they leave the table
+func cashOut(g *GameState, p *poker.Player) {
+	if p.Name == "" {
+		return
+	}
+	if _, err := g.Store.CashOut(g.Config.Name, p.Name); err != nil {
+		log.Printf("could not cash out %s: %v", p.Name, err)
+	}
+	leaveSession(g, p.Name)
+}
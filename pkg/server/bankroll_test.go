@@ -0,0 +1,92 @@
+package server_test
+
+import (
+	"encoding/json"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+	"github.com/richard-to/go-poker/pkg/server"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+var _ = Describe("Rake", func() {
+	AfterEach(func() {
+		for _, key := range []string{"POKER_RAKE_PERCENT", "POKER_RAKE_CAP", "POKER_RAKE_CAP_BY_PLAYERS"} {
+			os.Unsetenv(key)
+		}
+	})
+
+	It("checks the rake settings", func() {
+		for _, value := range []string{"-1", "100.5", "NaN"} {
+			os.Setenv("POKER_RAKE_PERCENT", value)
+			_, err := server.LoadTableConfig()
+			Expect(err).To(HaveOccurred())
+		}
+
+		os.Setenv("POKER_RAKE_PERCENT", "5")
+		os.Setenv("POKER_RAKE_CAP", "-3")
+		_, err := server.LoadTableConfig()
+		Expect(err).To(HaveOccurred())
+
+		os.Setenv("POKER_RAKE_CAP", "3")
+		os.Setenv("POKER_RAKE_CAP_BY_PLAYERS", "2:1,4:-2")
+		_, err = server.LoadTableConfig()
+		Expect(err).To(HaveOccurred())
+
+		os.Setenv("POKER_RAKE_CAP_BY_PLAYERS", "2:1,4:2")
+		config, err := server.LoadTableConfig()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Rake.Percent).To(Equal(5.0))
+		Expect(config.Rake.Cap).To(Equal(3))
+		Expect(config.Rake.CapByPlayers).To(Equal(map[int]int{2: 1, 4: 2}))
+	})
+
+	It("records the rake and pays it to the house", func() {
+		config := server.NewTableConfig()
+		config.Rake = &poker.Rake{Percent: 10, Cap: 5}
+		store := storage.NewMemoryStore()
+		g := server.NewGameState(config, store)
+		seat := g.Table.Seats
+		for i := 0; i < 3; i++ {
+			Expect(store.OpenBankroll(seat.Player.ID, 1000)).To(Succeed())
+			Expect(store.BuyIn(config.Name, seat.Player.ID, 100)).To(Succeed())
+			seat.Player.Name = seat.Player.ID
+			seat.Player.Chips = 100
+			seat.Player.Status = poker.PlayerSittingOut
+			seat.Player.IsHuman = true
+			seat = seat.Next()
+		}
+		server.StartNewHand(g)
+
+		// The big blind calls an all in raise from the dealer. The hand is played from the
+		// journal, so it is finished without pausing for the players.
+		for _, e := range []server.JournalEntry{
+			{Action: "raise", SeatID: g.Table.Dealer.Player.ID, Value: 100},
+			{Action: "fold", SeatID: g.Table.SmallBlind.Player.ID},
+			{Action: "call", SeatID: g.Table.BigBlind.Player.ID},
+		} {
+			data, err := json.Marshal(e)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.AppendJournal(config.Name, data)).To(Succeed())
+		}
+		hub := server.NewHub()
+		go hub.Run()
+		_, err := server.RecoverGameState(hub, config, store)
+		Expect(err).NotTo(HaveOccurred())
+
+		// 10% of the ℝ201 pot is over the cap
+		h, err := store.HandHistories().Get(g.History.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(h.Rake).To(Equal(5))
+		transactions, err := store.ListTransactions(storage.HouseAccount)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(transactions).To(HaveLen(1))
+		Expect(transactions[0].Amount).To(Equal(5))
+		Expect(transactions[0].Reason).To(Equal(storage.ReasonRake))
+		Expect(transactions[0].TableName).To(Equal("Main"))
+		Expect(store.GetBankroll(storage.HouseAccount)).To(Equal(5))
+	})
+})
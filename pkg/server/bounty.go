@@ -0,0 +1,108 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/richard-to/go-poker/pkg/history"
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+// awardBounties pays the bounties of the players who were knocked out at showdown.
+//
+// A bounty goes to the winners of the highest side pot that the knocked out player was in,
+// since those are the players who covered them. Winners of lower pots do not collect. If the
+// pot was split, the bounty is split too. In progressive knockout tournaments, half of the
+// bounty is added to the winner's own bounty.
+//
+// The winning hands must be in the same order as the side pots of the table's pot.
+func awardBounties(c *Client, winningHandsByPot [][]poker.PlayerHand) {
+	g := c.gameState
+	t := g.Tournament
+	if t == nil || g.Config.Tournament.Bounty == 0 || t.IsRunning() == false {
+		return
+	}
+	if t.BountiesWon == nil {
+		t.BountiesWon = make(map[string]int)
+	}
+
+	for _, p := range getSeatedPlayers(g) {
+		bounty := t.Bounties[p.Name]
+		if p.Chips > 0 || bounty == 0 {
+			continue
+		}
+
+		winners := make([]*poker.Player, 0)
+		for i, sidePot := range g.Table.Pot.SidePots {
+			if isPlayerInSidePot(sidePot, p) {
+				winners = winners[:0]
+				for _, ph := range winningHandsByPot[i] {
+					if ph.Player != p {
+						winners = append(winners, ph.Player)
+					}
+				}
+			}
+		}
+		if len(winners) == 0 {
+			continue
+		}
+
+		// Chips that can't be split evenly go to the first winners
+		t.Bounties[p.Name] = 0
+		for i, winner := range winners {
+			share := bounty / len(winners)
+			if i < bounty%len(winners) {
+				share++
+			}
+			awardBounty(c, winner, p, share)
+		}
+	}
+}
+
+// awardBounty pays a player their share of the bounty of a player they knocked out
+func awardBounty(c *Client, winner *poker.Player, eliminated *poker.Player, share int) {
+	g := c.gameState
+	t := g.Tournament
+
+	increase := 0
+	if g.Config.Tournament.Progressive {
+		increase = share / 2
+	}
+	t.Bounties[winner.Name] += increase
+	t.BountiesWon[winner.Name] += share - increase
+
+	message := fmt.Sprintf("%s wins a ℝ%d bounty for knocking out %s.", winner.Name, share-increase, eliminated.Name)
+	if increase > 0 {
+		message += fmt.Sprintf(" Their bounty goes up to ℝ%d.", t.Bounties[winner.Name])
+	}
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(systemUsername, message)))
+
+	if g.History != nil {
+		bounty := history.Bounty{
+			PlayerID:     winner.ID,
+			EliminatedID: eliminated.ID,
+			Amount:       share - increase,
+		}
+		if increase > 0 {
+			bounty.Increase = increase
+			bounty.Total = t.Bounties[winner.Name]
+		}
+		g.History.Bounties = append(g.History.Bounties, bounty)
+	}
+}
+
+// projectBounty gets the bounty on a player. Nil if the table does not have bounties.
+func projectBounty(g *GameState, p *poker.Player) interface{} {
+	if g.Tournament == nil || g.Config.Tournament.Bounty == 0 || p.Status == poker.PlayerVacated {
+		return nil
+	}
+	return g.Tournament.Bounties[p.Name]
+}
+
+func isPlayerInSidePot(sidePot *poker.SidePot, p *poker.Player) bool {
+	for _, player := range sidePot.Players {
+		if player == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,279 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Chat moderation events
+const actionChangeChatMode string = "change-chat-mode"
+const actionIgnorePlayer string = "ignore-player"
+const actionMutePlayer string = "mute-player"
+
+// Who can send chat messages
+const chatEveryone string = "everyone"
+const chatPlayersOnly string = "players"
+
+// Chat settings
+const defaultChatRateLimit int = 5
+const chatRateWindow = 10 * time.Second
+const maxChatMessageLength int = 280
+
+// Chat keeps track of who can send messages at a table.
+type Chat struct {
+	Mode  string          `json:"mode"`  // Everyone or players only. Spectators can always read the chat.
+	Muted map[string]bool `json:"muted"` // Accounts that can't send messages. Keyed by lowercase username.
+}
+
+// NewChat creates chat settings where no one is muted.
+func NewChat(mode string) *Chat {
+	if mode != chatPlayersOnly {
+		mode = chatEveryone
+	}
+	return &Chat{
+		Mode:  mode,
+		Muted: make(map[string]bool),
+	}
+}
+
+// HandleMutePlayer stops a player from sending messages, or lets them send messages again.
+//
+// Only admins and the host of a private table can mute players.
+func HandleMutePlayer(c *Client, username string, muted bool) error {
+	if err := checkModerator(c); err != nil {
+		return err
+	}
+	key := getAccountKey(username)
+	if key == "" {
+		return fmt.Errorf("Choose a player to mute")
+	}
+	if key == getAccountKey(c.account.Username) {
+		return fmt.Errorf("You cannot mute yourself")
+	}
+
+	chat := c.gameState.Chat
+	message := fmt.Sprintf("%s can chat again.", username)
+	if muted {
+		if chat.Muted[key] {
+			return fmt.Errorf("%s is already muted", username)
+		}
+		chat.Muted[key] = true
+		message = fmt.Sprintf("%s was muted.", username)
+	} else {
+		if chat.Muted[key] == false {
+			return fmt.Errorf("%s is not muted", username)
+		}
+		delete(chat.Muted, key)
+	}
+	logChatChange(c, message)
+	return nil
+}
+
+// HandleChangeChatMode lets everyone chat or only the players who have a seat.
+//
+// Only admins and the host of a private table can change who can chat.
+func HandleChangeChatMode(c *Client, mode string) error {
+	if err := checkModerator(c); err != nil {
+		return err
+	}
+	if mode != chatEveryone && mode != chatPlayersOnly {
+		return fmt.Errorf("Invalid chat mode chosen")
+	}
+	if c.gameState.Chat.Mode == mode {
+		return fmt.Errorf("The chat mode is already set to %s", mode)
+	}
+
+	c.gameState.Chat.Mode = mode
+	message := "Everyone can chat now."
+	if mode == chatPlayersOnly {
+		message = "Only players with a seat can chat now."
+	}
+	logChatChange(c, message)
+	return nil
+}
+
+// HandleIgnorePlayer hides a player's messages from the client, or shows them again.
+//
+// Messages are filtered on the server, so ignored messages are never sent to the client.
+func HandleIgnorePlayer(c *Client, username string, ignored bool) error {
+	if c.username == "" {
+		return fmt.Errorf("You must join the game before ignoring players")
+	}
+	key := getAccountKey(username)
+	if key == "" {
+		return fmt.Errorf("Choose a player to ignore")
+	}
+	if key == getAccountKey(c.username) {
+		return fmt.Errorf("You cannot ignore yourself")
+	}
+	if key == getAccountKey(systemUsername) {
+		return fmt.Errorf("You cannot ignore the dealer")
+	}
+	if ignored {
+		c.ignored[key] = true
+	} else {
+		delete(c.ignored, key)
+	}
+	broadcastUpdateGameEvent(c)
+	return nil
+}
+
+// checkChat checks that the client is allowed to send a message right now and records it for
+// the rate limit.
+//
+// Mutes and the rate limit go by account, so they also apply to the player's other connections.
+func checkChat(c *Client) error {
+	g := c.gameState
+	key := getAccountKey(c.account.Username)
+	if g.Chat.Muted[key] {
+		return fmt.Errorf("You have been muted")
+	}
+	if g.Chat.Mode == chatPlayersOnly && c.seatID == "" && checkModerator(c) != nil {
+		return fmt.Errorf("Only players with a seat can chat right now")
+	}
+	if g.Config.ChatRateLimit <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	recent := make([]time.Time, 0, g.Config.ChatRateLimit)
+	for _, sentAt := range g.chatTimes[key] {
+		if now.Sub(sentAt) < chatRateWindow {
+			recent = append(recent, sentAt)
+		}
+	}
+	if len(recent) >= g.Config.ChatRateLimit {
+		g.chatTimes[key] = recent
+		return fmt.Errorf("You are sending messages too quickly")
+	}
+	g.chatTimes[key] = append(recent, now)
+	return nil
+}
+
+// getAccountKey gets the key for an account's chat settings. Usernames are unique regardless
+// of case.
+func getAccountKey(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// checkModerator checks that the client is an admin or the host of the table
+func checkModerator(c *Client) error {
+	if c.isAdmin || (c.username != "" && c.username == c.gameState.Config.Host) {
+		return nil
+	}
+	return fmt.Errorf("Only the host or an admin can moderate the chat")
+}
+
+// cleanMessage trims the message, checks its length and stars out filtered words
+func cleanMessage(g *GameState, message string) (string, error) {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return "", fmt.Errorf("Messages cannot be empty")
+	}
+	if utf8.RuneCountInString(message) > maxChatMessageLength {
+		return "", fmt.Errorf("Messages cannot be longer than %d characters", maxChatMessageLength)
+	}
+	return filterWords(message, g.Config.ChatFilter), nil
+}
+
+// filterWords replaces each filtered word with asterisks. Words are matched regardless of case,
+// but only as whole words.
+//
+// Word boundaries are checked by hand, since \b in Go only knows about ASCII letters.
+func filterWords(message string, words []string) string {
+	patterns := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			patterns = append(patterns, regexp.QuoteMeta(word))
+		}
+	}
+	if len(patterns) == 0 {
+		return message
+	}
+
+	// Longer words are tried first, so that a word is not cut short by a word it starts with
+	sort.Slice(patterns, func(i, j int) bool {
+		return len(patterns[i]) > len(patterns[j])
+	})
+	filter := regexp.MustCompile(`(?i)(` + strings.Join(patterns, "|") + `)`)
+
+	var filtered strings.Builder
+	last := 0
+	for _, match := range filter.FindAllStringIndex(message, -1) {
+		start, end := match[0], match[1]
+		if isWholeWord(message, start, end) == false {
+			continue
+		}
+		filtered.WriteString(message[last:start])
+		filtered.WriteString(strings.Repeat("*", utf8.RuneCountInString(message[start:end])))
+		last = end
+	}
+	filtered.WriteString(message[last:])
+	return filtered.String()
+}
+
+// isWholeWord checks that the text between start and end is not part of a longer word
+func isWholeWord(message string, start int, end int) bool {
+	before, _ := utf8.DecodeLastRuneInString(message[:start])
+	after, _ := utf8.DecodeRuneInString(message[end:])
+	return isWordRune(before) == false && isWordRune(after) == false
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+// useDefaultChatConfig gives tables that are created while the server is running the same word
+// filter and rate limit as the default table
+func useDefaultChatConfig(registry *TableRegistry, config *TableConfig) {
+	if table := registry.GetDefaultTable(); table != nil {
+		config.ChatFilter = table.GameState.Config.ChatFilter
+		config.ChatRateLimit = table.GameState.Config.ChatRateLimit
+	}
+}
+
+// createChatEvent creates a message that is not sent to the clients who ignored the sender
+func createChatEvent(c *Client, message string) BroadcastEvent {
+	e := NewBroadcastEvent(createNewMessageEvent(c.username, message))
+	key := getAccountKey(c.username)
+	for id, client := range c.hub.copyClients() {
+		if client.ignored[key] {
+			e.ExcludeClients[id] = true
+		}
+	}
+	return e
+}
+
+func logChatChange(c *Client, message string) {
+	if c.gameState.Stage == Waiting {
+		saveSnapshot(c.gameState)
+	}
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(systemUsername, message)))
+	broadcastUpdateGameEvent(c)
+}
+
+// projectChat gets the chat settings and the players that the viewer ignored
+func projectChat(g *GameState, v Viewer) map[string]interface{} {
+	muted := make([]string, 0, len(g.Chat.Muted))
+	for username := range g.Chat.Muted {
+		muted = append(muted, username)
+	}
+	sort.Strings(muted)
+
+	ignored := make([]string, 0, len(v.Ignored))
+	for username := range v.Ignored {
+		ignored = append(ignored, username)
+	}
+	sort.Strings(ignored)
+
+	return map[string]interface{}{
+		"ignored": ignored,
+		"mode":    g.Chat.Mode,
+		"muted":   muted,
+	}
+}
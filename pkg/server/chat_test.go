@@ -0,0 +1,128 @@
+package server_test
+
+import (
+	"strings"
+
+	"github.com/gorilla/websocket"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/server"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+var _ = Describe("Chat", func() {
+	var accounts *auth.Accounts
+	var srv *tableServer
+
+	// join connects to the table and joins the game without taking a seat
+	join := func(username string) *websocket.Conn {
+		return srv.join(username, nil)
+	}
+
+	// readChat reads game updates until the chat settings have the value
+	readChat := func(conn *websocket.Conn, key string, value interface{}) {
+		readUntil(conn, func(e server.Event) bool {
+			if isUpdate(e) == false {
+				return false
+			}
+			ok, _ := Equal(value).Match(e.Params["chat"].(map[string]interface{})[key])
+			return ok
+		})
+	}
+
+	BeforeEach(func() {
+		var err error
+		users := auth.NewMemoryUserStore()
+		accounts, err = auth.NewAccounts(users, []byte("secret"), []string{})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = accounts.Register("ops", "password")
+		Expect(err).NotTo(HaveOccurred())
+		accounts, err = auth.NewAccounts(users, []byte("secret"), []string{"ops"})
+		Expect(err).NotTo(HaveOccurred())
+
+		config := server.NewTableConfig()
+		config.ChatFilter = []string{"darn", "ärger"}
+		config.ChatRateLimit = 2
+		hub := server.NewHub()
+		go hub.Run()
+		registry := server.NewTableRegistry()
+		registry.AddTable(hub, server.NewGameState(config, storage.NewMemoryStore()))
+
+		srv = newTableServer(registry, accounts)
+	})
+
+	AfterEach(func() {
+		srv.Close()
+	})
+
+	It("filters words and limits how quickly players can chat", func() {
+		alice := join("alice")
+		send(alice, "send-message", map[string]interface{}{"message": "  Darn it, darned river, Ärger  "})
+		Expect(readUntil(alice, isEvent("new-message")).Params["message"]).To(Equal("**** it, darned river, *****"))
+
+		send(alice, "send-message", map[string]interface{}{"message": "gg"})
+		Expect(readUntil(alice, isEvent("new-message")).Params["message"]).To(Equal("gg"))
+		send(alice, "send-message", map[string]interface{}{"message": "gg again"})
+		Expect(readUntil(alice, isEvent("error")).Params["error"]).To(Equal("You are sending messages too quickly"))
+
+		send(alice, "send-message", map[string]interface{}{"message": strings.Repeat("a", 281)})
+		Expect(readUntil(alice, isEvent("error")).Params["error"]).To(ContainSubstring("longer than"))
+	})
+
+	It("does not send messages from ignored players", func() {
+		alice := join("alice")
+		bob := join("bob")
+		carol := join("carol")
+		send(bob, "ignore-player", map[string]interface{}{"ignored": true, "username": "Alice"})
+		readChat(bob, "ignored", []interface{}{"alice"})
+
+		send(alice, "send-message", map[string]interface{}{"message": "hi"})
+		Expect(readUntil(carol, isEvent("new-message")).Params["message"]).To(Equal("hi"))
+		send(carol, "send-message", map[string]interface{}{"message": "bye"})
+		Expect(readUntil(bob, isEvent("new-message")).Params["message"]).To(Equal("bye"))
+	})
+
+	It("lets admins mute players and limit the chat to players", func() {
+		alice := join("alice")
+		ops := join("ops")
+
+		send(alice, "mute-player", map[string]interface{}{"muted": true, "username": "ops"})
+		Expect(readUntil(alice, isEvent("error")).Params["error"]).To(Equal("Only the host or an admin can moderate the chat"))
+
+		send(ops, "mute-player", map[string]interface{}{"muted": true, "username": "alice"})
+		readChat(alice, "muted", []interface{}{"alice"})
+		send(alice, "send-message", map[string]interface{}{"message": "hi"})
+		Expect(readUntil(alice, isEvent("error")).Params["error"]).To(Equal("You have been muted"))
+
+		send(ops, "mute-player", map[string]interface{}{"muted": false, "username": "alice"})
+		send(ops, "change-chat-mode", map[string]interface{}{"mode": "players"})
+		readChat(alice, "mode", "players")
+		send(alice, "send-message", map[string]interface{}{"message": "hi"})
+		Expect(readUntil(alice, isEvent("error")).Params["error"]).To(Equal("Only players with a seat can chat right now"))
+	})
+
+	It("sends an error for params that are missing or have the wrong type", func() {
+		alice := join("alice")
+		send(alice, "ignore-player", map[string]interface{}{"username": "bob"})
+		Expect(readUntil(alice, isEvent("error")).Params["error"]).To(Equal("The ignored param is missing or invalid"))
+		send(alice, "change-chat-mode", map[string]interface{}{"mode": 1})
+		Expect(readUntil(alice, isEvent("error")).Params["error"]).To(Equal("The mode param is missing or invalid"))
+
+		// The table is still unlocked
+		send(alice, "send-message", map[string]interface{}{"message": "hi"})
+		Expect(readUntil(alice, isEvent("new-message")).Params["message"]).To(Equal("hi"))
+	})
+
+	It("mutes the account on every connection", func() {
+		alice := join("alice")
+		otherAlice := join("alice")
+		ops := join("ops")
+
+		send(ops, "mute-player", map[string]interface{}{"muted": true, "username": "ALICE"})
+		readChat(alice, "muted", []interface{}{"alice"})
+		send(otherAlice, "send-message", map[string]interface{}{"message": "hi"})
+		Expect(readUntil(otherAlice, isEvent("error")).Params["error"]).To(Equal("You have been muted"))
+	})
+})
@@ -1,12 +1,16 @@
 package server
 
 import (
+	"fmt"
 	"log"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/richard-to/go-poker/pkg/auth"
 )
 
 const (
@@ -29,30 +33,66 @@ var upgrader = websocket.Upgrader{
 	CheckOrigin:     func(r *http.Request) bool { return true }, // Fix this later; only using for testing
 }
 
+// delayedEvent is an event that will be sent to the client at a later time.
+type delayedEvent struct {
+	epoch  uint64 // Events from an earlier epoch are dropped
+	event  Event
+	sendAt time.Time
+}
+
 // Client is a middleman between the websocket connection and the hub.
 type Client struct {
-	conn      *websocket.Conn
-	gameState *GameState
-	hub       *Hub
-	id        string
+	account  auth.Claims // Authenticated identity from the session token
+	autoMuck bool
+	// How long broadcasts to the client are delayed, in nanoseconds. Read by the hub, so it is
+	// read and written atomically.
+	broadcastDelay int64
+	conn           *websocket.Conn
+	// Buffered channel of outbound broadcasts. Spectator broadcasts may be delayed.
+	delayed chan delayedEvent
+	// Goes up when the client sits down. Read and written atomically.
+	delayEpoch uint64
+	gameState  *GameState
+	hub        *Hub
+	id         string
+	ignored    map[string]bool // Players whose messages the client does not want. Keyed by lowercase username.
+	ip         string          // Address the connection came from. Used for IP bans.
+	isAdmin    bool
+	// Set to 1 while the client has joined without a seat. Read and written atomically.
+	isSpectating int32
+	// Signals the client's goroutine that a move to another table is waiting
+	moveReady chan struct{}
+	moveMu    sync.Mutex
 	muted     bool
-	seatID    string
+	// Move to another table that the client's goroutine makes between events. Guarded by moveMu.
+	nextMove *tableMove
+	peerID   string // Public ID used for WebRTC signaling. The client ID is kept private.
+	// Copy of the username and seat ID for the admin API, which reads it without locking the table
+	seat   atomic.Value
+	seatID string
 	// Buffered channel of outbound messages.
 	send     chan Event
-	username string
+	username string // Only set once the client has joined the game
 }
 
 // readPump pumps messages from the websocket connection to the hub.
 //
 // The application runs readPump in a per-connection goroutine. The application
 // ensures that there is at most one reader on a connection by executing all
-// reads from this goroutine.
+// reads from the readEvents goroutine that it starts.
+//
+// Events are processed one at a time on this goroutine. Moves to other tables are also made
+// here between events, so the client's table does not change while an event is processed.
 func (c *Client) readPump() {
 	// Send unregister event to hub using defer is a good idea since it will run
 	// after the function finishes
 	defer func() {
 		DisconnectPlayer(c)
-		c.hub.unregister <- c
+		c.hub.connections.Done()
+		select {
+		case c.hub.unregister <- c:
+		case <-c.hub.stopped:
+		}
 		c.conn.Close()
 	}()
 	// Set max message size
@@ -61,6 +101,28 @@ func (c *Client) readPump() {
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
 	// Unsure what this means. But heartbeat, I think
 	c.conn.SetPongHandler(func(string) error { c.conn.SetReadDeadline(time.Now().Add(pongWait)); return nil })
+
+	events := make(chan Event)
+	go c.readEvents(events)
+	for {
+		select {
+		case e, ok := <-events:
+			if ok == false {
+				return
+			}
+			ProcessEvent(c, e)
+		case <-c.moveReady:
+			if move := c.takeMove(); move != nil {
+				moveClient(c, move)
+			}
+		}
+	}
+}
+
+// readEvents reads events from the websocket connection. The events channel is closed once
+// the connection closes.
+func (c *Client) readEvents(events chan<- Event) {
+	defer close(events)
 	for {
 		// Read message
 		var e Event
@@ -69,11 +131,149 @@ func (c *Client) readPump() {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("error: %v", err)
 			}
-			break
+			return
 		}
-		ProcessEvent(c, e)
+		events <- e
+	}
+}
+
+// queueMove asks the client's goroutine to move the client to another table. Only the latest
+// move is made.
+func (c *Client) queueMove(move *tableMove) {
+	c.moveMu.Lock()
+	c.nextMove = move
+	c.moveMu.Unlock()
+	select {
+	case c.moveReady <- struct{}{}:
+	default:
+		// The client's goroutine has already been told
+	}
+}
+
+// takeMove gets the move that is waiting, if any
+func (c *Client) takeMove() *tableMove {
+	c.moveMu.Lock()
+	defer c.moveMu.Unlock()
+	move := c.nextMove
+	c.nextMove = nil
+	return move
+}
+
+// delayPump pumps broadcasts from the hub to the send channel once they are due.
+//
+// Broadcasts are sent in order. They are queued here rather than in the delayed channel,
+// so that a long spectator delay can't fill up the channel. The send channel is closed
+// once the hub closes the delayed channel.
+func (c *Client) delayPump() {
+	defer close(c.send)
+	queue := make([]delayedEvent, 0)
+	for {
+		var due <-chan time.Time
+		var timer *time.Timer
+		if len(queue) > 0 {
+			timer = time.NewTimer(time.Until(queue[0].sendAt))
+			due = timer.C
+		}
+
+		select {
+		case e, ok := <-c.delayed:
+			if ok == false {
+				// Send what is already due, such as the message that the server is shutting down
+				for _, e := range queue {
+					if time.Now().Before(e.sendAt) {
+						break
+					}
+					c.sendDelayed(e)
+				}
+				return
+			}
+			// Broadcasts from before the client sat down are dropped right away, so that
+			// they don't hold up the broadcasts that are no longer delayed
+			epoch := atomic.LoadUint64(&c.delayEpoch)
+			if len(queue) > 0 && queue[0].epoch != epoch {
+				queue = dropStale(queue, epoch)
+			}
+			if e.epoch == epoch {
+				queue = append(queue, e)
+			}
+		case <-due:
+			c.sendDelayed(queue[0])
+			queue = queue[1:]
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// dropStale removes the broadcasts that were queued in an earlier epoch
+func dropStale(queue []delayedEvent, epoch uint64) []delayedEvent {
+	current := make([]delayedEvent, 0, len(queue))
+	for _, e := range queue {
+		if e.epoch == epoch {
+			current = append(current, e)
+		}
+	}
+	return current
+}
+
+// sendDelayed sends a broadcast once it is due, unless the client sat down after it was queued.
+// Broadcasts queued while the client was a spectator may show the hole cards of the players.
+func (c *Client) sendDelayed(e delayedEvent) {
+	if e.epoch != atomic.LoadUint64(&c.delayEpoch) {
+		return
+	}
+	select {
+	case c.send <- e.event:
+	default:
+		// Drop events if the client has stopped reading them
+	}
+}
+
+// sit links the client to a seat. Clients without a seat watch the table as spectators.
+func (c *Client) sit(seatID string) {
+	c.seatID = seatID
+	atomic.AddUint64(&c.delayEpoch, 1)
+	c.updateHubState()
+}
+
+// clientSeat is the username and seat ID of a client
+type clientSeat struct {
+	seatID   string
+	username string
+}
+
+// updateHubState saves what the hub and the admin API need to know about the client.
+//
+// The hub runs on its own goroutine, so it can't read the client's seat and table while they
+// are being changed. This needs to be called whenever they change.
+func (c *Client) updateHubState() {
+	c.seat.Store(clientSeat{seatID: c.seatID, username: c.username})
 
+	delay := time.Duration(0)
+	isSpectating := int32(0)
+	if c.seatID == "" && c.isAdmin == false {
+		// Only spectators have their broadcasts delayed so that they can't relay what is
+		// happening at the table to seated players
+		delay = c.gameState.Config.SpectatorDelay
+		if c.username != "" {
+			isSpectating = 1
+		}
 	}
+	atomic.StoreInt64(&c.broadcastDelay, int64(delay))
+	atomic.StoreInt32(&c.isSpectating, isSpectating)
+}
+
+// getBroadcastDelay gets how long broadcasts should be delayed for the client.
+func (c *Client) getBroadcastDelay() time.Duration {
+	return time.Duration(atomic.LoadInt64(&c.broadcastDelay))
+}
+
+// isSpectator checks if the client joined the table without taking a seat. Admins are not
+// counted.
+func (c *Client) isSpectator() bool {
+	return atomic.LoadInt32(&c.isSpectating) == 1
 }
 
 // writePump pumps messages from the hub to the websocket connection.
@@ -116,27 +316,101 @@ func (c *Client) writePump() {
 }
 
 // ServeWs handles websocket requests from the peer.
-func ServeWs(hub *Hub, gameState *GameState, w http.ResponseWriter, r *http.Request) {
+//
+// The request must have a valid session token. The client's identity comes from the
+// token, so players can't join, chat or sit as someone else.
+func ServeWs(hub *Hub, gameState *GameState, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request) {
+	account, err := authenticate(accounts, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if isTableClosed(gameState) {
+		http.Error(w, "The table is closed", http.StatusServiceUnavailable)
+		return
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println(err)
 		return
 	}
 	client := &Client{
+		account:   account,
+		autoMuck:  true,
 		conn:      conn,
+		delayed:   make(chan delayedEvent, 256),
 		gameState: gameState,
 		hub:       hub,
 		id:        uuid.New().String(),
+		ignored:   make(map[string]bool),
+		ip:        getRemoteIP(r),
+		isAdmin:   account.IsAdmin,
+		moveReady: make(chan struct{}, 1),
 		muted:     false,
+		peerID:    uuid.New().String(),
 		send:      make(chan Event, 256),
 	}
+	client.updateHubState()
 
 	// So when the websocket is activated, add/register client to hub
-	client.hub.register <- client
+	// The table may have closed while the connection was upgraded. The client is registered
+	// while the table is locked, so a table that closes afterwards moves them with everyone else.
+	gameState.lock()
+	if gameState.isClosed {
+		err = fmt.Errorf("The table is closed")
+	} else {
+		err = client.hub.registerClient(client)
+	}
+	gameState.unlock()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	client.hub.connections.Add(1)
 
 	// Allow collection of memory referenced by the caller by doing all work in
 	// new goroutines.
 	// Start read and write operations in goroutines
+	go client.delayPump()
 	go client.writePump()
 	go client.readPump()
 }
+
+// isTableClosed checks if the table was closed by its host or for a shutdown
+func isTableClosed(g *GameState) bool {
+	g.lock()
+	defer g.unlock()
+	return g.isClosed
+}
+
+// ServeTableWs handles websocket requests for one of the tables in the registry.
+//
+// The table is chosen using the table query param. If no table is chosen, players are sent
+// to the table they are seated at, so they get their seat back after reconnecting. Private
+// tables also need the invite code or password in the code query param.
+func ServeTableWs(registry *TableRegistry, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request) {
+	account, err := authenticate(accounts, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var table *RegisteredTable
+	if name := r.URL.Query().Get("table"); name != "" {
+		table = registry.GetTable(name)
+	} else if table = registry.FindPlayerTable(account.Username); table == nil {
+		table = registry.GetDefaultTable()
+	}
+	if table == nil {
+		http.Error(w, "Table not found", http.StatusNotFound)
+		return
+	}
+	if canJoinTable(table.GameState, account.Username, r.URL.Query().Get("code")) == false {
+		http.Error(w, "This table is private", http.StatusForbidden)
+		return
+	}
+
+	ServeWs(table.Hub, table.GameState, accounts, w, r)
+}
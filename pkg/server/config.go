@@ -0,0 +1,309 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+// TableConfig contains the settings for a table.
+type TableConfig struct {
+	BigBlind                  int               // Optional. Cash games use the default big blind if zero.
+	ChatFilter                []string          // Words that are starred out of chat messages
+	ChatMode                  string            // Who can chat when the table opens. Everyone if empty.
+	ChatRateLimit             int               // Most messages a player can send in the rate window. No limit if zero.
+	Host                      string            // Username of the player who runs a private table. Public if empty.
+	InviteCode                string            // Code that players need to join a private table
+	Name                      string            // Name of the table used in hand histories
+	PasswordHash              []byte            // Optional. Players can also join a private table with the password.
+	Rake                      *poker.Rake       // Optional. No rake is taken if nil.
+	RunItMaxTimes             int               // Max number of times players can run out the board when all in
+	SeatOfferTimeout          time.Duration     // Optional. Players on the waitlist get the default time to take a seat if zero.
+	ShowHoleCardsToSpectators bool              // Only used if there is a spectator delay
+	SpectatorDelay            time.Duration     // How far behind the table spectators are
+	Tournament                *TournamentConfig // Optional. The table is a cash game if nil.
+}
+
+const defaultTableName string = "Main"
+
+// NewTableConfig creates a table config with the default settings.
+func NewTableConfig() TableConfig {
+	return TableConfig{
+		ChatRateLimit: defaultChatRateLimit,
+		Name:          defaultTableName,
+		RunItMaxTimes: 1,
+	}
+}
+
+// LoadTableConfig loads the table config from environment variables.
+//
+// - POKER_TABLE_NAME: Name of the table used in hand histories
+// - POKER_CHAT_FILTER: Comma separated words that are starred out of chat messages
+// - POKER_CHAT_MODE: Set to "players" so that only players with a seat can chat
+// - POKER_CHAT_RATE_LIMIT: Most messages a player can send every 10 seconds. Set to 0 for no limit.
+// - POKER_RAKE_PERCENT: Percentage of each pot to rake (e.g. 5 or 2.5). The rake is disabled if not set.
+// - POKER_RAKE_CAP: Maximum rake per hand
+// - POKER_RAKE_CAP_BY_PLAYERS: Caps by number of players dealt in (e.g. "2:1,4:2,6:3")
+// - POKER_RAKE_NO_FLOP_NO_DROP: Set to "1" to skip the rake when a hand ends preflop
+// - POKER_RUN_IT_MAX_TIMES: Max number of times players can run it when all in (1 to 3)
+// - POKER_SPECTATOR_DELAY: How far behind the table spectators are (e.g. "30s")
+// - POKER_SPECTATOR_HOLE_CARDS: Set to "1" to show hole cards to spectators if there is a delay
+// - POKER_TOURNAMENT: Set to "1" to play a sit and go tournament instead of a cash game
+// - POKER_TOURNAMENT_BUY_IN: Chips each player pays to enter the tournament
+// - POKER_TOURNAMENT_STACK: Chips each player starts the tournament with
+// - POKER_TOURNAMENT_PLAYERS: Number of players needed to start the tournament
+// - POKER_TOURNAMENT_LEVEL_DURATION: How long each blind level lasts (e.g. "10m")
+// - POKER_TOURNAMENT_HANDS_PER_LEVEL: Number of hands in each blind level
+// - POKER_TOURNAMENT_BLINDS: Big blinds with optional antes for each level (e.g. "20,30,50,100:10")
+// - POKER_TOURNAMENT_PAYOUTS: Percentage of the prize pool paid to each place (e.g. "50,30,20")
+func LoadTableConfig() (TableConfig, error) {
+	var err error
+
+	config := NewTableConfig()
+
+	tableName := os.Getenv("POKER_TABLE_NAME")
+	if tableName != "" {
+		config.Name = tableName
+	}
+
+	chatFilter := os.Getenv("POKER_CHAT_FILTER")
+	if chatFilter != "" {
+		config.ChatFilter = strings.Split(chatFilter, ",")
+	}
+	config.ChatMode = os.Getenv("POKER_CHAT_MODE")
+
+	chatRateLimit := os.Getenv("POKER_CHAT_RATE_LIMIT")
+	if chatRateLimit != "" {
+		config.ChatRateLimit, err = strconv.Atoi(chatRateLimit)
+		if err != nil {
+			return config, err
+		}
+	}
+
+	spectatorDelay := os.Getenv("POKER_SPECTATOR_DELAY")
+	if spectatorDelay != "" {
+		config.SpectatorDelay, err = time.ParseDuration(spectatorDelay)
+		if err != nil {
+			return config, err
+		}
+	}
+	config.ShowHoleCardsToSpectators = os.Getenv("POKER_SPECTATOR_HOLE_CARDS") == "1"
+
+	runItMaxTimes := os.Getenv("POKER_RUN_IT_MAX_TIMES")
+	if runItMaxTimes != "" {
+		config.RunItMaxTimes, err = strconv.Atoi(runItMaxTimes)
+		if err != nil {
+			return config, err
+		}
+		if config.RunItMaxTimes < 1 || config.RunItMaxTimes > maxRunItTimes {
+			return config, fmt.Errorf("POKER_RUN_IT_MAX_TIMES must be between 1 and %d", maxRunItTimes)
+		}
+	}
+
+	rakePercent := os.Getenv("POKER_RAKE_PERCENT")
+	if rakePercent != "" {
+		rake := &poker.Rake{
+			CapByPlayers: make(map[int]int),
+			NoFlopNoDrop: os.Getenv("POKER_RAKE_NO_FLOP_NO_DROP") == "1",
+		}
+
+		rake.Percent, err = strconv.ParseFloat(rakePercent, 64)
+		if err != nil {
+			return config, err
+		}
+		if math.IsNaN(rake.Percent) || rake.Percent < 0 || rake.Percent > 100 {
+			return config, fmt.Errorf("POKER_RAKE_PERCENT must be between 0 and 100")
+		}
+
+		rakeCap := os.Getenv("POKER_RAKE_CAP")
+		if rakeCap != "" {
+			rake.Cap, err = strconv.Atoi(rakeCap)
+			if err != nil {
+				return config, err
+			}
+			if rake.Cap < 0 {
+				return config, fmt.Errorf("POKER_RAKE_CAP can't be negative")
+			}
+		}
+
+		rake.CapByPlayers, err = parseIntMap(os.Getenv("POKER_RAKE_CAP_BY_PLAYERS"))
+		if err != nil {
+			return config, err
+		}
+		for _, maxRake := range rake.CapByPlayers {
+			if maxRake < 0 {
+				return config, fmt.Errorf("POKER_RAKE_CAP_BY_PLAYERS can't have negative caps")
+			}
+		}
+
+		config.Rake = rake
+	}
+
+	if os.Getenv("POKER_TOURNAMENT") == "1" {
+		config.Tournament, err = loadTournamentConfig()
+		if err != nil {
+			return config, err
+		}
+		// Tournaments are paid out from the buy-ins, so there is no rake
+		config.Rake = nil
+	}
+
+	return config, nil
+}
+
+// LoadMultiTableTournamentConfig loads the multi-table tournament from environment variables.
+// Returns nil if no multi-table tournament is set up.
+//
+// - POKER_MTT: Set to "1" to run a multi-table tournament alongside the table
+// - POKER_MTT_NAME: Name of the tournament
+// - POKER_MTT_TABLE_SIZE: Number of players seated at each table
+// - POKER_MTT_PLAYERS: Number of players needed to start the tournament, or the most players
+// that can register for a scheduled tournament
+// - POKER_MTT_START: Optional start time in RFC 3339 format (e.g. 2021-01-02T19:00:00Z)
+// - POKER_MTT_MIN_PLAYERS: Scheduled tournaments are cancelled if fewer players register
+// - POKER_MTT_LATE_REGISTRATION: Number of blind levels that players can register late during
+// - POKER_MTT_RE_ENTRIES: Times each player can buy in again during late registration
+// - POKER_MTT_REBUY_LEVELS: Number of blind levels that players can rebuy during
+// - POKER_MTT_REBUYS: Times each player can rebuy
+//
+// The blinds, buy-in and payouts use the same settings as sit and go tournaments, but start
+// with POKER_MTT instead of POKER_TOURNAMENT (e.g. POKER_MTT_BLINDS).
+func LoadMultiTableTournamentConfig() (*MultiTableTournamentConfig, error) {
+	var err error
+
+	if os.Getenv("POKER_MTT") != "1" {
+		return nil, nil
+	}
+
+	config := NewMultiTableTournamentConfig()
+
+	name := os.Getenv("POKER_MTT_NAME")
+	if name != "" {
+		config.Name = name
+	}
+
+	ints := map[string]*int{
+		"POKER_MTT_TABLE_SIZE":        &config.TableSize,
+		"POKER_MTT_MIN_PLAYERS":       &config.MinPlayers,
+		"POKER_MTT_LATE_REGISTRATION": &config.LateRegistrationLevels,
+		"POKER_MTT_RE_ENTRIES":        &config.ReEntries,
+		"POKER_MTT_REBUY_LEVELS":      &config.RebuyLevels,
+		"POKER_MTT_REBUYS":            &config.Rebuys,
+	}
+	for key, value := range ints {
+		if s := os.Getenv(key); s != "" {
+			*value, err = strconv.Atoi(s)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	startAt := os.Getenv("POKER_MTT_START")
+	if startAt != "" {
+		config.StartAt, err = time.Parse(time.RFC3339, startAt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := loadTournamentSettings("POKER_MTT", &config.Tournament); err != nil {
+		return nil, err
+	}
+
+	return config, config.Validate()
+}
+
+// loadTournamentConfig loads the sit and go tournament settings from environment variables.
+func loadTournamentConfig() (*TournamentConfig, error) {
+	config := NewTournamentConfig()
+	if err := loadTournamentSettings("POKER_TOURNAMENT", config); err != nil {
+		return nil, err
+	}
+	return config, config.Validate()
+}
+
+// loadTournamentSettings loads the tournament settings from environment variables that start
+// with the prefix.
+func loadTournamentSettings(prefix string, config *TournamentConfig) error {
+	var err error
+
+	ints := map[string]*int{
+		prefix + "_BOUNTY":          &config.Bounty,
+		prefix + "_BUY_IN":          &config.BuyIn,
+		prefix + "_STACK":           &config.StartingStack,
+		prefix + "_PLAYERS":         &config.NumPlayers,
+		prefix + "_HANDS_PER_LEVEL": &config.HandsPerLevel,
+	}
+	for key, value := range ints {
+		if s := os.Getenv(key); s != "" {
+			*value, err = strconv.Atoi(s)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if progressive := os.Getenv(prefix + "_PROGRESSIVE"); progressive != "" {
+		config.Progressive = progressive == "1"
+	}
+
+	levelDuration := os.Getenv(prefix + "_LEVEL_DURATION")
+	if levelDuration != "" {
+		config.LevelDuration, err = time.ParseDuration(levelDuration)
+		if err != nil {
+			return err
+		}
+	}
+
+	blinds := os.Getenv(prefix + "_BLINDS")
+	if blinds != "" {
+		config.Levels, err = parseBlindLevels(blinds)
+		if err != nil {
+			return err
+		}
+	}
+
+	payouts := os.Getenv(prefix + "_PAYOUTS")
+	if payouts != "" {
+		config.Payouts = make([]int, 0)
+		for _, payout := range strings.Split(payouts, ",") {
+			percent, err := strconv.Atoi(strings.TrimSpace(payout))
+			if err != nil {
+				return err
+			}
+			config.Payouts = append(config.Payouts, percent)
+		}
+	}
+
+	return nil
+}
+
+// parseIntMap parses a comma separated list of key:value integer pairs (e.g. "2:1,4:2").
+func parseIntMap(s string) (map[int]int, error) {
+	m := make(map[int]int)
+	if s == "" {
+		return m, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(kv) != 2 {
+			return nil, strconv.ErrSyntax
+		}
+		k, err := strconv.Atoi(kv[0])
+		if err != nil {
+			return nil, err
+		}
+		v, err := strconv.Atoi(kv[1])
+		if err != nil {
+			return nil, err
+		}
+		m[k] = v
+	}
+	return m, nil
+}
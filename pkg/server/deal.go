@@ -0,0 +1,284 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+// Types of deals
+const dealChipChop string = "chip-chop"
+const dealICM string = "icm"
+
+// Deal is an offer to end a tournament by splitting the prize money that is left between the
+// players who are still in.
+//
+// The payouts are worked out from the stacks at the start of the hand, so the hand in
+// progress does not count. Every player who is still in must accept. No more hands are dealt
+// until the players have decided. If the stacks change before everyone accepts, the payouts
+// are worked out again and the players need to accept the new payouts.
+type Deal struct {
+	Accepted   map[string]bool `json:"accepted"` // Keyed by username
+	Payouts    map[string]int  `json:"payouts"`  // Keyed by username
+	ProposedBy string          `json:"proposedBy"`
+	Type       string          `json:"type"`
+}
+
+// IsAccepted checks if every player accepted the deal.
+func (d *Deal) IsAccepted() bool {
+	for _, accepted := range d.Accepted {
+		if accepted == false {
+			return false
+		}
+	}
+	return true
+}
+
+// HandleProposeDeal offers the other players a deal
+func HandleProposeDeal(c *Client, dealType string) error {
+	g := c.gameState
+	t := g.Tournament
+	if t == nil || t.IsRunning() == false {
+		return fmt.Errorf("Deals can only be made while a tournament is running")
+	}
+	if g.MTT != nil && len(g.MTT.tables) > 1 {
+		return fmt.Errorf("Deals can only be made at the final table")
+	}
+	if t.HasPlayer(c.username) == false || isEliminated(t, c.username) {
+		return fmt.Errorf("Only players who are still in the tournament can propose a deal")
+	}
+	if t.Deal != nil {
+		return fmt.Errorf("A deal has already been proposed")
+	}
+	if dealType != dealICM && dealType != dealChipChop {
+		return fmt.Errorf("Unknown deal: %s", dealType)
+	}
+	if dealType == dealICM && len(t.Players)-len(t.Eliminated) > poker.MaxICMPlayers {
+		return fmt.Errorf("ICM deals can only be made with %d players or fewer", poker.MaxICMPlayers)
+	}
+
+	t.Deal = newDeal(g, dealType, c.username)
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
+		systemUsername,
+		fmt.Sprintf("%s proposes %s: %s.", c.username, formatDealType(dealType), formatDeal(t.Deal)),
+	)))
+	broadcastUpdateGameEvent(c)
+	return nil
+}
+
+// HandleAcceptDeal accepts the deal that was proposed.
+//
+// The tournament ends straight away if the table is waiting for the players to decide.
+// Otherwise it ends once the hand in progress is over.
+func HandleAcceptDeal(c *Client) error {
+	g := c.gameState
+	deal := g.Tournament.getDeal()
+	if deal == nil {
+		return fmt.Errorf("There is no deal to accept")
+	}
+	if _, ok := deal.Accepted[c.username]; ok == false {
+		return fmt.Errorf("Only players who are still in the tournament can accept the deal")
+	}
+
+	deal.Accepted[c.username] = true
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
+		systemUsername,
+		fmt.Sprintf("%s accepts the deal.", c.username),
+	)))
+
+	if deal.IsAccepted() {
+		if g.Stage == Waiting {
+			makeDeal(g)
+			broadcastAnnouncements(c)
+			saveSnapshot(g)
+		} else {
+			c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
+				systemUsername,
+				"Every player accepted the deal. The tournament ends once the hand is over.",
+			)))
+		}
+	}
+	broadcastUpdateGameEvent(c)
+	return nil
+}
+
+// HandleDeclineDeal turns down the deal that was proposed. The table deals again if it was
+// waiting for the players to decide.
+func HandleDeclineDeal(c *Client) error {
+	g := c.gameState
+	deal := g.Tournament.getDeal()
+	if deal == nil {
+		return fmt.Errorf("There is no deal to decline")
+	}
+	if _, ok := deal.Accepted[c.username]; ok == false {
+		return fmt.Errorf("Only players who are still in the tournament can decline the deal")
+	}
+
+	g.Tournament.Deal = nil
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
+		systemUsername,
+		fmt.Sprintf("%s declines the deal.", c.username),
+	)))
+	resumeTournamentTable(c)
+	broadcastUpdateGameEvent(c)
+	HandleComputerMove(c)
+	return nil
+}
+
+// getDeal gets the deal that was proposed. Returns nil if there is no tournament.
+func (t *Tournament) getDeal() *Deal {
+	if t == nil {
+		return nil
+	}
+	return t.Deal
+}
+
+// newDeal works out the payouts for a deal from the stacks at the start of the hand.
+//
+// The prizes for the places that the players can still finish in are split between them.
+// Prizes already won by players who were knocked out are not part of the deal.
+func newDeal(g *GameState, dealType string, proposedBy string) *Deal {
+	t := g.Tournament
+	places := getDealPlaces(g)
+	players := places[:len(t.Players)-len(t.Eliminated)]
+	payouts := calculatePayouts(t.GetPrizePool(g.Config.Tournament), g.Config.Tournament.Payouts, places)
+
+	stacks := make([]int, len(players))
+	prizes := make([]int, len(players))
+	prizeMoney := 0
+	for i, username := range players {
+		stacks[i] = t.Stacks[username]
+		prizes[i] = payouts[username]
+		prizeMoney += prizes[i]
+	}
+
+	var evs []float64
+	if dealType == dealChipChop {
+		evs = poker.CalculateChipChop(stacks, prizes)
+	} else {
+		evs = poker.CalculateICM(stacks, prizes)
+	}
+
+	deal := &Deal{
+		Accepted:   make(map[string]bool),
+		Payouts:    make(map[string]int),
+		ProposedBy: proposedBy,
+		Type:       dealType,
+	}
+	for i, amount := range roundPrizes(evs, prizeMoney) {
+		deal.Accepted[players[i]] = players[i] == proposedBy
+		deal.Payouts[players[i]] = amount
+	}
+	return deal
+}
+
+// waitForDeal checks if the table needs to wait for the players to decide on a deal before
+// dealing the next hand. The deal is worked out again if the stacks have changed.
+func waitForDeal(g *GameState) bool {
+	deal := g.Tournament.Deal
+	if deal == nil {
+		return false
+	}
+
+	updated := newDeal(g, deal.Type, deal.ProposedBy)
+	isSame := len(updated.Payouts) == len(deal.Payouts)
+	for username, amount := range updated.Payouts {
+		if deal.Payouts[username] != amount {
+			isSame = false
+		}
+	}
+	if isSame == false {
+		g.Tournament.Deal = updated
+		g.announce(fmt.Sprintf("The stacks have changed. The deal is now: %s.", formatDeal(updated)))
+	}
+	g.announce("Waiting for the players to decide on the deal.")
+	return true
+}
+
+// makeDeal ends the tournament with the payouts that the players agreed to. The players in
+// the deal are ranked by their stacks.
+func makeDeal(g *GameState) {
+	t := g.Tournament
+	deal := t.Deal
+	t.Deal = nil
+
+	places := getDealPlaces(g)
+	payouts := calculatePayouts(t.GetPrizePool(g.Config.Tournament), g.Config.Tournament.Payouts, places)
+	for username, amount := range deal.Payouts {
+		payouts[username] = amount
+	}
+	announceTournament(g, fmt.Sprintf("The players agreed to %s.", formatDealType(deal.Type)))
+	payOutTournament(g, places, payouts)
+}
+
+// getDealPlaces ranks the players who are still in by their stacks, followed by the players
+// who were knocked out
+func getDealPlaces(g *GameState) []string {
+	t := g.Tournament
+	places := make([]string, 0, len(t.Players))
+	for _, username := range t.Players {
+		if isEliminated(t, username) == false {
+			places = append(places, username)
+		}
+	}
+	sort.SliceStable(places, func(i, j int) bool {
+		return t.Stacks[places[i]] > t.Stacks[places[j]]
+	})
+	for i := len(t.Eliminated) - 1; i >= 0; i-- {
+		places = append(places, t.Eliminated[i])
+	}
+	return places
+}
+
+// roundPrizes rounds the prizes down to whole chips. The chips left over go to the prizes
+// that were rounded down the most.
+func roundPrizes(evs []float64, total int) []int {
+	prizes := make([]int, len(evs))
+	left := total
+	for i, ev := range evs {
+		prizes[i] = int(math.Floor(ev))
+		left -= prizes[i]
+	}
+
+	order := make([]int, len(evs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return evs[order[i]]-math.Floor(evs[order[i]]) > evs[order[j]]-math.Floor(evs[order[j]])
+	})
+	for i := 0; i < left && i < len(order); i++ {
+		prizes[order[i]]++
+	}
+	return prizes
+}
+
+// formatDeal lists the payouts in a deal from biggest to smallest
+func formatDeal(d *Deal) string {
+	usernames := make([]string, 0, len(d.Payouts))
+	for username := range d.Payouts {
+		usernames = append(usernames, username)
+	}
+	sort.Slice(usernames, func(i, j int) bool {
+		if d.Payouts[usernames[i]] == d.Payouts[usernames[j]] {
+			return usernames[i] < usernames[j]
+		}
+		return d.Payouts[usernames[i]] > d.Payouts[usernames[j]]
+	})
+
+	payouts := make([]string, 0, len(usernames))
+	for _, username := range usernames {
+		payouts = append(payouts, fmt.Sprintf("%s ℝ%d", username, d.Payouts[username]))
+	}
+	return strings.Join(payouts, ", ")
+}
+
+func formatDealType(dealType string) string {
+	if dealType == dealChipChop {
+		return "a chip chop"
+	}
+	return "an ICM deal"
+}
@@ -3,13 +3,19 @@ package server
 import (
 	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/richard-to/go-poker/pkg/history"
 	"github.com/richard-to/go-poker/pkg/poker"
+	"github.com/richard-to/go-poker/pkg/stats"
+	"github.com/richard-to/go-poker/pkg/storage"
 )
 
 // General actions
+const actionAutoMuck string = "auto-muck"
 const actionDisconnect string = "disconnect"
 const actionError string = "error"
 const actionOnJoin string = "on-join"
@@ -25,12 +31,19 @@ const actionOnReceiveSignal string = "on-receive-signal"
 const actionSendSignal string = "send-signal"
 
 // Game actions
+const actionAcceptDeal string = "accept-deal"
 const actionBet string = "bet"
 const actionCall string = "call"
 const actionCheck string = "check"
+const actionDeclineDeal string = "decline-deal"
 const actionFold string = "fold"
+const actionMuckHand string = "muck-hand"
 const actionOnHoleCards string = "on-hole-cards"
+const actionProposeDeal string = "propose-deal"
 const actionRaise string = "raise"
+const actionRunIt string = "run-it"
+const actionShowCards string = "show-cards"
+const actionShowHand string = "show-hand"
 const actionUpdateGame string = "update-game"
 
 // Table settings
@@ -63,9 +76,16 @@ func (g GameStage) String() string {
 // There are cases where we don't want to broadcast to everyone. In this scenario
 // the exclude clients map can be used. This will prevent messages from being sent
 // to the specified client ID
+//
+// There are also cases where each client needs to see a different version of the
+// event, such as when the event contains private data. In this scenario the client
+// events map can be used. Clients that are not in the map will not be sent the event.
 type BroadcastEvent struct {
+	ClientEvents   map[string]Event
 	Event          Event
 	ExcludeClients map[string]bool
+
+	epochs map[string]uint64 // Delay epoch of each client when their event was projected
 }
 
 // Event is a JSON message in the game loop.
@@ -76,12 +96,59 @@ type Event struct {
 
 // GameState is the current state of the poker game
 type GameState struct {
-	BettingRound *poker.BettingRound
-	CurrentSeat  *poker.Seat
-	Deck         poker.Deck
-	PlayerMap    map[string]*poker.Player
-	Stage        GameStage
-	Table        poker.Table
+	BettingRound   *poker.BettingRound
+	Boards         []poker.Board // Only used when the board is run more than once
+	Chat           *Chat
+	Config         TableConfig
+	CurrentSeat    *poker.Seat
+	Deck           poker.Deck
+	HandHistories  history.Store
+	History        *history.HandHistory  // The hand that is currently being played
+	MTT            *MultiTableTournament // Only used if the table is part of a multi-table tournament
+	MuckedHands    []*ShowableHand       // Hands mucked at the last showdown
+	PlayerMap      map[string]*poker.Player
+	Private        *PrivateTable // Only used if the table is private
+	RunItVote      *RunItVote
+	SeatChanges    map[string]string // Seats that players move to once the hand is over. Keyed by seat ID.
+	SeatOffers     []*SeatOffer      // Open seats being held for players from the waitlist
+	Session        *RatingSession
+	ShowdownReveal *ShowdownReveal // Only set while the hands are being revealed at showdown
+	Stage          GameStage
+	Stats          *stats.Tracker
+	Store          storage.Store
+	Table          poker.Table
+	Tournament     *Tournament // Only used if the table is a tournament
+	UncontestedWin *ShowableHand
+	Waitlist       []string // Usernames of the players waiting for a seat
+
+	announcements  []string               // Messages posted to the chat once the next hand has been dealt
+	chatTimes      map[string][]time.Time // When each account sent its recent messages. Used for the rate limit.
+	isClosed       bool                   // Set once the connections are being closed on shutdown or the table is closed
+	isReplaying    bool                   // Set while the journal is being replayed after a restart
+	isShuttingDown bool                   // No new hands are dealt while shutting down
+	mu             sync.Mutex             // Held while the table is being changed, so that only one goroutine changes it at a time. Taken by lock.
+	recentPots     []int                  // Used for the average pot shown in the lobby
+	registry       *TableRegistry         // Only set for private tables, so that the host can close them
+	summary        atomic.Value           // Latest tableSummary. Published while the table is locked, so it can be read without the lock.
+}
+
+// lock locks the table. Tables in a multi-table tournament lock the tournament too, since
+// the tables share its state and change each other's seats.
+func (g *GameState) lock() {
+	g.mu.Lock()
+	if g.MTT != nil {
+		g.MTT.mu.Lock()
+	}
+}
+
+// unlock unlocks the table and its multi-table tournament. The summary of the table is
+// published first, so that it matches the changes that were made.
+func (g *GameState) unlock() {
+	g.publishSummary()
+	if g.MTT != nil {
+		g.MTT.mu.Unlock()
+	}
+	g.mu.Unlock()
 }
 
 // NewBroadcastEvent creates a new broadcast event that will send the message to all clients
@@ -99,49 +166,178 @@ func NewBroadcastEvent(e Event) BroadcastEvent {
 // - If the client is disconnected while it's their turn, the player will auto-fold or check
 // - Not all clients will be sitting at the table
 func DisconnectPlayer(c *Client) {
+	c.gameState.lock()
+	defer c.gameState.unlock()
+
+	// Players are disconnected on purpose when the server shuts down. Their seats are kept
+	// for when the server restarts.
+	if c.gameState.isClosed {
+		return
+	}
+
+	// Players who leave give up their place on the waitlist
+	if c.username != "" && leaveWaitlist(c) {
+		broadcastUpdateGameEvent(c)
+	}
+
 	player := poker.GetPlayerByID(&c.gameState.Table, c.seatID)
 	if player != nil {
 		player.IsHuman = false
-		if c.gameState.Stage == Waiting {
+		delete(c.gameState.SeatChanges, player.ID)
+		t := c.gameState.Tournament
+		if c.gameState.Stage == Waiting && (t == nil || t.IsRunning() == false) {
+			// Players who leave before the tournament starts get their buy-in back
+			cashOut(c.gameState, player)
+			if t != nil && t.HasStarted() == false {
+				t.RemovePlayer(player.Name)
+			}
 			player.Status = poker.PlayerVacated
-			c.hub.broadcast <- NewBroadcastEvent(createUpdateGameEvent(c, false))
+			offerOpenSeats(c)
+			saveSnapshot(c.gameState)
+			broadcastUpdateGameEvent(c)
+		} else if c.gameState.RunItVote != nil {
+			if c.gameState.RunItVote.Votes[player.ID] == 0 {
+				HandleRunIt(c, 1)
+			}
+		} else if c.gameState.ShowdownReveal.getChoosingSeatID() == player.ID {
+			HandleMuckHand(c)
 		} else if c.gameState.Stage < Showdown {
 			HandleComputerMove(c)
 		}
+
+		// Players who leave turn down the deal, since they can't accept it
+		if deal := c.gameState.Tournament.getDeal(); deal != nil {
+			if _, ok := deal.Accepted[c.username]; ok {
+				HandleDeclineDeal(c)
+			}
+		}
 	}
 
 	// If a client does not have a username set, that means they haven't technically
 	// joined the table yet. In that case we don't have to post a message.
 	if c.username != "" {
-		c.hub.broadcast <- NewBroadcastEvent(createNewMessageEvent(
+		c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
 			systemUsername,
 			fmt.Sprintf("%s has left the game.", c.username),
-		))
+		)))
 	}
 }
 
 // ProcessEvent process event
+//
+// The table is locked while the event is processed.
 func ProcessEvent(c *Client, e Event) {
+	c.gameState.lock()
+	defer c.gameState.unlock()
+
+	// Events that arrive once the table has closed are ignored
+	if c.gameState.isClosed {
+		return
+	}
+
 	var err error
+	p := eventParams{params: e.Params}
 	if e.Action == actionJoin {
-		err = HandleJoin(c, e.Params["username"].(string))
+		err = HandleJoin(c)
 	} else if e.Action == actionSendMessage {
-		err = HandleSendMessage(c, e.Params["username"].(string), e.Params["message"].(string))
+		message := p.getString("message")
+		if err = p.err; err == nil {
+			err = HandleSendMessage(c, message)
+		}
+	} else if e.Action == actionIgnorePlayer {
+		username, ignored := p.getString("username"), p.getBool("ignored")
+		if err = p.err; err == nil {
+			err = HandleIgnorePlayer(c, username, ignored)
+		}
+	} else if e.Action == actionMutePlayer {
+		username, muted := p.getString("username"), p.getBool("muted")
+		if err = p.err; err == nil {
+			err = HandleMutePlayer(c, username, muted)
+		}
+	} else if e.Action == actionChangeChatMode {
+		mode := p.getString("mode")
+		if err = p.err; err == nil {
+			err = HandleChangeChatMode(c, mode)
+		}
 	} else if e.Action == actionSendSignal {
-		err = HandleSendSignal(
-			c,
-			e.Params["peerID"].(string),
-			e.Params["streamID"].(string),
-			e.Params["signalData"],
-		)
+		peerID, streamID := p.getString("peerID"), p.getString("streamID")
+		if err = p.err; err == nil {
+			err = HandleSendSignal(c, peerID, streamID, e.Params["signalData"])
+		}
 	} else if e.Action == actionTakeSeat {
-		err = HandleTakeSeat(c, e.Params["seatID"].(string))
+		seatID := p.getString("seatID")
+		if err = p.err; err == nil {
+			err = HandleTakeSeat(c, seatID)
+		}
+	} else if e.Action == actionChangeSeat {
+		seatID := p.getString("seatID")
+		if err = p.err; err == nil {
+			err = HandleChangeSeat(c, seatID)
+		}
+	} else if e.Action == actionJoinWaitlist {
+		err = HandleJoinWaitlist(c)
+	} else if e.Action == actionLeaveWaitlist {
+		err = HandleLeaveWaitlist(c)
+	} else if e.Action == actionApprovePlayer {
+		username := p.getString("username")
+		if err = p.err; err == nil {
+			err = HandleApprovePlayer(c, username)
+		}
+	} else if e.Action == actionChangeBlinds {
+		bigBlind := p.getInt("bigBlind")
+		if err = p.err; err == nil {
+			err = HandleChangeBlinds(c, bigBlind)
+		}
+	} else if e.Action == actionCloseTable {
+		err = HandleCloseTable(c)
+	} else if e.Action == actionKickPlayer {
+		seatID := p.getString("seatID")
+		if err = p.err; err == nil {
+			err = HandleKickPlayer(c, seatID)
+		}
+	} else if e.Action == actionPauseTable {
+		err = HandlePauseTable(c)
+	} else if e.Action == actionResumeTable {
+		err = HandleResumeTable(c)
 	} else if e.Action == actionMuteVideo {
-		err = HandleMuteVideo(c, e.Params["muted"].(bool))
+		muted := p.getBool("muted")
+		if err = p.err; err == nil {
+			err = HandleMuteVideo(c, muted)
+		}
+	} else if e.Action == actionAutoMuck {
+		autoMuck := p.getBool("autoMuck")
+		if err = p.err; err == nil {
+			err = HandleAutoMuck(c, autoMuck)
+		}
+	} else if e.Action == actionShowCards {
+		cards := p.getInts("cards")
+		if err = p.err; err == nil {
+			err = HandleShowCards(c, cards)
+		}
+	} else if e.Action == actionRunIt {
+		times := p.getInt("times")
+		if err = p.err; err == nil {
+			err = HandleRunIt(c, times)
+		}
+	} else if e.Action == actionShowHand {
+		err = HandleShowHand(c)
+	} else if e.Action == actionMuckHand {
+		err = HandleMuckHand(c)
+	} else if e.Action == actionProposeDeal {
+		dealType := p.getString("type")
+		if err = p.err; err == nil {
+			err = HandleProposeDeal(c, dealType)
+		}
+	} else if e.Action == actionAcceptDeal {
+		err = HandleAcceptDeal(c)
+	} else if e.Action == actionDeclineDeal {
+		err = HandleDeclineDeal(c)
 	} else {
 		// The remaining actions are turn dependent. The player can only act if it's their turn.
 		if c.gameState.Stage < Preflop || c.gameState.Stage > River {
 			err = fmt.Errorf("You cannot move during the %s stage", c.gameState.Stage.String())
+		} else if c.gameState.RunItVote != nil {
+			err = fmt.Errorf("Waiting for players to decide how many times to run it")
 		} else if c.gameState.CurrentSeat.Player.ID != c.seatID {
 			err = fmt.Errorf("You cannot move out of turn")
 		} else if e.Action == actionFold {
@@ -151,8 +347,10 @@ func ProcessEvent(c *Client, e Event) {
 		} else if e.Action == actionCall {
 			err = HandleCall(c)
 		} else if e.Action == actionRaise {
-			raiseAmount := int(e.Params["value"].(float64))
-			err = HandleRaise(c, raiseAmount)
+			raiseAmount := p.getInt("value")
+			if err = p.err; err == nil {
+				err = HandleRaise(c, raiseAmount)
+			}
 		} else {
 			err = fmt.Errorf("Unknown action encountered: %s", e.Action)
 		}
@@ -163,69 +361,170 @@ func ProcessEvent(c *Client, e Event) {
 	}
 }
 
+// eventParams reads the params of an event. The params come from the player, so params that
+// are missing or have the wrong type are kept as an error instead of panicking while the table
+// is locked. Only the first error is kept.
+type eventParams struct {
+	err    error
+	params map[string]interface{}
+}
+
+func (p *eventParams) getString(name string) string {
+	v, ok := p.params[name].(string)
+	if ok == false {
+		p.fail(name)
+	}
+	return v
+}
+
+func (p *eventParams) getBool(name string) bool {
+	v, ok := p.params[name].(bool)
+	if ok == false {
+		p.fail(name)
+	}
+	return v
+}
+
+// getInt reads a number. JSON numbers are decoded as float64.
+func (p *eventParams) getInt(name string) int {
+	v, ok := p.params[name].(float64)
+	if ok == false {
+		p.fail(name)
+	}
+	return int(v)
+}
+
+func (p *eventParams) getInts(name string) []int {
+	values, ok := p.params[name].([]interface{})
+	if ok == false {
+		p.fail(name)
+		return nil
+	}
+	ints := make([]int, 0, len(values))
+	for _, value := range values {
+		v, ok := value.(float64)
+		if ok == false {
+			p.fail(name)
+			return nil
+		}
+		ints = append(ints, int(v))
+	}
+	return ints
+}
+
+func (p *eventParams) fail(name string) {
+	if p.err == nil {
+		p.err = fmt.Errorf("The %s param is missing or invalid", name)
+	}
+}
+
 // HandlePlayerError handles player error (not system error)
 func HandlePlayerError(c *Client, err error) error {
-	c.send <- createErrorEvent(err)
+	c.hub.sendToClient(c, createErrorEvent(err))
 	return nil
 }
 
 // HandleJoin handles join event
-func HandleJoin(c *Client, username string) error {
-	c.username = username
+//
+// The username comes from the client's session token.
+func HandleJoin(c *Client) error {
+	if c.username != "" {
+		return fmt.Errorf("You have already joined the game")
+	}
+	if err := c.gameState.Store.OpenBankroll(c.account.Username, defaultBankroll); err != nil {
+		return err
+	}
+	c.username = c.account.Username
+	c.updateHubState()
 
-	c.send <- createOnJoinEvent(c.id, c.username)
+	c.hub.sendToClient(c, createOnJoinEvent(c.peerID, c.username))
 
-	c.hub.broadcast <- NewBroadcastEvent(createNewMessageEvent(
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
 		systemUsername,
 		fmt.Sprintf("%s joined the game.", c.username),
-	))
+	)))
 
-	c.hub.broadcast <- NewBroadcastEvent(createUpdateGameEvent(c, false))
+	// Players who were disconnected during a hand get their seat back when they rejoin, unless
+	// the host kicked them
+	for _, p := range getSeatedPlayers(c.gameState) {
+		if p.Name == c.username && p.IsHuman == false && isApproved(c.gameState, c.username) {
+			return reclaimSeat(c, p)
+		}
+	}
+
+	broadcastUpdateGameEvent(c)
 	return nil
 }
 
 // HandleSendMessage handles send message event
-func HandleSendMessage(c *Client, username string, message string) error {
-	c.hub.broadcast <- NewBroadcastEvent(createNewMessageEvent(username, message))
+//
+// Messages are checked against the chat settings and the word filter. Clients who ignored the
+// player do not receive the message.
+func HandleSendMessage(c *Client, message string) error {
+	if c.username == "" {
+		return fmt.Errorf("You must join the game before sending messages")
+	}
+	message, err := cleanMessage(c.gameState, message)
+	if err != nil {
+		return err
+	}
+	if err := checkChat(c); err != nil {
+		return err
+	}
+	c.hub.broadcastEvent(createChatEvent(c, message))
 	return nil
 }
 
 // HandleSendSignal handles WebRTC signaling messages
 func HandleSendSignal(c *Client, recipientID string, streamID string, signalData interface{}) error {
-	recipient, ok := c.hub.clients[recipientID]
-	if ok == false {
+	recipient := findClientByPeerID(c.hub.copyClients(), recipientID)
+	if recipient == nil {
 		// Temporarily make this a noop until I can improve the error handling a bit
 		// return fmt.Errorf("Recipient userID (%s) does not exist", recipientID)
 		return nil
 	}
-	recipient.send <- createOnReceiveSignal(c.id, streamID, signalData)
+	c.hub.sendToClient(recipient, createOnReceiveSignal(c.peerID, streamID, signalData))
 	return nil
 }
 
 // HandleMuteVideo unmutes/mutes user
 func HandleMuteVideo(c *Client, muted bool) error {
 	c.muted = muted
-	c.hub.broadcast <- NewBroadcastEvent(createUpdateGameEvent(c, false))
+	broadcastUpdateGameEvent(c)
 	return nil
 }
 
 // HandleTakeSeat takes a seat for the user
+//
+// A user can only have one seat, even if they are connected more than once. If a user
+// disconnects during a hand, they can take back their seat until the hand is over.
 func HandleTakeSeat(c *Client, seatID string) error {
+	if c.username == "" {
+		return fmt.Errorf("You must join the game before taking a seat")
+	}
 	if c.seatID != "" {
 		return fmt.Errorf("You can only sit at one seat")
 	}
+	if err := requestApproval(c); err != nil {
+		return err
+	}
 
 	seat := c.gameState.Table.Seats
 
 	var selectedPlayer *poker.Player
 	for i := 0; i < seat.Len(); i++ {
+		if seat.Player.Status > poker.PlayerVacated && seat.Player.Name == c.username {
+			if seat.Player.IsHuman || seat.Player.ID != seatID {
+				return fmt.Errorf("You already have a seat at the table")
+			}
+			return reclaimSeat(c, seat.Player)
+		}
 		if seat.Player.ID == seatID {
 			// It's possible that two players picked the same seat at the same time
 			if seat.Player.Status > poker.PlayerVacated {
 				return fmt.Errorf("Seat has already been taken")
 			}
 			selectedPlayer = seat.Player
-			break
 		}
 		seat = seat.Next()
 	}
@@ -234,36 +533,101 @@ func HandleTakeSeat(c *Client, seatID string) error {
 		return fmt.Errorf("Invalid seat chosen")
 	}
 
+	if c.gameState.isShuttingDown {
+		return fmt.Errorf("The server is restarting. Please wait to take a seat")
+	}
+
+	if c.gameState.MTT != nil {
+		return fmt.Errorf("Seats are drawn at random in multi-table tournaments")
+	}
+
+	// Seats that open up are held for the players on the waitlist
+	if offer := getSeatOfferBySeat(c.gameState, seatID); offer != nil && offer.Username != c.username {
+		return fmt.Errorf("The seat is being held for %s", offer.Username)
+	}
+
+	// Tournament players pay the buy-in and all start with the same stack
+	buyIn := defaultChips
+	chips := defaultChips
+	t := c.gameState.Tournament
+	if t != nil {
+		if t.HasStarted() {
+			return fmt.Errorf("The tournament has already started")
+		}
+		buyIn = c.gameState.Config.Tournament.BuyIn
+		chips = c.gameState.Config.Tournament.StartingStack
+	}
+
+	if err := c.gameState.Store.BuyIn(c.gameState.Config.Name, c.username, buyIn); err != nil {
+		return err
+	}
+
 	// Link user with player seat
 	selectedPlayer.Name = c.username
-	selectedPlayer.Chips = defaultChips
+	selectedPlayer.Chips = chips
 	selectedPlayer.Status = poker.PlayerSittingOut
 	selectedPlayer.IsHuman = true
-	c.seatID = selectedPlayer.ID
+	c.sit(selectedPlayer.ID)
+	loadPlayerStats(c.gameState, c.username)
+	if t != nil {
+		t.Players = append(t.Players, c.username)
+	}
+	leaveWaitlist(c)
 
-	c.send <- createOnTakeSeatEvent(seatID, createClientSeatMap(c.hub.clients))
+	c.hub.sendToClient(c, createOnTakeSeatEvent(seatID, createPeerSeatMap(createPeers(c.hub.copyClients()), createViewer(c))))
 
 	// Try to start a new game if one hasn't started yet.
 	if c.gameState.Stage == Waiting {
 		StartNewHand(c.gameState)
-		sendHoleCardEvents(c.hub.clients)
+		sendHoleCardEvents(c.hub)
+		broadcastAnnouncements(c)
+	} else {
+		saveSnapshot(c.gameState)
 	}
 
-	c.hub.broadcast <- NewBroadcastEvent(createUpdateGameEvent(c, false))
+	broadcastUpdateGameEvent(c)
+
+	return nil
+}
+
+// reclaimSeat gives a disconnected player control of their seat again
+func reclaimSeat(c *Client, player *poker.Player) error {
+	player.IsHuman = true
+	c.sit(player.ID)
+	loadPlayerStats(c.gameState, player.Name)
+
+	c.hub.sendToClient(c, createOnTakeSeatEvent(player.ID, createPeerSeatMap(createPeers(c.hub.copyClients()), createViewer(c))))
+	c.hub.sendToClient(c, createPlayerHoleCardsEvent(player.ID, player.HoleCards))
+
+	// Tournament tables are paused while every player is disconnected
+	resumeTournamentTable(c)
+	broadcastUpdateGameEvent(c)
 
 	return nil
 }
 
+// resumeTournamentTable deals the next hand at a tournament table that was paused
+func resumeTournamentTable(c *Client) {
+	g := c.gameState
+	if g.Stage == Waiting && g.Tournament != nil && g.Tournament.IsRunning() {
+		StartNewHand(g)
+		sendHoleCardEvents(c.hub)
+		broadcastAnnouncements(c)
+	}
+}
+
 // HandleFold folds
 func HandleFold(c *Client) error {
 	err := c.gameState.CurrentSeat.Player.Fold(c.gameState.BettingRound)
 	if err != nil {
 		return err
 	}
-	c.hub.broadcast <- NewBroadcastEvent(createNewMessageEvent(
+	recordAction(c.gameState, c.gameState.CurrentSeat.Player, history.ActionFold, 0, 0)
+	journalAction(c.gameState, JournalEntry{Action: actionFold, SeatID: c.gameState.CurrentSeat.Player.ID})
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
 		systemUsername,
 		fmt.Sprintf("%s folds.", c.gameState.CurrentSeat.Player.Name),
-	))
+	)))
 	return GoToNextGameState(c)
 }
 
@@ -273,23 +637,29 @@ func HandleCheck(c *Client) error {
 	if err != nil {
 		return err
 	}
-	c.hub.broadcast <- NewBroadcastEvent(createNewMessageEvent(
+	recordAction(c.gameState, c.gameState.CurrentSeat.Player, history.ActionCheck, 0, 0)
+	journalAction(c.gameState, JournalEntry{Action: actionCheck, SeatID: c.gameState.CurrentSeat.Player.ID})
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
 		systemUsername,
 		fmt.Sprintf("%s checks.", c.gameState.CurrentSeat.Player.Name),
-	))
+	)))
 	return GoToNextGameState(c)
 }
 
 // HandleCall calls
 func HandleCall(c *Client) error {
-	err := c.gameState.CurrentSeat.Player.Call(&c.gameState.Table, c.gameState.BettingRound)
+	player := c.gameState.CurrentSeat.Player
+	chips := player.Chips
+	err := player.Call(&c.gameState.Table, c.gameState.BettingRound)
 	if err != nil {
 		return err
 	}
-	c.hub.broadcast <- NewBroadcastEvent(createNewMessageEvent(
+	recordAction(c.gameState, player, history.ActionCall, chips-player.Chips, 0)
+	journalAction(c.gameState, JournalEntry{Action: actionCall, SeatID: player.ID})
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
 		systemUsername,
 		fmt.Sprintf("%s calls.", c.gameState.CurrentSeat.Player.Name),
-	))
+	)))
 	return GoToNextGameState(c)
 }
 
@@ -298,19 +668,25 @@ func HandleRaise(c *Client, raiseAmount int) error {
 	// Determine whether we're betting or raising. A bet can be determined if call amount is 0,
 	// which means no one has bet anything yet.
 	actionLabel := "bets"
+	actionType := history.ActionBet
 	if c.gameState.BettingRound.CallAmount > 0 {
 		actionLabel = "raises to"
+		actionType = history.ActionRaise
 	}
 
-	err := c.gameState.CurrentSeat.Player.Raise(&c.gameState.Table, c.gameState.BettingRound, raiseAmount)
+	player := c.gameState.CurrentSeat.Player
+	chips := player.Chips
+	err := player.Raise(&c.gameState.Table, c.gameState.BettingRound, raiseAmount)
 	if err != nil {
 		return err
 	}
+	recordAction(c.gameState, player, actionType, chips-player.Chips, raiseAmount)
+	journalAction(c.gameState, JournalEntry{Action: actionRaise, SeatID: player.ID, Value: raiseAmount})
 
-	c.hub.broadcast <- NewBroadcastEvent(createNewMessageEvent(
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
 		systemUsername,
 		fmt.Sprintf("%s %s ℝ%d.", c.gameState.CurrentSeat.Player.Name, actionLabel, raiseAmount),
-	))
+	)))
 	return GoToNextGameState(c)
 }
 
@@ -322,14 +698,14 @@ func GoToNextGameState(c *Client) error {
 		if err != nil {
 			return err
 		}
-		if c.gameState.Stage == Waiting {
+		if c.gameState.Stage == Waiting || c.gameState.RunItVote != nil || c.gameState.ShowdownReveal != nil {
 			break
 		}
 		if c.gameState.CurrentSeat.Player.Status == poker.PlayerActive && c.gameState.CurrentSeat.Player.Chips > 0 {
 			break
 		}
 	}
-	c.hub.broadcast <- NewBroadcastEvent(createUpdateGameEvent(c, false))
+	broadcastUpdateGameEvent(c)
 
 	HandleComputerMove(c)
 
@@ -338,7 +714,7 @@ func GoToNextGameState(c *Client) error {
 
 // HandleComputerMove makes as move (check/folder) for a player who has been disconnected
 func HandleComputerMove(c *Client) {
-	if c.gameState.Stage == Waiting || c.gameState.Stage == Showdown {
+	if c.gameState.Stage == Waiting || c.gameState.Stage == Showdown || c.gameState.RunItVote != nil {
 		return
 	}
 	if c.gameState.CurrentSeat.Player.IsHuman {
@@ -380,14 +756,26 @@ func NextGameState(c *Client) error {
 	winnerByFold := poker.DetermineWinnerByFold(g.CurrentSeat)
 
 	if winnerByFold != nil {
-		c.hub.broadcast <- NewBroadcastEvent(createNewMessageEvent(
+		c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
 			systemUsername,
 			fmt.Sprintf("%s won the hand.", winnerByFold.Name),
-		))
+		)))
 		poker.AwardPot(&g.Table, winnerByFold)
+		collectRake(c)
+		settleHand(g)
+		handID := getHandID(g)
+		finishHandHistory(g, createWinningHandsByFold(&g.Table, winnerByFold), nil)
+		uncontestedWin := &ShowableHand{
+			HandID:    handID,
+			HoleCards: winnerByFold.HoleCards,
+			SeatID:    winnerByFold.ID,
+		}
 		StartNewHand(g)
-		sendHoleCardEvents(c.hub.clients)
-		c.hub.broadcast <- NewBroadcastEvent(createNewMessageEvent(systemUsername, "Starting new hand."))
+		g.UncontestedWin = uncontestedWin
+		offerOpenSeats(c)
+		sendHoleCardEvents(c.hub)
+		broadcastAnnouncements(c)
+		c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(systemUsername, "Starting new hand.")))
 		return nil
 	}
 
@@ -396,24 +784,12 @@ func NextGameState(c *Client) error {
 
 		skipToShowdown := poker.SkipToShowdown(g.CurrentSeat)
 		if skipToShowdown {
-			if g.Stage < Turn {
-				poker.DealFlop(&g.Deck, &g.Table)
-				g.Stage = Flop
-				c.hub.broadcast <- NewBroadcastEvent(createUpdateGameEvent(c, true))
-				time.Sleep(3 * time.Second)
-			}
-			if g.Stage < River {
-				poker.DealTurn(&g.Deck, &g.Table)
-				g.Stage = Turn
-				c.hub.broadcast <- NewBroadcastEvent(createUpdateGameEvent(c, true))
-				time.Sleep(2 * time.Second)
-			}
-			if g.Stage < Showdown {
-				poker.DealRiver(&g.Deck, &g.Table)
-				g.Stage = River
-				c.hub.broadcast <- NewBroadcastEvent(createUpdateGameEvent(c, true))
+			// Let the players decide how many times to run it if there are cards left to deal
+			if g.Stage < Showdown && g.Config.RunItMaxTimes > 1 {
+				startRunItVote(c)
+				return nil
 			}
-			g.Stage = Showdown
+			runOutBoard(c)
 		}
 
 		if g.Stage == Flop {
@@ -426,7 +802,7 @@ func NextGameState(c *Client) error {
 			if err != nil {
 				return err
 			}
-			c.hub.broadcast <- NewBroadcastEvent(createNewMessageEvent(systemUsername, "Dealing flop."))
+			c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(systemUsername, "Dealing flop.")))
 		} else if g.Stage == Turn {
 			poker.DealTurn(&g.Deck, &g.Table)
 			g.CurrentSeat, err = poker.GetNextActiveSeat(g.Table.Dealer)
@@ -437,7 +813,7 @@ func NextGameState(c *Client) error {
 			if err != nil {
 				return err
 			}
-			c.hub.broadcast <- NewBroadcastEvent(createNewMessageEvent(systemUsername, "Dealing turn."))
+			c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(systemUsername, "Dealing turn.")))
 		} else if g.Stage == River {
 			poker.DealRiver(&g.Deck, &g.Table)
 			g.CurrentSeat, err = poker.GetNextActiveSeat(g.Table.Dealer)
@@ -448,15 +824,9 @@ func NextGameState(c *Client) error {
 			if err != nil {
 				return err
 			}
-			c.hub.broadcast <- NewBroadcastEvent(createNewMessageEvent(systemUsername, "Dealing river."))
+			c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(systemUsername, "Dealing river.")))
 		} else if g.Stage == Showdown {
-			c.hub.broadcast <- NewBroadcastEvent(createUpdateGameEvent(c, true))
-			time.Sleep(2 * time.Second)
-			DetermineWinners(c)
-			time.Sleep(1 * time.Second)
-			StartNewHand(g)
-			sendHoleCardEvents(c.hub.clients)
-			c.hub.broadcast <- NewBroadcastEvent(createNewMessageEvent(systemUsername, "Starting new hand."))
+			showdown(c)
 		} else {
 			return fmt.Errorf("Invalid game stage encountered: %s", g.Stage.String())
 		}
@@ -465,11 +835,72 @@ func NextGameState(c *Client) error {
 	return nil
 }
 
+// runOutBoard deals the remaining community cards when no more betting is possible
+func runOutBoard(c *Client) {
+	g := c.gameState
+	showAllInHands(g)
+	if g.Stage < Turn {
+		poker.DealFlop(&g.Deck, &g.Table)
+		g.Stage = Flop
+		broadcastUpdateGameEvent(c)
+		pause(g, 3*time.Second)
+	}
+	if g.Stage < River {
+		poker.DealTurn(&g.Deck, &g.Table)
+		g.Stage = Turn
+		broadcastUpdateGameEvent(c)
+		pause(g, 2*time.Second)
+	}
+	if g.Stage < Showdown {
+		poker.DealRiver(&g.Deck, &g.Table)
+		g.Stage = River
+		broadcastUpdateGameEvent(c)
+	}
+	g.Stage = Showdown
+}
+
+// pause gives the players time to see what happened before the hand moves on. There is no
+// pause while the journal is replayed.
+//
+// The table stays locked, but a multi-table tournament is unlocked so that the other tables
+// can play on in the meantime.
+func pause(g *GameState, d time.Duration) {
+	if g.isReplaying {
+		return
+	}
+	if g.MTT != nil {
+		g.MTT.mu.Unlock()
+		defer g.MTT.mu.Lock()
+	}
+	time.Sleep(d)
+}
+
 // DetermineWinners determines who won the hand and awards chips to the winner
 func DetermineWinners(c *Client) {
 	g := c.gameState
 
-	allWinningHands := poker.DetermineWinners(&g.Table)
+	if len(g.Boards) > 1 {
+		allWinningHandsByBoard := poker.DetermineWinnersByBoard(&g.Table, g.Boards)
+		for i, allWinningHands := range allWinningHandsByBoard {
+			announceWinners(c, allWinningHands, fmt.Sprintf("Run %d: ", i+1))
+		}
+		collectRake(c)
+		settleHand(g)
+		winningHands := mergeWinningHandsByBoard(allWinningHandsByBoard)
+		awardBounties(c, winningHands)
+		finishHandHistory(g, winningHands, allWinningHandsByBoard)
+	} else {
+		allWinningHands := poker.DetermineWinners(&g.Table)
+		announceWinners(c, allWinningHands, "")
+		collectRake(c)
+		settleHand(g)
+		awardBounties(c, allWinningHands)
+		finishHandHistory(g, allWinningHands, nil)
+	}
+}
+
+// announceWinners posts the winners of each pot to the chat
+func announceWinners(c *Client, allWinningHands [][]poker.PlayerHand, prefix string) {
 	for i, winningHandsByPot := range allWinningHands {
 		potText := "main pot"
 		if i > 0 {
@@ -480,22 +911,49 @@ func DetermineWinners(c *Client) {
 			}
 		}
 		for _, ph := range winningHandsByPot {
-			c.hub.broadcast <- NewBroadcastEvent(createNewMessageEvent(
+			c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
 				systemUsername,
 				fmt.Sprintf(
-					"%s wins ℝ%d %s with %s.",
+					"%s%s wins ℝ%d %s with %s.",
+					prefix,
 					ph.Player.Name,
 					ph.ChipsWon,
 					potText,
 					strings.ToLower(ph.Hand.Rank.String()),
 				),
-			))
+			)))
 		}
 	}
 }
 
+// collectRake announces the rake taken from the pot. It is paid to the house when the hand is
+// settled.
+func collectRake(c *Client) {
+	rake := c.gameState.Table.Pot.Rake
+	if rake == 0 {
+		return
+	}
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
+		systemUsername,
+		fmt.Sprintf("Rake: ℝ%d.", rake),
+	)))
+}
+
+// announce queues a message to post to the chat once the next hand has been dealt
+func (g *GameState) announce(message string) {
+	g.announcements = append(g.announcements, message)
+}
+
+// broadcastAnnouncements posts the queued announcements to the chat
+func broadcastAnnouncements(c *Client) {
+	for _, message := range c.gameState.announcements {
+		c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(systemUsername, message)))
+	}
+	c.gameState.announcements = nil
+}
+
 // NewGameState creates a new game state
-func NewGameState() *GameState {
+func NewGameState(config TableConfig, store storage.Store) *GameState {
 	// Initialize vacated seats
 	playerMap := make(map[string]*poker.Player)
 	seats := poker.NewSeat(numPlayers)
@@ -509,43 +967,103 @@ func NewGameState() *GameState {
 		seats = seats.Next()
 	}
 
+	var tournament *Tournament
+	if config.Tournament != nil {
+		tournament = NewTournament()
+	}
+
+	var private *PrivateTable
+	if config.Host != "" {
+		private = NewPrivateTable(config.Host)
+	}
+
 	return &GameState{
-		BettingRound: nil,
-		CurrentSeat:  seats,
-		Deck:         poker.NewDeck(),
-		PlayerMap:    playerMap,
-		Stage:        Waiting,
+		BettingRound:  nil,
+		Chat:          NewChat(config.ChatMode),
+		Config:        config,
+		CurrentSeat:   seats,
+		Deck:          poker.NewDeck(),
+		HandHistories: store.HandHistories(),
+		PlayerMap:     playerMap,
+		Private:       private,
+		SeatChanges:   make(map[string]string),
+		SeatOffers:    make([]*SeatOffer, 0),
+		Stage:         Waiting,
+		Stats:         stats.NewTracker(),
+		Store:         store,
 		Table: poker.Table{
 			MinBet: defaultMinBet,
 			Pot:    poker.NewPot(),
+			Rake:   config.Rake,
 			Seats:  seats,
 		},
+		Tournament: tournament,
+		Waitlist:   make([]string, 0),
+		chatTimes:  make(map[string][]time.Time),
 	}
 }
 
 // StartNewHand starts a new hand
 func StartNewHand(g *GameState) {
-	deck := poker.NewDeck()
+	// Keep track of the seed so the hand can be replayed later
+	seed := newHandSeed()
+	deck := poker.NewDeckFromSeed(seed)
 	seats := g.Table.Seats
 
+	g.History = nil
+	g.MuckedHands = nil
+	g.ShowdownReveal = nil
+	g.UncontestedWin = nil
+
+	// Players who asked to change seats move before the next hand is dealt
+	changeSeats(g)
+
 	// Reset player hands
 	for i := 0; i < seats.Len(); i++ {
 		seats.Player.HoleCards = [2]*poker.Card{}
 		seats.Player.HasFolded = false
+		seats.Player.ShownCards = [2]bool{}
 		seats = seats.Next()
 	}
 
+	// Tournament players keep their seats until they are knocked out, even if they disconnect
+	canDeal := true
+	if g.isShuttingDown == false {
+		canDeal = updateTournament(g)
+	}
+	isTournamentRunning := g.Tournament != nil && g.Tournament.IsRunning()
+
+	// Tournament tables wait for a connected player so that disconnected players are not
+	// dealt hands against each other nonstop
+	if isTournamentRunning && hasConnectedPlayer(g) == false {
+		canDeal = false
+	}
+
+	// The host of a private table can stop new hands from being dealt
+	if g.Private != nil && g.Private.IsPaused {
+		canDeal = false
+	}
+
+	// Tournament players can play short stacked since they are all in once their chips run out
+	minChips := defaultMinBet
+	if isTournamentRunning {
+		minChips = 1
+	}
+
 	// Get active players for the next game
 	for i := 0; i < seats.Len(); i++ {
 		// If a player is computer controlled, then vacate the seat
-		if seats.Player.IsHuman == false {
+		if seats.Player.IsHuman == false && isTournamentRunning == false {
+			if seats.Player.Status > poker.PlayerVacated {
+				cashOut(g, seats.Player)
+			}
 			seats.Player.Name = ""
 			seats.Player.Chips = 0
 			seats.Player.Status = poker.PlayerVacated
 		}
 
 		if seats.Player.Status > poker.PlayerVacated {
-			if seats.Player.Chips < defaultMinBet {
+			if seats.Player.Chips < minChips {
 				seats.Player.Status = poker.PlayerSittingOut
 			} else {
 				seats.Player.Status = poker.PlayerActive
@@ -556,7 +1074,7 @@ func StartNewHand(g *GameState) {
 
 	activePlayerCount := poker.CountSeatsByPlayerStatus(seats, poker.PlayerActive)
 
-	if activePlayerCount < minPlayers {
+	if activePlayerCount < minPlayers || g.isShuttingDown || canDeal == false {
 		// Change active player status to sitting out if we don't have enough players
 		for i := 0; i < seats.Len(); i++ {
 			if seats.Player.Status == poker.PlayerActive {
@@ -564,12 +1082,16 @@ func StartNewHand(g *GameState) {
 			}
 			seats = seats.Next()
 		}
+		endSession(g)
+		g.Boards = nil
 		g.Stage = Waiting
 		g.Table = poker.Table{
-			MinBet: defaultMinBet,
+			MinBet: getBlindLevel(g).BigBlind,
 			Pot:    poker.NewPot(),
+			Rake:   g.Config.Rake,
 			Seats:  seats,
 		}
+		saveSnapshot(g)
 		return
 	}
 
@@ -597,16 +1119,20 @@ func StartNewHand(g *GameState) {
 		panic(err)
 	}
 
+	level := getBlindLevel(g)
 	table := poker.Table{
+		Ante:       level.Ante,
 		BigBlind:   bigBlind,
 		Dealer:     dealer,
-		MinBet:     defaultMinBet,
+		MinBet:     level.BigBlind,
 		Pot:        poker.NewPot(),
+		Rake:       g.Config.Rake,
 		Seats:      seats,
 		SmallBlind: smallBlind,
 	}
 
 	poker.DealHands(&deck, &table)
+	startHandHistory(g, seed, &table)
 
 	currentSeat, err := poker.GetNextActiveSeat(bigBlind)
 	if err != nil {
@@ -618,14 +1144,29 @@ func StartNewHand(g *GameState) {
 		panic(err)
 	}
 
+	antes := poker.TakeAntes(&table)
 	poker.TakeSmallBlind(&table, preflopRound)
 	poker.TakeBigBlind(&table, preflopRound)
 
+	// Skip players who went all in posting the ante or blinds. They are still the raiser, so
+	// the street ends when the action gets back to them.
+	for i := 0; i < seats.Len() && currentSeat.Player.Chips == 0; i++ {
+		currentSeat, err = poker.GetNextActiveSeat(currentSeat)
+		if err != nil {
+			panic(err)
+		}
+	}
+
 	g.BettingRound = preflopRound
+	g.Boards = nil
 	g.CurrentSeat = currentSeat
 	g.Deck = deck
 	g.Stage = Preflop
 	g.Table = table
+
+	recordBlinds(g, antes)
+
+	saveSnapshot(g)
 }
 
 // GetActions gets the actions available to active player
@@ -650,10 +1191,11 @@ func GetActions(g *GameState) []string {
 	return actions
 }
 
-func sendHoleCardEvents(clients map[string]*Client) {
-	for _, c := range clients {
+// sendHoleCardEvents sends the seated clients of the hub their hole cards
+func sendHoleCardEvents(hub *Hub) {
+	for _, c := range hub.copyClients() {
 		if p, ok := c.gameState.PlayerMap[c.seatID]; ok {
-			c.send <- createPlayerHoleCardsEvent(c.seatID, p.HoleCards)
+			hub.sendToClient(c, createPlayerHoleCardsEvent(c.seatID, p.HoleCards))
 		}
 	}
 }
@@ -689,117 +1231,6 @@ func createOnReceiveSignal(peerID string, streamID string, signalData interface{
 	}
 }
 
-func createUpdateGameEvent(c *Client, showCards bool) Event {
-	var actionBar map[string]interface{}
-
-	g := c.gameState
-
-	players := make([]map[string]interface{}, 0)
-	seats := g.Table.Seats
-
-	mutedSeatMap := createMutedSeatMap(c.hub.clients)
-
-	if g.Stage == Waiting {
-		// Players data
-		for i := 0; i < seats.Len(); i++ {
-			players = append(players, map[string]interface{}{
-				"chips":      seats.Player.Chips,
-				"chipsInPot": nil,
-				"hasFolded":  seats.Player.HasFolded,
-				"holeCards":  [2]*poker.Card{},
-				"id":         seats.Player.ID,
-				"isActive":   false,
-				"isDealer":   false,
-				"muted":      mutedSeatMap[seats.Player.ID],
-				"name":       seats.Player.Name,
-				"status":     seats.Player.Status.String(),
-			})
-			seats = seats.Next()
-		}
-
-		// Actions data
-		actionBar = map[string]interface{}{
-			"actions":        []string{},
-			"callAmount":     0,
-			"chipsInPot":     0,
-			"maxRaiseAmount": 0,
-			"minBetAmount":   0,
-			"minRaiseAmount": 0,
-			"totalChips":     0,
-		}
-	} else {
-		// Players data
-		activePlayer := g.CurrentSeat.Player
-		for i := 0; i < seats.Len(); i++ {
-			holeCards := [2]*poker.Card{}
-			if showCards && seats.Player.HasFolded == false {
-				holeCards = seats.Player.HoleCards
-			}
-			players = append(players, map[string]interface{}{
-				"chips":      seats.Player.Chips,
-				"chipsInPot": g.BettingRound.Bets[seats.Player.ID],
-				"hasFolded":  seats.Player.HasFolded,
-				"holeCards":  holeCards,
-				"id":         seats.Player.ID,
-				"isActive":   seats.Player.ID == activePlayer.ID,
-				"isDealer":   seats.Player.ID == g.Table.Dealer.Player.ID,
-				"muted":      mutedSeatMap[seats.Player.ID],
-				"name":       seats.Player.Name,
-				"status":     seats.Player.Status.String(),
-			})
-			seats = seats.Next()
-		}
-
-		// Actions data
-
-		// If the player does not have enough chips to meet the call amount, then set the max raise
-		// to the player's remaining chips/
-		callRemainingAmount := g.BettingRound.CallAmount - g.BettingRound.Bets[activePlayer.ID]
-		maxRaiseAmount := activePlayer.Chips - callRemainingAmount
-		if maxRaiseAmount < 0 {
-			maxRaiseAmount = activePlayer.Chips
-		}
-
-		// If the min raise amount is less than the max raise amount, then use the max raise
-		// as the min raise. Essentially this means that the play must go all in if they were
-		// to raise.
-		minRaiseAmount := g.BettingRound.RaiseByAmount
-		if minRaiseAmount > maxRaiseAmount {
-			minRaiseAmount = maxRaiseAmount
-		}
-
-		actionBar = map[string]interface{}{
-			"actions":        GetActions(g),
-			"callAmount":     g.BettingRound.CallAmount,
-			"chipsInPot":     g.BettingRound.Bets[activePlayer.ID],
-			"maxRaiseAmount": maxRaiseAmount,
-			"minBetAmount":   g.Table.MinBet,
-			"minRaiseAmount": minRaiseAmount,
-			"seatID":         activePlayer.ID,
-			"totalChips":     activePlayer.Chips,
-		}
-	}
-
-	// Table data
-	table := map[string]interface{}{
-		"flop":  g.Table.Flop,
-		"pot":   g.Table.Pot.GetTotal(),
-		"river": g.Table.River,
-		"turn":  g.Table.Turn,
-	}
-
-	return Event{
-		Action: actionUpdateGame,
-		Params: map[string]interface{}{
-			"actionBar":     actionBar,
-			"clientSeatMap": createClientSeatMap(c.hub.clients),
-			"players":       players,
-			"stage":         g.Stage.String(),
-			"table":         table,
-		},
-	}
-}
-
 func createNewMessageEvent(username string, message string) Event {
 	return Event{
 		Action: actionNewMessage,
@@ -829,21 +1260,3 @@ func createPlayerHoleCardsEvent(seatID string, c [2]*poker.Card) Event {
 		},
 	}
 }
-
-func createClientSeatMap(clients map[string]*Client) map[string]string {
-	clientSeatMap := make(map[string]string)
-	for _, c := range clients {
-		clientSeatMap[c.id] = c.seatID
-	}
-	return clientSeatMap
-}
-
-func createMutedSeatMap(clients map[string]*Client) map[string]bool {
-	mutedSeatMap := make(map[string]bool)
-	for _, c := range clients {
-		if c.seatID != "" {
-			mutedSeatMap[c.seatID] = c.muted
-		}
-	}
-	return mutedSeatMap
-}
@@ -0,0 +1,48 @@
+package server_test
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/server"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+var _ = Describe("Graph API", func() {
+	var accounts *auth.Accounts
+	var store *storage.MemoryStore
+
+	BeforeEach(func() {
+		store = newAPIStore()
+		accounts = newAPIAccounts()
+	})
+
+	serveGraph := func(w http.ResponseWriter, r *http.Request) {
+		server.ServeGraph(store, accounts, w, r)
+	}
+
+	It("sends a player's results graph", func() {
+		w, body := getJSON("/api/graph", login(accounts, "bob"), serveGraph)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		points := body["points"].([]interface{})
+		Expect(points).To(HaveLen(1))
+		Expect(points[0].(map[string]interface{})["winnings"]).To(BeEquivalentTo(22))
+		Expect(points[0].(map[string]interface{})["stack"]).To(BeEquivalentTo(122))
+
+		w, _ = getJSON("/api/graph", "", serveGraph)
+		Expect(w.Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("only sends other players' graphs to admins", func() {
+		w, body := getJSON("/api/graph?player=bob", login(accounts, "alice"), serveGraph)
+		Expect(w.Code).To(Equal(http.StatusForbidden))
+		Expect(body["error"]).To(Equal("You can only look up your own results"))
+
+		w, body = getJSON("/api/graph?player=bob", login(accounts, "pitboss"), serveGraph)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(body["points"]).To(HaveLen(1))
+	})
+})
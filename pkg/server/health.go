@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+// Health tells the deploy whether the server is alive and whether it can take traffic.
+//
+// The server is only ready once the tables have been restored, and stops being ready as soon as
+// it starts shutting down, so that new players are sent to another instance.
+type Health struct {
+	isReady int32 // Read and written atomically
+	store   storage.Store
+}
+
+// NewHealth creates a health check that is not ready yet.
+func NewHealth(store storage.Store) *Health {
+	return &Health{store: store}
+}
+
+// SetReady sets whether the server can take traffic.
+func (h *Health) SetReady(isReady bool) {
+	value := int32(0)
+	if isReady {
+		value = 1
+	}
+	atomic.StoreInt32(&h.isReady, value)
+}
+
+// IsReady checks if the server can take traffic.
+func (h *Health) IsReady() bool {
+	return atomic.LoadInt32(&h.isReady) == 1
+}
+
+// ServeHealth responds as long as the server is running.
+func ServeHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// ServeReady responds with an error if the server is not ready or the store can't be read.
+func ServeReady(health *Health, w http.ResponseWriter, r *http.Request) {
+	if health.IsReady() == false {
+		writeJSONError(w, http.StatusServiceUnavailable, "Not ready")
+		return
+	}
+	if _, err := health.store.GetBankroll(storage.HouseAccount); err != nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "Store is unavailable")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
@@ -0,0 +1,306 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/richard-to/go-poker/pkg/history"
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+// startHandHistory starts recording a hand that has just been dealt.
+//
+// This needs to be called before the blinds are taken so that the starting stacks are correct.
+// Tables deal at the same time, so hands are given a random ID instead of one based on the time.
+func startHandHistory(g *GameState, seed int64, t *poker.Table) {
+	g.History = history.NewHandHistory(uuid.New().String(), seed, g.Config.Name, t)
+}
+
+// newHandSeed creates the seed that the deck is shuffled with. The seed comes from a secure
+// source so that players can't work out the next deck from the hands they have seen.
+func newHandSeed() int64 {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return int64(binary.BigEndian.Uint64(b[:]) >> 1)
+}
+
+// recordBlinds records the antes and blinds that were posted at the start of the hand
+//
+// Short stacked players may have posted less than the full amount.
+func recordBlinds(g *GameState, antes map[*poker.Player]int) {
+	if g.History == nil {
+		return
+	}
+	seat := g.Table.Dealer.Next()
+	for i := 0; i < seat.Len(); i++ {
+		if ante, ok := antes[seat.Player]; ok && ante > 0 {
+			// Players who went all in posting a blind were not all in after the ante
+			g.History.AddAction(history.Action{
+				Street:   getStreet(g.Stage),
+				PlayerID: seat.Player.ID,
+				Type:     history.ActionPostAnte,
+				Amount:   ante,
+				IsAllIn:  seat.Player.Chips == 0 && g.BettingRound.Bets[seat.Player.ID] == 0,
+			})
+		}
+		seat = seat.Next()
+	}
+	smallBlind := g.Table.SmallBlind.Player
+	if amount := g.BettingRound.Bets[smallBlind.ID]; amount > 0 {
+		recordAction(g, smallBlind, history.ActionPostSmallBlind, amount, 0)
+	}
+	bigBlind := g.Table.BigBlind.Player
+	if amount := g.BettingRound.Bets[bigBlind.ID]; amount > 0 {
+		recordAction(g, bigBlind, history.ActionPostBigBlind, amount, 0)
+	}
+}
+
+// recordAction records an action taken by a player during the current street
+func recordAction(g *GameState, p *poker.Player, actionType history.ActionType, amount int, raiseTo int) {
+	if g.History == nil {
+		return
+	}
+	g.History.AddAction(history.Action{
+		Street:   getStreet(g.Stage),
+		PlayerID: p.ID,
+		Type:     actionType,
+		Amount:   amount,
+		RaiseTo:  raiseTo,
+		IsAllIn:  amount > 0 && p.Chips == 0,
+	})
+}
+
+// recordShowdown records which players showed and mucked their hands
+func recordShowdown(g *GameState, order []*poker.Player, mucked []*poker.Player) {
+	if g.History == nil {
+		return
+	}
+	for _, p := range order {
+		if isPlayerMucked(mucked, p) {
+			g.History.AddAction(history.Action{
+				Street:   history.StreetShowdown,
+				PlayerID: p.ID,
+				Type:     history.ActionMuck,
+			})
+		} else {
+			g.History.AddAction(history.Action{
+				Street:   history.StreetShowdown,
+				PlayerID: p.ID,
+				Type:     history.ActionShow,
+				Cards:    p.HoleCards[:],
+			})
+		}
+	}
+}
+
+// recordShownCards records cards that a player showed after the hand was over.
+//
+// The hand history has already been saved, so the cards are added to the saved hand. Cards
+// that were already recorded are skipped, since the action is replayed from the journal
+// after a restart.
+func recordShownCards(g *GameState, handID string, seatID string, cards []*poker.Card) {
+	if handID == "" || g.HandHistories == nil {
+		return
+	}
+	h, err := g.HandHistories.Get(handID)
+	if err != nil {
+		log.Printf("could not record shown cards: %v", err)
+		return
+	}
+	recorded := h.GetShownCards(seatID)
+	newCards := make([]*poker.Card, 0)
+	for _, card := range cards {
+		if containsCard(recorded, card) == false {
+			newCards = append(newCards, card)
+		}
+	}
+	if len(newCards) == 0 {
+		return
+	}
+	err = g.HandHistories.AddAction(handID, history.Action{
+		Street:   history.StreetShowdown,
+		PlayerID: seatID,
+		Type:     history.ActionShow,
+		Cards:    newCards,
+	})
+	if err != nil {
+		log.Printf("could not record shown cards: %v", err)
+	}
+}
+
+// getHandID gets the ID of the hand history being recorded. The ID is empty if hands are
+// not being recorded.
+func getHandID(g *GameState) string {
+	if g.History == nil {
+		return ""
+	}
+	return g.History.ID
+}
+
+func containsCard(cards []*poker.Card, card *poker.Card) bool {
+	for _, c := range cards {
+		if c.Rank == card.Rank && c.Suit == card.Suit {
+			return true
+		}
+	}
+	return false
+}
+
+// finishHandHistory records the pots that were awarded and saves the hand history.
+//
+// The winning hands must be in the same order as the side pots of the table's pot. The winning
+// hands on each board are only needed when the board was run more than once.
+func finishHandHistory(g *GameState, winningHandsByPot [][]poker.PlayerHand, winningHandsByBoard [][][]poker.PlayerHand) {
+	h := g.History
+	if h == nil {
+		return
+	}
+	g.History = nil
+
+	t := &g.Table
+	h.Board = t.GetBoard()
+	if len(g.Boards) > 1 {
+		h.Boards = g.Boards
+	}
+	h.Rake = t.Pot.Rake
+	h.EndedAt = time.Now().UTC()
+
+	// The uncalled bet is part of the last side pot, but it is not really won
+	uncalledBet := t.Pot.GetUncalledBet()
+	var uncalledBettor *poker.Player
+	if uncalledBet > 0 {
+		uncalledBettor = getLargestBettor(t.Pot)
+		h.UncalledBet = &history.UncalledBet{
+			PlayerID: uncalledBettor.ID,
+			Amount:   uncalledBet,
+		}
+	}
+
+	for i, sidePot := range t.Pot.SidePots {
+		pot := history.Pot{
+			Number:  len(h.Pots),
+			Amount:  sidePot.Total + sidePot.Rake,
+			Rake:    sidePot.Rake,
+			Winners: make([]history.Winner, 0),
+		}
+		isLastPot := i == len(t.Pot.SidePots)-1
+		if isLastPot && uncalledBettor != nil {
+			pot.Amount -= uncalledBet
+		}
+		for _, ph := range winningHandsByPot[i] {
+			winner := history.Winner{
+				PlayerID: ph.Player.ID,
+				Amount:   ph.ChipsWon,
+			}
+			if len(winningHandsByBoard) > 0 {
+				winner.HandRanks = getHandRanksByBoard(winningHandsByBoard, i, ph.Player)
+			} else if ph.Hand != nil {
+				winner.HandRank = ph.Hand.Rank.String()
+			}
+			if isLastPot && ph.Player == uncalledBettor {
+				winner.Amount -= uncalledBet
+			}
+			if winner.Amount > 0 {
+				pot.Winners = append(pot.Winners, winner)
+			}
+		}
+		if pot.Amount > 0 {
+			h.Pots = append(h.Pots, pot)
+		}
+	}
+
+	total := 0
+	for _, pot := range h.Pots {
+		total += pot.Amount
+	}
+	recordPot(g, total)
+
+	if g.HandHistories != nil {
+		g.HandHistories.Save(h)
+	}
+	if g.Stats != nil {
+		g.Stats.Add(h)
+	}
+	recordSessionHand(g, h)
+}
+
+// mergeWinningHandsByBoard combines the winnings from each board into one list of winners per pot
+func mergeWinningHandsByBoard(allWinningHandsByBoard [][][]poker.PlayerHand) [][]poker.PlayerHand {
+	if len(allWinningHandsByBoard) == 0 {
+		return nil
+	}
+	merged := make([][]poker.PlayerHand, len(allWinningHandsByBoard[0]))
+	for _, allWinningHands := range allWinningHandsByBoard {
+		for i, winningHands := range allWinningHands {
+			for _, ph := range winningHands {
+				found := false
+				for j := range merged[i] {
+					if merged[i][j].Player == ph.Player {
+						merged[i][j].ChipsWon += ph.ChipsWon
+						found = true
+						break
+					}
+				}
+				if found == false {
+					merged[i] = append(merged[i], ph)
+				}
+			}
+		}
+	}
+	return merged
+}
+
+// getHandRanksByBoard gets the hand that a player won a pot with on each board. The rank is
+// empty for boards where the player did not win the pot.
+func getHandRanksByBoard(winningHandsByBoard [][][]poker.PlayerHand, pot int, p *poker.Player) []string {
+	ranks := make([]string, len(winningHandsByBoard))
+	for i, allWinningHands := range winningHandsByBoard {
+		if pot >= len(allWinningHands) {
+			continue
+		}
+		for _, ph := range allWinningHands[pot] {
+			if ph.Player == p && ph.Hand != nil {
+				ranks[i] = ph.Hand.Rank.String()
+			}
+		}
+	}
+	return ranks
+}
+
+// createWinningHandsByFold creates the winners of each pot when everyone else folded
+func createWinningHandsByFold(t *poker.Table, winner *poker.Player) [][]poker.PlayerHand {
+	winningHandsByPot := make([][]poker.PlayerHand, len(t.Pot.SidePots))
+	for i, sidePot := range t.Pot.SidePots {
+		winningHandsByPot[i] = []poker.PlayerHand{{Player: winner, ChipsWon: sidePot.Total}}
+	}
+	return winningHandsByPot
+}
+
+func getLargestBettor(pot *poker.Pot) *poker.Player {
+	var largestBettor *poker.Player
+	for p, betAmount := range pot.Bets {
+		if largestBettor == nil || betAmount > pot.Bets[largestBettor] {
+			largestBettor = p
+		}
+	}
+	return largestBettor
+}
+
+func getStreet(stage GameStage) history.Street {
+	switch stage {
+	case Flop:
+		return history.StreetFlop
+	case Turn:
+		return history.StreetTurn
+	case River:
+		return history.StreetRiver
+	case Showdown:
+		return history.StreetShowdown
+	}
+	return history.StreetPreflop
+}
@@ -1,5 +1,12 @@
 package server
 
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
 // Hub maintains the set of active clients and broadcasts messages to the
 // clients.
 type Hub struct {
@@ -9,21 +16,52 @@ type Hub struct {
 	// Inbound messages from the clients.
 	broadcast chan BroadcastEvent
 
-	// Send message to a specific client.
-	send chan Event
+	// Send message to a specific client. Sent through the hub, which closes the client's
+	// channels once the client leaves.
+	send chan clientEvent
 
 	// Register requests from the clients.
 	register chan *Client
 
 	// Unregister requests from clients.
 	unregister chan *Client
+
+	// Remove clients without closing their connection so that they can move to another hub.
+	release chan *Client
+
+	// Requests for a copy of the registered clients.
+	copies chan chan map[string]*Client
+
+	// Requests for the number of players watching the table without a seat.
+	spectators chan chan int
+
+	// Close all connections and stop the hub. The channel is closed once done.
+	stop chan chan struct{}
+
+	// Closed once the hub has stopped, so that clients don't wait on a hub that has stopped.
+	stopped chan struct{}
+
+	// Open websocket connections. Used to wait for connections to close on shutdown.
+	connections sync.WaitGroup
+}
+
+// clientEvent is an event for one client
+type clientEvent struct {
+	client *Client
+	event  Event
 }
 
 // NewHub creates a new hub.
 func NewHub() *Hub {
 	return &Hub{
 		broadcast:  make(chan BroadcastEvent),
+		copies:     make(chan chan map[string]*Client),
 		register:   make(chan *Client),
+		release:    make(chan *Client),
+		send:       make(chan clientEvent),
+		spectators: make(chan chan int),
+		stop:       make(chan chan struct{}),
+		stopped:    make(chan struct{}),
 		unregister: make(chan *Client),
 		clients:    make(map[string]*Client),
 	}
@@ -35,23 +73,164 @@ func (h *Hub) Run() {
 		select {
 		case client := <-h.register:
 			h.clients[client.id] = client
+		case done := <-h.stop:
+			for id, client := range h.clients {
+				delete(h.clients, id)
+				close(client.delayed)
+			}
+			close(h.stopped)
+			close(done)
+			return
+		case client := <-h.release:
+			delete(h.clients, client.id)
+		case reply := <-h.copies:
+			clients := make(map[string]*Client, len(h.clients))
+			for id, client := range h.clients {
+				clients[id] = client
+			}
+			reply <- clients
+		case reply := <-h.spectators:
+			count := 0
+			for _, client := range h.clients {
+				if client.isSpectator() {
+					count++
+				}
+			}
+			reply <- count
+		case e := <-h.send:
+			// Events are dropped if the client left the hub after they were sent
+			if h.clients[e.client.id] != e.client {
+				continue
+			}
+			select {
+			case e.client.send <- e.event:
+			default:
+				delete(h.clients, e.client.id)
+				close(e.client.delayed)
+			}
 		case client := <-h.unregister:
 			if _, ok := h.clients[client.id]; ok {
 				delete(h.clients, client.id)
-				close(client.send)
+				close(client.delayed)
 			}
 		case e := <-h.broadcast:
 			for id, client := range h.clients {
 				if _, ok := e.ExcludeClients[id]; ok {
 					continue
 				}
+				event := e.Event
+				if e.ClientEvents != nil {
+					clientEvent, ok := e.ClientEvents[id]
+					if ok == false {
+						continue
+					}
+					event = clientEvent
+				}
+				// Projected events are dropped if the client sat down after they were projected
+				epoch, ok := e.epochs[id]
+				if ok == false {
+					epoch = atomic.LoadUint64(&client.delayEpoch)
+				}
 				select {
-				case client.send <- e.Event:
+				case client.delayed <- delayedEvent{
+					epoch:  epoch,
+					event:  event,
+					sendAt: time.Now().Add(client.getBroadcastDelay()),
+				}:
 				default:
 					delete(h.clients, id)
-					close(client.send)
+					close(client.delayed)
 				}
 			}
 		}
 	}
 }
+
+// sendToClient sends an event to a client registered with the hub. Fails if the hub has
+// stopped, since the client's connection is being closed.
+func (h *Hub) sendToClient(c *Client, e Event) error {
+	select {
+	case h.send <- clientEvent{client: c, event: e}:
+		return nil
+	case <-h.stopped:
+		return fmt.Errorf("The table is closed")
+	}
+}
+
+// registerClient adds a client to the hub. Clients can't join a hub that has stopped.
+func (h *Hub) registerClient(c *Client) error {
+	select {
+	case h.register <- c:
+		return nil
+	case <-h.stopped:
+		return fmt.Errorf("The table is closed")
+	}
+}
+
+// releaseClient removes a client from the hub without closing their connection. Fails if the
+// hub has stopped, since the client's connection is being closed.
+func (h *Hub) releaseClient(c *Client) error {
+	select {
+	case h.release <- c:
+		return nil
+	case <-h.stopped:
+		return fmt.Errorf("The table is closed")
+	}
+}
+
+// broadcastEvent sends an event to the clients of the hub. Fails if the hub has stopped, since
+// there is no one left to send it to.
+func (h *Hub) broadcastEvent(e BroadcastEvent) error {
+	select {
+	case h.broadcast <- e:
+		return nil
+	case <-h.stopped:
+		return fmt.Errorf("The table is closed")
+	}
+}
+
+// copyClients copies the registered clients, so that they can be read outside of the hub. No
+// clients are left once the hub has stopped.
+func (h *Hub) copyClients() map[string]*Client {
+	reply := make(chan map[string]*Client, 1)
+	select {
+	case h.copies <- reply:
+		return <-reply
+	case <-h.stopped:
+		return make(map[string]*Client)
+	}
+}
+
+// CountSpectators counts the players who joined the table without taking a seat. No one is
+// watching once the hub has stopped.
+func (h *Hub) CountSpectators() int {
+	reply := make(chan int, 1)
+	select {
+	case h.spectators <- reply:
+		return <-reply
+	case <-h.stopped:
+		return 0
+	}
+}
+
+// Close closes every websocket connection and stops the hub.
+//
+// Waits until the connections have been closed or the timeout has passed.
+func (h *Hub) Close(timeout time.Duration) {
+	done := make(chan struct{})
+	select {
+	case h.stop <- done:
+		<-done
+	case <-h.stopped:
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		h.connections.Wait()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(timeout):
+	}
+}
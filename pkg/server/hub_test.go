@@ -0,0 +1,84 @@
+package server_test
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/websocket"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/server"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+var _ = Describe("Hub", func() {
+	var accounts *auth.Accounts
+
+	BeforeEach(func() {
+		var err error
+		accounts, err = auth.NewAccounts(auth.NewMemoryUserStore(), []byte("secret"), nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("does not send to players who disconnect while the computer plays for someone else", func() {
+		usernames := []string{"alice", "bob", "carol"}
+		for i := 0; i < 10; i++ {
+			g := server.NewGameState(server.NewTableConfig(), storage.NewMemoryStore())
+			hub := server.NewHub()
+			go hub.Run()
+			registry := server.NewTableRegistry()
+			registry.AddTable(hub, g)
+			srv := newTableServer(registry, accounts)
+
+			seat := g.Table.Seats
+			conns := make([]*websocket.Conn, 0)
+			for _, username := range usernames {
+				conn := srv.join(username, nil)
+				send(conn, "take-seat", map[string]interface{}{"seatID": seat.Player.ID})
+				conns = append(conns, conn)
+				seat = seat.Next()
+			}
+			readUntil(conns[len(conns)-1], func(e server.Event) bool {
+				return isUpdate(e) && e.Params["stage"] == "Preflop"
+			})
+
+			// The computer folds for each player as they leave, which ends the hand and deals
+			// the next one to the players who are still connected
+			for _, conn := range conns {
+				conn.Close()
+			}
+
+			// The table keeps running
+			watcher := srv.join("dave", url.Values{})
+			readUntil(watcher, isUpdate)
+			srv.Close()
+		}
+	})
+
+	It("does not wait on a hub that has stopped", func() {
+		g := server.NewGameState(server.NewTableConfig(), storage.NewMemoryStore())
+		hub := server.NewHub()
+		go hub.Run()
+		registry := server.NewTableRegistry()
+		registry.AddTable(hub, g)
+		srv := newTableServer(registry, accounts)
+		defer srv.Close()
+		server.Shutdown(hub, g, 0)
+
+		// Players are turned away from the closed table
+		_, resp, err := srv.dial("alice", nil)
+		Expect(err).To(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+
+		// Shutting down the table again does not wait on the hub
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			server.Shutdown(hub, g, 0)
+			close(done)
+		}()
+		Eventually(done).Should(BeClosed())
+	})
+})
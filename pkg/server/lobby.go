@@ -0,0 +1,391 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/richard-to/go-poker/pkg/auth"
+)
+
+// Lobby events
+const actionOnLobby string = "on-lobby"
+const actionUpdateLobby string = "update-lobby"
+
+// Types of games listed in the lobby
+const gameCash string = "cash"
+const gameMultiTable string = "multi-table"
+const gameSitAndGo string = "sit-and-go"
+
+// Tournament statuses listed in the lobby
+const tournamentCancelled string = "cancelled"
+const tournamentFinished string = "finished"
+const tournamentRegistering string = "registering"
+const tournamentRunning string = "running"
+
+// Only No Limit Hold'em is dealt
+const variantNoLimitHoldem string = "No Limit Hold'em"
+
+// Number of recent pots used to work out the average pot at a table
+const numRecentPots = 20
+
+// Number of clients that can connect to the lobby before it gets to them
+const maxLobbyRegistrations = 256
+
+// LobbyTable is a table as it is listed in the lobby.
+type LobbyTable struct {
+	Ante             int    `json:"ante"`
+	AveragePot       int    `json:"averagePot"` // Average of the most recent pots
+	BigBlind         int    `json:"bigBlind"`
+	Game             string `json:"game"`
+	IsPrivate        bool   `json:"isPrivate"` // Players need the invite code or password to join
+	Name             string `json:"name"`
+	NumSeated        int    `json:"numSeated"`
+	NumSeats         int    `json:"numSeats"`
+	NumWaiting       int    `json:"numWaiting"`  // Players on the waitlist, including any who were offered a seat
+	NumWatching      int    `json:"numWatching"` // Players watching the table without a seat
+	SmallBlind       int    `json:"smallBlind"`
+	Tournament       string `json:"tournament,omitempty"`       // Name of the multi-table tournament
+	TournamentStatus string `json:"tournamentStatus,omitempty"` // Only set for tournament tables
+	Variant          string `json:"variant"`
+}
+
+// LobbyTournament is a multi-table tournament as it is listed in the lobby.
+type LobbyTournament struct {
+	BuyIn                  int    `json:"buyIn"`
+	IsLateRegistrationOpen bool   `json:"isLateRegistrationOpen"`
+	Level                  int    `json:"level"`
+	Name                   string `json:"name"`
+	NumPlayers             int    `json:"numPlayers"` // Most players that can register
+	NumRegistered          int    `json:"numRegistered"`
+	NumRemaining           int    `json:"numRemaining"`
+	NumTables              int    `json:"numTables"`
+	PrizePool              int    `json:"prizePool"`
+	StartAt                string `json:"startAt,omitempty"` // Only set for scheduled tournaments
+	Status                 string `json:"status"`
+}
+
+// Lobby streams the tables and tournaments on the server to clients who are choosing where to
+// play.
+//
+// Clients are sent the full list when they connect. After that, the lobby checks for changes
+// at a regular interval and only sends the tables and tournaments that changed.
+type Lobby struct {
+	clients     map[*lobbyClient]bool
+	interval    time.Duration
+	register    chan *lobbyClient
+	registry    *TableRegistry
+	tables      map[string]LobbyTable // As last sent to the clients
+	tournaments map[string]LobbyTournament
+	unregister  chan *lobbyClient
+}
+
+// lobbyClient is a websocket connection to the lobby. The lobby only sends events, so
+// anything the client sends is ignored.
+type lobbyClient struct {
+	conn  *websocket.Conn
+	lobby *Lobby
+	send  chan Event
+}
+
+// NewLobby creates a lobby that checks the tables and tournaments for changes at the interval.
+func NewLobby(registry *TableRegistry, interval time.Duration) *Lobby {
+	return &Lobby{
+		clients:     make(map[*lobbyClient]bool),
+		interval:    interval,
+		register:    make(chan *lobbyClient, maxLobbyRegistrations),
+		registry:    registry,
+		tables:      make(map[string]LobbyTable),
+		tournaments: make(map[string]LobbyTournament),
+		unregister:  make(chan *lobbyClient),
+	}
+}
+
+// Run sends the lobby to the clients that connect and the changes to every client.
+func (l *Lobby) Run() {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case client := <-l.register:
+			// Bring the other clients up to date first, so that every client has the same list
+			l.broadcastChanges()
+			l.clients[client] = true
+			l.sendToClient(client, l.createOnLobbyEvent())
+		case client := <-l.unregister:
+			if _, ok := l.clients[client]; ok {
+				delete(l.clients, client)
+				close(client.send)
+			}
+		case <-ticker.C:
+			l.broadcastChanges()
+		}
+	}
+}
+
+// broadcastChanges sends the tables and tournaments that were added, changed or removed since
+// the last check
+func (l *Lobby) broadcastChanges() {
+	tables := make([]LobbyTable, 0)
+	removedTables := make([]string, 0)
+	current := make(map[string]LobbyTable)
+	for _, table := range l.registry.ListTables() {
+		summary := summarizeLobbyTable(table)
+		current[summary.Name] = summary
+		if previous, ok := l.tables[summary.Name]; ok == false || previous != summary {
+			tables = append(tables, summary)
+		}
+	}
+	for name := range l.tables {
+		if _, ok := current[name]; ok == false {
+			removedTables = append(removedTables, name)
+		}
+	}
+	l.tables = current
+
+	tournaments := make([]LobbyTournament, 0)
+	removedTournaments := make([]string, 0)
+	currentTournaments := make(map[string]LobbyTournament)
+	for _, m := range l.registry.ListTournaments() {
+		summary := m.summarizeForLobby()
+		currentTournaments[summary.Name] = summary
+		if previous, ok := l.tournaments[summary.Name]; ok == false || previous != summary {
+			tournaments = append(tournaments, summary)
+		}
+	}
+	for name := range l.tournaments {
+		if _, ok := currentTournaments[name]; ok == false {
+			removedTournaments = append(removedTournaments, name)
+		}
+	}
+	l.tournaments = currentTournaments
+
+	if len(tables)+len(removedTables)+len(tournaments)+len(removedTournaments) == 0 {
+		return
+	}
+	e := Event{
+		Action: actionUpdateLobby,
+		Params: map[string]interface{}{
+			"removedTables":      removedTables,
+			"removedTournaments": removedTournaments,
+			"tables":             tables,
+			"tournaments":        tournaments,
+		},
+	}
+	for client := range l.clients {
+		l.sendToClient(client, e)
+	}
+}
+
+// sendToClient sends an event to a client. Clients that stopped reading are disconnected.
+func (l *Lobby) sendToClient(client *lobbyClient, e Event) {
+	select {
+	case client.send <- e:
+	default:
+		delete(l.clients, client)
+		close(client.send)
+	}
+}
+
+// createOnLobbyEvent creates the event with every table and tournament
+func (l *Lobby) createOnLobbyEvent() Event {
+	tables := make([]LobbyTable, 0, len(l.tables))
+	for _, table := range l.registry.ListTables() {
+		if summary, ok := l.tables[table.GameState.Config.Name]; ok {
+			tables = append(tables, summary)
+		}
+	}
+	tournaments := make([]LobbyTournament, 0, len(l.tournaments))
+	for _, m := range l.registry.ListTournaments() {
+		if summary, ok := l.tournaments[m.Config.Name]; ok {
+			tournaments = append(tournaments, summary)
+		}
+	}
+	return Event{
+		Action: actionOnLobby,
+		Params: map[string]interface{}{
+			"tables":      tables,
+			"tournaments": tournaments,
+		},
+	}
+}
+
+// tableSummary is the state of a table that is shown without locking the table
+type tableSummary struct {
+	lobby LobbyTable // NumWatching is counted by the hub when the table is listed
+	stage GameStage
+}
+
+// publishSummary publishes the summary of the table. The table must be locked.
+func (g *GameState) publishSummary() {
+	level := getBlindLevel(g)
+	summary := LobbyTable{
+		Ante:       level.Ante,
+		AveragePot: getAveragePot(g),
+		BigBlind:   level.BigBlind,
+		Game:       gameCash,
+		IsPrivate:  g.Private != nil,
+		Name:       g.Config.Name,
+		NumSeated:  len(getSeatedPlayers(g)),
+		NumSeats:   g.Table.Seats.Len(),
+		NumWaiting: len(g.Waitlist) + len(g.SeatOffers),
+		SmallBlind: level.BigBlind / 2,
+		Variant:    variantNoLimitHoldem,
+	}
+	if g.MTT != nil {
+		summary.Game = gameMultiTable
+		summary.NumSeats = g.MTT.Config.TableSize
+		summary.Tournament = g.MTT.Config.Name
+	} else if g.Tournament != nil {
+		summary.Game = gameSitAndGo
+	}
+	if g.Tournament != nil {
+		summary.TournamentStatus = getTournamentStatus(g.Tournament)
+	}
+	g.summary.Store(tableSummary{lobby: summary, stage: g.Stage})
+}
+
+// getSummary gets the summary that the table last published
+func (g *GameState) getSummary() tableSummary {
+	return g.summary.Load().(tableSummary)
+}
+
+// summarizeLobbyTable creates the lobby listing for a table.
+//
+// The listing is read from the summary that the table publishes, so that the lobby never
+// waits on a table that is in the middle of a hand.
+func summarizeLobbyTable(table *RegisteredTable) LobbyTable {
+	summary := table.GameState.getSummary().lobby
+	summary.NumWatching = table.Hub.CountSpectators()
+	return summary
+}
+
+// summarizeForLobby creates the lobby listing for the tournament
+func (m *MultiTableTournament) summarizeForLobby() LobbyTournament {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.Tournament
+	summary := LobbyTournament{
+		BuyIn:                  m.Config.Tournament.BuyIn,
+		IsLateRegistrationOpen: m.isLateRegistrationOpen(),
+		Level:                  t.Level + 1,
+		Name:                   m.Config.Name,
+		NumPlayers:             m.Config.Tournament.NumPlayers,
+		NumRegistered:          len(t.Players),
+		NumRemaining:           len(t.Players) - len(t.Eliminated),
+		NumTables:              len(m.tables),
+		PrizePool:              t.GetPrizePool(&m.Config.Tournament),
+		Status:                 getTournamentStatus(t),
+	}
+	if m.isScheduled() {
+		summary.StartAt = m.Config.StartAt.UTC().Format(time.RFC3339)
+	}
+	if m.isCancelled {
+		summary.Status = tournamentCancelled
+	}
+	return summary
+}
+
+// recordPot keeps track of the most recent pots at the table for the lobby
+func recordPot(g *GameState, amount int) {
+	g.recentPots = append(g.recentPots, amount)
+	if len(g.recentPots) > numRecentPots {
+		g.recentPots = g.recentPots[len(g.recentPots)-numRecentPots:]
+	}
+}
+
+// getAveragePot gets the average of the most recent pots at the table. Zero if no hands have
+// been played.
+func getAveragePot(g *GameState) int {
+	if len(g.recentPots) == 0 {
+		return 0
+	}
+	total := 0
+	for _, amount := range g.recentPots {
+		total += amount
+	}
+	return total / len(g.recentPots)
+}
+
+func getTournamentStatus(t *Tournament) string {
+	if t.IsFinished() {
+		return tournamentFinished
+	}
+	if t.IsRunning() {
+		return tournamentRunning
+	}
+	return tournamentRegistering
+}
+
+// readPump reads from the connection until it closes
+func (c *lobbyClient) readPump() {
+	defer func() {
+		c.lobby.unregister <- c
+		c.conn.Close()
+	}()
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error { c.conn.SetReadDeadline(time.Now().Add(pongWait)); return nil })
+	for {
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				log.Printf("error: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// writePump sends the lobby events to the connection and keeps it alive
+func (c *lobbyClient) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+	for {
+		select {
+		case event, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				// The lobby closed the channel.
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// ServeLobbyWs handles websocket requests for the lobby. The request must have a valid
+// session token.
+func ServeLobbyWs(lobby *Lobby, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request) {
+	if _, err := authenticate(accounts, r); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	client := &lobbyClient{
+		conn:  conn,
+		lobby: lobby,
+		send:  make(chan Event, 256),
+	}
+	lobby.register <- client
+
+	go client.writePump()
+	go client.readPump()
+}
@@ -0,0 +1,127 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/poker"
+	"github.com/richard-to/go-poker/pkg/server"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+var _ = Describe("Lobby", func() {
+	var accounts *auth.Accounts
+	var g *server.GameState
+	var lobby *server.Lobby
+	var registry *server.TableRegistry
+	var srv *httptest.Server
+	var store *storage.MemoryStore
+
+	connect := func(token string) (*websocket.Conn, *http.Response, error) {
+		url := "ws" + strings.TrimPrefix(srv.URL, "http") + "?token=" + token
+		return websocket.DefaultDialer.Dial(url, nil)
+	}
+
+	read := func(conn *websocket.Conn) server.Event {
+		var e server.Event
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		Expect(conn.ReadJSON(&e)).To(Succeed())
+		return e
+	}
+
+	BeforeEach(func() {
+		var err error
+		store = storage.NewMemoryStore()
+		registry = server.NewTableRegistry()
+		g = server.NewGameState(server.NewTableConfig(), store)
+		hub := server.NewHub()
+		go hub.Run()
+		registry.AddTable(hub, g)
+
+		lobby = server.NewLobby(registry, 10*time.Millisecond)
+		go lobby.Run()
+
+		accounts, err = auth.NewAccounts(auth.NewMemoryUserStore(), []byte("secret"), nil)
+		Expect(err).NotTo(HaveOccurred())
+		srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			server.ServeLobbyWs(lobby, accounts, w, r)
+		}))
+	})
+
+	AfterEach(func() {
+		srv.Close()
+	})
+
+	It("sends the tables and tournaments and then the changes", func() {
+		token, err := accounts.Register("alice", "password")
+		Expect(err).NotTo(HaveOccurred())
+		conn, _, err := connect(token)
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+
+		e := read(conn)
+		Expect(e.Action).To(Equal("on-lobby"))
+		Expect(e.Params["tournaments"]).To(BeEmpty())
+		Expect(e.Params["tables"]).To(Equal([]interface{}{
+			map[string]interface{}{
+				"ante":        0.0,
+				"averagePot":  0.0,
+				"bigBlind":    2.0,
+				"game":        "cash",
+				"isPrivate":   false,
+				"name":        "Main",
+				"numSeated":   0.0,
+				"numSeats":    6.0,
+				"numWaiting":  0.0,
+				"numWatching": 0.0,
+				"smallBlind":  1.0,
+				"variant":     "No Limit Hold'em",
+			},
+		}))
+
+		// The table is set up before it is added, since the lobby reads it from then on
+		config := server.NewTableConfig()
+		config.Name = "Side"
+		side := server.NewGameState(config, store)
+		p := side.Table.Seats.Player
+		p.Name = "bob"
+		p.Chips = 100
+		p.Status = poker.PlayerSittingOut
+		hub := server.NewHub()
+		go hub.Run()
+		registry.AddTable(hub, side)
+		e = read(conn)
+		Expect(e.Action).To(Equal("update-lobby"))
+		Expect(e.Params["tables"]).To(HaveLen(1))
+		Expect(e.Params["tables"].([]interface{})[0]).To(HaveKeyWithValue("name", "Side"))
+		Expect(e.Params["tables"].([]interface{})[0]).To(HaveKeyWithValue("numSeated", 1.0))
+		Expect(e.Params["tournaments"]).To(BeEmpty())
+
+		m, err := server.NewMultiTableTournament(*server.NewMultiTableTournamentConfig(), registry, store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(registry.AddTournament(m)).To(Succeed())
+		e = read(conn)
+		Expect(e.Params["tables"]).To(BeEmpty())
+		Expect(e.Params["tournaments"]).To(HaveLen(1))
+		tournament := e.Params["tournaments"].([]interface{})[0]
+		Expect(tournament).To(HaveKeyWithValue("name", "Main Event"))
+		Expect(tournament).To(HaveKeyWithValue("status", "registering"))
+
+		registry.RemoveTable("Main")
+		e = read(conn)
+		Expect(e.Params["removedTables"]).To(Equal([]interface{}{"Main"}))
+	})
+
+	It("needs a session token", func() {
+		_, resp, err := connect("")
+		Expect(err).To(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+})
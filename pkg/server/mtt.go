@@ -0,0 +1,946 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/poker"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+const defaultMultiTableTournamentName string = "Main Event"
+
+// MultiTableTournamentConfig contains the settings for a multi-table tournament.
+//
+// Tournaments without a start time start once NumPlayers players have registered. Scheduled
+// tournaments start at their start time with however many players registered by then, up to
+// NumPlayers.
+type MultiTableTournamentConfig struct {
+	LateRegistrationLevels int              // Players can register until this many blind levels have been played
+	MinPlayers             int              // Scheduled tournaments are cancelled if fewer players register
+	Name                   string           // Name used for the tables, buy-ins and ratings
+	ReEntries              int              // Times each player can buy in again after being knocked out during late registration
+	RebuyLevels            int              // Players can rebuy until this many blind levels have been played
+	Rebuys                 int              // Times each player can rebuy
+	StartAt                time.Time        // Optional. The tournament starts once it is full if zero.
+	TableSize              int              // Number of players seated at each table
+	Tournament             TournamentConfig // NumPlayers is the most players that can register
+}
+
+// MultiTableTournament is a tournament played across several tables.
+//
+// Players are seated at random once everyone has registered. As players are knocked out,
+// players are moved from the biggest tables to the smallest tables, and tables are broken
+// up once the players fit at fewer tables. On the bubble, every table plays hand for hand.
+// Each table waits for the others to finish their hand, so that players knocked out at the
+// same time are ranked by their stacks at the start of the hand.
+//
+// Players who register late are seated at the table with the fewest players. Players who
+// rebuy get another starting stack before their next hand.
+//
+// Tournaments are not resumed after the server restarts. The buy-ins are refunded instead.
+//
+// Tables lock themselves before the tournament, so a goroutine holding the tournament's lock
+// must not lock a table.
+type MultiTableTournament struct {
+	Config     MultiTableTournamentConfig
+	Tournament *Tournament // Shared by every table
+
+	isCancelled    bool
+	mu             sync.Mutex      // Guards the tournament, including Tournament and the seats at its tables. Tables hold it while they change.
+	numTables      int             // Number of tables created. Used to name new tables.
+	pending        []string        // Players knocked out while playing hand for hand
+	readyTables    map[string]bool // Tables that finished their hand while playing hand for hand
+	rebuys         map[string]bool // Players who get another starting stack before their next hand
+	registry       *TableRegistry
+	releasedTables map[string]bool // Tables that can deal once every table finished hand for hand
+	store          storage.Store
+	tables         []*RegisteredTable // Tables that are still running
+}
+
+// NewMultiTableTournamentConfig creates a multi-table tournament config with the default settings.
+func NewMultiTableTournamentConfig() *MultiTableTournamentConfig {
+	tournament := NewTournamentConfig()
+	tournament.NumPlayers = 3 * numPlayers
+	tournament.Payouts = []int{50, 30, 20}
+	return &MultiTableTournamentConfig{
+		MinPlayers: minPlayers,
+		Name:       defaultMultiTableTournamentName,
+		TableSize:  numPlayers,
+		Tournament: *tournament,
+	}
+}
+
+// Validate checks that the tournament can be played with the config.
+func (c *MultiTableTournamentConfig) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("Tournaments need a name")
+	}
+	if c.TableSize < minPlayers || c.TableSize > numPlayers {
+		return fmt.Errorf("Tables can seat between %d and %d players", minPlayers, numPlayers)
+	}
+	if c.StartAt.IsZero() == false && (c.MinPlayers < minPlayers || c.MinPlayers > c.Tournament.NumPlayers) {
+		return fmt.Errorf("Scheduled tournaments need between %d and %d players to start", minPlayers, c.Tournament.NumPlayers)
+	}
+	if c.LateRegistrationLevels < 0 || c.RebuyLevels < 0 || c.ReEntries < 0 || c.Rebuys < 0 {
+		return fmt.Errorf("Late registration, re-entries and rebuys cannot be negative")
+	}
+	if c.ReEntries > 0 && c.LateRegistrationLevels == 0 {
+		return fmt.Errorf("Players can only re-enter during late registration")
+	}
+	if c.Rebuys > 0 && c.RebuyLevels == 0 {
+		return fmt.Errorf("Rebuys need a rebuy period")
+	}
+	return c.Tournament.validate(math.MaxInt32)
+}
+
+// NewMultiTableTournament creates a tournament that players can register for.
+//
+// Buy-ins left over from a tournament that did not finish before the server restarted are
+// refunded.
+func NewMultiTableTournament(config MultiTableTournamentConfig, registry *TableRegistry, store storage.Store) (*MultiTableTournament, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if err := store.CashOutTable(config.Name); err != nil {
+		return nil, err
+	}
+	return newMultiTableTournament(config, registry, store), nil
+}
+
+func newMultiTableTournament(config MultiTableTournamentConfig, registry *TableRegistry, store storage.Store) *MultiTableTournament {
+	return &MultiTableTournament{
+		Config:         config,
+		Tournament:     NewTournament(),
+		readyTables:    make(map[string]bool),
+		rebuys:         make(map[string]bool),
+		registry:       registry,
+		releasedTables: make(map[string]bool),
+		store:          store,
+		tables:         make([]*RegisteredTable, 0),
+	}
+}
+
+// Register buys a player into the tournament. Tournaments without a start time start once
+// they are full.
+//
+// Players can still register after the tournament has started during late registration.
+// Players who were knocked out can buy in again if they have re-entries left.
+func (m *MultiTableTournament) Register(username string) error {
+	m.mu.Lock()
+	if m.isCancelled == false && m.Tournament.HasStarted() {
+		m.mu.Unlock()
+		return m.registerLate(username)
+	}
+	defer m.mu.Unlock()
+
+	t := m.Tournament
+	if m.isCancelled {
+		return fmt.Errorf("The tournament was cancelled")
+	}
+	if t.HasPlayer(username) {
+		return fmt.Errorf("You have already registered")
+	}
+	if len(t.Players) >= m.Config.Tournament.NumPlayers {
+		return fmt.Errorf("The tournament is full")
+	}
+	if err := m.buyIn(username); err != nil {
+		return err
+	}
+	t.Players = append(t.Players, username)
+
+	if m.isScheduled() {
+		return m.save()
+	}
+	if len(t.Players) >= m.Config.Tournament.NumPlayers {
+		m.start()
+	}
+	return nil
+}
+
+// Unregister refunds a player's buy-in if the tournament has not started yet.
+func (m *MultiTableTournament) Unregister(username string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.Tournament
+	if t.HasStarted() {
+		return fmt.Errorf("The tournament has already started")
+	}
+	if t.HasPlayer(username) == false {
+		return fmt.Errorf("You have not registered")
+	}
+	if _, err := m.store.CashOut(m.Config.Name, username); err != nil {
+		return err
+	}
+	t.RemovePlayer(username)
+
+	if m.isScheduled() {
+		return m.save()
+	}
+	return nil
+}
+
+// Rebuy buys a player another starting stack during the rebuy period. Players can rebuy once
+// their stack is no bigger than the starting stack. The chips are added before their next hand.
+func (m *MultiTableTournament) Rebuy(username string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.Tournament
+	config := &m.Config.Tournament
+	if m.isRebuyOpen() == false {
+		return fmt.Errorf("Rebuys are closed")
+	}
+	_, p := m.findPlayer(username)
+	if p == nil || isEliminated(t, username) || m.isPending(username) {
+		return fmt.Errorf("Only players who are still in the tournament can rebuy")
+	}
+	if m.rebuys[username] {
+		return fmt.Errorf("You already rebought for your next hand")
+	}
+	if t.Rebuys[username] >= m.Config.Rebuys {
+		return fmt.Errorf("You have no rebuys left")
+	}
+	if p.Chips > config.StartingStack {
+		return fmt.Errorf("You can only rebuy once you have %d chips or fewer", config.StartingStack)
+	}
+	if err := m.buyIn(username); err != nil {
+		return err
+	}
+
+	// The rebuy counts towards the prize pool and bounty straight away so that it is paid
+	// out even if the tournament ends before the chips are added
+	t.Rebuys[username]++
+	t.Bounties[username] += config.Bounty
+	m.rebuys[username] = true
+	return nil
+}
+
+// registerLate buys a player in after the tournament has started and seats them.
+//
+// The player's table may be in the middle of a hand, so it is locked while they are seated.
+// The table is chosen before it is locked, so the choice is checked again once it has been
+// locked.
+func (m *MultiTableTournament) registerLate(username string) error {
+	for {
+		m.mu.Lock()
+		if err := m.checkLateRegistration(username); err != nil {
+			m.mu.Unlock()
+			return err
+		}
+		table := m.getLateTable()
+		if table == nil {
+			// New tables are not dealing yet, so the player is seated without locking the table
+			defer m.mu.Unlock()
+			isReEntry, err := m.enterLate(username)
+			if err != nil {
+				return err
+			}
+			m.seatLatePlayer(m.addTable(), username, isReEntry)
+			return nil
+		}
+		m.mu.Unlock()
+
+		table.GameState.lock()
+		if m.getTable(table.GameState.Config.Name) == table && len(m.getTablePlayers(table)) < m.Config.TableSize {
+			isReEntry, err := m.enterLate(username)
+			if err == nil {
+				m.seatLatePlayer(table, username, isReEntry)
+			}
+			table.GameState.unlock()
+			return err
+		}
+		table.GameState.unlock()
+	}
+}
+
+// checkLateRegistration checks if a player can register after the tournament has started
+func (m *MultiTableTournament) checkLateRegistration(username string) error {
+	t := m.Tournament
+	if m.isCancelled || m.isLateRegistrationOpen() == false {
+		return fmt.Errorf("The tournament has already started")
+	}
+	if t.HasPlayer(username) == false {
+		if len(t.Players) >= m.Config.Tournament.NumPlayers {
+			return fmt.Errorf("The tournament is full")
+		}
+		return nil
+	}
+	if isEliminated(t, username) == false {
+		return fmt.Errorf("You are still in the tournament")
+	}
+	if t.ReEntries[username] >= m.Config.ReEntries {
+		return fmt.Errorf("You have no re-entries left")
+	}
+	return nil
+}
+
+// enterLate buys a player in after the tournament has started. Returns true if the player
+// re-entered the tournament.
+func (m *MultiTableTournament) enterLate(username string) (bool, error) {
+	t := m.Tournament
+	if err := m.checkLateRegistration(username); err != nil {
+		return false, err
+	}
+	if err := m.buyIn(username); err != nil {
+		return false, err
+	}
+
+	// Players who re-enter start again, so their earlier finish no longer counts
+	isReEntry := t.HasPlayer(username)
+	if isReEntry {
+		for i, eliminated := range t.Eliminated {
+			if eliminated == username {
+				t.Eliminated = append(t.Eliminated[:i], t.Eliminated[i+1:]...)
+				break
+			}
+		}
+		t.ReEntries[username]++
+	} else {
+		t.Players = append(t.Players, username)
+	}
+	t.Bounties[username] += m.Config.Tournament.Bounty
+	return isReEntry, nil
+}
+
+// buyIn takes the buy-in from a player's bankroll
+func (m *MultiTableTournament) buyIn(username string) error {
+	if err := m.store.OpenBankroll(username, defaultBankroll); err != nil {
+		return err
+	}
+	return m.store.BuyIn(m.Config.Name, username, m.Config.Tournament.BuyIn)
+}
+
+// isLateRegistrationOpen checks if players can still register after the tournament started
+func (m *MultiTableTournament) isLateRegistrationOpen() bool {
+	return m.Tournament.IsRunning() && m.Tournament.Level < m.Config.LateRegistrationLevels
+}
+
+// isRebuyOpen checks if the tournament is in its rebuy period
+func (m *MultiTableTournament) isRebuyOpen() bool {
+	return m.Tournament.IsRunning() && m.Tournament.Level < m.Config.RebuyLevels
+}
+
+// GetTables gets the tables that are still running.
+func (m *MultiTableTournament) GetTables() []*RegisteredTable {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*RegisteredTable{}, m.tables...)
+}
+
+// start draws seats at random and starts dealing at each table.
+//
+// Players are spread across the tables so that the tables differ by at most one player.
+// Players who are watching a table are moved to their seat.
+func (m *MultiTableTournament) start() {
+	t := m.Tournament
+	t.start(&m.Config.Tournament)
+
+	players := append([]string{}, t.Players...)
+	rand.Shuffle(len(players), func(i, j int) {
+		players[i], players[j] = players[j], players[i]
+	})
+
+	numTables := (len(players) + m.Config.TableSize - 1) / m.Config.TableSize
+	for i := 0; i < numTables; i++ {
+		m.addTable()
+	}
+	for i, username := range players {
+		table := m.tables[i%numTables]
+		seat := getVacatedSeat(table.GameState)
+		seat.Name = username
+		seat.Chips = m.Config.Tournament.StartingStack
+		seat.Status = poker.PlayerSittingOut
+		loadPlayerStats(table.GameState, username)
+		t.Stacks[username] = seat.Chips
+	}
+
+	for _, table := range m.tables {
+		table.GameState.announce(fmt.Sprintf(
+			"The tournament has started. Blinds are %s.",
+			formatBlindLevel(getBlindLevel(table.GameState)),
+		))
+	}
+
+	// Seat the players who are watching a table
+	for _, table := range m.registry.ListTables() {
+		for _, c := range listClients(table.Hub) {
+			if destination, p := m.findPlayer(c.account.Username); p != nil {
+				p.IsHuman = true
+				requestSpectatorMove(c, destination, p.ID)
+			}
+		}
+	}
+
+	for _, table := range m.tables {
+		if hasConnectedPlayer(table.GameState) {
+			go dealTournamentTable(table)
+		}
+	}
+}
+
+// getLateTable gets the table with the fewest players for a player who registered late. Nil
+// if every table is full.
+func (m *MultiTableTournament) getLateTable() *RegisteredTable {
+	counts := make(map[*RegisteredTable]int)
+	for _, table := range m.tables {
+		counts[table] = len(m.getTablePlayers(table))
+	}
+	return m.getSmallestTable(counts, nil)
+}
+
+// seatLatePlayer seats a player who registered late at the table. The table must be locked,
+// unless it was just opened. If the player is watching a table, they are moved to their seat.
+func (m *MultiTableTournament) seatLatePlayer(table *RegisteredTable, username string, isReEntry bool) {
+	seat := getVacatedSeat(table.GameState)
+	seat.Name = username
+	seat.Chips = m.Config.Tournament.StartingStack
+	seat.Status = poker.PlayerSittingOut
+	loadPlayerStats(table.GameState, username)
+	m.Tournament.Stacks[username] = seat.Chips
+
+	if isReEntry {
+		table.GameState.announce(fmt.Sprintf("%s re-enters the tournament.", username))
+	} else {
+		table.GameState.announce(fmt.Sprintf("%s registers late and takes a seat.", username))
+	}
+
+	for _, other := range m.registry.ListTables() {
+		for _, c := range listClients(other.Hub) {
+			if c.account.Username == username {
+				seat.IsHuman = true
+				requestSpectatorMove(c, table, seat.ID)
+			}
+		}
+	}
+
+	// Tables that stopped dealing because they were short of players start again
+	if table.GameState.Stage == Waiting && m.readyTables[table.GameState.Config.Name] == false && hasConnectedPlayer(table.GameState) {
+		go dealTournamentTable(table)
+	}
+}
+
+// addTable creates a new table for the tournament
+func (m *MultiTableTournament) addTable() *RegisteredTable {
+	m.numTables++
+	config := NewTableConfig()
+	useDefaultChatConfig(m.registry, &config)
+	config.Name = fmt.Sprintf("%s - Table %d", m.Config.Name, m.numTables)
+	config.Tournament = &m.Config.Tournament
+
+	g := NewGameState(config, m.store)
+	g.MTT = m
+	g.Tournament = m.Tournament
+
+	hub := NewHub()
+	go hub.Run()
+
+	table := m.registry.AddTable(hub, g)
+	m.tables = append(m.tables, table)
+	return table
+}
+
+// update knocks out players, balances the tables and finishes the tournament before the
+// table deals its next hand. Returns false if the table should wait instead of dealing.
+//
+// The table holds the tournament's lock while it is dealt.
+func (m *MultiTableTournament) update(g *GameState) bool {
+	t := m.Tournament
+	table := m.getTable(g.Config.Name)
+	if t.IsRunning() == false || table == nil {
+		return false
+	}
+
+	m.addRebuys(g)
+
+	if m.releasedTables[g.Config.Name] {
+		// The players knocked out during the hand for hand round have already been handled
+		delete(m.releasedTables, g.Config.Name)
+	} else {
+		isHandForHand := m.isHandForHand()
+		busted := make([]string, 0)
+		for _, username := range getBustedPlayers(g) {
+			if m.isPending(username) == false {
+				busted = append(busted, username)
+			}
+		}
+		if isHandForHand {
+			m.pending = append(m.pending, busted...)
+		} else {
+			eliminatePlayers(g, busted)
+			m.unseatEliminatedPlayers(table)
+		}
+		updateBlindLevel(g)
+
+		if isHandForHand {
+			m.readyTables[g.Config.Name] = true
+			if m.isRoundFinished() == false {
+				g.announce("Playing hand for hand. Waiting for the other tables to finish their hand.")
+				return false
+			}
+			m.finishRound(g)
+		}
+	}
+
+	// The tournament is over once one player has every chip
+	remaining := m.getRemainingPlayers()
+	if len(remaining) == 1 {
+		m.finish(g, remaining[0])
+		return false
+	}
+
+	if m.balanceTables(table) == false {
+		return false
+	}
+	recordTournamentStacks(g)
+	return waitForDeal(g) == false
+}
+
+// addRebuys adds the chips that players at the table rebought
+func (m *MultiTableTournament) addRebuys(g *GameState) {
+	for _, p := range getSeatedPlayers(g) {
+		if m.rebuys[p.Name] == false {
+			continue
+		}
+		delete(m.rebuys, p.Name)
+		p.Chips += m.Config.Tournament.StartingStack
+		g.announce(fmt.Sprintf("%s rebuys for %d chips.", p.Name, m.Config.Tournament.StartingStack))
+	}
+}
+
+// isHandForHand checks if the tournament is on the bubble and more than one table is left
+func (m *MultiTableTournament) isHandForHand() bool {
+	remaining := len(m.Tournament.Players) - len(m.Tournament.Eliminated)
+	return len(m.tables) > 1 && remaining == len(m.Config.Tournament.Payouts)+1
+}
+
+// isRoundFinished checks if every table has finished its hand while playing hand for hand.
+// Tables that are not dealing are treated as finished.
+func (m *MultiTableTournament) isRoundFinished() bool {
+	for _, table := range m.tables {
+		if m.readyTables[table.GameState.Config.Name] == false && table.GameState.Stage != Waiting {
+			return false
+		}
+	}
+	return true
+}
+
+// finishRound knocks out the players who lost their chips during the hand for hand round and
+// lets the other tables deal again
+func (m *MultiTableTournament) finishRound(g *GameState) {
+	pending := m.pending
+	m.pending = nil
+	eliminatePlayers(g, pending)
+	for _, table := range m.tables {
+		m.unseatEliminatedPlayers(table)
+	}
+
+	m.readyTables = make(map[string]bool)
+	for _, table := range m.tables {
+		if table.GameState == g {
+			continue
+		}
+		m.releasedTables[table.GameState.Config.Name] = true
+		if hasConnectedPlayer(table.GameState) {
+			go dealTournamentTable(table)
+		}
+	}
+}
+
+// balanceTables moves players from the table to other tables. Returns false if the table
+// was broken up.
+//
+// A table is broken up if the players fit at fewer tables and it is one of the smallest
+// tables. Otherwise players are moved to the smallest table until the table has at most one
+// more player than it.
+func (m *MultiTableTournament) balanceTables(table *RegisteredTable) bool {
+	counts := make(map[*RegisteredTable]int)
+	total := 0
+	fewest := math.MaxInt32
+	for _, other := range m.tables {
+		counts[other] = len(m.getTablePlayers(other))
+		total += counts[other]
+		if counts[other] < fewest {
+			fewest = counts[other]
+		}
+	}
+
+	neededTables := (total + m.Config.TableSize - 1) / m.Config.TableSize
+	if len(m.tables) > neededTables {
+		if counts[table] > fewest {
+			return true
+		}
+		for _, p := range m.getTablePlayers(table) {
+			destination := m.getSmallestTable(counts, table)
+			if destination == nil {
+				return true
+			}
+			m.movePlayer(table, destination, p)
+			counts[destination]++
+		}
+		m.closeTable(table, m.tables[0])
+		if len(m.tables) == 1 {
+			announceTournament(m.tables[0].GameState, "The final table is set.")
+		}
+		return false
+	}
+
+	for len(m.tables) > 1 {
+		destination := m.getSmallestTable(counts, table)
+		if destination == nil || counts[table] <= counts[destination]+1 {
+			break
+		}
+		p := getNextBigBlind(table.GameState, m.getTablePlayers(table))
+		m.movePlayer(table, destination, p)
+		counts[table]--
+		counts[destination]++
+	}
+	return true
+}
+
+// getSmallestTable gets the table with the fewest players, not counting the excluded table
+func (m *MultiTableTournament) getSmallestTable(counts map[*RegisteredTable]int, exclude *RegisteredTable) *RegisteredTable {
+	var smallest *RegisteredTable
+	for _, table := range m.tables {
+		if table == exclude || counts[table] >= m.Config.TableSize {
+			continue
+		}
+		if smallest == nil || counts[table] < counts[smallest] {
+			smallest = table
+		}
+	}
+	return smallest
+}
+
+// movePlayer moves a player to an empty seat at another table.
+//
+// The player sits out until the next hand at the new table. If the player is connected,
+// their client is moved to the new table too.
+func (m *MultiTableTournament) movePlayer(from *RegisteredTable, to *RegisteredTable, p *poker.Player) {
+	seat := getVacatedSeat(to.GameState)
+	seat.Name = p.Name
+	seat.Chips = p.Chips
+	seat.Status = poker.PlayerSittingOut
+	seat.IsHuman = p.IsHuman
+	loadPlayerStats(to.GameState, p.Name)
+
+	if p.IsHuman {
+		for _, c := range listClients(from.Hub) {
+			if c.seatID == p.ID {
+				requestMove(c, to, seat.ID)
+			}
+		}
+	}
+
+	from.GameState.announce(fmt.Sprintf("%s moves to %s.", p.Name, to.GameState.Config.Name))
+	to.GameState.announce(fmt.Sprintf("%s joins the table from %s.", p.Name, from.GameState.Config.Name))
+
+	vacateSeat(p)
+
+	// Tables that stopped dealing because they were short of players start again
+	if to.GameState.Stage == Waiting && m.readyTables[to.GameState.Config.Name] == false && hasConnectedPlayer(to.GameState) {
+		go dealTournamentTable(to)
+	}
+}
+
+// unseatEliminatedPlayers frees up the seats of knocked out players so that other players
+// can be moved there. Knocked out players can keep watching the table.
+func (m *MultiTableTournament) unseatEliminatedPlayers(table *RegisteredTable) {
+	for _, p := range getSeatedPlayers(table.GameState) {
+		if isEliminated(m.Tournament, p.Name) == false {
+			continue
+		}
+		for _, c := range table.Hub.copyClients() {
+			if c.seatID == p.ID {
+				c.sit("")
+			}
+		}
+		vacateSeat(p)
+	}
+}
+
+// closeTable removes a table once its players have been moved. Anyone still watching the
+// table is moved to the destination table.
+//
+// The hub keeps running so that the hand that was being finished can still broadcast.
+func (m *MultiTableTournament) closeTable(table *RegisteredTable, destination *RegisteredTable) {
+	for i, other := range m.tables {
+		if other == table {
+			m.tables = append(m.tables[:i], m.tables[i+1:]...)
+			break
+		}
+	}
+	m.registry.RemoveTable(table.GameState.Config.Name)
+	delete(m.readyTables, table.GameState.Config.Name)
+	delete(m.releasedTables, table.GameState.Config.Name)
+
+	for _, c := range listClients(table.Hub) {
+		requestMove(c, destination, "")
+	}
+}
+
+// finish pays out the tournament at the winner's table and closes the other tables
+func (m *MultiTableTournament) finish(g *GameState, winner string) {
+	finalTable, _ := m.findPlayer(winner)
+	finishTournament(finalTable.GameState, winner)
+	for _, table := range append([]*RegisteredTable{}, m.tables...) {
+		if table != finalTable {
+			m.closeTable(table, finalTable)
+		}
+	}
+
+	// The table that knocked out the last player posts the results once its hand is over
+	if finalTable.GameState != g {
+		go func() {
+			finalTable.GameState.lock()
+			defer finalTable.GameState.unlock()
+			c := newSystemClient(finalTable.Hub, finalTable.GameState, "")
+			broadcastAnnouncements(c)
+			broadcastUpdateGameEvent(c)
+		}()
+	}
+}
+
+// getTable gets a running table by name
+func (m *MultiTableTournament) getTable(name string) *RegisteredTable {
+	for _, table := range m.tables {
+		if table.GameState.Config.Name == name {
+			return table
+		}
+	}
+	return nil
+}
+
+// getTablePlayers gets the players at a table who are still in the tournament
+func (m *MultiTableTournament) getTablePlayers(table *RegisteredTable) []*poker.Player {
+	players := make([]*poker.Player, 0)
+	for _, p := range getSeatedPlayers(table.GameState) {
+		if isEliminated(m.Tournament, p.Name) == false && m.isPending(p.Name) == false {
+			players = append(players, p)
+		}
+	}
+	return players
+}
+
+// getRemainingPlayers gets the usernames of the players still in the tournament
+func (m *MultiTableTournament) getRemainingPlayers() []string {
+	remaining := make([]string, 0)
+	for _, table := range m.tables {
+		for _, p := range m.getTablePlayers(table) {
+			remaining = append(remaining, p.Name)
+		}
+	}
+	return remaining
+}
+
+// findPlayer finds a player's table and seat
+func (m *MultiTableTournament) findPlayer(username string) (*RegisteredTable, *poker.Player) {
+	for _, table := range m.tables {
+		for _, p := range getSeatedPlayers(table.GameState) {
+			if p.Name == username {
+				return table, p
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (m *MultiTableTournament) isPending(username string) bool {
+	for _, pending := range m.pending {
+		if pending == username {
+			return true
+		}
+	}
+	return false
+}
+
+// summarize creates the tournament data for the tournament endpoints
+func (m *MultiTableTournament) summarize() map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.Tournament
+	tables := make([]map[string]interface{}, 0)
+	for _, table := range m.tables {
+		players := make([]map[string]interface{}, 0)
+		for _, p := range m.getTablePlayers(table) {
+			players = append(players, map[string]interface{}{
+				"chips": p.Chips,
+				"name":  p.Name,
+			})
+		}
+		tables = append(tables, map[string]interface{}{
+			"name":    table.GameState.Config.Name,
+			"players": players,
+		})
+	}
+
+	level := m.Config.Tournament.Levels[0]
+	if t.Level < len(m.Config.Tournament.Levels) {
+		level = m.Config.Tournament.Levels[t.Level]
+	}
+
+	// Nil unless the tournament is scheduled
+	var startAt interface{}
+	if m.isScheduled() {
+		startAt = m.Config.StartAt
+	}
+
+	return map[string]interface{}{
+		"ante":                   level.Ante,
+		"bigBlind":               level.BigBlind,
+		"buyIn":                  m.Config.Tournament.BuyIn,
+		"eliminated":             t.Eliminated,
+		"isCancelled":            m.isCancelled,
+		"isFinished":             t.IsFinished(),
+		"isHandForHand":          m.isHandForHand(),
+		"isLateRegistrationOpen": m.isLateRegistrationOpen(),
+		"isRebuyOpen":            m.isRebuyOpen(),
+		"isRunning":              t.IsRunning(),
+		"level":                  t.Level + 1,
+		"minPlayers":             m.Config.MinPlayers,
+		"name":                   m.Config.Name,
+		"numPlayers":             m.Config.Tournament.NumPlayers,
+		"payouts":                t.Payouts,
+		"players":                t.Players,
+		"prizePool":              t.GetPrizePool(&m.Config.Tournament),
+		"reEntries":              t.ReEntries,
+		"rebuys":                 t.Rebuys,
+		"smallBlind":             level.BigBlind / 2,
+		"startAt":                startAt,
+		"tables":                 tables,
+	}
+}
+
+// dealTournamentTable deals the next hand at a tournament table that is not dealing
+func dealTournamentTable(table *RegisteredTable) {
+	table.GameState.lock()
+	defer table.GameState.unlock()
+
+	// Another goroutine may have dealt while waiting for the table
+	if table.GameState.Stage != Waiting {
+		return
+	}
+	c := newSystemClient(table.Hub, table.GameState, "")
+	StartNewHand(table.GameState)
+	sendHoleCardEvents(table.Hub)
+	broadcastAnnouncements(c)
+	broadcastUpdateGameEvent(c)
+	HandleComputerMove(c)
+}
+
+// getVacatedSeat gets the player in the first empty seat at the table
+func getVacatedSeat(g *GameState) *poker.Player {
+	seat := g.Table.Seats
+	for i := 0; i < seat.Len(); i++ {
+		if seat.Player.Status == poker.PlayerVacated {
+			return seat.Player
+		}
+		seat = seat.Next()
+	}
+	return nil
+}
+
+// getNextBigBlind gets the player who would post the big blind next. This player is moved
+// when balancing tables since they have not paid for the hands they would miss.
+func getNextBigBlind(g *GameState, players []*poker.Player) *poker.Player {
+	seat := g.Table.BigBlind
+	if seat == nil {
+		seat = g.Table.Seats
+	}
+	for i := 0; i < seat.Len(); i++ {
+		seat = seat.Next()
+		for _, p := range players {
+			if seat.Player == p {
+				return p
+			}
+		}
+	}
+	return players[0]
+}
+
+// vacateSeat empties a seat
+func vacateSeat(p *poker.Player) {
+	p.Name = ""
+	p.Chips = 0
+	p.IsHuman = false
+	p.Status = poker.PlayerVacated
+}
+
+// ServeTournamentList lists the multi-table tournaments.
+func ServeTournamentList(registry *TableRegistry, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request) {
+	if _, err := authenticate(accounts, r); err != nil {
+		writeJSONError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	tournaments := make([]map[string]interface{}, 0)
+	for _, m := range registry.ListTournaments() {
+		tournaments = append(tournaments, m.summarize())
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tournaments": tournaments})
+}
+
+// ServeTournament sends the state of a multi-table tournament, including where each player
+// is seated.
+func ServeTournament(registry *TableRegistry, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request, name string) {
+	if _, err := authenticate(accounts, r); err != nil {
+		writeJSONError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	m := registry.GetTournament(name)
+	if m == nil {
+		writeJSONError(w, http.StatusNotFound, "Tournament not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, m.summarize())
+}
+
+// ServeTournamentRegister registers the requester for a multi-table tournament.
+func ServeTournamentRegister(registry *TableRegistry, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request, name string) {
+	serveTournamentRegistration(registry, accounts, w, r, name, (*MultiTableTournament).Register)
+}
+
+// ServeTournamentRebuy buys the requester another starting stack during the rebuy period.
+func ServeTournamentRebuy(registry *TableRegistry, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request, name string) {
+	serveTournamentRegistration(registry, accounts, w, r, name, (*MultiTableTournament).Rebuy)
+}
+
+// ServeTournamentUnregister unregisters the requester from a multi-table tournament and
+// refunds their buy-in.
+func ServeTournamentUnregister(registry *TableRegistry, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request, name string) {
+	serveTournamentRegistration(registry, accounts, w, r, name, (*MultiTableTournament).Unregister)
+}
+
+func serveTournamentRegistration(
+	registry *TableRegistry,
+	accounts *auth.Accounts,
+	w http.ResponseWriter,
+	r *http.Request,
+	name string,
+	register func(*MultiTableTournament, string) error,
+) {
+	requester, err := authenticate(accounts, r)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	m := registry.GetTournament(name)
+	if m == nil {
+		writeJSONError(w, http.StatusNotFound, "Tournament not found")
+		return
+	}
+	if err := register(m, requester.Username); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, m.summarize())
+}
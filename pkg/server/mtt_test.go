@@ -0,0 +1,385 @@
+package server_test
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/poker"
+	"github.com/richard-to/go-poker/pkg/server"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+var _ = Describe("MultiTableTournament", func() {
+	var config *server.MultiTableTournamentConfig
+	var registry *server.TableRegistry
+	var store *storage.MemoryStore
+
+	// seatedPlayers lists the players with a seat at a table
+	seatedPlayers := func(table *server.RegisteredTable) []*poker.Player {
+		players := make([]*poker.Player, 0)
+		seat := table.GameState.Table.Seats
+		for i := 0; i < seat.Len(); i++ {
+			if seat.Player.Status > poker.PlayerVacated {
+				players = append(players, seat.Player)
+			}
+			seat = seat.Next()
+		}
+		return players
+	}
+
+	// bust takes the chips of players at a table. Stacks are the players' stacks at the start
+	// of the hand, which decide who finishes higher when players are knocked out together.
+	bust := func(m *server.MultiTableTournament, table *server.RegisteredTable, stacks ...int) []string {
+		busted := make([]string, 0)
+		for i, p := range seatedPlayers(table)[:len(stacks)] {
+			m.Tournament.Stacks[p.Name] = stacks[i]
+			p.Chips = 0
+			busted = append(busted, p.Name)
+		}
+		return busted
+	}
+
+	// findSeat finds the table and seat of a player
+	findSeat := func(m *server.MultiTableTournament, username string) (*server.RegisteredTable, *poker.Player) {
+		for _, table := range m.GetTables() {
+			for _, p := range seatedPlayers(table) {
+				if p.Name == username {
+					return table, p
+				}
+			}
+		}
+		return nil, nil
+	}
+
+	findTable := func(m *server.MultiTableTournament, username string) *server.RegisteredTable {
+		table, _ := findSeat(m, username)
+		return table
+	}
+
+	start := func() *server.MultiTableTournament {
+		m, err := server.NewMultiTableTournament(*config, registry, store)
+		Expect(err).NotTo(HaveOccurred())
+		for i := 0; i < config.Tournament.NumPlayers; i++ {
+			Expect(m.Register(fmt.Sprintf("player%d", i))).To(Succeed())
+		}
+		Expect(m.Tournament.IsRunning()).To(BeTrue())
+		return m
+	}
+
+	BeforeEach(func() {
+		store = storage.NewMemoryStore()
+		registry = server.NewTableRegistry()
+		config = server.NewMultiTableTournamentConfig()
+		config.Name = "Test Event"
+		config.TableSize = 4
+		config.Tournament.NumPlayers = 8
+		config.Tournament.Payouts = []int{50, 30, 20}
+	})
+
+	It("takes buy-ins until the tournament is full", func() {
+		config.Tournament.NumPlayers = 2
+		config.Tournament.Payouts = []int{100}
+		m, err := server.NewMultiTableTournament(*config, registry, store)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(m.Register("alice")).To(Succeed())
+		Expect(m.Register("alice")).NotTo(Succeed())
+		Expect(store.GetBankroll("alice")).To(Equal(900))
+		Expect(store.GetStacks("Test Event")).To(Equal(map[string]int{"alice": 100}))
+
+		Expect(m.Unregister("alice")).To(Succeed())
+		Expect(store.GetBankroll("alice")).To(Equal(1000))
+		Expect(m.Unregister("alice")).NotTo(Succeed())
+
+		Expect(m.Register("alice")).To(Succeed())
+		Expect(m.Register("bob")).To(Succeed())
+		Expect(m.Tournament.IsRunning()).To(BeTrue())
+		Expect(m.Register("carol")).NotTo(Succeed())
+		Expect(m.Unregister("alice")).NotTo(Succeed())
+	})
+
+	It("draws seats across tables once the tournament is full", func() {
+		config.Tournament.NumPlayers = 7
+		m := start()
+
+		tables := m.GetTables()
+		Expect(tables).To(HaveLen(2))
+		Expect(seatedPlayers(tables[0])).To(HaveLen(4))
+		Expect(seatedPlayers(tables[1])).To(HaveLen(3))
+
+		seated := make([]string, 0)
+		for _, table := range tables {
+			Expect(registry.GetTable(table.GameState.Config.Name)).To(Equal(table))
+			for _, p := range seatedPlayers(table) {
+				Expect(p.Chips).To(Equal(1500))
+				seated = append(seated, p.Name)
+			}
+		}
+		Expect(seated).To(ConsistOf(m.Tournament.Players))
+	})
+
+	It("moves players from bigger tables to smaller tables", func() {
+		m := start()
+		tables := m.GetTables()
+
+		bust(m, tables[1], 500, 600)
+		server.StartNewHand(tables[1].GameState)
+		Expect(m.Tournament.Eliminated).To(HaveLen(2))
+		Expect(seatedPlayers(tables[1])).To(HaveLen(2))
+
+		server.StartNewHand(tables[0].GameState)
+		Expect(seatedPlayers(tables[0])).To(HaveLen(3))
+		Expect(seatedPlayers(tables[1])).To(HaveLen(3))
+	})
+
+	It("breaks up a table once the players fit at fewer tables", func() {
+		config.Tournament.NumPlayers = 6
+		config.Tournament.Payouts = []int{100}
+		m := start()
+		tables := m.GetTables()
+
+		bust(m, tables[0], 500, 600)
+		server.StartNewHand(tables[0].GameState)
+
+		Expect(m.GetTables()).To(Equal([]*server.RegisteredTable{tables[1]}))
+		Expect(registry.GetTable(tables[0].GameState.Config.Name)).To(BeNil())
+		Expect(seatedPlayers(tables[0])).To(BeEmpty())
+		Expect(seatedPlayers(tables[1])).To(HaveLen(4))
+	})
+
+	It("plays hand for hand on the bubble", func() {
+		config.Tournament.Payouts = []int{30, 20, 15, 15, 10, 10}
+		m := start()
+		tables := m.GetTables()
+
+		first := bust(m, tables[0], 500)
+		server.StartNewHand(tables[0].GameState)
+		Expect(m.Tournament.Eliminated).To(Equal(first))
+
+		// The first table waits for the second table to finish its hand
+		tables[1].GameState.Stage = server.Preflop
+		bubble := bust(m, tables[0], 500)
+		server.StartNewHand(tables[0].GameState)
+		Expect(tables[0].GameState.Stage).To(Equal(server.Waiting))
+		Expect(m.Tournament.Eliminated).To(Equal(first))
+
+		// Both players are knocked out together. The bigger stack finishes higher.
+		second := bust(m, tables[1], 800)
+		server.StartNewHand(tables[1].GameState)
+		Expect(m.Tournament.Eliminated).To(Equal(append(first, bubble[0], second[0])))
+	})
+
+	It("pays out the prize pool once one player has every chip", func() {
+		config.TableSize = 2
+		config.Tournament.NumPlayers = 4
+		config.Tournament.Payouts = []int{70, 30}
+		m := start()
+		tables := m.GetTables()
+
+		fourth := bust(m, tables[0], 500)
+		server.StartNewHand(tables[0].GameState)
+		winner := seatedPlayers(tables[0])[0].Name
+
+		busted := bust(m, tables[1], 800, 900)
+		server.StartNewHand(tables[1].GameState)
+
+		Expect(m.Tournament.IsFinished()).To(BeTrue())
+		Expect(m.Tournament.Eliminated).To(Equal(append(fourth, busted...)))
+		Expect(m.Tournament.Payouts[winner]).To(Equal(280))
+		Expect(m.Tournament.Payouts[busted[1]]).To(Equal(120))
+		Expect(m.GetTables()).To(Equal([]*server.RegisteredTable{tables[0]}))
+
+		Expect(store.GetStacks("Test Event")).To(BeEmpty())
+		Expect(store.GetBankroll(winner)).To(Equal(1180))
+		Expect(store.GetBankroll(busted[1])).To(Equal(1020))
+		Expect(store.GetBankroll(busted[0])).To(Equal(900))
+	})
+
+	It("seats players who register late and lets knocked out players re-enter", func() {
+		config.LateRegistrationLevels = 2
+		config.MinPlayers = 3
+		config.ReEntries = 1
+		config.StartAt = time.Now().Add(10 * time.Millisecond)
+		m, err := server.ScheduleTournament(*config, registry, store)
+		Expect(err).NotTo(HaveOccurred())
+		for _, username := range []string{"alice", "bob", "carol"} {
+			Expect(m.Register(username)).To(Succeed())
+		}
+		Eventually(m.GetTables).Should(HaveLen(1))
+
+		// Late players fill the smallest table before a new table is opened
+		Expect(m.Register("dave")).To(Succeed())
+		Expect(m.Register("eve")).To(Succeed())
+		tables := m.GetTables()
+		Expect(tables).To(HaveLen(2))
+		Expect(seatedPlayers(tables[0])).To(HaveLen(4))
+		Expect(seatedPlayers(tables[1])).To(HaveLen(1))
+		Expect(seatedPlayers(tables[1])[0].Chips).To(Equal(1500))
+		Expect(m.Tournament.GetPrizePool(&config.Tournament)).To(Equal(500))
+
+		busted := bust(m, tables[0], 500)
+		server.StartNewHand(tables[0].GameState)
+		Expect(m.Tournament.Eliminated).To(Equal(busted))
+		Expect(m.Register(busted[0])).To(Succeed())
+		Expect(m.Tournament.Eliminated).To(BeEmpty())
+		Expect(m.Tournament.ReEntries).To(Equal(map[string]int{busted[0]: 1}))
+		Expect(m.Tournament.GetPrizePool(&config.Tournament)).To(Equal(600))
+		Expect(store.GetBankroll(busted[0])).To(Equal(800))
+
+		_, p := findSeat(m, busted[0])
+		Expect(p.Chips).To(Equal(1500))
+		Expect(m.Register(busted[0])).To(MatchError("You are still in the tournament"))
+		p.Chips = 0
+		server.StartNewHand(findTable(m, busted[0]).GameState)
+		Expect(m.Register(busted[0])).To(MatchError("You have no re-entries left"))
+
+		m.Tournament.Level = 2
+		Expect(m.Register("frank")).To(MatchError("The tournament has already started"))
+	})
+
+	It("waits for a table to finish its hand before seating a player who registers late", func() {
+		accounts, err := auth.NewAccounts(auth.NewMemoryUserStore(), []byte("secret"), []string{})
+		Expect(err).NotTo(HaveOccurred())
+		srv := newTableServer(registry, accounts)
+		defer srv.Close()
+		for _, username := range []string{"alice", "bob"} {
+			srv.token(username)
+		}
+		config.LateRegistrationLevels = 1
+		config.MinPlayers = 2
+		config.StartAt = time.Now().Add(10 * time.Millisecond)
+		m, err := server.ScheduleTournament(*config, registry, store)
+		Expect(err).NotTo(HaveOccurred())
+		for _, username := range []string{"alice", "bob"} {
+			Expect(m.Register(username)).To(Succeed())
+		}
+		Eventually(m.GetTables).Should(HaveLen(1))
+
+		// The table does not deal until a player connects. Both players call or check until
+		// the showdown. The updates that alice sees are kept.
+		tableName := m.GetTables()[0].GameState.Config.Name
+		updates := make(chan server.Event, 1024)
+		for _, username := range []string{"alice", "bob"} {
+			_, p := findSeat(m, username)
+			conn := srv.join(username, url.Values{"table": {tableName}})
+			send(conn, "take-seat", map[string]interface{}{"seatID": p.ID})
+			if username == "alice" {
+				go playHands(conn, p.ID, updates)
+			} else {
+				go playHands(conn, p.ID, nil)
+			}
+		}
+
+		waitForUpdate := func(match func(server.Event) bool) {
+			timeout := time.After(readTimeout)
+			for {
+				select {
+				case e := <-updates:
+					if isUpdate(e) && match(e) {
+						return
+					}
+				case <-timeout:
+					Fail("The update did not arrive")
+				}
+			}
+		}
+
+		// The table stays locked while the players see the hands at showdown, once both hands
+		// have been shown
+		waitForUpdate(func(e server.Event) bool {
+			if e.Params["stage"] != "Showdown" {
+				return false
+			}
+			actionBar := e.Params["actionBar"].(map[string]interface{})
+			for _, a := range actionBar["actions"].([]interface{}) {
+				if a == "show-hand" {
+					return false
+				}
+			}
+			return true
+		})
+		registered := make(chan error, 1)
+		go func() {
+			registered <- m.Register("carol")
+		}()
+		Consistently(registered, 500*time.Millisecond).ShouldNot(Receive())
+		Eventually(registered, 5*time.Second).Should(Receive(BeNil()))
+
+		waitForUpdate(func(e server.Event) bool {
+			for _, p := range e.Params["players"].([]interface{}) {
+				if p.(map[string]interface{})["name"] == "carol" {
+					Expect(p).To(HaveKeyWithValue("status", "sitting-out"))
+					return true
+				}
+			}
+			return false
+		})
+	})
+
+	It("adds the chips that players rebuy before their next hand", func() {
+		config.RebuyLevels = 1
+		config.Rebuys = 1
+		m := start()
+		table := m.GetTables()[0]
+		players := seatedPlayers(table)
+
+		Expect(m.Rebuy(players[0].Name)).To(Succeed())
+		Expect(m.Rebuy(players[0].Name)).To(MatchError("You already rebought for your next hand"))
+		Expect(store.GetBankroll(players[0].Name)).To(Equal(800))
+		Expect(m.Tournament.GetPrizePool(&config.Tournament)).To(Equal(900))
+
+		// Players who lost their chips are not knocked out if they rebought
+		bust(m, table, 500, 600)
+		Expect(m.Rebuy(players[1].Name)).To(Succeed())
+		server.StartNewHand(table.GameState)
+		Expect(players[0].Chips).To(Equal(1500))
+		Expect(players[1].Chips).To(Equal(1500))
+		Expect(m.Tournament.Eliminated).To(BeEmpty())
+
+		Expect(m.Rebuy(players[0].Name)).To(MatchError("You have no rebuys left"))
+		players[2].Chips = 3000
+		Expect(m.Rebuy(players[2].Name)).To(MatchError("You can only rebuy once you have 1500 chips or fewer"))
+
+		m.Tournament.Level = 1
+		Expect(m.Rebuy(players[3].Name)).To(MatchError("Rebuys are closed"))
+	})
+})
+
+var _ = Describe("MultiTableTournamentConfig", func() {
+	It("checks that the tables can seat the players", func() {
+		config := server.NewMultiTableTournamentConfig()
+		Expect(config.Validate()).To(Succeed())
+		config.TableSize = 1
+		Expect(config.Validate()).NotTo(Succeed())
+		config.TableSize = 7
+		Expect(config.Validate()).NotTo(Succeed())
+	})
+
+	It("checks the schedule, late registration and rebuys", func() {
+		config := server.NewMultiTableTournamentConfig()
+		config.StartAt = time.Now().Add(time.Hour)
+		Expect(config.Validate()).To(Succeed())
+		config.MinPlayers = 1
+		Expect(config.Validate()).NotTo(Succeed())
+		config.MinPlayers = config.Tournament.NumPlayers + 1
+		Expect(config.Validate()).NotTo(Succeed())
+
+		config = server.NewMultiTableTournamentConfig()
+		config.ReEntries = 1
+		Expect(config.Validate()).NotTo(Succeed())
+		config.LateRegistrationLevels = 2
+		Expect(config.Validate()).To(Succeed())
+
+		config.Rebuys = 1
+		Expect(config.Validate()).NotTo(Succeed())
+		config.RebuyLevels = 2
+		Expect(config.Validate()).To(Succeed())
+		config.RebuyLevels = -1
+		Expect(config.Validate()).NotTo(Succeed())
+	})
+})
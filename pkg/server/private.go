@@ -0,0 +1,427 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/poker"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+// Host events
+const actionApprovePlayer string = "approve-player"
+const actionChangeBlinds string = "change-blinds"
+const actionCloseTable string = "close-table"
+const actionKickPlayer string = "kick-player"
+const actionPauseTable string = "pause-table"
+const actionResumeTable string = "resume-table"
+
+// Longest name allowed for a private table
+const maxTableNameLength = 32
+
+// PrivateTable keeps track of who may sit at a private table and whether the host paused it.
+type PrivateTable struct {
+	Approved map[string]bool `json:"approved"` // Usernames that the host approved to sit
+	IsPaused bool            `json:"isPaused"` // No new hands are dealt while paused
+	Requests []string        `json:"requests"` // Usernames waiting for the host to approve them
+}
+
+// privateTableRequest is the body of a request to create a private table
+type privateTableRequest struct {
+	BigBlind int    `json:"bigBlind"`
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+// NewPrivateTable creates a private table where only the host is approved to sit.
+func NewPrivateTable(host string) *PrivateTable {
+	return &PrivateTable{
+		Approved: map[string]bool{host: true},
+		Requests: make([]string, 0),
+	}
+}
+
+// CreatePrivateTable creates a cash game that players can only join with the invite code or
+// the password.
+//
+// The player who creates the table is the host. The host decides who may sit and can pause,
+// change the blinds, kick players and close the table. The table is saved so that it is
+// restored after a restart.
+func CreatePrivateTable(registry *TableRegistry, store storage.Store, host string, name string, password string, bigBlind int) (*RegisteredTable, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxTableNameLength {
+		return nil, fmt.Errorf("The table name must be between 1 and %d characters", maxTableNameLength)
+	}
+	if registry.GetTable(name) != nil {
+		return nil, fmt.Errorf("A table named %s already exists", name)
+	}
+	var existing TableConfig
+	if err := store.GetTableConfig(name, &existing); err != storage.ErrTableConfigNotFound {
+		return nil, fmt.Errorf("A table named %s already exists", name)
+	}
+	if bigBlind != 0 {
+		if err := validateBigBlind(bigBlind); err != nil {
+			return nil, err
+		}
+	}
+
+	config := NewTableConfig()
+	useDefaultChatConfig(registry, &config)
+	config.BigBlind = bigBlind
+	config.Host = host
+	config.InviteCode = strings.ToUpper(strings.ReplaceAll(uuid.New().String(), "-", "")[:8])
+	config.Name = name
+	if password != "" {
+		passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		config.PasswordHash = passwordHash
+	}
+	if err := store.SaveTableConfig(name, config); err != nil {
+		return nil, err
+	}
+
+	g := NewGameState(config, store)
+	g.registry = registry
+	hub := NewHub()
+	go hub.Run()
+	return registry.AddTable(hub, g), nil
+}
+
+// RestorePrivateTables adds the private tables that were open before the server restarted.
+// The hand that was in progress at each table is resumed.
+func RestorePrivateTables(registry *TableRegistry, store storage.Store) error {
+	saved, err := store.ListTableConfigs()
+	if err != nil {
+		return err
+	}
+
+	for _, data := range saved {
+		var config TableConfig
+		if err := json.Unmarshal(data, &config); err != nil {
+			return err
+		}
+		if config.Host == "" || registry.GetTable(config.Name) != nil {
+			continue
+		}
+
+		hub := NewHub()
+		go hub.Run()
+		g, err := RecoverGameState(hub, config, store)
+		if err != nil {
+			return err
+		}
+		g.registry = registry
+		registry.AddTable(hub, g)
+	}
+	return nil
+}
+
+// canJoinTable checks if a player can connect to a table. Private tables need the invite code
+// or password, unless the player is the host or already has a seat.
+//
+// The table is locked while it is checked, but not while the password is compared.
+func canJoinTable(g *GameState, username string, code string) bool {
+	g.lock()
+	config := g.Config
+	isSeated := hasSeat(g, username)
+	g.unlock()
+	if config.Host == "" || config.Host == username || isSeated {
+		return true
+	}
+	if code == "" {
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.ToUpper(code)), []byte(config.InviteCode)) == 1 {
+		return true
+	}
+	return config.PasswordHash != nil && bcrypt.CompareHashAndPassword(config.PasswordHash, []byte(code)) == nil
+}
+
+// isApproved checks if the player may sit at the table. Anyone may sit at a public table.
+func isApproved(g *GameState, username string) bool {
+	return g.Private == nil || g.Private.Approved[username]
+}
+
+// requestApproval asks the host to approve a player who wants to sit at a private table
+func requestApproval(c *Client) error {
+	g := c.gameState
+	if isApproved(g, c.username) {
+		return nil
+	}
+	for _, username := range g.Private.Requests {
+		if username == c.username {
+			return fmt.Errorf("The host needs to approve you before you can sit")
+		}
+	}
+
+	g.Private.Requests = append(g.Private.Requests, c.username)
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
+		systemUsername,
+		fmt.Sprintf("%s asked to sit at the table.", c.username),
+	)))
+	broadcastUpdateGameEvent(c)
+	return fmt.Errorf("The host needs to approve you before you can sit")
+}
+
+// checkHost checks that the client is the host of a private table
+func checkHost(c *Client) error {
+	if c.gameState.Private == nil || c.username == "" || c.username != c.gameState.Config.Host {
+		return fmt.Errorf("Only the host can change the table")
+	}
+	return nil
+}
+
+// HandleApprovePlayer lets a player sit at a private table
+func HandleApprovePlayer(c *Client, username string) error {
+	if err := checkHost(c); err != nil {
+		return err
+	}
+	p := c.gameState.Private
+	if p.Approved[username] {
+		return fmt.Errorf("%s can already sit at the table", username)
+	}
+
+	p.Approved[username] = true
+	for i, request := range p.Requests {
+		if request == username {
+			p.Requests = append(p.Requests[:i:i], p.Requests[i+1:]...)
+			break
+		}
+	}
+	logHostChange(c, fmt.Sprintf("The host approved %s to sit at the table.", username))
+	return nil
+}
+
+// HandlePauseTable stops new hands from being dealt. The hand in progress is played out.
+func HandlePauseTable(c *Client) error {
+	if err := checkHost(c); err != nil {
+		return err
+	}
+	g := c.gameState
+	if g.Private.IsPaused {
+		return fmt.Errorf("The table is already paused")
+	}
+
+	g.Private.IsPaused = true
+	message := "The host paused the table."
+	if g.Stage != Waiting {
+		message = "The host paused the table. No new hands will be dealt once this hand is over."
+	}
+	logHostChange(c, message)
+	return nil
+}
+
+// HandleResumeTable deals hands again at a paused table
+func HandleResumeTable(c *Client) error {
+	if err := checkHost(c); err != nil {
+		return err
+	}
+	g := c.gameState
+	if g.Private.IsPaused == false {
+		return fmt.Errorf("The table is not paused")
+	}
+
+	g.Private.IsPaused = false
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(systemUsername, "The host resumed the table.")))
+	if g.Stage == Waiting {
+		StartNewHand(g)
+		sendHoleCardEvents(c.hub)
+		broadcastAnnouncements(c)
+	} else {
+		saveSnapshot(g)
+	}
+	broadcastUpdateGameEvent(c)
+	return nil
+}
+
+// HandleChangeBlinds changes the blinds from the next hand
+func HandleChangeBlinds(c *Client, bigBlind int) error {
+	if err := checkHost(c); err != nil {
+		return err
+	}
+	if err := validateBigBlind(bigBlind); err != nil {
+		return err
+	}
+	g := c.gameState
+	if getBlindLevel(g).BigBlind == bigBlind {
+		return fmt.Errorf("The blinds are already %d/%d", bigBlind/2, bigBlind)
+	}
+
+	g.Config.BigBlind = bigBlind
+	if err := g.Store.SaveTableConfig(g.Config.Name, g.Config); err != nil {
+		return err
+	}
+	message := fmt.Sprintf("The host changed the blinds to %d/%d.", bigBlind/2, bigBlind)
+	if g.Stage == Waiting {
+		g.Table.MinBet = bigBlind
+	} else {
+		message += " The new blinds start with the next hand."
+	}
+	logHostChange(c, message)
+	return nil
+}
+
+// HandleKickPlayer removes a player from a private table and takes away their approval to sit.
+//
+// Players who are kicked during a hand fold or check until the hand is over. Their chips are
+// returned to their bankroll once they leave the seat.
+func HandleKickPlayer(c *Client, seatID string) error {
+	if err := checkHost(c); err != nil {
+		return err
+	}
+	g := c.gameState
+	player := poker.GetPlayerByID(&g.Table, seatID)
+	if player == nil || player.Status == poker.PlayerVacated {
+		return fmt.Errorf("There is no player in that seat")
+	}
+	if player.Name == c.username {
+		return fmt.Errorf("You cannot kick yourself")
+	}
+
+	delete(g.Private.Approved, player.Name)
+	delete(g.SeatChanges, player.ID)
+	for _, client := range c.hub.copyClients() {
+		if client.seatID == player.ID {
+			client.sit("")
+		}
+	}
+	player.IsHuman = false
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
+		systemUsername,
+		fmt.Sprintf("The host removed %s from the table.", player.Name),
+	)))
+
+	if g.Stage == Waiting {
+		cashOut(g, player)
+		vacateSeat(player)
+		offerOpenSeats(c)
+		saveSnapshot(g)
+	} else if g.RunItVote != nil {
+		if g.RunItVote.Votes[player.ID] == 0 {
+			HandleRunIt(newSystemClient(c.hub, g, player.ID), 1)
+		}
+	} else if g.ShowdownReveal.getChoosingSeatID() == player.ID {
+		HandleMuckHand(newSystemClient(c.hub, g, player.ID))
+	} else if g.Stage < Showdown {
+		HandleComputerMove(c)
+	}
+	broadcastUpdateGameEvent(c)
+	return nil
+}
+
+// HandleCloseTable cashes out the players and closes a private table. Everyone at the table
+// is moved to the default table.
+func HandleCloseTable(c *Client) error {
+	if err := checkHost(c); err != nil {
+		return err
+	}
+	g := c.gameState
+	if g.Stage != Waiting {
+		return fmt.Errorf("Pause the table and wait for the hand to finish before closing it")
+	}
+	if g.registry == nil {
+		return fmt.Errorf("The table cannot be closed")
+	}
+
+	// Players can't find the table once it is removed, so no one else joins it while it closes
+	g.registry.RemoveTable(g.Config.Name)
+	for _, p := range getSeatedPlayers(g) {
+		cashOut(g, p)
+		vacateSeat(p)
+	}
+	g.isClosed = true
+	if err := g.Store.DeleteTableConfig(g.Config.Name); err != nil {
+		log.Printf("could not delete table config: %v", err)
+	}
+	saveSnapshot(g)
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(systemUsername, "The host closed the table.")))
+
+	destination := g.registry.GetDefaultTable()
+	if destination == nil {
+		go c.hub.Close(closeConnectionsTimeout)
+		return nil
+	}
+	for _, client := range listClients(c.hub) {
+		requestMove(client, destination, "")
+	}
+
+	// The hub is stopped once everyone has left the table
+	hub := c.hub
+	go func() {
+		hub.connections.Wait()
+		hub.Close(0)
+	}()
+	return nil
+}
+
+// logHostChange posts a change the host made to the chat and sends the new game state
+func logHostChange(c *Client, message string) {
+	if c.gameState.Stage == Waiting {
+		saveSnapshot(c.gameState)
+	}
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(systemUsername, message)))
+	broadcastUpdateGameEvent(c)
+}
+
+func validateBigBlind(bigBlind int) error {
+	if bigBlind < defaultMinBet || bigBlind > defaultChips/2 || bigBlind%2 != 0 {
+		return fmt.Errorf("The big blind must be an even number between %d and %d", defaultMinBet, defaultChips/2)
+	}
+	return nil
+}
+
+// projectPrivateTable creates the private table data for the update game event. Only the host
+// can see the invite code and who is asking to sit.
+func projectPrivateTable(g *GameState, v Viewer) map[string]interface{} {
+	p := g.Private
+	if p == nil {
+		return nil
+	}
+	level := getBlindLevel(g)
+	private := map[string]interface{}{
+		"bigBlind":   level.BigBlind,
+		"host":       g.Config.Host,
+		"inviteCode": nil,
+		"isPaused":   p.IsPaused,
+		"requests":   nil,
+	}
+	if v.IsHost {
+		private["inviteCode"] = g.Config.InviteCode
+		private["requests"] = p.Requests
+	}
+	return private
+}
+
+// ServeCreatePrivateTable creates a private table hosted by the requester.
+func ServeCreatePrivateTable(registry *TableRegistry, store storage.Store, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request) {
+	requester, err := authenticate(accounts, r)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	var req privateTableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	table, err := CreatePrivateTable(registry, store, requester.Username, req.Name, req.Password, req.BigBlind)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"inviteCode": table.GameState.Config.InviteCode,
+		"name":       table.GameState.Config.Name,
+	})
+}
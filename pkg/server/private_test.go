@@ -0,0 +1,147 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/poker"
+	"github.com/richard-to/go-poker/pkg/server"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+var _ = Describe("Private tables", func() {
+	var registry *server.TableRegistry
+	var store *storage.MemoryStore
+
+	BeforeEach(func() {
+		store = storage.NewMemoryStore()
+		registry = server.NewTableRegistry()
+		hub := server.NewHub()
+		go hub.Run()
+		registry.AddTable(hub, server.NewGameState(server.NewTableConfig(), store))
+	})
+
+	It("creates a table hosted by the player", func() {
+		table, err := server.CreatePrivateTable(registry, store, "alice", "Friday Game", "", 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(registry.GetTable("Friday Game")).To(Equal(table))
+
+		g := table.GameState
+		Expect(g.Config.Host).To(Equal("alice"))
+		Expect(g.Config.InviteCode).To(HaveLen(8))
+		Expect(g.Private.Approved).To(Equal(map[string]bool{"alice": true}))
+		Expect(g.Table.MinBet).To(Equal(2))
+
+		var config server.TableConfig
+		Expect(store.GetTableConfig("Friday Game", &config)).To(Succeed())
+		Expect(config.InviteCode).To(Equal(g.Config.InviteCode))
+	})
+
+	It("checks the table name and blinds", func() {
+		_, err := server.CreatePrivateTable(registry, store, "alice", "Main", "", 0)
+		Expect(err).To(MatchError("A table named Main already exists"))
+		_, err = server.CreatePrivateTable(registry, store, "alice", " ", "", 0)
+		Expect(err).To(HaveOccurred())
+		_, err = server.CreatePrivateTable(registry, store, "alice", "Friday Game", "", 3)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("uses the host's blinds and does not deal while paused", func() {
+		table, err := server.CreatePrivateTable(registry, store, "alice", "Friday Game", "", 10)
+		Expect(err).NotTo(HaveOccurred())
+		g := table.GameState
+		seat := g.Table.Seats
+		for i := 0; i < 3; i++ {
+			seat.Player.Name = seat.Player.ID
+			seat.Player.Chips = 100
+			seat.Player.Status = poker.PlayerSittingOut
+			seat.Player.IsHuman = true
+			seat = seat.Next()
+		}
+
+		g.Private.IsPaused = true
+		server.StartNewHand(g)
+		Expect(g.Stage).To(Equal(server.Waiting))
+
+		g.Private.IsPaused = false
+		server.StartNewHand(g)
+		Expect(g.Stage).To(Equal(server.Preflop))
+		Expect(g.Table.MinBet).To(Equal(10))
+		Expect(g.Table.Pot.GetTotal()).To(Equal(15))
+	})
+
+	It("restores the tables after a restart", func() {
+		table, err := server.CreatePrivateTable(registry, store, "alice", "Friday Game", "secret", 0)
+		Expect(err).NotTo(HaveOccurred())
+		table.GameState.Private.Approved["bob"] = true
+		server.StartNewHand(table.GameState)
+
+		restored := server.NewTableRegistry()
+		Expect(server.RestorePrivateTables(restored, store)).To(Succeed())
+		Expect(restored.ListTables()).To(HaveLen(1))
+		g := restored.GetTable("Friday Game").GameState
+		Expect(g.Config.Host).To(Equal("alice"))
+		Expect(g.Private.Approved).To(HaveKey("bob"))
+	})
+
+	Describe("joining", func() {
+		var srv *tableServer
+		var table *server.RegisteredTable
+
+		connect := func(username string, code string) (*http.Response, error) {
+			_, resp, err := srv.dial(username, url.Values{"code": {code}, "table": {"Friday Game"}})
+			return resp, err
+		}
+
+		BeforeEach(func() {
+			var err error
+			table, err = server.CreatePrivateTable(registry, store, "alice", "Friday Game", "secret", 0)
+			Expect(err).NotTo(HaveOccurred())
+			accounts, err := auth.NewAccounts(auth.NewMemoryUserStore(), []byte("secret"), nil)
+			Expect(err).NotTo(HaveOccurred())
+			srv = newTableServer(registry, accounts)
+		})
+
+		AfterEach(func() {
+			srv.Close()
+		})
+
+		It("needs the invite code or password", func() {
+			resp, err := connect("bob", "")
+			Expect(err).To(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+
+			resp, err = connect("carol", "wrong")
+			Expect(err).To(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+
+			_, err = connect("dave", strings.ToLower(table.GameState.Config.InviteCode))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = connect("erin", "secret")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = connect("alice", "")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("moves everyone to the default table once the host closes the table", func() {
+			conn := srv.join("alice", url.Values{"table": {"Friday Game"}})
+			send(conn, "close-table", map[string]interface{}{})
+			readUntil(conn, func(e server.Event) bool {
+				return isUpdate(e) && getTableName(e) == "Main"
+			})
+			Expect(registry.GetTable("Friday Game")).To(BeNil())
+
+			// Players who found the table before it was removed can't join it
+			r := httptest.NewRequest("GET", "/?"+url.Values{"token": {srv.token("bob")}}.Encode(), nil)
+			w := httptest.NewRecorder()
+			server.ServeWs(table.Hub, table.GameState, srv.accounts, w, r)
+			Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+		})
+	})
+})
@@ -0,0 +1,312 @@
+package server
+
+import (
+	"sync/atomic"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+// ViewerRole is the role of a client that is viewing the table
+type ViewerRole int
+
+// Viewer roles
+const (
+	ViewerSpectator ViewerRole = iota
+	ViewerPlayer
+	ViewerAdmin
+)
+
+func (r ViewerRole) String() string {
+	return [...]string{"spectator", "player", "admin"}[r]
+}
+
+// Viewer is a client that is viewing the table.
+//
+// The game state is projected separately for each viewer so that clients only
+// receive the data they are allowed to see.
+//
+// - Players can see their own hole cards and hole cards that have been shown
+// - Spectators can only see hole cards that have been shown
+// - Spectators can see all hole cards if the table delays what they see
+// - Admins can see all hole cards, unless they take a seat
+type Viewer struct {
+	Ignored       map[string]bool // Lowercase usernames of the players whose messages the viewer does not want
+	IsHost        bool            // Host of a private table
+	PeerID        string
+	Role          ViewerRole
+	SeatID        string
+	ShowHoleCards bool
+}
+
+// Peer is the public information about a client that is connected to the table.
+//
+// The peer ID is used for WebRTC signaling. The private client ID is never shared.
+type Peer struct {
+	AutoMuck    bool
+	ID          string
+	IsSpectator bool // Peers who have joined the table without taking a seat. Admins are not counted.
+	Muted       bool
+	SeatID      string
+}
+
+// ProjectGameState creates an update game event that only contains what the viewer is allowed to see.
+func ProjectGameState(g *GameState, peers []Peer, v Viewer) Event {
+	var actionBar map[string]interface{}
+
+	players := make([]map[string]interface{}, 0)
+	seats := g.Table.Seats
+
+	numSpectators := 0
+	peerSeatMap := make(map[string]Peer)
+	for _, peer := range peers {
+		if peer.SeatID != "" {
+			peerSeatMap[peer.SeatID] = peer
+		}
+		if peer.IsSpectator {
+			numSpectators++
+		}
+	}
+
+	if g.Stage == Waiting {
+		// Players data
+		for i := 0; i < seats.Len(); i++ {
+			players = append(players, map[string]interface{}{
+				"autoMuck":   projectAutoMuck(v, peerSeatMap[seats.Player.ID]),
+				"bounty":     projectBounty(g, seats.Player),
+				"chips":      seats.Player.Chips,
+				"chipsInPot": nil,
+				"hasFolded":  seats.Player.HasFolded,
+				"holeCards":  [2]*poker.Card{},
+				"hud":        projectHUD(v, g, seats.Player),
+				"id":         seats.Player.ID,
+				"isActive":   false,
+				"isDealer":   false,
+				"muted":      peerSeatMap[seats.Player.ID].Muted,
+				"name":       seats.Player.Name,
+				"status":     seats.Player.Status.String(),
+			})
+			seats = seats.Next()
+		}
+
+		// Actions data
+		actionBar = map[string]interface{}{
+			"actions":        []string{},
+			"callAmount":     0,
+			"chipsInPot":     0,
+			"maxRaiseAmount": 0,
+			"minBetAmount":   0,
+			"minRaiseAmount": 0,
+			"totalChips":     0,
+		}
+	} else {
+		// Players data. At showdown, the player choosing to show or muck is active.
+		activePlayer := g.CurrentSeat.Player
+		if seatID := g.ShowdownReveal.getChoosingSeatID(); seatID != "" {
+			activePlayer = g.PlayerMap[seatID]
+		}
+		for i := 0; i < seats.Len(); i++ {
+			players = append(players, map[string]interface{}{
+				"autoMuck":   projectAutoMuck(v, peerSeatMap[seats.Player.ID]),
+				"bounty":     projectBounty(g, seats.Player),
+				"chips":      seats.Player.Chips,
+				"chipsInPot": g.BettingRound.Bets[seats.Player.ID],
+				"hasFolded":  seats.Player.HasFolded,
+				"holeCards":  projectHoleCards(v, seats.Player),
+				"hud":        projectHUD(v, g, seats.Player),
+				"id":         seats.Player.ID,
+				"isActive":   seats.Player.ID == activePlayer.ID,
+				"isDealer":   seats.Player.ID == g.Table.Dealer.Player.ID,
+				"muted":      peerSeatMap[seats.Player.ID].Muted,
+				"name":       seats.Player.Name,
+				"status":     seats.Player.Status.String(),
+			})
+			seats = seats.Next()
+		}
+
+		// Actions data
+
+		// If the player does not have enough chips to meet the call amount, then set the max raise
+		// to the player's remaining chips/
+		callRemainingAmount := g.BettingRound.CallAmount - g.BettingRound.Bets[activePlayer.ID]
+		maxRaiseAmount := activePlayer.Chips - callRemainingAmount
+		if maxRaiseAmount < 0 {
+			maxRaiseAmount = activePlayer.Chips
+		}
+
+		// If the min raise amount is less than the max raise amount, then use the max raise
+		// as the min raise. Essentially this means that the play must go all in if they were
+		// to raise.
+		minRaiseAmount := g.BettingRound.RaiseByAmount
+		if minRaiseAmount > maxRaiseAmount {
+			minRaiseAmount = maxRaiseAmount
+		}
+
+		actionBar = map[string]interface{}{
+			"actions":        GetActions(g),
+			"callAmount":     g.BettingRound.CallAmount,
+			"chipsInPot":     g.BettingRound.Bets[activePlayer.ID],
+			"maxRaiseAmount": maxRaiseAmount,
+			"minBetAmount":   g.Table.MinBet,
+			"minRaiseAmount": minRaiseAmount,
+			"seatID":         activePlayer.ID,
+			"totalChips":     activePlayer.Chips,
+		}
+		if g.ShowdownReveal.getChoosingSeatID() != "" {
+			actionBar["actions"] = []string{actionShowHand, actionMuckHand}
+		}
+	}
+
+	// Table data
+	boards := g.Boards
+	if boards == nil {
+		boards = []poker.Board{}
+	}
+	table := map[string]interface{}{
+		"boards": boards,
+		"flop":   g.Table.Flop,
+		"name":   g.Config.Name,
+		"pot":    g.Table.Pot.GetTotal(),
+		"river":  g.Table.River,
+		"turn":   g.Table.Turn,
+	}
+
+	// Uncontested winners and players who mucked can show their cards until the next hand ends
+	showCardsSeatID := ""
+	if v.SeatID != "" && getShowableHand(g, v.SeatID) != nil {
+		showCardsSeatID = v.SeatID
+	}
+
+	// Run it vote data
+	var runItVote map[string]interface{}
+	if g.RunItVote != nil {
+		runItVote = map[string]interface{}{
+			"maxTimes": g.Config.RunItMaxTimes,
+			"votes":    g.RunItVote.Votes,
+		}
+	}
+
+	return Event{
+		Action: actionUpdateGame,
+		Params: map[string]interface{}{
+			"actionBar":     actionBar,
+			"chat":          projectChat(g, v),
+			"clientSeatMap": createPeerSeatMap(peers, v),
+			"players":       players,
+			"private":       projectPrivateTable(g, v),
+			"role":          v.Role.String(),
+			"runItVote":     runItVote,
+			"seatOffers":    g.SeatOffers,
+			"showCardsSeat": showCardsSeatID,
+			"spectators": map[string]interface{}{
+				"count": numSpectators,
+				"delay": g.Config.SpectatorDelay.Seconds(),
+			},
+			"stage":      g.Stage.String(),
+			"table":      table,
+			"tournament": projectTournament(g),
+			"waitlist":   g.Waitlist,
+		},
+	}
+}
+
+// broadcastUpdateGameEvent sends each client their own projection of the game state
+func broadcastUpdateGameEvent(c *Client) {
+	// The table stays locked while the hand pauses, so the summary is published with each update
+	c.gameState.publishSummary()
+
+	clients := c.hub.copyClients()
+	peers := createPeers(clients)
+	clientEvents := make(map[string]Event)
+	epochs := make(map[string]uint64)
+	for id, client := range clients {
+		clientEvents[id] = ProjectGameState(client.gameState, peers, createViewer(client))
+		epochs[id] = atomic.LoadUint64(&client.delayEpoch)
+	}
+	c.hub.broadcastEvent(BroadcastEvent{
+		ClientEvents: clientEvents,
+		epochs:       epochs,
+	})
+}
+
+// projectHoleCards gets the hole cards of a player that the viewer is allowed to see
+func projectHoleCards(v Viewer, p *poker.Player) [2]*poker.Card {
+	holeCards := [2]*poker.Card{}
+	if v.Role == ViewerAdmin || v.ShowHoleCards || (v.Role == ViewerPlayer && v.SeatID == p.ID) {
+		return p.HoleCards
+	}
+	if p.HasFolded {
+		return holeCards
+	}
+	for i, isShown := range p.ShownCards {
+		if isShown {
+			holeCards[i] = p.HoleCards[i]
+		}
+	}
+	return holeCards
+}
+
+// projectAutoMuck only lets players see their own auto muck preference
+func projectAutoMuck(v Viewer, peer Peer) bool {
+	if v.Role == ViewerPlayer && v.SeatID == peer.SeatID {
+		return peer.AutoMuck
+	}
+	return false
+}
+
+// createPeerSeatMap maps peer IDs to seat IDs for WebRTC streams.
+//
+// Players need every peer since they stream their video to everyone at the table.
+// Everyone else only needs the peers who are seated so they can match streams to seats.
+func createPeerSeatMap(peers []Peer, v Viewer) map[string]string {
+	peerSeatMap := make(map[string]string)
+	for _, peer := range peers {
+		if v.Role == ViewerSpectator && peer.SeatID == "" && peer.ID != v.PeerID {
+			continue
+		}
+		peerSeatMap[peer.ID] = peer.SeatID
+	}
+	return peerSeatMap
+}
+
+// createViewer gets what the client is allowed to see. Admins who take a seat only see what the
+// other players see.
+func createViewer(c *Client) Viewer {
+	role := ViewerSpectator
+	if c.seatID != "" {
+		role = ViewerPlayer
+	} else if c.isAdmin {
+		role = ViewerAdmin
+	}
+	config := c.gameState.Config
+	return Viewer{
+		Ignored:       c.ignored,
+		IsHost:        c.username != "" && c.username == config.Host,
+		PeerID:        c.peerID,
+		Role:          role,
+		SeatID:        c.seatID,
+		ShowHoleCards: role == ViewerSpectator && config.SpectatorDelay > 0 && config.ShowHoleCardsToSpectators,
+	}
+}
+
+func createPeers(clients map[string]*Client) []Peer {
+	peers := make([]Peer, 0)
+	for _, c := range clients {
+		peers = append(peers, Peer{
+			AutoMuck:    c.autoMuck,
+			ID:          c.peerID,
+			IsSpectator: c.username != "" && c.seatID == "" && c.isAdmin == false,
+			Muted:       c.muted,
+			SeatID:      c.seatID,
+		})
+	}
+	return peers
+}
+
+func findClientByPeerID(clients map[string]*Client, peerID string) *Client {
+	for _, c := range clients {
+		if c.peerID == peerID {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package server_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/poker"
+	"github.com/richard-to/go-poker/pkg/server"
+	"github.com/richard-to/go-poker/pkg/stats"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+// getHoleCards gets the hole cards of each seat from an update game event
+func getHoleCards(e server.Event) map[string][2]*poker.Card {
+	holeCards := make(map[string][2]*poker.Card)
+	for _, p := range e.Params["players"].([]map[string]interface{}) {
+		holeCards[p["id"].(string)] = p["holeCards"].([2]*poker.Card)
+	}
+	return holeCards
+}
+
+// advanceStage deals the next street and moves the game to the next stage
+func advanceStage(g *server.GameState) {
+	if g.Stage == server.Preflop {
+		poker.DealFlop(&g.Deck, &g.Table)
+	} else if g.Stage == server.Flop {
+		poker.DealTurn(&g.Deck, &g.Table)
+	} else if g.Stage == server.Turn {
+		poker.DealRiver(&g.Deck, &g.Table)
+	}
+	g.Stage++
+}
+
+var _ = Describe("ProjectGameState", func() {
+	var g *server.GameState
+	var ps []*poker.Player
+	var peers []server.Peer
+
+	BeforeEach(func() {
+		g = server.NewGameState(server.NewTableConfig(), storage.NewMemoryStore())
+		ps = make([]*poker.Player, 0)
+		seat := g.Table.Seats
+		for i := 0; i < 3; i++ {
+			seat.Player.Name = seat.Player.ID
+			seat.Player.Chips = 100
+			seat.Player.Status = poker.PlayerSittingOut
+			seat.Player.IsHuman = true
+			ps = append(ps, seat.Player)
+			seat = seat.Next()
+		}
+		server.StartNewHand(g)
+
+		peers = []server.Peer{
+			{ID: "peer-0", SeatID: ps[0].ID},
+			{ID: "peer-1", SeatID: ps[1].ID},
+			{ID: "peer-2", SeatID: ps[2].ID},
+			{ID: "peer-3"},
+			{ID: "peer-4"},
+		}
+	})
+
+	Context("when the hand is in progress", func() {
+		It("never sends hole cards to the wrong viewer", func() {
+			player := server.Viewer{PeerID: "peer-0", Role: server.ViewerPlayer, SeatID: ps[0].ID}
+			spectator := server.Viewer{PeerID: "peer-3", Role: server.ViewerSpectator}
+			admin := server.Viewer{PeerID: "peer-4", Role: server.ViewerAdmin}
+
+			for g.Stage < server.Showdown {
+				playerCards := getHoleCards(server.ProjectGameState(g, peers, player))
+				Expect(playerCards[ps[0].ID]).To(Equal(ps[0].HoleCards))
+				Expect(playerCards[ps[1].ID]).To(Equal([2]*poker.Card{}))
+				Expect(playerCards[ps[2].ID]).To(Equal([2]*poker.Card{}))
+
+				spectatorCards := getHoleCards(server.ProjectGameState(g, peers, spectator))
+				for _, p := range ps {
+					Expect(spectatorCards[p.ID]).To(Equal([2]*poker.Card{}))
+				}
+
+				adminCards := getHoleCards(server.ProjectGameState(g, peers, admin))
+				for _, p := range ps {
+					Expect(adminCards[p.ID]).To(Equal(p.HoleCards))
+				}
+
+				advanceStage(g)
+			}
+		})
+	})
+
+	Context("when a player has mucked at showdown", func() {
+		It("only sends hole cards that were shown", func() {
+			for g.Stage < server.Showdown {
+				advanceStage(g)
+			}
+			ps[1].HasFolded = true
+			ps[2].ShowHoleCards()
+
+			spectator := server.Viewer{PeerID: "peer-3", Role: server.ViewerSpectator}
+			spectatorCards := getHoleCards(server.ProjectGameState(g, peers, spectator))
+			Expect(spectatorCards[ps[0].ID]).To(Equal([2]*poker.Card{}))
+			Expect(spectatorCards[ps[1].ID]).To(Equal([2]*poker.Card{}))
+			Expect(spectatorCards[ps[2].ID]).To(Equal(ps[2].HoleCards))
+
+			player := server.Viewer{PeerID: "peer-1", Role: server.ViewerPlayer, SeatID: ps[1].ID}
+			playerCards := getHoleCards(server.ProjectGameState(g, peers, player))
+			Expect(playerCards[ps[0].ID]).To(Equal([2]*poker.Card{}))
+			Expect(playerCards[ps[1].ID]).To(Equal(ps[1].HoleCards))
+			Expect(playerCards[ps[2].ID]).To(Equal(ps[2].HoleCards))
+		})
+	})
+
+	Context("when the players are all in", func() {
+		It("shows the hands of the players who are all in", func() {
+			ps[0].HasFolded = true
+			ps[1].ShowHoleCards()
+			ps[2].ShowHoleCards()
+
+			spectator := server.Viewer{PeerID: "peer-3", Role: server.ViewerSpectator}
+			for g.Stage < server.Showdown {
+				spectatorCards := getHoleCards(server.ProjectGameState(g, peers, spectator))
+				Expect(spectatorCards[ps[0].ID]).To(Equal([2]*poker.Card{}))
+				Expect(spectatorCards[ps[1].ID]).To(Equal(ps[1].HoleCards))
+				Expect(spectatorCards[ps[2].ID]).To(Equal(ps[2].HoleCards))
+				advanceStage(g)
+			}
+		})
+	})
+
+	Context("when the viewer is a spectator", func() {
+		It("only includes seated peers and the spectator", func() {
+			spectator := server.Viewer{PeerID: "peer-3", Role: server.ViewerSpectator}
+			e := server.ProjectGameState(g, peers, spectator)
+			Expect(e.Params["clientSeatMap"]).To(Equal(map[string]string{
+				"peer-0": ps[0].ID,
+				"peer-1": ps[1].ID,
+				"peer-2": ps[2].ID,
+				"peer-3": "",
+			}))
+		})
+	})
+
+	Context("when spectators can see hole cards after a delay", func() {
+		It("sends every hole card to the spectator", func() {
+			spectator := server.Viewer{PeerID: "peer-3", Role: server.ViewerSpectator, ShowHoleCards: true}
+			spectatorCards := getHoleCards(server.ProjectGameState(g, peers, spectator))
+			for _, p := range ps {
+				Expect(spectatorCards[p.ID]).To(Equal(p.HoleCards))
+			}
+		})
+	})
+
+	Context("when there are spectators", func() {
+		It("counts the spectators who have joined", func() {
+			peers[3].IsSpectator = true
+			spectator := server.Viewer{PeerID: "peer-3", Role: server.ViewerSpectator}
+			e := server.ProjectGameState(g, peers, spectator)
+			Expect(e.Params["spectators"].(map[string]interface{})["count"]).To(Equal(1))
+		})
+	})
+
+	Context("when players have stats", func() {
+		It("only sends the HUD to players at the table", func() {
+			for _, p := range ps {
+				g.Stats.Load(p.Name, nil)
+			}
+
+			player := server.Viewer{PeerID: "peer-0", Role: server.ViewerPlayer, SeatID: ps[0].ID}
+			for _, p := range server.ProjectGameState(g, peers, player).Params["players"].([]map[string]interface{}) {
+				if p["name"] == "" {
+					Expect(p["hud"]).To(BeNil())
+				} else {
+					Expect(p["hud"].(*stats.HUD).Hands).To(Equal(0))
+				}
+			}
+
+			spectator := server.Viewer{PeerID: "peer-3", Role: server.ViewerSpectator}
+			for _, p := range server.ProjectGameState(g, peers, spectator).Params["players"].([]map[string]interface{}) {
+				Expect(p["hud"]).To(BeNil())
+			}
+		})
+	})
+})
+
+var _ = Describe("Seated admins", func() {
+	var g *server.GameState
+	var srv *tableServer
+
+	BeforeEach(func() {
+		users := auth.NewMemoryUserStore()
+		accounts, err := auth.NewAccounts(users, []byte("secret"), []string{})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = accounts.Register("ops", "password")
+		Expect(err).NotTo(HaveOccurred())
+		accounts, err = auth.NewAccounts(users, []byte("secret"), []string{"ops"})
+		Expect(err).NotTo(HaveOccurred())
+
+		store := storage.NewMemoryStore()
+		g = server.NewGameState(server.NewTableConfig(), store)
+		seat := g.Table.Seats
+		for i := 0; i < 2; i++ {
+			Expect(store.OpenBankroll(seat.Player.ID, 1000)).To(Succeed())
+			Expect(store.BuyIn(g.Config.Name, seat.Player.ID, 100)).To(Succeed())
+			seat.Player.Name = seat.Player.ID
+			seat.Player.Chips = 100
+			seat.Player.Status = poker.PlayerSittingOut
+			seat.Player.IsHuman = true
+			seat = seat.Next()
+		}
+		server.StartNewHand(g)
+
+		hub := server.NewHub()
+		go hub.Run()
+		registry := server.NewTableRegistry()
+		registry.AddTable(hub, g)
+		srv = newTableServer(registry, accounts)
+	})
+
+	AfterEach(func() {
+		srv.Close()
+	})
+
+	It("do not see the other players' hole cards", func() {
+		var seatID string
+		for _, p := range g.PlayerMap {
+			if p.Status == poker.PlayerVacated {
+				seatID = p.ID
+				break
+			}
+		}
+
+		conn := srv.join("ops", nil)
+		send(conn, "take-seat", map[string]interface{}{"seatID": seatID})
+
+		e := readUntil(conn, func(e server.Event) bool {
+			return isUpdate(e) && e.Params["role"] == "player"
+		})
+		for _, p := range e.Params["players"].([]interface{}) {
+			player := p.(map[string]interface{})
+			if player["id"] != seatID {
+				Expect(player["holeCards"]).To(Equal([]interface{}{nil, nil}))
+			}
+		}
+	})
+})
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/history"
+	"github.com/richard-to/go-poker/pkg/rating"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+// Players need to play this many hands in a session for it to count towards their rating
+const minRatedHands int = 10
+
+// Number of players shown on the leaderboard by default
+const defaultLeaderboardSize int = 50
+
+// RatingSession keeps track of how players did since the table started dealing hands.
+//
+// The session ends when the table stops dealing hands, when one of its players leaves and
+// when the server shuts down. Ratings are then updated from each player's big blinds won per
+// 100 hands.
+type RatingSession struct {
+	BigBlindsWon map[string]float64 `json:"bigBlindsWon"`
+	Hands        map[string]int     `json:"hands"`
+	StartedAt    time.Time          `json:"startedAt"`
+}
+
+// ServeLeaderboard lists the players with the highest ratings.
+//
+// The number of players can be changed with the limit query param.
+func ServeLeaderboard(store storage.Store, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request) {
+	if _, err := authenticate(accounts, r); err != nil {
+		writeJSONError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	limit := defaultLeaderboardSize
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			writeJSONError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+	}
+
+	ratings, err := store.Ratings().List()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	rating.SortByRating(ratings)
+	if len(ratings) > limit {
+		ratings = ratings[:limit]
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ratings": ratings})
+}
+
+// ServeRatingHistory sends a player's rating and the changes to it.
+//
+// The requester's rating is sent unless another player is chosen with the player query param.
+func ServeRatingHistory(store storage.Store, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request) {
+	requester, err := authenticate(accounts, r)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	playerName := r.URL.Query().Get("player")
+	if playerName == "" {
+		playerName = requester.Username
+	}
+
+	current, err := store.Ratings().Get(playerName)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	changes, err := store.Ratings().ListChanges(playerName)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"changes": changes,
+		"rating":  current,
+	})
+}
+
+// recordSessionHand adds the result of a finished hand to the rating session
+//
+// Tournaments are rated by finishing place instead.
+func recordSessionHand(g *GameState, h *history.HandHistory) {
+	if h.BigBlind == 0 || g.Tournament != nil {
+		return
+	}
+	if g.Session == nil {
+		g.Session = &RatingSession{
+			BigBlindsWon: make(map[string]float64),
+			Hands:        make(map[string]int),
+			StartedAt:    h.StartedAt,
+		}
+	}
+	for _, seat := range h.Seats {
+		g.Session.Hands[seat.Name]++
+		g.Session.BigBlindsWon[seat.Name] += float64(h.GetNetWinnings(seat.PlayerID)) / float64(h.BigBlind)
+	}
+}
+
+// endSession updates the ratings of the players who played enough hands in the session
+func endSession(g *GameState) {
+	session := g.Session
+	if session == nil {
+		return
+	}
+	g.Session = nil
+
+	results := make([]rating.Result, 0)
+	for username, hands := range session.Hands {
+		if hands >= minRatedHands {
+			results = append(results, rating.Result{
+				Score:    session.BigBlindsWon[username] / float64(hands) * 100,
+				Username: username,
+			})
+		}
+	}
+	updateRatings(g, results, rating.ReasonSession)
+}
+
+// leaveSession ends the session when one of its players leaves the table.
+//
+// Players are rated against each other, so the players who stay start a new session.
+func leaveSession(g *GameState, username string) {
+	if g.Session != nil && g.Session.Hands[username] > 0 {
+		endSession(g)
+	}
+}
+
+// updateRatings rates the players from their results and saves the new ratings
+func updateRatings(g *GameState, results []rating.Result, reason string) {
+	if len(results) < 2 {
+		return
+	}
+	ratings := make(map[string]rating.Rating)
+	for _, result := range results {
+		r, err := g.Store.Ratings().Get(result.Username)
+		if err != nil {
+			log.Printf("could not get rating for %s: %v", result.Username, err)
+			return
+		}
+		ratings[result.Username] = r
+	}
+	updated, changes := rating.Update(ratings, results, reason, getTableName(g))
+	if err := g.Store.Ratings().Save(updated, changes); err != nil {
+		log.Printf("could not save ratings: %v", err)
+	}
+}
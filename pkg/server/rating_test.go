@@ -0,0 +1,149 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/poker"
+	"github.com/richard-to/go-poker/pkg/rating"
+	"github.com/richard-to/go-poker/pkg/server"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+var _ = Describe("Ratings", func() {
+	var g *server.GameState
+	var store *storage.MemoryStore
+
+	BeforeEach(func() {
+		store = storage.NewMemoryStore()
+		g = server.NewGameState(server.NewTableConfig(), store)
+		seat := g.Table.Seats
+		for _, name := range []string{"alice", "bob", "carol"} {
+			Expect(store.OpenBankroll(name, 1000)).To(Succeed())
+			Expect(store.BuyIn(g.Config.Name, name, 100)).To(Succeed())
+			seat.Player.Name = name
+			seat.Player.Chips = 100
+			seat.Player.Status = poker.PlayerSittingOut
+			seat.Player.IsHuman = true
+			seat = seat.Next()
+		}
+		server.StartNewHand(g)
+	})
+
+	// leaveTable makes every player leave so that the table stops dealing hands
+	leaveTable := func() {
+		for _, p := range g.PlayerMap {
+			p.IsHuman = false
+		}
+		server.StartNewHand(g)
+		Expect(g.Stage).To(Equal(server.Waiting))
+	}
+
+	It("rates the players who played enough hands when the session ends", func() {
+		g.Session = &server.RatingSession{
+			BigBlindsWon: map[string]float64{"alice": 20, "bob": -15, "carol": -5},
+			Hands:        map[string]int{"alice": 40, "bob": 40, "carol": 5},
+		}
+		leaveTable()
+		Expect(g.Session).To(BeNil())
+
+		alice, err := store.Ratings().Get("alice")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(alice.Rating).To(Equal(rating.DefaultRating + 16))
+		Expect(alice.Games).To(Equal(1))
+
+		bob, _ := store.Ratings().Get("bob")
+		Expect(bob.Rating).To(Equal(rating.DefaultRating - 16))
+
+		carol, _ := store.Ratings().Get("carol")
+		Expect(carol.Games).To(Equal(0))
+
+		changes, _ := store.Ratings().ListChanges("alice")
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].Reason).To(Equal(rating.ReasonSession))
+		Expect(changes[0].Score).To(Equal(50.0))
+	})
+
+	It("ends the session when a player leaves", func() {
+		g.Session = &server.RatingSession{
+			BigBlindsWon: map[string]float64{"alice": 20, "bob": -15, "carol": -5},
+			Hands:        map[string]int{"alice": 40, "bob": 40, "carol": 40},
+		}
+		g.Table.Seats.Player.IsHuman = false
+		server.StartNewHand(g)
+		Expect(g.Stage).To(Equal(server.Preflop))
+		Expect(g.Session).To(BeNil())
+
+		for _, name := range []string{"alice", "bob", "carol"} {
+			r, err := store.Ratings().Get(name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(r.Games).To(Equal(1))
+		}
+	})
+
+	It("ends the session when the server shuts down", func() {
+		g.Session = &server.RatingSession{
+			BigBlindsWon: map[string]float64{"alice": 20, "bob": -20},
+			Hands:        map[string]int{"alice": 40, "bob": 40},
+		}
+		hub := server.NewHub()
+		go hub.Run()
+		server.Shutdown(hub, g, 0)
+		Expect(g.Session).To(BeNil())
+
+		alice, err := store.Ratings().Get("alice")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(alice.Games).To(Equal(1))
+	})
+
+	It("does not rate a session with one player", func() {
+		g.Session = &server.RatingSession{
+			BigBlindsWon: map[string]float64{"alice": 20},
+			Hands:        map[string]int{"alice": 40},
+		}
+		leaveTable()
+		Expect(store.Ratings().List()).To(BeEmpty())
+	})
+
+	It("lists the leaderboard and rating history", func() {
+		g.Session = &server.RatingSession{
+			BigBlindsWon: map[string]float64{"alice": -20, "bob": 15},
+			Hands:        map[string]int{"alice": 40, "bob": 40},
+		}
+		leaveTable()
+
+		accounts, err := auth.NewAccounts(auth.NewMemoryUserStore(), []byte("secret"), nil)
+		Expect(err).NotTo(HaveOccurred())
+		token, err := accounts.Register("alice", "password")
+		Expect(err).NotTo(HaveOccurred())
+
+		get := func(url string, serve func(w http.ResponseWriter, r *http.Request)) map[string]interface{} {
+			r := httptest.NewRequest("GET", url, nil)
+			r.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			serve(w, r)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			body := make(map[string]interface{})
+			Expect(json.Unmarshal(w.Body.Bytes(), &body)).To(Succeed())
+			return body
+		}
+
+		body := get("/api/leaderboard?limit=1", func(w http.ResponseWriter, r *http.Request) {
+			server.ServeLeaderboard(store, accounts, w, r)
+		})
+		ratings := body["ratings"].([]interface{})
+		Expect(ratings).To(HaveLen(1))
+		Expect(ratings[0].(map[string]interface{})["username"]).To(Equal("bob"))
+
+		body = get("/api/ratings/history", func(w http.ResponseWriter, r *http.Request) {
+			server.ServeRatingHistory(store, accounts, w, r)
+		})
+		Expect(body["rating"].(map[string]interface{})["rating"]).To(BeEquivalentTo(1484))
+		Expect(body["changes"]).To(HaveLen(1))
+	})
+})
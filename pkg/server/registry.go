@@ -0,0 +1,239 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// RegisteredTable is a table and the hub that its clients are connected to.
+type RegisteredTable struct {
+	GameState *GameState
+	Hub       *Hub
+}
+
+// TableRegistry keeps track of the tables and tournaments running on the server.
+type TableRegistry struct {
+	defaultTable string // Table that clients join if they don't choose one
+	mu           sync.RWMutex
+	tables       map[string]*RegisteredTable
+	tournaments  map[string]*MultiTableTournament
+}
+
+// tableMove is a client's new table and seat. The seat ID is empty for spectators.
+type tableMove struct {
+	ifSpectating bool // Only move the client if they joined their table without taking a seat
+	seatID       string
+	table        *RegisteredTable
+}
+
+// NewTableRegistry creates an empty table registry.
+func NewTableRegistry() *TableRegistry {
+	return &TableRegistry{
+		tables:      make(map[string]*RegisteredTable),
+		tournaments: make(map[string]*MultiTableTournament),
+	}
+}
+
+// AddTable adds a table to the registry. The first table added is the default table.
+//
+// The table's summary is published first. The table is not shared yet, so it isn't locked.
+func (r *TableRegistry) AddTable(hub *Hub, g *GameState) *RegisteredTable {
+	g.publishSummary()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	table := &RegisteredTable{GameState: g, Hub: hub}
+	r.tables[g.Config.Name] = table
+	if r.defaultTable == "" {
+		r.defaultTable = g.Config.Name
+	}
+	return table
+}
+
+// GetTable gets a table by name. Returns nil if the table does not exist.
+func (r *TableRegistry) GetTable(name string) *RegisteredTable {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.tables[name]
+}
+
+// GetDefaultTable gets the table that clients join if they don't choose one.
+func (r *TableRegistry) GetDefaultTable() *RegisteredTable {
+	return r.GetTable(r.defaultTable)
+}
+
+// ListTables lists the tables sorted by name.
+func (r *TableRegistry) ListTables() []*RegisteredTable {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tables := make([]*RegisteredTable, 0, len(r.tables))
+	for _, table := range r.tables {
+		tables = append(tables, table)
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].GameState.Config.Name < tables[j].GameState.Config.Name
+	})
+	return tables
+}
+
+// RemoveTable removes a table from the registry.
+func (r *TableRegistry) RemoveTable(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tables, name)
+}
+
+// FindPlayerTable finds the table that a player is seated at. Returns nil if the player does
+// not have a seat.
+//
+// Each table is locked while its seats are checked.
+func (r *TableRegistry) FindPlayerTable(username string) *RegisteredTable {
+	for _, table := range r.ListTables() {
+		table.GameState.lock()
+		isSeated := hasSeat(table.GameState, username)
+		table.GameState.unlock()
+		if isSeated {
+			return table
+		}
+	}
+	return nil
+}
+
+// AddTournament adds a multi-table tournament to the registry.
+func (r *TableRegistry) AddTournament(m *MultiTableTournament) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tournaments[m.Config.Name]; ok {
+		return fmt.Errorf("A tournament named %s already exists", m.Config.Name)
+	}
+	r.tournaments[m.Config.Name] = m
+	return nil
+}
+
+// GetTournament gets a multi-table tournament by name. Returns nil if it does not exist.
+func (r *TableRegistry) GetTournament(name string) *MultiTableTournament {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.tournaments[name]
+}
+
+// ListTournaments lists the multi-table tournaments sorted by name.
+func (r *TableRegistry) ListTournaments() []*MultiTableTournament {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tournaments := make([]*MultiTableTournament, 0, len(r.tournaments))
+	for _, m := range r.tournaments {
+		tournaments = append(tournaments, m)
+	}
+	sort.Slice(tournaments, func(i, j int) bool {
+		return tournaments[i].Config.Name < tournaments[j].Config.Name
+	})
+	return tournaments
+}
+
+// listClients copies the clients connected to a hub, so that clients can be moved to another
+// hub while looping through them
+func listClients(hub *Hub) []*Client {
+	copies := hub.copyClients()
+	clients := make([]*Client, 0, len(copies))
+	for _, c := range copies {
+		clients = append(clients, c)
+	}
+	return clients
+}
+
+// requestMove moves a client to another table.
+//
+// The move is made by the client's goroutine once it has finished the event that it is
+// processing, since the event handlers expect the client's table to stay the same.
+func requestMove(c *Client, table *RegisteredTable, seatID string) {
+	c.queueMove(&tableMove{seatID: seatID, table: table})
+}
+
+// requestSpectatorMove moves a client to a seat at another table, as long as they are still
+// watching their table without a seat when the move is made.
+func requestSpectatorMove(c *Client, table *RegisteredTable, seatID string) {
+	c.queueMove(&tableMove{ifSpectating: true, seatID: seatID, table: table})
+}
+
+// moveClient moves a client to another table without closing their websocket connection.
+//
+// Both tables are locked, since the clients at each table are read while the table changes.
+func moveClient(c *Client, move *tableMove) {
+	from := c.hub
+	to := move.table.Hub
+
+	unlock := lockTables(c.gameState, move.table.GameState)
+	defer unlock()
+	if move.ifSpectating && (c.username == "" || c.seatID != "") {
+		return
+	}
+	// The client stays where they are if the table closed before the move was made
+	if move.table.GameState.isClosed {
+		return
+	}
+
+	// The client's connection is being closed if the table was closed
+	if err := from.releaseClient(c); err != nil {
+		return
+	}
+	to.connections.Add(1)
+	from.connections.Done()
+
+	c.gameState = move.table.GameState
+	c.hub = to
+	c.sit(move.seatID)
+	if err := to.registerClient(c); err != nil {
+		// The client is not in either hub, so no hub will close their connection
+		close(c.delayed)
+		return
+	}
+
+	if move.seatID != "" {
+		to.sendToClient(c, createOnTakeSeatEvent(move.seatID, createPeerSeatMap(createPeers(to.copyClients()), createViewer(c))))
+	}
+	to.sendToClient(c, createNewMessageEvent(
+		systemUsername,
+		fmt.Sprintf("You have been moved to %s.", move.table.GameState.Config.Name),
+	))
+	broadcastUpdateGameEvent(c)
+}
+
+// lockTables locks two tables. Tables are locked in order of their names, so that two
+// goroutines locking the same tables can't wait on each other. The multi-table tournaments of
+// the tables are locked after both tables, like lock does for one table.
+func lockTables(a *GameState, b *GameState) func() {
+	if b.Config.Name < a.Config.Name {
+		a, b = b, a
+	}
+	a.mu.Lock()
+	if b != a {
+		b.mu.Lock()
+	}
+
+	tournaments := make([]*MultiTableTournament, 0)
+	for _, g := range []*GameState{a, b} {
+		if g.MTT != nil && (len(tournaments) == 0 || tournaments[0] != g.MTT) {
+			tournaments = append(tournaments, g.MTT)
+		}
+	}
+	if len(tournaments) == 2 && tournaments[1].Config.Name < tournaments[0].Config.Name {
+		tournaments[0], tournaments[1] = tournaments[1], tournaments[0]
+	}
+	for _, m := range tournaments {
+		m.mu.Lock()
+	}
+
+	return func() {
+		a.publishSummary()
+		b.publishSummary()
+		for i := len(tournaments) - 1; i >= 0; i-- {
+			tournaments[i].mu.Unlock()
+		}
+		if b != a {
+			b.mu.Unlock()
+		}
+		a.mu.Unlock()
+	}
+}
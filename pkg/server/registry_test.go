@@ -0,0 +1,67 @@
+package server_test
+
+import (
+	"net/url"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/poker"
+	"github.com/richard-to/go-poker/pkg/server"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+var _ = Describe("TableRegistry", func() {
+	var accounts *auth.Accounts
+	var aliceSeatID string
+	var srv *tableServer
+
+	BeforeEach(func() {
+		var err error
+		accounts, err = auth.NewAccounts(auth.NewMemoryUserStore(), []byte("secret"), nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		store := storage.NewMemoryStore()
+		registry := server.NewTableRegistry()
+		hub := server.NewHub()
+		go hub.Run()
+		registry.AddTable(hub, server.NewGameState(server.NewTableConfig(), store))
+
+		// The table is set up before it is added, since it can be found from then on. Bob lost
+		// their connection, but keeps their seat until the hand is over.
+		config := server.NewTableConfig()
+		config.Name = "Side"
+		side := server.NewGameState(config, store)
+		p := side.Table.Seats.Player
+		p.Name = "bob"
+		p.Chips = 100
+		p.IsHuman = true
+		p.Status = poker.PlayerSittingOut
+		aliceSeatID = side.Table.Seats.Next().Player.ID
+		hub = server.NewHub()
+		go hub.Run()
+		registry.AddTable(hub, side)
+
+		srv = newTableServer(registry, accounts)
+		for _, username := range []string{"alice", "bob", "carol"} {
+			srv.token(username)
+		}
+	})
+
+	AfterEach(func() {
+		srv.Close()
+	})
+
+	It("sends players who reconnect to the table they are seated at", func() {
+		// The table deals while bob reconnects
+		alice := srv.join("alice", url.Values{"table": {"Side"}})
+		send(alice, "take-seat", map[string]interface{}{"seatID": aliceSeatID})
+
+		bob := srv.join("bob", nil)
+		Expect(getTableName(readUntil(bob, isUpdate))).To(Equal("Side"))
+
+		carol := srv.join("carol", nil)
+		Expect(getTableName(readUntil(carol, isUpdate))).To(Equal("Main"))
+	})
+})
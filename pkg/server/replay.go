@@ -0,0 +1,154 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/richard-to/go-poker/pkg/history"
+)
+
+// CreateReplayFrames creates an update game event for each step of a recorded hand.
+//
+// The frames have the same shape as the events sent to the table, so a client can replay
+// the hand with the table view. Each frame also includes a description of the step.
+//
+// Viewers can see their own hole cards and the hole cards that were shown at showdown.
+// Admins can see all hole cards.
+func CreateReplayFrames(h *history.HandHistory, viewerName string, isAdmin bool) ([]Event, error) {
+	frames := make([]Event, 0)
+	err := StreamReplayFrames(h, viewerName, isAdmin, func(frame Event) error {
+		frames = append(frames, frame)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return frames, nil
+}
+
+// StreamReplayFrames creates the same frames as CreateReplayFrames, but sends each frame as
+// soon as it is created. Creating the frames stops if a frame can't be sent.
+func StreamReplayFrames(h *history.HandHistory, viewerName string, isAdmin bool, send func(Event) error) error {
+	r, err := history.NewReplay(h.ForViewer(viewerName, isAdmin))
+	if err != nil {
+		return err
+	}
+
+	if err := send(createReplayFrame(r, 0, fmt.Sprintf("Hand #%s.", h.ID))); err != nil {
+		return err
+	}
+	for step := 1; r.IsDone() == false; step++ {
+		a := r.GetNextAction()
+		if err := r.Next(); err != nil {
+			return err
+		}
+		description := ""
+		if a != nil {
+			description = describeReplayAction(h, *a)
+		} else {
+			description = describeReplayWinnings(h, r.GetWinnings())
+		}
+		if err := send(createReplayFrame(r, step, description)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createReplayFrame projects the replay table using the same format as the update game event
+func createReplayFrame(r *history.Replay, step int, description string) Event {
+	g := &GameState{
+		BettingRound: r.BettingRound,
+		Chat:         NewChat(chatEveryone),
+		Config:       NewTableConfig(),
+		CurrentSeat:  r.Table.Dealer,
+		Stage:        getReplayStage(r),
+		Table:        r.Table,
+	}
+
+	if a := r.GetNextAction(); a != nil {
+		seat := r.Table.Seats
+		for i := 0; i < seat.Len(); i++ {
+			if seat.Player.ID == a.PlayerID {
+				g.CurrentSeat = seat
+			}
+			seat = seat.Next()
+		}
+	}
+
+	if r.IsDone() && len(r.History.Boards) > 1 {
+		g.Boards = r.History.Boards
+	}
+
+	// The hand history has already removed the hole cards the viewer is not allowed to see
+	e := ProjectGameState(g, []Peer{}, Viewer{Role: ViewerSpectator, ShowHoleCards: true})
+	e.Params["replay"] = map[string]interface{}{
+		"description": description,
+		"handID":      r.History.ID,
+		"isDone":      r.IsDone(),
+		"step":        step,
+	}
+	return e
+}
+
+func getReplayStage(r *history.Replay) GameStage {
+	if r.IsDone() && r.Street == history.StreetRiver {
+		return Showdown
+	}
+	switch r.Street {
+	case history.StreetFlop:
+		return Flop
+	case history.StreetTurn:
+		return Turn
+	case history.StreetRiver:
+		return River
+	}
+	return Preflop
+}
+
+// describeReplayAction describes an action using the same wording as the table chat
+func describeReplayAction(h *history.HandHistory, a history.Action) string {
+	name := ""
+	if seat := h.GetSeat(a.PlayerID); seat != nil {
+		name = seat.Name
+	}
+
+	switch a.Type {
+	case history.ActionPostSmallBlind:
+		return fmt.Sprintf("%s posts the small blind ℝ%d.", name, a.Amount)
+	case history.ActionPostBigBlind:
+		return fmt.Sprintf("%s posts the big blind ℝ%d.", name, a.Amount)
+	case history.ActionPostAnte:
+		return fmt.Sprintf("%s posts the ante ℝ%d.", name, a.Amount)
+	case history.ActionFold:
+		return fmt.Sprintf("%s folds.", name)
+	case history.ActionCheck:
+		return fmt.Sprintf("%s checks.", name)
+	case history.ActionCall:
+		return fmt.Sprintf("%s calls.", name)
+	case history.ActionBet:
+		return fmt.Sprintf("%s bets ℝ%d.", name, a.RaiseTo)
+	case history.ActionRaise:
+		return fmt.Sprintf("%s raises to ℝ%d.", name, a.RaiseTo)
+	case history.ActionShow:
+		cards := make([]string, 0)
+		for _, c := range a.Cards {
+			cards = append(cards, c.Symbol())
+		}
+		return fmt.Sprintf("%s shows %s.", name, strings.Join(cards, " "))
+	case history.ActionMuck:
+		return fmt.Sprintf("%s mucks.", name)
+	}
+	return ""
+}
+
+// describeReplayWinnings describes who won chips at the end of the hand
+func describeReplayWinnings(h *history.HandHistory, winnings map[string]int) string {
+	messages := make([]string, 0)
+	for _, seat := range h.Seats {
+		if winnings[seat.PlayerID] > 0 {
+			messages = append(messages, fmt.Sprintf("%s wins ℝ%d.", seat.Name, winnings[seat.PlayerID]))
+		}
+	}
+	return strings.Join(messages, " ")
+}
@@ -0,0 +1,208 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/history"
+	"github.com/richard-to/go-poker/pkg/poker"
+	"github.com/richard-to/go-poker/pkg/server"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+const replayHand = `PokerStars Hand #5001: Hold'em No Limit (1/2) - 2021/02/03 10:00:00 UTC
+Table 'Main' 6-max Seat #1 is the button
+Seat 1: alice (100 in chips)
+Seat 2: bob (100 in chips)
+Seat 3: carol (100 in chips)
+bob: posts small blind 1
+carol: posts big blind 2
+*** HOLE CARDS ***
+alice: raises 4 to 6
+bob: calls 5
+carol: calls 4
+*** FLOP *** [Kd 8s 3c]
+bob: checks
+carol: checks
+alice: bets 10
+bob: calls 10
+carol: folds
+*** TURN *** [Kd 8s 3c] [2h]
+bob: checks
+alice: checks
+*** RIVER *** [Kd 8s 3c 2h] [7d]
+bob: checks
+alice: checks
+*** SHOW DOWN ***
+bob: shows [Kh Qc] (a pair of Kings)
+alice: mucks hand
+bob collected 38 from pot
+*** SUMMARY ***
+Total pot 38 | Rake 0
+Board [Kd 8s 3c 2h 7d]
+`
+
+// newReplayHand creates a recorded hand where every player's hole cards are known
+func newReplayHand() *history.HandHistory {
+	histories, err := history.ParsePokerStars(replayHand)
+	Expect(err).NotTo(HaveOccurred())
+	h := histories[0]
+	h.GetSeatByName("alice").HoleCards = [2]*poker.Card{
+		{Rank: poker.Ace, Suit: poker.Hearts},
+		{Rank: poker.Jack, Suit: poker.Diamonds},
+	}
+	h.GetSeatByName("carol").HoleCards = [2]*poker.Card{
+		{Rank: poker.Nine, Suit: poker.Clubs},
+		{Rank: poker.Nine, Suit: poker.Hearts},
+	}
+	return h
+}
+
+// getFrameHoleCards gets the hole cards of each player by name from a replay frame
+func getFrameHoleCards(e server.Event) map[string][2]*poker.Card {
+	holeCards := make(map[string][2]*poker.Card)
+	for _, p := range e.Params["players"].([]map[string]interface{}) {
+		holeCards[p["name"].(string)] = p["holeCards"].([2]*poker.Card)
+	}
+	return holeCards
+}
+
+var _ = Describe("CreateReplayFrames", func() {
+	It("creates a frame for each step of the hand", func() {
+		frames, err := server.CreateReplayFrames(newReplayHand(), "alice", false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(frames).To(HaveLen(18))
+
+		first := frames[0]
+		Expect(first.Action).To(Equal("update-game"))
+		Expect(first.Params["stage"]).To(Equal("Preflop"))
+		Expect(first.Params["replay"].(map[string]interface{})["description"]).To(Equal("Hand #5001."))
+
+		Expect(frames[3].Params["replay"].(map[string]interface{})["description"]).To(Equal("alice raises to ℝ6."))
+		Expect(frames[3].Params["table"].(map[string]interface{})["pot"]).To(Equal(9))
+
+		last := frames[len(frames)-1]
+		Expect(last.Params["stage"]).To(Equal("Showdown"))
+		Expect(last.Params["replay"].(map[string]interface{})["description"]).To(Equal("bob wins ℝ38."))
+		Expect(last.Params["replay"].(map[string]interface{})["isDone"]).To(BeTrue())
+	})
+
+	It("only shows the viewer's hole cards and cards shown at showdown", func() {
+		frames, err := server.CreateReplayFrames(newReplayHand(), "alice", false)
+		Expect(err).NotTo(HaveOccurred())
+		for _, frame := range frames {
+			holeCards := getFrameHoleCards(frame)
+			Expect(holeCards["alice"][0]).NotTo(BeNil())
+			Expect(holeCards["bob"][0]).NotTo(BeNil())
+			Expect(holeCards["carol"][0]).To(BeNil())
+		}
+	})
+
+	It("shows all hole cards to admins", func() {
+		frames, err := server.CreateReplayFrames(newReplayHand(), "", true)
+		Expect(err).NotTo(HaveOccurred())
+		holeCards := getFrameHoleCards(frames[0])
+		Expect(holeCards["alice"][0]).NotTo(BeNil())
+		Expect(holeCards["carol"][0]).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("Hand API", func() {
+	var accounts *auth.Accounts
+	var store *storage.MemoryStore
+
+	BeforeEach(func() {
+		store = newAPIStore()
+		accounts = newAPIAccounts()
+	})
+
+	listHands := func(w http.ResponseWriter, r *http.Request) {
+		server.ServeHandList(store, accounts, w, r)
+	}
+
+	// getReplay requests the replay of a hand and decodes each line of the response
+	getReplay := func(handID string, token string) (*httptest.ResponseRecorder, []map[string]interface{}) {
+		r := httptest.NewRequest("GET", "/api/hands/"+handID+"/replay", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.ServeHandReplay(store, accounts, w, r, handID)
+		lines := make([]map[string]interface{}, 0)
+		decoder := json.NewDecoder(w.Body)
+		for decoder.More() {
+			line := make(map[string]interface{})
+			Expect(decoder.Decode(&line)).To(Succeed())
+			lines = append(lines, line)
+		}
+		return w, lines
+	}
+
+	It("requires a session", func() {
+		w, _ := getJSON("/api/hands", "", listHands)
+		Expect(w.Code).To(Equal(http.StatusUnauthorized))
+
+		w, _ = getJSON("/api/hands", "unknown", listHands)
+		Expect(w.Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("lists the hands the player took part in", func() {
+		w, body := getJSON("/api/hands", login(accounts, "bob"), listHands)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		hands := body["hands"].([]interface{})
+		Expect(hands).To(HaveLen(1))
+		Expect(hands[0].(map[string]interface{})["id"]).To(Equal("5001"))
+		Expect(hands[0].(map[string]interface{})["winnings"]).To(BeEquivalentTo(38))
+
+		_, body = getJSON("/api/hands", login(accounts, "dave"), listHands)
+		Expect(body["hands"]).To(BeEmpty())
+	})
+
+	It("lets admins list the hands of any player", func() {
+		_, body := getJSON("/api/hands?player=carol", login(accounts, "pitboss"), listHands)
+		Expect(body["hands"]).To(HaveLen(1))
+	})
+
+	It("finds hands from every table", func() {
+		config := server.NewTableConfig()
+		config.Host = "bob"
+		config.Name = "Friends"
+		private := server.NewGameState(config, store)
+		h := newReplayHand()
+		h.ID = "5002"
+		h.TableName = config.Name
+		private.HandHistories.Save(h)
+
+		token := login(accounts, "bob")
+		_, body := getJSON("/api/hands", token, listHands)
+		Expect(body["hands"]).To(HaveLen(2))
+
+		w, _ := getReplay("5002", token)
+		Expect(w.Code).To(Equal(http.StatusOK))
+	})
+
+	It("streams a frame per line", func() {
+		w, frames := getReplay("5001", login(accounts, "carol"))
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("Content-Type")).To(Equal("application/x-ndjson"))
+		Expect(w.Flushed).To(BeTrue())
+		Expect(frames).To(HaveLen(18))
+		for i, frame := range frames {
+			replay := frame["params"].(map[string]interface{})["replay"].(map[string]interface{})
+			Expect(replay["handID"]).To(Equal("5001"))
+			Expect(replay["step"]).To(BeEquivalentTo(i))
+		}
+	})
+
+	It("only replays hands the player took part in", func() {
+		w, lines := getReplay("5001", login(accounts, "dave"))
+		Expect(w.Code).To(Equal(http.StatusNotFound))
+		Expect(lines).To(Equal([]map[string]interface{}{{"error": "Hand not found"}}))
+
+		w, _ = getReplay("5001", login(accounts, "pitboss"))
+		Expect(w.Code).To(Equal(http.StatusOK))
+	})
+})
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+// Most times the board can be run out. More runs could use up the deck when a full table is all in.
+const maxRunItTimes int = 3
+
+// RunItVote keeps track of how many times the players in an all in hand want to run out the board.
+//
+// Every player still in the hand must agree. If the players disagree, the board is run
+// the fewest number of times that was chosen.
+type RunItVote struct {
+	Votes map[string]int // Keyed by seat ID. Zero means the player has not voted yet.
+}
+
+// IsComplete checks if every player has voted.
+func (v *RunItVote) IsComplete() bool {
+	for _, times := range v.Votes {
+		if times == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// GetTimes gets the number of times that all players agreed to run it.
+func (v *RunItVote) GetTimes() int {
+	minTimes := 0
+	for _, times := range v.Votes {
+		if minTimes == 0 || times < minTimes {
+			minTimes = times
+		}
+	}
+	if minTimes < 1 {
+		return 1
+	}
+	return minTimes
+}
+
+// startRunItVote asks the players in the hand how many times they want to run it.
+//
+// Computer controlled players will always choose to run it once.
+func startRunItVote(c *Client) {
+	g := c.gameState
+	vote := &RunItVote{
+		Votes: make(map[string]int),
+	}
+
+	seat := g.Table.Seats
+	for i := 0; i < seat.Len(); i++ {
+		if seat.Player.Status == poker.PlayerActive && seat.Player.HasFolded == false {
+			vote.Votes[seat.Player.ID] = 0
+			if seat.Player.IsHuman == false {
+				vote.Votes[seat.Player.ID] = 1
+			}
+		}
+		seat = seat.Next()
+	}
+
+	g.RunItVote = vote
+	if vote.IsComplete() {
+		finishRunItVote(c)
+		return
+	}
+
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
+		systemUsername,
+		fmt.Sprintf("All in! Players can choose to run it up to %s.", formatRunItTimes(g.Config.RunItMaxTimes)),
+	)))
+}
+
+// HandleRunIt records how many times a player wants to run it
+func HandleRunIt(c *Client, times int) error {
+	g := c.gameState
+	if g.RunItVote == nil {
+		return fmt.Errorf("There is nothing to run out")
+	}
+	if _, ok := g.RunItVote.Votes[c.seatID]; ok == false {
+		return fmt.Errorf("Only players in the hand can choose how many times to run it")
+	}
+	if times < 1 || times > g.Config.RunItMaxTimes {
+		return fmt.Errorf("You can only run it between 1 and %d times", g.Config.RunItMaxTimes)
+	}
+
+	g.RunItVote.Votes[c.seatID] = times
+	journalAction(g, JournalEntry{Action: actionRunIt, SeatID: c.seatID, Value: times})
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
+		systemUsername,
+		fmt.Sprintf("%s wants to run it %s.", g.PlayerMap[c.seatID].Name, formatRunItTimes(times)),
+	)))
+
+	if g.RunItVote.IsComplete() {
+		finishRunItVote(c)
+		broadcastUpdateGameEvent(c)
+		HandleComputerMove(c)
+	} else {
+		broadcastUpdateGameEvent(c)
+	}
+	return nil
+}
+
+// finishRunItVote runs out the board the agreed number of times and goes to the showdown
+func finishRunItVote(c *Client) {
+	g := c.gameState
+	times := g.RunItVote.GetTimes()
+	g.RunItVote = nil
+
+	if times > 1 {
+		if err := runOutBoards(c, times); err != nil {
+			log.Printf("Could not run it %s: %s", formatRunItTimes(times), err)
+			runOutBoard(c)
+		}
+	} else {
+		runOutBoard(c)
+	}
+	showdown(c)
+}
+
+// runOutBoards deals the remaining community cards multiple times. Nothing is dealt if there
+// are not enough cards left in the deck.
+func runOutBoards(c *Client, times int) error {
+	g := c.gameState
+	boards, err := poker.DealBoards(&g.Deck, &g.Table, times)
+	if err != nil {
+		return err
+	}
+
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
+		systemUsername,
+		fmt.Sprintf("Running it %s.", formatRunItTimes(times)),
+	)))
+
+	showAllInHands(g)
+	for i, board := range boards {
+		g.Boards = boards[:i+1]
+		g.Table.SetBoard(board)
+		broadcastUpdateGameEvent(c)
+		c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
+			systemUsername,
+			fmt.Sprintf("Run %d: %s", i+1, formatBoard(board)),
+		)))
+		pause(g, 2*time.Second)
+	}
+	g.Table.SetBoard(boards[0])
+	g.Stage = Showdown
+	return nil
+}
+
+func formatRunItTimes(times int) string {
+	if times == 1 {
+		return "once"
+	} else if times == 2 {
+		return "twice"
+	}
+	return fmt.Sprintf("%d times", times)
+}
+
+func formatBoard(b poker.Board) string {
+	cards := make([]string, 0)
+	for _, card := range b.Flop {
+		cards = append(cards, card.Symbol())
+	}
+	cards = append(cards, b.Turn.Symbol(), b.River.Symbol())
+	return strings.Join(cards, " ")
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+// scheduledTournament is what is saved for a scheduled tournament so that the registrations
+// survive a restart
+type scheduledTournament struct {
+	Config    MultiTableTournamentConfig `json:"config"`
+	Players   []string                   `json:"players"`
+	StartedAt time.Time                  `json:"startedAt"`
+}
+
+// ScheduleTournament creates a multi-table tournament that starts at the start time in its
+// config.
+//
+// Players can register until the start time or until the tournament is full. If fewer than
+// the minimum number of players have registered by the start time, the tournament is
+// cancelled and the buy-ins are refunded. The tournament is saved so that it is restored
+// after a restart.
+func ScheduleTournament(config MultiTableTournamentConfig, registry *TableRegistry, store storage.Store) (*MultiTableTournament, error) {
+	if config.StartAt.IsZero() {
+		return nil, fmt.Errorf("Scheduled tournaments need a start time")
+	}
+	if config.StartAt.Before(time.Now()) {
+		return nil, fmt.Errorf("The start time has already passed")
+	}
+	if registry.GetTournament(config.Name) != nil {
+		return nil, fmt.Errorf("A tournament named %s already exists", config.Name)
+	}
+
+	m, err := NewMultiTableTournament(config, registry, store)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.save(); err != nil {
+		return nil, err
+	}
+	if err := registry.AddTournament(m); err != nil {
+		return nil, err
+	}
+	m.schedule()
+	return m, nil
+}
+
+// RestoreScheduledTournaments adds the scheduled tournaments that were saved before the
+// server restarted.
+//
+// Tournaments that had not started yet keep their registrations. They start straight away if
+// their start time passed while the server was down. Tournaments that had already started are
+// not resumed, so their buy-ins are refunded.
+func RestoreScheduledTournaments(registry *TableRegistry, store storage.Store) error {
+	saved, err := store.ListScheduledTournaments()
+	if err != nil {
+		return err
+	}
+
+	for _, data := range saved {
+		var s scheduledTournament
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+
+		if s.StartedAt.IsZero() == false {
+			if err := store.CashOutTable(s.Config.Name); err != nil {
+				return err
+			}
+			if err := store.DeleteScheduledTournament(s.Config.Name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		m := newMultiTableTournament(s.Config, registry, store)
+		m.Tournament.Players = append(m.Tournament.Players, s.Players...)
+		if err := registry.AddTournament(m); err != nil {
+			return err
+		}
+		m.schedule()
+	}
+	return nil
+}
+
+// isScheduled checks if the tournament starts at a set time instead of once it is full
+func (m *MultiTableTournament) isScheduled() bool {
+	return m.Config.StartAt.IsZero() == false
+}
+
+// schedule starts the tournament once the start time arrives
+func (m *MultiTableTournament) schedule() {
+	time.AfterFunc(time.Until(m.Config.StartAt), m.startOnSchedule)
+}
+
+// startOnSchedule starts the tournament, or cancels it if too few players registered
+func (m *MultiTableTournament) startOnSchedule() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.Tournament
+	if t.HasStarted() || m.isCancelled {
+		return
+	}
+	if len(t.Players) < m.Config.MinPlayers {
+		m.cancel()
+		return
+	}
+
+	// The tournament stays saved so that the buy-ins are refunded if the server restarts
+	m.start()
+	if err := m.save(); err != nil {
+		log.Printf("could not save tournament: %v", err)
+	}
+}
+
+// cancel refunds the buy-ins of a tournament that did not get enough players
+func (m *MultiTableTournament) cancel() {
+	m.isCancelled = true
+	if err := m.store.CashOutTable(m.Config.Name); err != nil {
+		log.Printf("could not refund tournament: %v", err)
+	}
+	if err := m.store.DeleteScheduledTournament(m.Config.Name); err != nil {
+		log.Printf("could not delete tournament: %v", err)
+	}
+}
+
+// save saves the registrations for the tournament
+func (m *MultiTableTournament) save() error {
+	return m.store.SaveScheduledTournament(m.Config.Name, scheduledTournament{
+		Config:    m.Config,
+		Players:   m.Tournament.Players,
+		StartedAt: m.Tournament.StartedAt,
+	})
+}
@@ -0,0 +1,102 @@
+package server_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/server"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+var _ = Describe("ScheduleTournament", func() {
+	var config *server.MultiTableTournamentConfig
+	var registry *server.TableRegistry
+	var store *storage.MemoryStore
+
+	BeforeEach(func() {
+		store = storage.NewMemoryStore()
+		registry = server.NewTableRegistry()
+		config = server.NewMultiTableTournamentConfig()
+		config.Name = "Sunday Event"
+		config.MinPlayers = 3
+		config.StartAt = time.Now().Add(20 * time.Millisecond)
+		config.TableSize = 4
+		config.Tournament.NumPlayers = 8
+	})
+
+	It("starts at the start time with the players who registered", func() {
+		m, err := server.ScheduleTournament(*config, registry, store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(registry.GetTournament("Sunday Event")).To(Equal(m))
+		for _, username := range []string{"alice", "bob", "carol", "dave", "eve"} {
+			Expect(m.Register(username)).To(Succeed())
+		}
+		Expect(m.GetTables()).To(BeEmpty())
+
+		Eventually(m.GetTables).Should(HaveLen(2))
+	})
+
+	It("cancels the tournament and refunds the buy-ins if too few players registered", func() {
+		m, err := server.ScheduleTournament(*config, registry, store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.Register("alice")).To(Succeed())
+		Expect(m.Register("bob")).To(Succeed())
+		Expect(store.GetBankroll("alice")).To(Equal(900))
+
+		Eventually(func() (int, error) {
+			return store.GetBankroll("alice")
+		}).Should(Equal(1000))
+		Expect(m.Register("carol")).To(MatchError("The tournament was cancelled"))
+		Expect(m.GetTables()).To(BeEmpty())
+		Expect(store.GetBankroll("bob")).To(Equal(1000))
+		Expect(store.ListScheduledTournaments()).To(BeEmpty())
+	})
+
+	It("keeps the registrations after a restart", func() {
+		config.StartAt = time.Now().Add(time.Hour)
+		m, err := server.ScheduleTournament(*config, registry, store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.Register("alice")).To(Succeed())
+		Expect(m.Register("bob")).To(Succeed())
+		Expect(m.Register("carol")).To(Succeed())
+		Expect(m.Unregister("carol")).To(Succeed())
+
+		restarted := server.NewTableRegistry()
+		Expect(server.RestoreScheduledTournaments(restarted, store)).To(Succeed())
+		restored := restarted.GetTournament("Sunday Event")
+		Expect(restored).NotTo(BeNil())
+		Expect(restored.Config.StartAt.Equal(config.StartAt)).To(BeTrue())
+		Expect(restored.Tournament.Players).To(Equal([]string{"alice", "bob"}))
+		Expect(store.GetBankroll("alice")).To(Equal(900))
+
+		Expect(restored.Unregister("alice")).To(Succeed())
+		Expect(store.GetBankroll("alice")).To(Equal(1000))
+	})
+
+	It("refunds tournaments that had started before a restart", func() {
+		m, err := server.ScheduleTournament(*config, registry, store)
+		Expect(err).NotTo(HaveOccurred())
+		for _, username := range []string{"alice", "bob", "carol"} {
+			Expect(m.Register(username)).To(Succeed())
+		}
+		Eventually(m.GetTables).ShouldNot(BeEmpty())
+
+		restarted := server.NewTableRegistry()
+		Expect(server.RestoreScheduledTournaments(restarted, store)).To(Succeed())
+		Expect(restarted.GetTournament("Sunday Event")).To(BeNil())
+		Expect(store.GetBankroll("alice")).To(Equal(1000))
+		Expect(store.ListScheduledTournaments()).To(BeEmpty())
+	})
+
+	It("needs a start time in the future", func() {
+		config.StartAt = time.Time{}
+		_, err := server.ScheduleTournament(*config, registry, store)
+		Expect(err).To(HaveOccurred())
+
+		config.StartAt = time.Now().Add(-time.Minute)
+		_, err = server.ScheduleTournament(*config, registry, store)
+		Expect(err).To(MatchError("The start time has already passed"))
+	})
+})
@@ -0,0 +1,193 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/server"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+func TestServer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Server Suite")
+}
+
+// How long tests wait for an event from the server
+const readTimeout = 5 * time.Second
+
+// login gets a session token for the player, registering them if needed
+func login(accounts *auth.Accounts, username string) string {
+	token, err := accounts.Login(username, "password")
+	if err != nil {
+		token, err = accounts.Register(username, "password")
+	}
+	Expect(err).NotTo(HaveOccurred())
+	return token
+}
+
+// newAPIStore creates a store with the replay hand saved to it
+func newAPIStore() *storage.MemoryStore {
+	store := storage.NewMemoryStore()
+	Expect(store.HandHistories().Save(newReplayHand())).To(Succeed())
+	return store
+}
+
+// newAPIAccounts creates accounts where pitboss was registered as an admin at startup
+func newAPIAccounts() *auth.Accounts {
+	users := auth.NewMemoryUserStore()
+	accounts, err := auth.NewAccounts(users, []byte("secret"), []string{})
+	Expect(err).NotTo(HaveOccurred())
+	_, err = accounts.Register("pitboss", "password")
+	Expect(err).NotTo(HaveOccurred())
+	accounts, err = auth.NewAccounts(users, []byte("secret"), []string{"pitboss"})
+	Expect(err).NotTo(HaveOccurred())
+	return accounts
+}
+
+// getJSON sends a GET request to an API handler and decodes the JSON response
+func getJSON(url string, token string, serve func(w http.ResponseWriter, r *http.Request)) (*httptest.ResponseRecorder, map[string]interface{}) {
+	r := httptest.NewRequest("GET", url, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	serve(w, r)
+	body := make(map[string]interface{})
+	Expect(json.Unmarshal(w.Body.Bytes(), &body)).To(Succeed())
+	return w, body
+}
+
+// tableServer serves the tables in a registry over websockets
+type tableServer struct {
+	accounts *auth.Accounts
+	conns    []*websocket.Conn
+	srv      *httptest.Server
+	tokens   map[string]string // Keyed by username
+}
+
+// newTableServer starts a websocket server for the tables in the registry
+func newTableServer(registry *server.TableRegistry, accounts *auth.Accounts) *tableServer {
+	return &tableServer{
+		accounts: accounts,
+		tokens:   make(map[string]string),
+		srv: httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			server.ServeTableWs(registry, accounts, w, r)
+		})),
+	}
+}
+
+// token gets a session token for the player. Tokens are kept, since logging in is slow.
+func (s *tableServer) token(username string) string {
+	if _, ok := s.tokens[username]; ok == false {
+		s.tokens[username] = login(s.accounts, username)
+	}
+	return s.tokens[username]
+}
+
+// dial opens a websocket connection for the player. The query can choose the table and have
+// the code for a private table.
+func (s *tableServer) dial(username string, query url.Values) (*websocket.Conn, *http.Response, error) {
+	q := url.Values{"token": {s.token(username)}}
+	for key, values := range query {
+		q[key] = values
+	}
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.srv.URL, "http")+"?"+q.Encode(), nil)
+	if err == nil {
+		s.conns = append(s.conns, conn)
+	}
+	return conn, resp, err
+}
+
+// join connects the player and joins the game without taking a seat
+func (s *tableServer) join(username string, query url.Values) *websocket.Conn {
+	conn, _, err := s.dial(username, query)
+	Expect(err).NotTo(HaveOccurred())
+	send(conn, "join", map[string]interface{}{})
+	return conn
+}
+
+// Close closes the connections and stops the server
+func (s *tableServer) Close() {
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.srv.Close()
+}
+
+// send sends an event to the server
+func send(conn *websocket.Conn, action string, params map[string]interface{}) {
+	Expect(conn.WriteJSON(server.Event{Action: action, Params: params})).To(Succeed())
+}
+
+// readUntil reads events until one matches
+func readUntil(conn *websocket.Conn, match func(server.Event) bool) server.Event {
+	conn.SetReadDeadline(time.Now().Add(readTimeout))
+	for {
+		var e server.Event
+		Expect(conn.ReadJSON(&e)).To(Succeed())
+		if match(e) {
+			return e
+		}
+	}
+}
+
+// isEvent matches events with the action that were not sent by the dealer
+func isEvent(action string) func(server.Event) bool {
+	return func(e server.Event) bool {
+		return e.Action == action && e.Params["username"] != "System"
+	}
+}
+
+// isUpdate matches game updates
+func isUpdate(e server.Event) bool {
+	return e.Action == "update-game"
+}
+
+// isMessage matches chat messages from the dealer with the text
+func isMessage(message string) func(server.Event) bool {
+	return func(e server.Event) bool {
+		return e.Action == "new-message" && e.Params["username"] == "System" && e.Params["message"] == message
+	}
+}
+
+// getTableName gets the name of the table from a game update
+func getTableName(e server.Event) string {
+	return e.Params["table"].(map[string]interface{})["name"].(string)
+}
+
+// playHands checks or calls whenever it is the seat's turn until the connection closes. Hands
+// are shown at showdown. The events that are read are passed on if there is a channel for them.
+func playHands(conn *websocket.Conn, seatID string, events chan<- server.Event) {
+	defer GinkgoRecover()
+	for {
+		var e server.Event
+		if err := conn.ReadJSON(&e); err != nil {
+			return
+		}
+		if events != nil {
+			events <- e
+		}
+		actionBar, _ := e.Params["actionBar"].(map[string]interface{})
+		if isUpdate(e) == false || actionBar == nil || actionBar["seatID"] != seatID {
+			continue
+		}
+		action := "check"
+		for _, a := range actionBar["actions"].([]interface{}) {
+			if a == "call" || a == "show-hand" {
+				action = a.(string)
+			}
+		}
+		conn.WriteJSON(server.Event{Action: action, Params: map[string]interface{}{}})
+	}
+}
@@ -0,0 +1,319 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+// How long players have to choose to show or muck their hand at showdown
+const showdownChoiceTimeout = 10 * time.Second
+
+// ShowdownReveal keeps track of the players revealing their hands at showdown.
+//
+// Hands are revealed in showdown order. Players whose hands can no longer win choose to show
+// or muck them when it is their turn. Players who don't choose in time fall back on their auto
+// muck setting. Computer controlled players always muck.
+type ShowdownReveal struct {
+	Mucked []string `json:"mucked"` // Seat IDs of the players who mucked
+	Next   int      `json:"next"`   // Index in the order of the player who is revealing their hand
+	Order  []string `json:"order"`  // Seat IDs in showdown order
+}
+
+// getChoosingSeatID gets the seat of the player who is choosing to show or muck. Empty if no
+// one is choosing.
+func (s *ShowdownReveal) getChoosingSeatID() string {
+	if s == nil || s.Next >= len(s.Order) {
+		return ""
+	}
+	return s.Order[s.Next]
+}
+
+// ShowableHand is a hand that was not shown when the last hand ended.
+//
+// Players who win without a showdown or muck a losing hand at showdown can choose to
+// show one or both of their cards until the next hand ends.
+type ShowableHand struct {
+	HandID     string // The hand history the shown cards are recorded in
+	HoleCards  [2]*poker.Card
+	SeatID     string
+	ShownCards [2]bool
+}
+
+// HandleAutoMuck sets whether the player's losing hands are mucked at showdown if they don't
+// choose to show or muck in time
+func HandleAutoMuck(c *Client, autoMuck bool) error {
+	c.autoMuck = autoMuck
+	broadcastUpdateGameEvent(c)
+	return nil
+}
+
+// HandleShowCards shows the cards of a player who won the last hand without a showdown
+// or mucked their hand at showdown
+func HandleShowCards(c *Client, cards []int) error {
+	win := getShowableHand(c.gameState, c.seatID)
+	if win == nil {
+		return fmt.Errorf("You can only show your cards after winning a hand uncontested or mucking at showdown")
+	}
+	if len(cards) == 0 {
+		return fmt.Errorf("No cards were chosen to show")
+	}
+
+	for _, i := range cards {
+		if i < 0 || i >= len(win.HoleCards) {
+			return fmt.Errorf("Invalid card chosen")
+		}
+	}
+
+	cardSymbols := make([]string, 0)
+	shownCards := make([]*poker.Card, 0)
+	for _, i := range cards {
+		if win.ShownCards[i] == false {
+			win.ShownCards[i] = true
+			cardSymbols = append(cardSymbols, win.HoleCards[i].Symbol())
+			shownCards = append(shownCards, win.HoleCards[i])
+		}
+	}
+	if len(cardSymbols) == 0 {
+		return nil
+	}
+	journalAction(c.gameState, JournalEntry{Action: actionShowCards, Cards: cards, SeatID: c.seatID})
+	recordShownCards(c.gameState, win.HandID, c.seatID, shownCards)
+
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
+		systemUsername,
+		fmt.Sprintf("%s shows %s.", c.gameState.PlayerMap[c.seatID].Name, strings.Join(cardSymbols, " ")),
+	)))
+	return nil
+}
+
+// showAllInHands reveals the hands of all players still in the hand.
+//
+// Hands are always shown when players are all in and there is no more betting.
+func showAllInHands(g *GameState) {
+	seat := g.Table.Seats
+	for i := 0; i < seat.Len(); i++ {
+		if seat.Player.Status == poker.PlayerActive && seat.Player.HasFolded == false {
+			seat.Player.ShowHoleCards()
+		}
+		seat = seat.Next()
+	}
+}
+
+// HandleShowHand shows the hand of the player who is choosing to show or muck at showdown
+func HandleShowHand(c *Client) error {
+	return chooseShowOrMuck(c, true)
+}
+
+// HandleMuckHand mucks the hand of the player who is choosing to show or muck at showdown
+func HandleMuckHand(c *Client) error {
+	return chooseShowOrMuck(c, false)
+}
+
+// chooseShowOrMuck reveals the hand of the player who is choosing and moves on to the next
+// player in showdown order
+func chooseShowOrMuck(c *Client, show bool) error {
+	g := c.gameState
+	if c.seatID == "" || g.ShowdownReveal.getChoosingSeatID() != c.seatID {
+		return fmt.Errorf("You can only show or muck when it is your turn at showdown")
+	}
+
+	action := actionMuckHand
+	if show {
+		action = actionShowHand
+	}
+	journalAction(g, JournalEntry{Action: action, SeatID: c.seatID})
+
+	p := g.PlayerMap[c.seatID]
+	if show {
+		showHand(c, p)
+	} else {
+		muckHand(c, p)
+	}
+	g.ShowdownReveal.Next++
+	continueShowdown(c)
+
+	broadcastUpdateGameEvent(c)
+	HandleComputerMove(c)
+	return nil
+}
+
+// showdown reveals the hands of the players still in the hand, then awards the pot
+func showdown(c *Client) {
+	g := c.gameState
+	order := poker.GetShowdownOrder(&g.Table, g.BettingRound.Aggressor)
+	g.ShowdownReveal = &ShowdownReveal{
+		Mucked: make([]string, 0),
+		Order:  make([]string, 0, len(order)),
+	}
+	for _, p := range order {
+		g.ShowdownReveal.Order = append(g.ShowdownReveal.Order, p.ID)
+	}
+	continueShowdown(c)
+}
+
+// continueShowdown reveals hands in showdown order until a player needs to choose to show or
+// muck. A hand that can still win a pot is always shown. The pot is awarded once every hand
+// has been revealed.
+func continueShowdown(c *Client) {
+	g := c.gameState
+	s := g.ShowdownReveal
+	for ; s.Next < len(s.Order); s.Next++ {
+		p := g.PlayerMap[s.Order[s.Next]]
+		if poker.CanMuck(&g.Table, getShownPlayers(g), p) == false {
+			showHand(c, p)
+		} else if p.IsHuman == false {
+			// Journaled, since every seated player is treated as connected while replaying
+			journalAction(g, JournalEntry{Action: actionMuckHand, SeatID: p.ID})
+			muckHand(c, p)
+		} else {
+			c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
+				systemUsername,
+				fmt.Sprintf("%s can show or muck.", p.Name),
+			)))
+			startShowdownTimer(c)
+			return
+		}
+	}
+	finishShowdown(c)
+}
+
+// startShowdownTimer falls back on the player's auto muck setting if they don't choose to show
+// or muck in time. There is no timer while the journal is replayed, since the choice that was
+// made is in the journal.
+func startShowdownTimer(c *Client) {
+	g := c.gameState
+	if g.isReplaying {
+		return
+	}
+	hub := c.hub
+	s := g.ShowdownReveal
+	next := s.Next
+	time.AfterFunc(showdownChoiceTimeout, func() {
+		g.lock()
+		defer g.unlock()
+		if g.isClosed || g.ShowdownReveal != s || s.Next != next {
+			return
+		}
+
+		seatID := s.getChoosingSeatID()
+		autoMuck, ok := createAutoMuckSeatMap(hub.copyClients())[seatID]
+		player := newSystemClient(hub, g, seatID)
+		if ok && autoMuck == false {
+			HandleShowHand(player)
+		} else {
+			HandleMuckHand(player)
+		}
+	})
+}
+
+// finishShowdown awards the pot to the best hands and starts the next hand
+func finishShowdown(c *Client) {
+	g := c.gameState
+	order := make([]*poker.Player, 0)
+	mucked := make([]*poker.Player, 0)
+	for _, seatID := range g.ShowdownReveal.Order {
+		order = append(order, g.PlayerMap[seatID])
+	}
+	for _, seatID := range g.ShowdownReveal.Mucked {
+		mucked = append(mucked, g.PlayerMap[seatID])
+	}
+	recordShowdown(g, order, mucked)
+	handID := getHandID(g)
+
+	broadcastUpdateGameEvent(c)
+	pause(g, 2*time.Second)
+	DetermineWinners(c)
+	pause(g, 1*time.Second)
+	muckedHands := createMuckedHands(handID, mucked)
+	StartNewHand(g)
+	g.MuckedHands = muckedHands
+	offerOpenSeats(c)
+	sendHoleCardEvents(c.hub)
+	broadcastAnnouncements(c)
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(systemUsername, "Starting new hand.")))
+}
+
+// showHand shows a player's hand at showdown
+func showHand(c *Client, p *poker.Player) {
+	p.ShowHoleCards()
+	holeCards, _ := p.PrintHoleCards()
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
+		systemUsername,
+		fmt.Sprintf("%s shows %s.", p.Name, holeCards),
+	)))
+}
+
+// muckHand mucks a player's hand at showdown
+func muckHand(c *Client, p *poker.Player) {
+	c.gameState.ShowdownReveal.Mucked = append(c.gameState.ShowdownReveal.Mucked, p.ID)
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
+		systemUsername,
+		fmt.Sprintf("%s mucks.", p.Name),
+	)))
+}
+
+// getShownPlayers gets the players who have shown their hands at showdown so far
+func getShownPlayers(g *GameState) []*poker.Player {
+	shown := make([]*poker.Player, 0)
+	for _, seatID := range g.ShowdownReveal.Order[:g.ShowdownReveal.Next] {
+		if containsSeatID(g.ShowdownReveal.Mucked, seatID) == false {
+			shown = append(shown, g.PlayerMap[seatID])
+		}
+	}
+	return shown
+}
+
+func containsSeatID(seatIDs []string, seatID string) bool {
+	for _, id := range seatIDs {
+		if id == seatID {
+			return true
+		}
+	}
+	return false
+}
+
+// createMuckedHands keeps the mucked hands so the players can still choose to show them
+func createMuckedHands(handID string, mucked []*poker.Player) []*ShowableHand {
+	hands := make([]*ShowableHand, 0)
+	for _, p := range mucked {
+		if p.IsHuman {
+			hands = append(hands, &ShowableHand{HandID: handID, HoleCards: p.HoleCards, SeatID: p.ID})
+		}
+	}
+	return hands
+}
+
+// getShowableHand gets the hand from the last hand that the player can still show
+func getShowableHand(g *GameState, seatID string) *ShowableHand {
+	if g.UncontestedWin != nil && g.UncontestedWin.SeatID == seatID {
+		return g.UncontestedWin
+	}
+	for _, hand := range g.MuckedHands {
+		if hand.SeatID == seatID {
+			return hand
+		}
+	}
+	return nil
+}
+
+func isPlayerMucked(mucked []*poker.Player, p *poker.Player) bool {
+	for _, player := range mucked {
+		if player == p {
+			return true
+		}
+	}
+	return false
+}
+
+func createAutoMuckSeatMap(clients map[string]*Client) map[string]bool {
+	autoMuckSeatMap := make(map[string]bool)
+	for _, c := range clients {
+		if c.seatID != "" {
+			autoMuckSeatMap[c.seatID] = c.autoMuck
+		}
+	}
+	return autoMuckSeatMap
+}
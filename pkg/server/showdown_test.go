@@ -0,0 +1,172 @@
+package server_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/history"
+	"github.com/richard-to/go-poker/pkg/poker"
+	"github.com/richard-to/go-poker/pkg/server"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+var _ = Describe("Showdown", func() {
+	var config server.TableConfig
+	var g *server.GameState
+	var store *storage.MemoryStore
+	var loserID string
+	var winnerID string
+
+	appendJournal := func(entries ...server.JournalEntry) {
+		for _, e := range entries {
+			data, err := json.Marshal(e)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.AppendJournal(config.Name, data)).To(Succeed())
+		}
+	}
+
+	// recover replays the hand from the journal, so it is played out without pausing for the players
+	recover := func(entries ...server.JournalEntry) (*server.GameState, error) {
+		appendJournal(entries...)
+		hub := server.NewHub()
+		go hub.Run()
+		return server.RecoverGameState(hub, config, store)
+	}
+
+	getShowdownActions := func(handID string) []history.Action {
+		h, err := store.HandHistories().Get(handID)
+		Expect(err).NotTo(HaveOccurred())
+		actions := make([]history.Action, 0)
+		for _, a := range h.Actions {
+			if a.Street == history.StreetShowdown {
+				actions = append(actions, a)
+			}
+		}
+		return actions
+	}
+
+	BeforeEach(func() {
+		config = server.NewTableConfig()
+		store = storage.NewMemoryStore()
+		g = server.NewGameState(config, store)
+		seat := g.Table.Seats
+		for i := 0; i < 3; i++ {
+			Expect(store.OpenBankroll(seat.Player.ID, 1000)).To(Succeed())
+			Expect(store.BuyIn(config.Name, seat.Player.ID, 100)).To(Succeed())
+			seat.Player.Name = seat.Player.ID
+			seat.Player.Chips = 100
+			seat.Player.Status = poker.PlayerSittingOut
+			seat.Player.IsHuman = true
+			seat = seat.Next()
+		}
+		server.StartNewHand(g)
+
+		// The dealer's aces beat the big blind's seven high on a board that can't tie
+		winnerID = g.Table.Dealer.Player.ID
+		loserID = g.Table.BigBlind.Player.ID
+		g.Table.Dealer.Player.HoleCards = [2]*poker.Card{
+			{Rank: poker.Ace, Suit: poker.Spades},
+			{Rank: poker.Ace, Suit: poker.Hearts},
+		}
+		g.Table.BigBlind.Player.HoleCards = [2]*poker.Card{
+			{Rank: poker.Seven, Suit: poker.Clubs},
+			{Rank: poker.Two, Suit: poker.Diamonds},
+		}
+		g.Deck = poker.NewStackedDeck([]poker.Card{
+			{Rank: poker.King, Suit: poker.Spades},
+			{Rank: poker.Queen, Suit: poker.Diamonds},
+			{Rank: poker.Nine, Suit: poker.Clubs},
+			{Rank: poker.Five, Suit: poker.Hearts},
+			{Rank: poker.Three, Suit: poker.Diamonds},
+		})
+		data, err := json.Marshal(g.Snapshot())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.SaveSnapshot(config.Name, data)).To(Succeed())
+
+		// The dealer and the big blind check down to the river, where the big blind calls a bet
+		// from the dealer. The dealer shows first, since they made the last bet.
+		smallBlindID := g.Table.SmallBlind.Player.ID
+		appendJournal(
+			server.JournalEntry{Action: "call", SeatID: winnerID},
+			server.JournalEntry{Action: "fold", SeatID: smallBlindID},
+			server.JournalEntry{Action: "check", SeatID: loserID},
+		)
+		for i := 0; i < 2; i++ {
+			appendJournal(
+				server.JournalEntry{Action: "check", SeatID: loserID},
+				server.JournalEntry{Action: "check", SeatID: winnerID},
+			)
+		}
+		appendJournal(
+			server.JournalEntry{Action: "check", SeatID: loserID},
+			server.JournalEntry{Action: "raise", SeatID: winnerID, Value: 2},
+			server.JournalEntry{Action: "call", SeatID: loserID},
+		)
+	})
+
+	It("waits for the player with the losing hand to show or muck", func() {
+		recovered, err := recover()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recovered.Stage).To(Equal(server.Showdown))
+		Expect(recovered.ShowdownReveal.Order).To(Equal([]string{winnerID, loserID}))
+		Expect(recovered.ShowdownReveal.Next).To(Equal(1))
+		Expect(recovered.PlayerMap[winnerID].ShownCards).To(Equal([2]bool{true, true}))
+		Expect(recovered.PlayerMap[loserID].ShownCards).To(Equal([2]bool{false, false}))
+	})
+
+	It("mucks the losing hand", func() {
+		recovered, err := recover(server.JournalEntry{Action: "muck-hand", SeatID: loserID})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recovered.Stage).To(Equal(server.Preflop))
+		Expect(recovered.MuckedHands).To(HaveLen(1))
+		Expect(recovered.MuckedHands[0].SeatID).To(Equal(loserID))
+
+		actions := getShowdownActions(g.History.ID)
+		Expect(actions).To(HaveLen(2))
+		Expect(actions[0].PlayerID).To(Equal(winnerID))
+		Expect(actions[0].Type).To(Equal(history.ActionShow))
+		Expect(actions[1].PlayerID).To(Equal(loserID))
+		Expect(actions[1].Type).To(Equal(history.ActionMuck))
+	})
+
+	It("records the cards shown after mucking", func() {
+		_, err := recover(
+			server.JournalEntry{Action: "muck-hand", SeatID: loserID},
+			server.JournalEntry{Action: "show-cards", SeatID: loserID, Cards: []int{1}},
+			server.JournalEntry{Action: "show-cards", SeatID: loserID, Cards: []int{0, 1}},
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		actions := getShowdownActions(g.History.ID)
+		Expect(actions).To(HaveLen(4))
+		Expect(actions[2].PlayerID).To(Equal(loserID))
+		Expect(actions[2].Type).To(Equal(history.ActionShow))
+		Expect(actions[2].Cards).To(Equal([]*poker.Card{{Rank: poker.Two, Suit: poker.Diamonds}}))
+		Expect(actions[3].Cards).To(Equal([]*poker.Card{{Rank: poker.Seven, Suit: poker.Clubs}}))
+
+		// Replaying the journal again does not record the cards twice
+		_, err = recover()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(getShowdownActions(g.History.ID)).To(HaveLen(4))
+	})
+
+	It("shows the losing hand", func() {
+		recovered, err := recover(server.JournalEntry{Action: "show-hand", SeatID: loserID})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recovered.Stage).To(Equal(server.Preflop))
+		Expect(recovered.MuckedHands).To(BeEmpty())
+
+		actions := getShowdownActions(g.History.ID)
+		Expect(actions).To(HaveLen(2))
+		Expect(actions[1].PlayerID).To(Equal(loserID))
+		Expect(actions[1].Type).To(Equal(history.ActionShow))
+		Expect(actions[1].Cards).To(HaveLen(2))
+	})
+
+	It("only lets the player whose turn it is choose", func() {
+		_, err := recover(server.JournalEntry{Action: "muck-hand", SeatID: winnerID})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+// How often to check if the hand has finished while shutting down
+const shutdownPollInterval = 100 * time.Millisecond
+
+// How long to wait for websocket connections to close
+const closeConnectionsTimeout = 5 * time.Second
+
+// Shutdown stops the table so that the server can exit without losing any chips.
+//
+// - No new hands are dealt and no one can take a seat
+// - The hand in progress is played out. Disconnected players still fold or check.
+// - If the hand is not over by the deadline, each player's bets are returned to them
+// - Stacks stay at the table, so players get their seats back when the server restarts
+// - The websocket connections are closed once everyone has been told
+//
+// The table is only locked while it is changed, so that the hand can be played out in the
+// meantime.
+func Shutdown(hub *Hub, g *GameState, timeout time.Duration) {
+	c := newSystemClient(hub, g, "")
+
+	g.lock()
+	g.isShuttingDown = true
+	hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
+		systemUsername,
+		"The server is restarting. The current hand will be finished, but no new hands will be dealt.",
+	)))
+	g.unlock()
+
+	deadline := time.Now().Add(timeout)
+	for isHandInProgress(g) && time.Now().Before(deadline) {
+		time.Sleep(shutdownPollInterval)
+	}
+
+	g.lock()
+	if g.Stage != Waiting {
+		refundHand(c)
+	}
+	// The players are rated before the server exits, so the session is not resumed after a restart
+	if g.Session != nil {
+		endSession(g)
+		saveSnapshot(g)
+	}
+	hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
+		systemUsername,
+		"The server is restarting now. Your chips have been saved.",
+	)))
+	g.isClosed = true
+	g.unlock()
+
+	// Closing the connections disconnects the players, which needs the table to be unlocked
+	hub.Close(closeConnectionsTimeout)
+}
+
+// ShutdownTables shuts down every table in the registry at the same time, so that each table
+// has the whole timeout to finish its hand.
+func ShutdownTables(registry *TableRegistry, timeout time.Duration) {
+	var wg sync.WaitGroup
+	for _, table := range registry.ListTables() {
+		wg.Add(1)
+		go func(table *RegisteredTable) {
+			defer wg.Done()
+			Shutdown(table.Hub, table.GameState, timeout)
+		}(table)
+	}
+	wg.Wait()
+}
+
+// isHandInProgress checks if the table is still playing a hand
+func isHandInProgress(g *GameState) bool {
+	g.lock()
+	defer g.unlock()
+	return g.Stage != Waiting
+}
+
+// refundHand cancels the hand in progress and returns each player's bets
+func refundHand(c *Client) {
+	g := c.gameState
+	for p, betAmount := range g.Table.Pot.Bets {
+		p.Chips += betAmount
+	}
+	g.Table.Pot.Bets = make(map[*poker.Player]int)
+	g.MuckedHands = nil
+	g.RunItVote = nil
+	g.UncontestedWin = nil
+
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
+		systemUsername,
+		"The hand was cancelled. All bets have been returned.",
+	)))
+
+	// The stacks are back to what they were at the start of the hand
+	settleHand(g)
+	StartNewHand(g)
+	broadcastUpdateGameEvent(c)
+}
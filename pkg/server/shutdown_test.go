@@ -0,0 +1,97 @@
+package server_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+	"github.com/richard-to/go-poker/pkg/server"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+var _ = Describe("Shutdown", func() {
+	var g *server.GameState
+	var hub *server.Hub
+	var store *storage.MemoryStore
+
+	BeforeEach(func() {
+		store = storage.NewMemoryStore()
+		hub = server.NewHub()
+		go hub.Run()
+
+		g = server.NewGameState(server.NewTableConfig(), store)
+		seat := g.Table.Seats
+		for i := 0; i < 3; i++ {
+			Expect(store.OpenBankroll(seat.Player.ID, 1000)).To(Succeed())
+			Expect(store.BuyIn(g.Config.Name, seat.Player.ID, 100)).To(Succeed())
+			seat.Player.Name = seat.Player.ID
+			seat.Player.Chips = 100
+			seat.Player.Status = poker.PlayerSittingOut
+			seat.Player.IsHuman = true
+			seat = seat.Next()
+		}
+		server.StartNewHand(g)
+	})
+
+	It("returns the bets if the hand does not finish in time", func() {
+		Expect(g.Stage).To(Equal(server.Preflop))
+		Expect(g.Table.Pot.GetTotal()).To(BeNumerically(">", 0))
+
+		server.Shutdown(hub, g, 10*time.Millisecond)
+
+		Expect(g.Stage).To(Equal(server.Waiting))
+		Expect(g.History).To(BeNil())
+		for _, p := range g.PlayerMap {
+			if p.Name != "" {
+				Expect(p.Chips).To(Equal(100))
+			}
+		}
+		stacks, err := store.GetStacks(g.Config.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stacks).To(HaveLen(3))
+		for _, chips := range stacks {
+			Expect(chips).To(Equal(100))
+		}
+	})
+
+	It("shuts down every table", func() {
+		private := server.NewTableConfig()
+		private.Name = "Friends"
+		other := server.NewGameState(private, store)
+		seat := other.Table.Seats
+		for i := 0; i < 2; i++ {
+			Expect(store.OpenBankroll(seat.Player.ID, 1000)).To(Succeed())
+			Expect(store.BuyIn(private.Name, seat.Player.ID, 100)).To(Succeed())
+			seat.Player.Name = seat.Player.ID
+			seat.Player.Chips = 100
+			seat.Player.Status = poker.PlayerSittingOut
+			seat.Player.IsHuman = true
+			seat = seat.Next()
+		}
+		server.StartNewHand(other)
+		otherHub := server.NewHub()
+		go otherHub.Run()
+
+		registry := server.NewTableRegistry()
+		registry.AddTable(hub, g)
+		registry.AddTable(otherHub, other)
+		server.ShutdownTables(registry, 10*time.Millisecond)
+
+		for _, table := range []*server.GameState{g, other} {
+			Expect(table.Stage).To(Equal(server.Waiting))
+			stacks, err := store.GetStacks(table.Config.Name)
+			Expect(err).NotTo(HaveOccurred())
+			for _, chips := range stacks {
+				Expect(chips).To(Equal(100))
+			}
+		}
+	})
+
+	It("does not deal a new hand", func() {
+		server.Shutdown(hub, g, 0)
+		server.StartNewHand(g)
+		Expect(g.Stage).To(Equal(server.Waiting))
+	})
+})
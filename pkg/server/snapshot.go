@@ -0,0 +1,333 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/richard-to/go-poker/pkg/history"
+	"github.com/richard-to/go-poker/pkg/poker"
+	"github.com/richard-to/go-poker/pkg/stats"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+// How long players have to reconnect after a restart before their turns are played for them
+const reconnectGracePeriod = 30 * time.Second
+
+// GameSnapshot is a copy of the game state that can be serialized.
+//
+// A snapshot is saved at the start of every hand and when players sit down or leave. The
+// actions taken since the last snapshot are saved in a journal. To resume a hand after a
+// restart, the snapshot is restored and the journal is replayed on top of it.
+type GameSnapshot struct {
+	BettingRound   *poker.BettingRoundSnapshot `json:"bettingRound"`
+	Boards         []poker.Board               `json:"boards"`
+	Chat           *Chat                       `json:"chat"`
+	CurrentSeat    int                         `json:"currentSeat"` // Seat index
+	Deck           poker.DeckSnapshot          `json:"deck"`
+	History        *history.HandHistory        `json:"history"`
+	MuckedHands    []*ShowableHand             `json:"muckedHands"`
+	Private        *PrivateTable               `json:"private"`
+	RunItVote      *RunItVote                  `json:"runItVote"`
+	Session        *RatingSession              `json:"session"`
+	ShowdownReveal *ShowdownReveal             `json:"showdownReveal"`
+	Stage          GameStage                   `json:"stage"`
+	Table          poker.TableSnapshot         `json:"table"`
+	Tournament     *Tournament                 `json:"tournament"`
+	UncontestedWin *ShowableHand               `json:"uncontestedWin"`
+}
+
+// JournalEntry is an action taken by a player since the last snapshot.
+//
+// Moves made for disconnected players are also recorded, so the journal can be replayed
+// without knowing who was connected at the time.
+type JournalEntry struct {
+	Action string `json:"action"`
+	Cards  []int  `json:"cards,omitempty"` // Cards shown after an uncontested win or a muck
+	SeatID string `json:"seatID"`
+	Value  int    `json:"value,omitempty"` // Raise amount or number of times to run it
+}
+
+// Snapshot creates a serializable copy of the game state.
+func (g *GameState) Snapshot() GameSnapshot {
+	s := GameSnapshot{
+		Boards:         g.Boards,
+		Chat:           g.Chat,
+		CurrentSeat:    poker.GetSeatIndex(&g.Table, g.CurrentSeat),
+		Deck:           g.Deck.Snapshot(),
+		History:        g.History,
+		MuckedHands:    g.MuckedHands,
+		Private:        g.Private,
+		RunItVote:      g.RunItVote,
+		Session:        g.Session,
+		ShowdownReveal: g.ShowdownReveal,
+		Stage:          g.Stage,
+		Table:          g.Table.Snapshot(),
+		Tournament:     g.Tournament,
+		UncontestedWin: g.UncontestedWin,
+	}
+	if g.BettingRound != nil {
+		bettingRound := g.BettingRound.Snapshot()
+		s.BettingRound = &bettingRound
+	}
+	return s
+}
+
+// RestoreGameState rebuilds the game state from a snapshot.
+func RestoreGameState(config TableConfig, store storage.Store, s GameSnapshot) (*GameState, error) {
+	table, err := poker.RestoreTable(s.Table)
+	if err != nil {
+		return nil, err
+	}
+	// The rake comes from the current config in case it changed
+	table.Rake = config.Rake
+
+	g := &GameState{
+		Boards:         s.Boards,
+		Chat:           s.Chat,
+		Config:         config,
+		CurrentSeat:    table.Seats,
+		Deck:           poker.RestoreDeck(s.Deck),
+		HandHistories:  store.HandHistories(),
+		History:        s.History,
+		MuckedHands:    s.MuckedHands,
+		PlayerMap:      make(map[string]*poker.Player),
+		Private:        s.Private,
+		RunItVote:      s.RunItVote,
+		SeatChanges:    make(map[string]string),
+		SeatOffers:     make([]*SeatOffer, 0),
+		Session:        s.Session,
+		ShowdownReveal: s.ShowdownReveal,
+		Stage:          s.Stage,
+		Stats:          stats.NewTracker(),
+		Store:          store,
+		Table:          table,
+		Tournament:     s.Tournament,
+		UncontestedWin: s.UncontestedWin,
+		Waitlist:       make([]string, 0),
+		chatTimes:      make(map[string][]time.Time),
+	}
+	// Snapshots saved before chat moderation was added don't have chat settings
+	if g.Chat == nil {
+		g.Chat = NewChat(config.ChatMode)
+	}
+	// A tournament is only played if the table is still set up for one
+	if config.Tournament == nil {
+		g.Tournament = nil
+	} else if g.Tournament == nil {
+		g.Tournament = NewTournament()
+	}
+	// The same goes for private tables
+	if config.Host == "" {
+		g.Private = nil
+	} else if g.Private == nil {
+		g.Private = NewPrivateTable(config.Host)
+	}
+	if s.CurrentSeat >= 0 {
+		g.CurrentSeat = table.Seats.Move(s.CurrentSeat)
+	}
+	if s.BettingRound != nil {
+		g.BettingRound, err = poker.RestoreBettingRound(*s.BettingRound, &g.Table)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	seat := table.Seats
+	for i := 0; i < seat.Len(); i++ {
+		g.PlayerMap[seat.Player.ID] = seat.Player
+		if seat.Player.Status > poker.PlayerVacated {
+			loadPlayerStats(g, seat.Player.Name)
+		}
+		seat = seat.Next()
+	}
+	return g, nil
+}
+
+// RecoverGameState resumes the table from its last snapshot and journal.
+//
+// If there is no snapshot, a new game is started and any chips left at the table are
+// returned to the players' bankrolls. The journal is applied to the state right away. Nothing
+// is sent to the hub, and the pauses and timers that pace a live hand are skipped.
+func RecoverGameState(hub *Hub, config TableConfig, store storage.Store) (*GameState, error) {
+	data, journal, err := store.LoadSnapshot(config.Name)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		if err := store.CashOutTable(config.Name); err != nil {
+			return nil, err
+		}
+		return NewGameState(config, store), nil
+	}
+
+	var s GameSnapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	g, err := RestoreGameState(config, store, s)
+	if err != nil {
+		return nil, err
+	}
+
+	// Moves for disconnected players are in the journal, so no moves should be made for them
+	// while replaying
+	seatedPlayers := getSeatedPlayers(g)
+	for _, p := range seatedPlayers {
+		p.IsHuman = true
+	}
+	if err := replayJournal(g, journal); err != nil {
+		return nil, err
+	}
+
+	// No one is connected yet, so players will need to reconnect to take back their seats
+	for _, p := range seatedPlayers {
+		p.IsHuman = false
+	}
+	if g.Stage != Waiting {
+		time.AfterFunc(reconnectGracePeriod, func() {
+			playDisconnectedTurns(newSystemClient(hub, g, ""))
+		})
+	}
+	return g, nil
+}
+
+// saveSnapshot saves the game state and clears the journal
+func saveSnapshot(g *GameState) {
+	// Multi-table tournaments are refunded instead of resumed after a restart
+	if g.MTT != nil {
+		return
+	}
+	data, err := json.Marshal(g.Snapshot())
+	if err == nil {
+		err = g.Store.SaveSnapshot(g.Config.Name, data)
+	}
+	if err != nil {
+		log.Printf("could not save snapshot: %v", err)
+	}
+}
+
+// journalAction records an action taken since the last snapshot
+func journalAction(g *GameState, e JournalEntry) {
+	if g.isReplaying || g.MTT != nil {
+		return
+	}
+	data, err := json.Marshal(e)
+	if err == nil {
+		err = g.Store.AppendJournal(g.Config.Name, data)
+	}
+	if err != nil {
+		log.Printf("could not journal action: %v", err)
+	}
+}
+
+// replayJournal applies the actions from the journal to the game state.
+//
+// The actions go through the same handlers as live actions so that the hand plays out the same
+// way, but they only change the state. Messages go to a hub that no one is connected to, and the
+// pauses and timers that pace a live hand are skipped.
+func replayJournal(g *GameState, journal [][]byte) error {
+	if len(journal) == 0 {
+		return nil
+	}
+	hub := NewHub()
+	go hub.Run()
+	defer hub.Close(0)
+
+	g.isReplaying = true
+	defer func() {
+		g.isReplaying = false
+	}()
+	for i, data := range journal {
+		var e JournalEntry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return err
+		}
+		if err := replayJournalEntry(hub, g, e); err != nil {
+			return fmt.Errorf("Journal entry %d: %s", i+1, err)
+		}
+	}
+	return nil
+}
+
+// replayJournalEntry applies an action from the journal
+func replayJournalEntry(hub *Hub, g *GameState, e JournalEntry) error {
+	c := newSystemClient(hub, g, e.SeatID)
+
+	switch e.Action {
+	case actionRunIt:
+		return HandleRunIt(c, e.Value)
+	case actionShowCards:
+		return HandleShowCards(c, e.Cards)
+	case actionShowHand:
+		return HandleShowHand(c)
+	case actionMuckHand:
+		return HandleMuckHand(c)
+	}
+
+	if g.Stage < Preflop || g.Stage > River || g.CurrentSeat.Player.ID != e.SeatID {
+		return fmt.Errorf("%s is out of turn", e.Action)
+	}
+	switch e.Action {
+	case actionFold:
+		return HandleFold(c)
+	case actionCheck:
+		return HandleCheck(c)
+	case actionCall:
+		return HandleCall(c)
+	case actionRaise:
+		return HandleRaise(c, e.Value)
+	}
+	return fmt.Errorf("Unknown action encountered: %s", e.Action)
+}
+
+// playDisconnectedTurns plays for disconnected players who did not come back after a restart
+func playDisconnectedTurns(c *Client) {
+	g := c.gameState
+	g.lock()
+	defer g.unlock()
+
+	if g.RunItVote != nil {
+		for seatID, times := range g.RunItVote.Votes {
+			if times == 0 && g.PlayerMap[seatID].IsHuman == false {
+				HandleRunIt(newSystemClient(c.hub, g, seatID), 1)
+			}
+		}
+		return
+	}
+	// Players who came back get the usual time to choose to show or muck
+	if seatID := g.ShowdownReveal.getChoosingSeatID(); seatID != "" {
+		if g.PlayerMap[seatID].IsHuman {
+			startShowdownTimer(c)
+		} else {
+			HandleMuckHand(newSystemClient(c.hub, g, seatID))
+		}
+		return
+	}
+	HandleComputerMove(c)
+}
+
+// newSystemClient creates a client that is not connected to anyone, so that the server can
+// take actions on behalf of a seat.
+func newSystemClient(hub *Hub, g *GameState, seatID string) *Client {
+	return &Client{
+		gameState: g,
+		hub:       hub,
+		seatID:    seatID,
+		send:      make(chan Event, 256),
+		username:  systemUsername,
+	}
+}
+
+func getSeatedPlayers(g *GameState) []*poker.Player {
+	players := make([]*poker.Player, 0)
+	seat := g.Table.Seats
+	for i := 0; i < seat.Len(); i++ {
+		if seat.Player.Status > poker.PlayerVacated {
+			players = append(players, seat.Player)
+		}
+		seat = seat.Next()
+	}
+	return players
+}
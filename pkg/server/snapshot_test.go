@@ -0,0 +1,120 @@
+package server_test
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+	"github.com/richard-to/go-poker/pkg/server"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+var _ = Describe("RecoverGameState", func() {
+	var config server.TableConfig
+	var g *server.GameState
+	var hub *server.Hub
+	var store *storage.MemoryStore
+
+	BeforeEach(func() {
+		config = server.NewTableConfig()
+		store = storage.NewMemoryStore()
+		hub = server.NewHub()
+		go hub.Run()
+
+		g = server.NewGameState(config, store)
+		seat := g.Table.Seats
+		for i := 0; i < 3; i++ {
+			seat.Player.Name = seat.Player.ID
+			seat.Player.Chips = 100
+			seat.Player.Status = poker.PlayerSittingOut
+			seat.Player.IsHuman = true
+			seat = seat.Next()
+		}
+		server.StartNewHand(g)
+	})
+
+	It("starts a new game if there is no snapshot", func() {
+		recovered, err := server.RecoverGameState(hub, config, storage.NewMemoryStore())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recovered.Stage).To(Equal(server.Waiting))
+	})
+
+	It("resumes the hand from the snapshot taken at the start of the hand", func() {
+		recovered, err := server.RecoverGameState(hub, config, store)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(recovered.Stage).To(Equal(server.Preflop))
+		Expect(recovered.CurrentSeat.Player.ID).To(Equal(g.CurrentSeat.Player.ID))
+		Expect(recovered.Table.Dealer.Player.ID).To(Equal(g.Table.Dealer.Player.ID))
+		Expect(recovered.Table.Pot.GetTotal()).To(Equal(g.Table.Pot.GetTotal()))
+		Expect(recovered.BettingRound.Bets).To(Equal(g.BettingRound.Bets))
+		Expect(recovered.History.ID).To(Equal(g.History.ID))
+		for id, p := range g.PlayerMap {
+			Expect(recovered.PlayerMap[id].HoleCards).To(Equal(p.HoleCards))
+			Expect(recovered.PlayerMap[id].Chips).To(Equal(p.Chips))
+		}
+	})
+
+	It("replays the journal on top of the snapshot", func() {
+		firstPlayer := g.CurrentSeat.Player
+		nextPlayer := g.CurrentSeat.Next().Player
+		for _, e := range []server.JournalEntry{
+			{Action: "call", SeatID: firstPlayer.ID},
+			{Action: "fold", SeatID: nextPlayer.ID},
+		} {
+			data, err := json.Marshal(e)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.AppendJournal(config.Name, data)).To(Succeed())
+		}
+
+		recovered, err := server.RecoverGameState(hub, config, store)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(recovered.PlayerMap[firstPlayer.ID].Chips).To(Equal(firstPlayer.Chips - g.Table.MinBet))
+		Expect(recovered.PlayerMap[nextPlayer.ID].HasFolded).To(BeTrue())
+		Expect(recovered.Table.Pot.GetTotal()).To(Equal(g.Table.Pot.GetTotal() + g.Table.MinBet))
+
+		// Players need to reconnect to take back their seats
+		for _, p := range recovered.PlayerMap {
+			Expect(p.IsHuman).To(BeFalse())
+		}
+	})
+
+	It("finishes a hand from the journal without pausing for the players", func() {
+		// The big blind calls an all in raise from the dealer
+		for _, e := range []server.JournalEntry{
+			{Action: "raise", SeatID: g.Table.Dealer.Player.ID, Value: 100},
+			{Action: "fold", SeatID: g.Table.SmallBlind.Player.ID},
+			{Action: "call", SeatID: g.Table.BigBlind.Player.ID},
+		} {
+			data, err := json.Marshal(e)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.AppendJournal(config.Name, data)).To(Succeed())
+		}
+
+		startedAt := time.Now()
+		recovered, err := server.RecoverGameState(hub, config, store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(time.Since(startedAt)).To(BeNumerically("<", time.Second))
+
+		Expect(recovered.History).NotTo(BeNil())
+		Expect(recovered.History.ID).NotTo(Equal(g.History.ID))
+		chips := 0
+		for _, p := range recovered.PlayerMap {
+			chips += p.Chips
+		}
+		Expect(chips + recovered.Table.Pot.GetTotal()).To(Equal(300))
+	})
+
+	It("fails if the journal does not match the snapshot", func() {
+		data, err := json.Marshal(server.JournalEntry{Action: "call", SeatID: g.CurrentSeat.Next().Player.ID})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.AppendJournal(config.Name, data)).To(Succeed())
+
+		_, err = server.RecoverGameState(hub, config, store)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,69 @@
+package server_test
+
+import (
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/server"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+var _ = Describe("Spectator delay", func() {
+	var seatID string
+	var srv *tableServer
+
+	BeforeEach(func() {
+		accounts, err := auth.NewAccounts(auth.NewMemoryUserStore(), []byte("secret"), []string{})
+		Expect(err).NotTo(HaveOccurred())
+
+		config := server.NewTableConfig()
+		config.ShowHoleCardsToSpectators = true
+		config.SpectatorDelay = 500 * time.Millisecond
+		hub := server.NewHub()
+		go hub.Run()
+		g := server.NewGameState(config, storage.NewMemoryStore())
+		seatID = g.Table.Seats.Player.ID
+		registry := server.NewTableRegistry()
+		registry.AddTable(hub, g)
+
+		srv = newTableServer(registry, accounts)
+	})
+
+	AfterEach(func() {
+		srv.Close()
+	})
+
+	It("drops the delayed updates once the spectator sits down", func() {
+		srv.token("bob")
+		conn := srv.join("bob", nil)
+		send(conn, "take-seat", map[string]interface{}{"seatID": seatID})
+		satAt := time.Now()
+
+		roles := make([]interface{}, 0)
+		var firstUpdateAt time.Time
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		for {
+			var e server.Event
+			if err := conn.ReadJSON(&e); err != nil {
+				netErr, ok := err.(net.Error)
+				Expect(ok && netErr.Timeout()).To(BeTrue())
+				break
+			}
+			if e.Action == "update-game" {
+				roles = append(roles, e.Params["role"])
+				if firstUpdateAt.IsZero() {
+					firstUpdateAt = time.Now()
+				}
+			}
+		}
+		Expect(roles).NotTo(BeEmpty())
+		Expect(roles).NotTo(ContainElement("spectator"))
+
+		// The player's updates are not held up behind the updates that were dropped
+		Expect(firstUpdateAt.Sub(satAt)).To(BeNumerically("<", 250*time.Millisecond))
+	})
+})
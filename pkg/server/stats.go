@@ -0,0 +1,137 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/poker"
+	"github.com/richard-to/go-poker/pkg/stats"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+// ServeStats sends a player's stats.
+//
+// Stats can be limited to a table using the table query param and to a session using the
+// since and until query params, which are RFC 3339 timestamps. Players can only look up their
+// own stats. Admins can look up the stats of any player.
+func ServeStats(store storage.Store, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request) {
+	requester, err := authenticate(accounts, r)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	playerName, filter, err := parseStatsQuery(requester, r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if canViewResults(requester, playerName) == false {
+		writeJSONError(w, http.StatusForbidden, "You can only look up your own results")
+		return
+	}
+
+	histories, err := store.HandHistories().ListByPlayer(playerName)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"player": playerName,
+		"stats":  stats.Compute(histories, playerName, filter),
+	})
+}
+
+// ServeGraph sends a time series of a player's stack, winnings and all in EV for charting.
+//
+// The same query params as ServeStats are supported. Players can only chart their own results,
+// since the graph shows their winnings. Admins can chart the results of any player.
+func ServeGraph(store storage.Store, accounts *auth.Accounts, w http.ResponseWriter, r *http.Request) {
+	requester, err := authenticate(accounts, r)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	playerName, filter, err := parseStatsQuery(requester, r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if canViewResults(requester, playerName) == false {
+		writeJSONError(w, http.StatusForbidden, "You can only look up your own results")
+		return
+	}
+
+	histories, err := store.HandHistories().ListByPlayer(playerName)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"player": playerName,
+		"points": stats.Graph(histories, playerName, filter),
+	})
+}
+
+// parseStatsQuery gets the player and the filter from the query params
+func parseStatsQuery(requester auth.Claims, r *http.Request) (string, stats.Filter, error) {
+	var err error
+	query := r.URL.Query()
+	playerName := query.Get("player")
+	if playerName == "" {
+		playerName = requester.Username
+	}
+
+	filter := stats.Filter{TableName: query.Get("table")}
+	if since := query.Get("since"); since != "" {
+		filter.Since, err = time.Parse(time.RFC3339, since)
+		if err != nil {
+			return "", filter, fmt.Errorf("Invalid since time")
+		}
+	}
+	if until := query.Get("until"); until != "" {
+		filter.Until, err = time.Parse(time.RFC3339, until)
+		if err != nil {
+			return "", filter, fmt.Errorf("Invalid until time")
+		}
+	}
+	return playerName, filter, nil
+}
+
+// canViewResults checks if the requester can look up the results of a player
+func canViewResults(requester auth.Claims, playerName string) bool {
+	return requester.IsAdmin || requester.Username == playerName
+}
+
+// loadPlayerStats loads the all time stats of a player who sat down at the table.
+//
+// Players can have played a lot of hands, so their hands are loaded without holding the table
+// lock. The HUD shows the stats from the next update once they have loaded.
+func loadPlayerStats(g *GameState, playerName string) {
+	if g.Stats.StartLoading(playerName) == false {
+		return
+	}
+	go func() {
+		histories, err := g.HandHistories.ListByPlayer(playerName)
+		if err != nil {
+			g.Stats.CancelLoading(playerName)
+			return
+		}
+		g.Stats.Load(playerName, histories)
+	}()
+}
+
+// projectHUD gets the HUD of a player if the viewer is allowed to see it
+//
+// Only players at the table and admins see the HUD.
+func projectHUD(v Viewer, g *GameState, p *poker.Player) *stats.HUD {
+	if v.Role == ViewerSpectator || p.Status == poker.PlayerVacated {
+		return nil
+	}
+	return g.Stats.GetHUD(p.Name)
+}
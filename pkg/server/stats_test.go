@@ -0,0 +1,51 @@
+package server_test
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/server"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+var _ = Describe("Stats API", func() {
+	var accounts *auth.Accounts
+	var store *storage.MemoryStore
+
+	BeforeEach(func() {
+		store = newAPIStore()
+		accounts = newAPIAccounts()
+	})
+
+	serveStats := func(w http.ResponseWriter, r *http.Request) {
+		server.ServeStats(store, accounts, w, r)
+	}
+
+	It("sends a player's stats", func() {
+		w, body := getJSON("/api/stats", login(accounts, "alice"), serveStats)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(body["player"]).To(Equal("alice"))
+		Expect(body["stats"].(map[string]interface{})["hands"]).To(BeEquivalentTo(1))
+		Expect(body["stats"].(map[string]interface{})["pfr"]).To(BeEquivalentTo(100))
+
+		_, body = getJSON("/api/stats?table=Other", login(accounts, "bob"), serveStats)
+		Expect(body["stats"].(map[string]interface{})["hands"]).To(BeEquivalentTo(0))
+
+		w, _ = getJSON("/api/stats?since=yesterday", login(accounts, "dave"), serveStats)
+		Expect(w.Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("only sends other players' stats to admins", func() {
+		w, body := getJSON("/api/stats?player=bob", login(accounts, "carol"), serveStats)
+		Expect(w.Code).To(Equal(http.StatusForbidden))
+		Expect(body["error"]).To(Equal("You can only look up your own results"))
+
+		w, body = getJSON("/api/stats?player=bob", login(accounts, "pitboss"), serveStats)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(body["player"]).To(Equal("bob"))
+		Expect(body["stats"].(map[string]interface{})["hands"]).To(BeEquivalentTo(1))
+	})
+})
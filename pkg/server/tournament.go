@@ -0,0 +1,514 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+	"github.com/richard-to/go-poker/pkg/rating"
+)
+
+// BlindLevel is a level in a tournament's blind schedule. The small blind is half the big blind.
+type BlindLevel struct {
+	Ante     int `json:"ante"`
+	BigBlind int `json:"bigBlind"`
+}
+
+// TournamentConfig contains the settings for a sit and go tournament.
+//
+// The blinds go up when either the level duration or the number of hands per level is
+// reached. Levels after the last level in the schedule stay at the last level.
+type TournamentConfig struct {
+	Bounty        int           // Part of the buy-in that is put on each player's head. Zero means no bounties.
+	BuyIn         int           // Chips taken from each player's bankroll
+	HandsPerLevel int           // Optional. Not used if zero. Counted across every table in multi-table tournaments.
+	LevelDuration time.Duration // Optional. Not used if zero.
+	Levels        []BlindLevel
+	NumPlayers    int   // The tournament starts once this many players have bought in
+	Payouts       []int // Percentage of the prize pool paid to each place, starting with first place
+	Progressive   bool  // Half of each bounty won is added to the winner's own bounty
+	StartingStack int
+}
+
+// Tournament is the state of a sit and go tournament.
+//
+// A sit and go's tournament is guarded by its table's lock. The tournament shared by the
+// tables of a multi-table tournament is guarded by the multi-table tournament's lock.
+type Tournament struct {
+	Bounties       map[string]int `json:"bounties"`    // Bounty on each player who is still in
+	BountiesWon    map[string]int `json:"bountiesWon"` // Paid out once the tournament finishes
+	Deal           *Deal          `json:"deal"`        // Nil unless a deal has been proposed
+	Eliminated     []string       `json:"eliminated"`  // Usernames in the order they were knocked out
+	FinishedAt     time.Time      `json:"finishedAt"`
+	HandsAtLevel   int            `json:"handsAtLevel"`
+	Level          int            `json:"level"` // Index of the current blind level
+	LevelStartedAt time.Time      `json:"levelStartedAt"`
+	Payouts        map[string]int `json:"payouts"`   // Set once the tournament has finished
+	Players        []string       `json:"players"`   // Usernames of the players who bought in
+	ReEntries      map[string]int `json:"reEntries"` // Times each player bought in again after being knocked out
+	Rebuys         map[string]int `json:"rebuys"`
+	Stacks         map[string]int `json:"stacks"` // Chips at the start of the current hand
+	StartedAt      time.Time      `json:"startedAt"`
+}
+
+// NewTournamentConfig creates a tournament config with the default settings.
+func NewTournamentConfig() *TournamentConfig {
+	return &TournamentConfig{
+		BuyIn:         100,
+		LevelDuration: 10 * time.Minute,
+		Levels: []BlindLevel{
+			{BigBlind: 20},
+			{BigBlind: 30},
+			{BigBlind: 50},
+			{BigBlind: 100},
+			{BigBlind: 150, Ante: 25},
+			{BigBlind: 200, Ante: 25},
+			{BigBlind: 300, Ante: 50},
+			{BigBlind: 400, Ante: 50},
+			{BigBlind: 600, Ante: 75},
+			{BigBlind: 800, Ante: 100},
+			{BigBlind: 1000, Ante: 100},
+		},
+		NumPlayers:    numPlayers,
+		Payouts:       []int{65, 35},
+		StartingStack: 1500,
+	}
+}
+
+// Validate checks that the tournament can be played with the config.
+func (c *TournamentConfig) Validate() error {
+	return c.validate(numPlayers)
+}
+
+// validate checks the config for a tournament with at most maxPlayers players
+func (c *TournamentConfig) validate(maxPlayers int) error {
+	if c.NumPlayers < minPlayers || c.NumPlayers > maxPlayers {
+		return fmt.Errorf("Tournaments need between %d and %d players", minPlayers, maxPlayers)
+	}
+	if c.BuyIn < 0 {
+		return fmt.Errorf("The buy-in cannot be negative")
+	}
+	if c.Bounty < 0 || c.Bounty > c.BuyIn {
+		return fmt.Errorf("The bounty must be between 0 and the buy-in")
+	}
+	if len(c.Levels) == 0 {
+		return fmt.Errorf("The blind schedule needs at least one level")
+	}
+	for _, level := range c.Levels {
+		if level.BigBlind < 2 || level.BigBlind%2 != 0 || level.Ante < 0 {
+			return fmt.Errorf("Big blinds must be even and at least 2")
+		}
+	}
+	if c.StartingStack < c.Levels[0].BigBlind {
+		return fmt.Errorf("The starting stack must be at least one big blind")
+	}
+	if len(c.Payouts) == 0 || len(c.Payouts) > c.NumPlayers {
+		return fmt.Errorf("Between 1 and %d places can be paid", c.NumPlayers)
+	}
+	total := 0
+	for _, percent := range c.Payouts {
+		total += percent
+	}
+	if total != 100 {
+		return fmt.Errorf("Payouts must add up to 100%%")
+	}
+	return nil
+}
+
+// NewTournament creates a tournament that players can buy into.
+func NewTournament() *Tournament {
+	return &Tournament{
+		Bounties:    make(map[string]int),
+		BountiesWon: make(map[string]int),
+		Eliminated:  make([]string, 0),
+		Players:     make([]string, 0),
+		ReEntries:   make(map[string]int),
+		Rebuys:      make(map[string]int),
+		Stacks:      make(map[string]int),
+	}
+}
+
+// HasStarted checks if the tournament has started. Players can no longer buy in once it has.
+func (t *Tournament) HasStarted() bool {
+	return t.StartedAt.IsZero() == false
+}
+
+// start starts the first blind level and puts a bounty on each player
+func (t *Tournament) start(config *TournamentConfig) {
+	now := time.Now()
+	t.StartedAt = now
+	t.LevelStartedAt = now
+	if config.Bounty > 0 {
+		for _, username := range t.Players {
+			t.Bounties[username] = config.Bounty
+		}
+	}
+}
+
+// IsRunning checks if the tournament has started and has not finished yet.
+func (t *Tournament) IsRunning() bool {
+	return t.HasStarted() && t.IsFinished() == false
+}
+
+// IsFinished checks if one player has won every chip.
+func (t *Tournament) IsFinished() bool {
+	return t.FinishedAt.IsZero() == false
+}
+
+// HasPlayer checks if a player bought in.
+func (t *Tournament) HasPlayer(username string) bool {
+	for _, player := range t.Players {
+		if player == username {
+			return true
+		}
+	}
+	return false
+}
+
+// RemovePlayer removes a player who left before the tournament started.
+func (t *Tournament) RemovePlayer(username string) {
+	for i, player := range t.Players {
+		if player == username {
+			t.Players = append(t.Players[:i], t.Players[i+1:]...)
+			return
+		}
+	}
+}
+
+// GetPrizePool gets the total of the buy-ins, re-entries and rebuys, minus the part that goes
+// to the bounties.
+func (t *Tournament) GetPrizePool(config *TournamentConfig) int {
+	entries := len(t.Players)
+	for _, count := range t.ReEntries {
+		entries += count
+	}
+	for _, count := range t.Rebuys {
+		entries += count
+	}
+	return entries * (config.BuyIn - config.Bounty)
+}
+
+// GetPlaces gets the finishing order of the players, starting with the winner. Players who
+// are still in the tournament are not included.
+func (t *Tournament) GetPlaces(winner string) []string {
+	places := []string{winner}
+	for i := len(t.Eliminated) - 1; i >= 0; i-- {
+		places = append(places, t.Eliminated[i])
+	}
+	return places
+}
+
+// getBlindLevel gets the blinds of the table. Cash games use the default blinds.
+func getBlindLevel(g *GameState) BlindLevel {
+	if g.Tournament == nil && g.Config.BigBlind > 0 {
+		return BlindLevel{BigBlind: g.Config.BigBlind}
+	}
+	if g.Tournament == nil {
+		return BlindLevel{BigBlind: defaultMinBet}
+	}
+	levels := g.Config.Tournament.Levels
+	if g.Tournament.Level >= len(levels) {
+		return levels[len(levels)-1]
+	}
+	return levels[g.Tournament.Level]
+}
+
+// updateTournament starts, levels up and finishes the tournament before a new hand is dealt.
+//
+// Players who have lost all their chips are knocked out. If two players are knocked out in
+// the same hand, the player who started the hand with more chips finishes higher. Returns
+// false if the table should wait instead of dealing the next hand.
+func updateTournament(g *GameState) bool {
+	t := g.Tournament
+	config := g.Config.Tournament
+	if t == nil {
+		return true
+	}
+
+	// Once every player accepts a deal, the tournament ends after the hand in progress
+	if t.Deal != nil && t.Deal.IsAccepted() {
+		makeDeal(g)
+		return false
+	}
+
+	if g.MTT != nil {
+		return g.MTT.update(g)
+	}
+	if t.IsFinished() {
+		return false
+	}
+
+	if t.HasStarted() == false {
+		if len(t.Players) < config.NumPlayers {
+			return false
+		}
+		t.start(config)
+		g.announce(fmt.Sprintf("The tournament has started. Blinds are %s.", formatBlindLevel(getBlindLevel(g))))
+	} else {
+		eliminatePlayers(g, getBustedPlayers(g))
+		updateBlindLevel(g)
+	}
+
+	// The tournament is over once one player has every chip
+	remaining := make([]*poker.Player, 0)
+	for _, p := range getSeatedPlayers(g) {
+		if p.Chips > 0 {
+			remaining = append(remaining, p)
+		}
+	}
+	if len(remaining) == 1 {
+		finishTournament(g, remaining[0].Name)
+		return false
+	}
+
+	recordTournamentStacks(g)
+	return waitForDeal(g) == false
+}
+
+// getBustedPlayers gets the players at the table who lost their chips in the last hand
+func getBustedPlayers(g *GameState) []string {
+	busted := make([]string, 0)
+	for _, p := range getSeatedPlayers(g) {
+		if p.Chips == 0 && isEliminated(g.Tournament, p.Name) == false {
+			busted = append(busted, p.Name)
+		}
+	}
+	return busted
+}
+
+// eliminatePlayers knocks out players in order of their stacks at the start of the hand
+func eliminatePlayers(g *GameState, busted []string) {
+	t := g.Tournament
+	sort.SliceStable(busted, func(i, j int) bool {
+		return t.Stacks[busted[i]] < t.Stacks[busted[j]]
+	})
+	for _, username := range busted {
+		place := len(t.Players) - len(t.Eliminated)
+		t.Eliminated = append(t.Eliminated, username)
+		announceTournament(g, fmt.Sprintf("%s finished in %s place.", username, formatPlace(place)))
+	}
+}
+
+// updateBlindLevel goes up a level when the time or number of hands has been reached
+func updateBlindLevel(g *GameState) {
+	t := g.Tournament
+	config := g.Config.Tournament
+	t.HandsAtLevel++
+	isLevelOver := (config.HandsPerLevel > 0 && t.HandsAtLevel >= config.HandsPerLevel) ||
+		(config.LevelDuration > 0 && time.Since(t.LevelStartedAt) >= config.LevelDuration)
+	if isLevelOver && t.Level < len(config.Levels)-1 {
+		t.Level++
+		t.HandsAtLevel = 0
+		t.LevelStartedAt = time.Now()
+		announceTournament(g, fmt.Sprintf("Blinds are now %s.", formatBlindLevel(getBlindLevel(g))))
+	}
+}
+
+// recordTournamentStacks keeps track of the stacks at the start of the hand
+func recordTournamentStacks(g *GameState) {
+	for _, p := range getSeatedPlayers(g) {
+		g.Tournament.Stacks[p.Name] = p.Chips
+	}
+}
+
+// announceTournament posts a message at every table in the tournament
+func announceTournament(g *GameState, message string) {
+	if g.MTT == nil {
+		g.announce(message)
+		return
+	}
+	for _, table := range g.MTT.tables {
+		table.GameState.announce(message)
+	}
+}
+
+// finishTournament pays out the prize pool once one player has every chip
+func finishTournament(g *GameState, winner string) {
+	t := g.Tournament
+	config := g.Config.Tournament
+	places := t.GetPlaces(winner)
+	announceTournament(g, fmt.Sprintf("%s wins the tournament!", winner))
+	payOutTournament(g, places, calculatePayouts(t.GetPrizePool(config), config.Payouts, places))
+}
+
+// payOutTournament ends the tournament, pays out the prize pool and rates the players by
+// their finishing places.
+//
+// Players who are still in keep their own bounties.
+func payOutTournament(g *GameState, places []string, payouts map[string]int) {
+	t := g.Tournament
+	t.FinishedAt = time.Now()
+	t.Payouts = payouts
+
+	// Buy-ins are held at the table until the tournament finishes
+	winnings := make(map[string]int)
+	for _, username := range t.Players {
+		winnings[username] = payouts[username] + t.BountiesWon[username] + t.Bounties[username]
+	}
+	tableName := getTableName(g)
+	if err := g.Store.SettleHand(tableName, winnings, 0); err != nil {
+		log.Printf("could not pay out tournament: %v", err)
+	} else if err := g.Store.CashOutTable(tableName); err != nil {
+		log.Printf("could not pay out tournament: %v", err)
+	}
+
+	updateRatings(g, rating.PlacesToResults(places), rating.ReasonTournament)
+
+	// The chips left at the table have been paid out
+	for _, p := range getSeatedPlayers(g) {
+		p.Chips = 0
+	}
+
+	for i, username := range places {
+		if t.Payouts[username] > 0 {
+			announceTournament(g, fmt.Sprintf("%s: %s wins ℝ%d.", formatPlace(i+1), username, t.Payouts[username]))
+		}
+	}
+	for _, username := range places {
+		if bounties := t.BountiesWon[username] + t.Bounties[username]; bounties > 0 {
+			announceTournament(g, fmt.Sprintf("%s collects ℝ%d in bounties.", username, bounties))
+		}
+	}
+}
+
+// getTableName gets the name that chips and ratings are recorded under.
+//
+// Buy-ins for multi-table tournaments are held under the tournament's name since players
+// move between tables.
+func getTableName(g *GameState) string {
+	if g.MTT != nil {
+		return g.MTT.Config.Name
+	}
+	return g.Config.Name
+}
+
+// calculatePayouts splits the prize pool between the places that are paid.
+//
+// Every player is included so that the payouts can be settled against the buy-ins. Chips that
+// can't be split evenly go to first place.
+func calculatePayouts(prizePool int, percents []int, places []string) map[string]int {
+	payouts := make(map[string]int)
+	paid := 0
+	for i, username := range places {
+		payouts[username] = 0
+		if i < len(percents) {
+			payouts[username] = prizePool * percents[i] / 100
+			paid += payouts[username]
+		}
+	}
+	if len(places) > 0 {
+		payouts[places[0]] += prizePool - paid
+	}
+	return payouts
+}
+
+// projectTournament creates the tournament data for the update game event
+func projectTournament(g *GameState) map[string]interface{} {
+	t := g.Tournament
+	if t == nil {
+		return nil
+	}
+	config := g.Config.Tournament
+	level := getBlindLevel(g)
+
+	var nextLevel *BlindLevel
+	if t.Level < len(config.Levels)-1 {
+		nextLevel = &config.Levels[t.Level+1]
+	}
+
+	// Time and hands until the blinds go up. Nil if the level does not end that way.
+	var secondsToNextLevel interface{}
+	var handsToNextLevel interface{}
+	if t.IsRunning() && nextLevel != nil {
+		if config.LevelDuration > 0 {
+			remaining := config.LevelDuration - time.Since(t.LevelStartedAt)
+			if remaining < 0 {
+				remaining = 0
+			}
+			secondsToNextLevel = int(remaining.Seconds())
+		}
+		if config.HandsPerLevel > 0 {
+			handsToNextLevel = config.HandsPerLevel - t.HandsAtLevel
+		}
+	}
+
+	return map[string]interface{}{
+		"ante":               level.Ante,
+		"bigBlind":           level.BigBlind,
+		"bounty":             config.Bounty,
+		"buyIn":              config.BuyIn,
+		"deal":               t.Deal,
+		"eliminated":         t.Eliminated,
+		"handsToNextLevel":   handsToNextLevel,
+		"isFinished":         t.IsFinished(),
+		"isRunning":          t.IsRunning(),
+		"level":              t.Level + 1,
+		"nextLevel":          nextLevel,
+		"numPlayers":         config.NumPlayers,
+		"payouts":            t.Payouts,
+		"players":            t.Players,
+		"prizePool":          t.GetPrizePool(config),
+		"progressive":        config.Progressive,
+		"secondsToNextLevel": secondsToNextLevel,
+		"smallBlind":         level.BigBlind / 2,
+	}
+}
+
+// hasConnectedPlayer checks if any player at the table is connected
+func hasConnectedPlayer(g *GameState) bool {
+	for _, p := range getSeatedPlayers(g) {
+		if p.IsHuman {
+			return true
+		}
+	}
+	return false
+}
+
+func isEliminated(t *Tournament, username string) bool {
+	for _, eliminated := range t.Eliminated {
+		if eliminated == username {
+			return true
+		}
+	}
+	return false
+}
+
+func formatBlindLevel(level BlindLevel) string {
+	text := fmt.Sprintf("ℝ%d/ℝ%d", level.BigBlind/2, level.BigBlind)
+	if level.Ante > 0 {
+		text += fmt.Sprintf(" with a ℝ%d ante", level.Ante)
+	}
+	return text
+}
+
+// formatPlace formats a finishing place (e.g. 1st, 2nd, 3rd)
+func formatPlace(place int) string {
+	suffix := "th"
+	if place%100 < 11 || place%100 > 13 {
+		suffix = [...]string{"th", "st", "nd", "rd", "th", "th", "th", "th", "th", "th"}[place%10]
+	}
+	return fmt.Sprintf("%d%s", place, suffix)
+}
+
+// parseBlindLevels parses a comma separated blind schedule of big blinds with optional antes
+// (e.g. "20,30,50,100:10").
+func parseBlindLevels(s string) ([]BlindLevel, error) {
+	levels := make([]BlindLevel, 0)
+	for _, value := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(value), ":", 2)
+		level := BlindLevel{}
+		if _, err := fmt.Sscanf(parts[0], "%d", &level.BigBlind); err != nil {
+			return nil, fmt.Errorf("Invalid blind level: %s", value)
+		}
+		if len(parts) == 2 {
+			if _, err := fmt.Sscanf(parts[1], "%d", &level.Ante); err != nil {
+				return nil, fmt.Errorf("Invalid ante: %s", value)
+			}
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
@@ -0,0 +1,287 @@
+package server_test
+
+import (
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/history"
+	"github.com/richard-to/go-poker/pkg/poker"
+	"github.com/richard-to/go-poker/pkg/rating"
+	"github.com/richard-to/go-poker/pkg/server"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+var _ = Describe("Tournament", func() {
+	var g *server.GameState
+	var store *storage.MemoryStore
+	var players map[string]*poker.Player
+
+	// register seats a player and pays their buy-in
+	register := func(name string) {
+		seat := g.Table.Seats
+		for seat.Player.Status > poker.PlayerVacated {
+			seat = seat.Next()
+		}
+		Expect(store.OpenBankroll(name, 1000)).To(Succeed())
+		Expect(store.BuyIn(g.Config.Name, name, g.Config.Tournament.BuyIn)).To(Succeed())
+		seat.Player.Name = name
+		seat.Player.Chips = g.Config.Tournament.StartingStack
+		seat.Player.Status = poker.PlayerSittingOut
+		seat.Player.IsHuman = true
+		g.Tournament.Players = append(g.Tournament.Players, name)
+		players[name] = seat.Player
+	}
+
+	BeforeEach(func() {
+		store = storage.NewMemoryStore()
+		config := server.NewTableConfig()
+		config.Tournament = &server.TournamentConfig{
+			BuyIn:         100,
+			HandsPerLevel: 2,
+			Levels:        []server.BlindLevel{{BigBlind: 20}, {BigBlind: 40, Ante: 5}},
+			NumPlayers:    3,
+			Payouts:       []int{70, 30},
+			StartingStack: 1000,
+		}
+		g = server.NewGameState(config, store)
+		players = make(map[string]*poker.Player)
+	})
+
+	It("waits until every player has bought in", func() {
+		register("alice")
+		register("bob")
+		server.StartNewHand(g)
+		Expect(g.Stage).To(Equal(server.Waiting))
+		Expect(g.Tournament.HasStarted()).To(BeFalse())
+
+		register("carol")
+		server.StartNewHand(g)
+		Expect(g.Stage).To(Equal(server.Preflop))
+		Expect(g.Tournament.IsRunning()).To(BeTrue())
+		Expect(g.Table.MinBet).To(Equal(20))
+		Expect(g.Table.Pot.GetTotal()).To(Equal(30))
+	})
+
+	It("raises the blinds and takes antes at the next level", func() {
+		for _, name := range []string{"alice", "bob", "carol"} {
+			register(name)
+		}
+		server.StartNewHand(g)
+		server.StartNewHand(g)
+		Expect(g.Tournament.Level).To(Equal(0))
+
+		server.StartNewHand(g)
+		Expect(g.Tournament.Level).To(Equal(1))
+		Expect(g.Table.MinBet).To(Equal(40))
+		Expect(g.Table.Ante).To(Equal(5))
+		Expect(g.Table.Pot.GetTotal()).To(Equal(75))
+
+		Expect(g.History.Ante).To(Equal(5))
+		antes := 0
+		for _, a := range g.History.Actions {
+			if a.Type == history.ActionPostAnte {
+				antes++
+				Expect(a.Amount).To(Equal(5))
+			}
+		}
+		Expect(antes).To(Equal(3))
+	})
+
+	It("goes up a level once the level duration has passed", func() {
+		g.Config.Tournament.HandsPerLevel = 0
+		g.Config.Tournament.LevelDuration = time.Minute
+		for _, name := range []string{"alice", "bob", "carol"} {
+			register(name)
+		}
+		server.StartNewHand(g)
+		server.StartNewHand(g)
+		Expect(g.Tournament.Level).To(Equal(0))
+
+		g.Tournament.LevelStartedAt = time.Now().Add(-2 * time.Minute)
+		server.StartNewHand(g)
+		Expect(g.Tournament.Level).To(Equal(1))
+	})
+
+	It("lets short stacked players post what they have", func() {
+		for _, name := range []string{"alice", "bob", "carol"} {
+			register(name)
+		}
+		for _, p := range players {
+			p.Chips = 15
+		}
+		server.StartNewHand(g)
+		Expect(g.Stage).To(Equal(server.Preflop))
+		Expect(g.Table.BigBlind.Player.Chips).To(Equal(0))
+		Expect(g.Table.Pot.GetTotal()).To(Equal(25))
+
+		// The hand history has the amounts that were posted, not the blind level
+		Expect(g.History.BigBlind).To(Equal(20))
+		Expect(g.History.Actions[0].Type).To(Equal(history.ActionPostSmallBlind))
+		Expect(g.History.Actions[0].Amount).To(Equal(10))
+		Expect(g.History.Actions[1].Type).To(Equal(history.ActionPostBigBlind))
+		Expect(g.History.Actions[1].Amount).To(Equal(15))
+		Expect(g.History.Actions[1].IsAllIn).To(BeTrue())
+	})
+
+	It("pays out the prize pool and rates the players once one player has every chip", func() {
+		for _, name := range []string{"alice", "bob", "carol"} {
+			register(name)
+		}
+		server.StartNewHand(g)
+
+		// Both players are knocked out in the same hand. The bigger stack finishes higher.
+		g.Tournament.Stacks = map[string]int{"alice": 500, "bob": 1000, "carol": 1500}
+		players["alice"].Chips = 0
+		players["bob"].Chips = 0
+		players["carol"].Chips = 3000
+		server.StartNewHand(g)
+
+		Expect(g.Stage).To(Equal(server.Waiting))
+		Expect(g.Tournament.IsFinished()).To(BeTrue())
+		Expect(g.Tournament.Eliminated).To(Equal([]string{"alice", "bob"}))
+		Expect(g.Tournament.Payouts).To(Equal(map[string]int{"alice": 0, "bob": 90, "carol": 210}))
+
+		Expect(store.GetStacks(g.Config.Name)).To(BeEmpty())
+		Expect(store.GetBankroll("alice")).To(Equal(900))
+		Expect(store.GetBankroll("bob")).To(Equal(990))
+		Expect(store.GetBankroll("carol")).To(Equal(1110))
+
+		carol, err := store.Ratings().Get("carol")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(carol.Rating).To(BeNumerically(">", rating.DefaultRating))
+		changes, _ := store.Ratings().ListChanges("carol")
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].Reason).To(Equal(rating.ReasonTournament))
+
+		// No more hands are dealt once the tournament has finished
+		server.StartNewHand(g)
+		Expect(g.Stage).To(Equal(server.Waiting))
+	})
+
+	It("pays out the bounties that were won once the tournament finishes", func() {
+		g.Config.Tournament.Bounty = 40
+		for _, name := range []string{"alice", "bob", "carol"} {
+			register(name)
+		}
+		server.StartNewHand(g)
+		Expect(g.Tournament.Bounties).To(Equal(map[string]int{"alice": 40, "bob": 40, "carol": 40}))
+
+		// Carol knocked out alice and bob won part of a bounty in a split pot earlier
+		g.Tournament.Bounties = map[string]int{"alice": 0, "bob": 0, "carol": 60}
+		g.Tournament.BountiesWon = map[string]int{"bob": 20, "carol": 40}
+		g.Tournament.Stacks = map[string]int{"alice": 500, "bob": 1000, "carol": 1500}
+		players["alice"].Chips = 0
+		players["bob"].Chips = 0
+		players["carol"].Chips = 3000
+		server.StartNewHand(g)
+
+		Expect(g.Tournament.IsFinished()).To(BeTrue())
+		Expect(g.Tournament.Payouts).To(Equal(map[string]int{"alice": 0, "bob": 54, "carol": 126}))
+		Expect(store.GetBankroll("alice")).To(Equal(900))
+		Expect(store.GetBankroll("bob")).To(Equal(974))
+		Expect(store.GetBankroll("carol")).To(Equal(1126))
+	})
+
+	It("ends the tournament with a deal once every player accepts it", func() {
+		for _, name := range []string{"alice", "bob", "carol"} {
+			register(name)
+		}
+		server.StartNewHand(g)
+
+		g.Tournament.Deal = &server.Deal{
+			Accepted: map[string]bool{"alice": true, "bob": true, "carol": false},
+			Payouts:  map[string]int{"alice": 120, "bob": 100, "carol": 80},
+			Type:     "icm",
+		}
+
+		// No more hands are dealt until everyone has decided. The blinds changed the stacks,
+		// so the players need to accept the new payouts.
+		server.StartNewHand(g)
+		Expect(g.Stage).To(Equal(server.Waiting))
+		Expect(g.Tournament.IsRunning()).To(BeTrue())
+		Expect(g.Tournament.Deal.Accepted).To(Equal(map[string]bool{"alice": false, "bob": false, "carol": false}))
+		total := 0
+		for _, amount := range g.Tournament.Deal.Payouts {
+			total += amount
+		}
+		Expect(total).To(Equal(300))
+
+		g.Tournament.Deal.Payouts = map[string]int{"alice": 120, "bob": 100, "carol": 80}
+		for name := range g.Tournament.Deal.Accepted {
+			g.Tournament.Deal.Accepted[name] = true
+		}
+		server.StartNewHand(g)
+		Expect(g.Tournament.IsFinished()).To(BeTrue())
+		Expect(g.Tournament.Deal).To(BeNil())
+		Expect(g.Tournament.Payouts).To(Equal(map[string]int{"alice": 120, "bob": 100, "carol": 80}))
+		Expect(store.GetBankroll("alice")).To(Equal(1020))
+		Expect(store.GetBankroll("carol")).To(Equal(980))
+	})
+
+	It("keeps the tournament when the game state is restored", func() {
+		for _, name := range []string{"alice", "bob", "carol"} {
+			register(name)
+		}
+		server.StartNewHand(g)
+
+		restored, err := server.RestoreGameState(g.Config, store, g.Snapshot())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restored.Tournament.IsRunning()).To(BeTrue())
+		Expect(restored.Tournament.Players).To(Equal([]string{"alice", "bob", "carol"}))
+		Expect(restored.Table.MinBet).To(Equal(20))
+	})
+})
+
+var _ = Describe("TournamentConfig", func() {
+	AfterEach(func() {
+		for _, key := range []string{
+			"POKER_TOURNAMENT",
+			"POKER_TOURNAMENT_BLINDS",
+			"POKER_TOURNAMENT_PAYOUTS",
+			"POKER_TOURNAMENT_PLAYERS",
+		} {
+			os.Unsetenv(key)
+		}
+	})
+
+	It("loads the tournament from environment variables", func() {
+		os.Setenv("POKER_TOURNAMENT", "1")
+		os.Setenv("POKER_TOURNAMENT_BLINDS", "20,40,100:10")
+		os.Setenv("POKER_TOURNAMENT_PAYOUTS", "50,30,20")
+		os.Setenv("POKER_TOURNAMENT_PLAYERS", "4")
+
+		config, err := server.LoadTableConfig()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Tournament.Levels).To(Equal([]server.BlindLevel{
+			{BigBlind: 20},
+			{BigBlind: 40},
+			{BigBlind: 100, Ante: 10},
+		}))
+		Expect(config.Tournament.Payouts).To(Equal([]int{50, 30, 20}))
+		Expect(config.Tournament.NumPlayers).To(Equal(4))
+	})
+
+	It("is a cash game by default", func() {
+		config, err := server.LoadTableConfig()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Tournament).To(BeNil())
+	})
+
+	It("checks that the payouts add up to the prize pool", func() {
+		config := server.NewTournamentConfig()
+		Expect(config.Validate()).To(Succeed())
+		config.Payouts = []int{60, 30}
+		Expect(config.Validate()).NotTo(Succeed())
+	})
+
+	It("checks that the bounty is part of the buy-in", func() {
+		config := server.NewTournamentConfig()
+		config.Bounty = config.BuyIn / 2
+		Expect(config.Validate()).To(Succeed())
+		config.Bounty = config.BuyIn + 1
+		Expect(config.Validate()).NotTo(Succeed())
+	})
+})
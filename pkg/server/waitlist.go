@@ -0,0 +1,278 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+// Waitlist events
+const actionChangeSeat string = "change-seat"
+const actionJoinWaitlist string = "join-waitlist"
+const actionLeaveWaitlist string = "leave-waitlist"
+
+// How long the player at the front of the waitlist has to take the seat they were offered
+const defaultSeatOfferTimeout = 30 * time.Second
+
+// SeatOffer is an open seat that is being held for a player from the waitlist.
+type SeatOffer struct {
+	ExpiresAt time.Time `json:"expiresAt"`
+	SeatID    string    `json:"seatID"`
+	Username  string    `json:"username"`
+}
+
+// HandleJoinWaitlist adds the player to the waitlist of a full table
+func HandleJoinWaitlist(c *Client) error {
+	g := c.gameState
+	if c.username == "" {
+		return fmt.Errorf("You must join the game before joining the waitlist")
+	}
+	if c.seatID != "" || hasSeat(g, c.username) {
+		return fmt.Errorf("You already have a seat at the table")
+	}
+	if g.Tournament != nil {
+		return fmt.Errorf("Tournament tables do not have a waitlist")
+	}
+	if isOnWaitlist(g, c.username) || getSeatOffer(g, c.username) != nil {
+		return fmt.Errorf("You are already on the waitlist")
+	}
+	if len(getOpenSeats(g)) > 0 {
+		return fmt.Errorf("There is an open seat at the table")
+	}
+	if err := requestApproval(c); err != nil {
+		return err
+	}
+
+	g.Waitlist = append(g.Waitlist, c.username)
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
+		systemUsername,
+		fmt.Sprintf("%s joined the waitlist.", c.username),
+	)))
+	broadcastUpdateGameEvent(c)
+	return nil
+}
+
+// HandleLeaveWaitlist takes the player off the waitlist. Players who were offered a seat pass
+// it on to the next player.
+func HandleLeaveWaitlist(c *Client) error {
+	if leaveWaitlist(c) == false {
+		return fmt.Errorf("You are not on the waitlist")
+	}
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
+		systemUsername,
+		fmt.Sprintf("%s left the waitlist.", c.username),
+	)))
+	broadcastUpdateGameEvent(c)
+	return nil
+}
+
+// HandleChangeSeat moves a seated player to an open seat at the same table.
+//
+// If a hand is being played, the player moves once the hand is over.
+func HandleChangeSeat(c *Client, seatID string) error {
+	g := c.gameState
+	player := poker.GetPlayerByID(&g.Table, c.seatID)
+	if player == nil {
+		return fmt.Errorf("You must have a seat to change seats")
+	}
+	if g.MTT != nil {
+		return fmt.Errorf("Seats are drawn at random in multi-table tournaments")
+	}
+
+	target := poker.GetPlayerByID(&g.Table, seatID)
+	if target == nil {
+		return fmt.Errorf("Invalid seat chosen")
+	}
+	if target.Status > poker.PlayerVacated {
+		return fmt.Errorf("Seat has already been taken")
+	}
+	if offer := getSeatOfferBySeat(g, seatID); offer != nil {
+		return fmt.Errorf("The seat is being held for %s", offer.Username)
+	}
+
+	message := fmt.Sprintf("%s changed seats.", player.Name)
+	if g.Stage == Waiting {
+		moveSeat(g, player, target)
+		saveSnapshot(g)
+	} else {
+		g.SeatChanges[player.ID] = target.ID
+		message = fmt.Sprintf("%s will change seats once the hand is over.", player.Name)
+	}
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(systemUsername, message)))
+	broadcastUpdateGameEvent(c)
+	return nil
+}
+
+// leaveWaitlist takes the player off the waitlist and gives up any seat that is being held
+// for them. Returns false if the player was not on the waitlist.
+func leaveWaitlist(c *Client) bool {
+	g := c.gameState
+	if offer := getSeatOffer(g, c.username); offer != nil {
+		removeSeatOffer(g, offer)
+		offerOpenSeats(c)
+		return true
+	}
+	for i, username := range g.Waitlist {
+		if username == c.username {
+			g.Waitlist = append(g.Waitlist[:i:i], g.Waitlist[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// offerOpenSeats holds each open seat for the next player on the waitlist. If the player does
+// not take the seat in time, it is offered to the player after them.
+func offerOpenSeats(c *Client) {
+	g := c.gameState
+	hub := c.hub
+	// The waitlist is not kept after a restart
+	if g.isReplaying {
+		return
+	}
+	timeout := getSeatOfferTimeout(g.Config)
+	for _, p := range getOpenSeats(g) {
+		if len(g.Waitlist) == 0 {
+			return
+		}
+		offer := &SeatOffer{
+			ExpiresAt: time.Now().Add(timeout),
+			SeatID:    p.ID,
+			Username:  g.Waitlist[0],
+		}
+		g.Waitlist = g.Waitlist[1:]
+		g.SeatOffers = append(g.SeatOffers, offer)
+		hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
+			systemUsername,
+			fmt.Sprintf("A seat opened up. %s has %d seconds to take it.", offer.Username, int(timeout.Seconds())),
+		)))
+		time.AfterFunc(timeout, func() {
+			expireSeatOffer(newSystemClient(hub, g, ""), offer)
+		})
+	}
+}
+
+// expireSeatOffer passes the seat on to the next player on the waitlist if it was not taken.
+//
+// The offer expires on a timer, so the table is locked like it is for the players' events.
+func expireSeatOffer(c *Client, offer *SeatOffer) {
+	g := c.gameState
+	g.lock()
+	defer g.unlock()
+
+	if g.isClosed || removeSeatOffer(g, offer) == false {
+		return
+	}
+	c.hub.broadcastEvent(NewBroadcastEvent(createNewMessageEvent(
+		systemUsername,
+		fmt.Sprintf("%s did not take the seat in time.", offer.Username),
+	)))
+	offerOpenSeats(c)
+	broadcastUpdateGameEvent(c)
+}
+
+// getSeatOfferTimeout gets how long a player from the waitlist has to take an open seat
+func getSeatOfferTimeout(config TableConfig) time.Duration {
+	if config.SeatOfferTimeout > 0 {
+		return config.SeatOfferTimeout
+	}
+	return defaultSeatOfferTimeout
+}
+
+// changeSeats moves the players who asked to change seats during the last hand
+func changeSeats(g *GameState) {
+	for playerID, seatID := range g.SeatChanges {
+		player := poker.GetPlayerByID(&g.Table, playerID)
+		target := poker.GetPlayerByID(&g.Table, seatID)
+		if player == nil || target == nil || player.Status == poker.PlayerVacated || player.IsHuman == false {
+			continue
+		}
+		if target.Status > poker.PlayerVacated || getSeatOfferBySeat(g, seatID) != nil {
+			g.announce(fmt.Sprintf("%s could not change seats because the seat was taken.", player.Name))
+			continue
+		}
+		moveSeat(g, player, target)
+		g.announce(fmt.Sprintf("%s changed seats.", player.Name))
+	}
+	g.SeatChanges = make(map[string]string)
+}
+
+// moveSeat swaps a player with an open seat. The player keeps their seat ID, so their
+// connection does not need to be updated.
+func moveSeat(g *GameState, player *poker.Player, target *poker.Player) {
+	var from *poker.Seat
+	var to *poker.Seat
+	seat := g.Table.Seats
+	for i := 0; i < seat.Len(); i++ {
+		if seat.Player == player {
+			from = seat
+		} else if seat.Player == target {
+			to = seat
+		}
+		seat = seat.Next()
+	}
+	from.Player = target
+	to.Player = player
+}
+
+// getOpenSeats gets the vacated seats that are not being held for anyone
+func getOpenSeats(g *GameState) []*poker.Player {
+	players := make([]*poker.Player, 0)
+	seat := g.Table.Seats
+	for i := 0; i < seat.Len(); i++ {
+		if seat.Player.Status == poker.PlayerVacated && getSeatOfferBySeat(g, seat.Player.ID) == nil {
+			players = append(players, seat.Player)
+		}
+		seat = seat.Next()
+	}
+	return players
+}
+
+func getSeatOffer(g *GameState, username string) *SeatOffer {
+	for _, offer := range g.SeatOffers {
+		if offer.Username == username {
+			return offer
+		}
+	}
+	return nil
+}
+
+func getSeatOfferBySeat(g *GameState, seatID string) *SeatOffer {
+	for _, offer := range g.SeatOffers {
+		if offer.SeatID == seatID {
+			return offer
+		}
+	}
+	return nil
+}
+
+// removeSeatOffer removes the offer. Returns false if the offer was already taken or expired.
+func removeSeatOffer(g *GameState, offer *SeatOffer) bool {
+	for i, o := range g.SeatOffers {
+		if o == offer {
+			g.SeatOffers = append(g.SeatOffers[:i:i], g.SeatOffers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func isOnWaitlist(g *GameState, username string) bool {
+	for _, u := range g.Waitlist {
+		if u == username {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSeat checks if the user is seated, including players who were disconnected during a hand
+func hasSeat(g *GameState, username string) bool {
+	for _, p := range getSeatedPlayers(g) {
+		if p.Name == username {
+			return true
+		}
+	}
+	return false
+}
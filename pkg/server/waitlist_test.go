@@ -0,0 +1,148 @@
+package server_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/poker"
+	"github.com/richard-to/go-poker/pkg/server"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+// getSeatIDs gets the seat IDs in the order that they are seated at the table
+func getSeatIDs(g *server.GameState) []string {
+	ids := make([]string, 0)
+	seat := g.Table.Seats
+	for i := 0; i < seat.Len(); i++ {
+		ids = append(ids, seat.Player.ID)
+		seat = seat.Next()
+	}
+	return ids
+}
+
+var _ = Describe("Waitlist", func() {
+	var g *server.GameState
+	var ps []*poker.Player
+
+	BeforeEach(func() {
+		g = server.NewGameState(server.NewTableConfig(), storage.NewMemoryStore())
+		ps = make([]*poker.Player, 0)
+		seat := g.Table.Seats
+		for i := 0; i < seat.Len(); i++ {
+			ps = append(ps, seat.Player)
+			seat = seat.Next()
+		}
+		for _, p := range ps[:3] {
+			p.Name = p.ID
+			p.Chips = 100
+			p.Status = poker.PlayerSittingOut
+			p.IsHuman = true
+		}
+		server.StartNewHand(g)
+	})
+
+	It("moves players to their new seat before the next hand", func() {
+		g.SeatChanges[ps[0].ID] = ps[4].ID
+		server.StartNewHand(g)
+
+		Expect(g.SeatChanges).To(BeEmpty())
+		Expect(getSeatIDs(g)).To(Equal([]string{ps[4].ID, ps[1].ID, ps[2].ID, ps[3].ID, ps[0].ID, ps[5].ID}))
+		Expect(ps[0].Status).To(Equal(poker.PlayerActive))
+		Expect(ps[0].Chips).To(BeNumerically(">", 0))
+		Expect(g.PlayerMap[ps[0].ID]).To(Equal(ps[0]))
+	})
+
+	It("does not move players if their new seat was taken", func() {
+		g.SeatChanges[ps[0].ID] = ps[4].ID
+		ps[4].Name = "carol"
+		ps[4].Chips = 100
+		ps[4].Status = poker.PlayerSittingOut
+		ps[4].IsHuman = true
+		server.StartNewHand(g)
+
+		Expect(g.SeatChanges).To(BeEmpty())
+		Expect(getSeatIDs(g)).To(Equal([]string{ps[0].ID, ps[1].ID, ps[2].ID, ps[3].ID, ps[4].ID, ps[5].ID}))
+		Expect(ps[4].Name).To(Equal("carol"))
+	})
+
+	It("shows the waitlist and the seats being held", func() {
+		g.Waitlist = append(g.Waitlist, "dave")
+		g.SeatOffers = append(g.SeatOffers, &server.SeatOffer{SeatID: ps[5].ID, Username: "carol"})
+
+		e := server.ProjectGameState(g, []server.Peer{}, server.Viewer{Role: server.ViewerSpectator})
+		Expect(e.Params["waitlist"]).To(Equal([]string{"dave"}))
+		Expect(e.Params["seatOffers"]).To(HaveLen(1))
+	})
+})
+
+var _ = Describe("Seat offers", func() {
+	var openSeatID string
+	var srv *tableServer
+
+	hasSeatOffer := func(username string) func(server.Event) bool {
+		return func(e server.Event) bool {
+			offers, _ := e.Params["seatOffers"].([]interface{})
+			for _, offer := range offers {
+				if offer.(map[string]interface{})["username"] == username {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	hasWaitlist := func(length int) func(server.Event) bool {
+		return func(e server.Event) bool {
+			return isUpdate(e) && len(e.Params["waitlist"].([]interface{})) == length
+		}
+	}
+
+	BeforeEach(func() {
+		accounts, err := auth.NewAccounts(auth.NewMemoryUserStore(), []byte("secret"), nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		// Every seat but one is taken by players who are out of chips, so no hand is dealt
+		config := server.NewTableConfig()
+		config.SeatOfferTimeout = 100 * time.Millisecond
+		g := server.NewGameState(config, storage.NewMemoryStore())
+		seat := g.Table.Seats
+		for i := 0; i < seat.Len()-1; i++ {
+			seat.Player.Name = seat.Player.ID
+			seat.Player.IsHuman = true
+			seat.Player.Status = poker.PlayerSittingOut
+			seat = seat.Next()
+		}
+		openSeatID = seat.Player.ID
+
+		registry := server.NewTableRegistry()
+		hub := server.NewHub()
+		go hub.Run()
+		registry.AddTable(hub, g)
+		srv = newTableServer(registry, accounts)
+	})
+
+	AfterEach(func() {
+		srv.Close()
+	})
+
+	It("offers the seat to the next player on the waitlist once the offer expires", func() {
+		alice := srv.join("alice", nil)
+		send(alice, "take-seat", map[string]interface{}{"seatID": openSeatID})
+
+		carol := srv.join("carol", nil)
+		send(carol, "join-waitlist", map[string]interface{}{})
+		readUntil(carol, hasWaitlist(1))
+
+		dave := srv.join("dave", nil)
+		send(dave, "join-waitlist", map[string]interface{}{})
+		readUntil(dave, hasWaitlist(2))
+
+		// Carol does not take the seat that alice leaves
+		alice.Close()
+		readUntil(dave, hasSeatOffer("carol"))
+		readUntil(dave, hasSeatOffer("dave"))
+	})
+})
@@ -0,0 +1,174 @@
+package stats
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/richard-to/go-poker/pkg/history"
+	"github.com/richard-to/go-poker/pkg/poker"
+)
+
+// GraphPoint is a player's results after a hand.
+//
+// Winnings and all in EV are totals across all the hands up to and including this one.
+// The all in EV line replaces the result of hands where the player was all in before the
+// river with the chips they were expected to win, which separates bad luck from bad play.
+type GraphPoint struct {
+	AllInEV   float64   `json:"allInEV"`
+	HandID    string    `json:"handID"`
+	IsAllIn   bool      `json:"isAllIn"` // Whether the result of this hand was adjusted
+	Stack     int       `json:"stack"`   // Chips at the end of the hand
+	StartedAt time.Time `json:"startedAt"`
+	TableName string    `json:"tableName"`
+	Winnings  int       `json:"winnings"`
+}
+
+// Graph creates a time series of a player's stack and results from the hands that pass the filter.
+func Graph(histories []*history.HandHistory, playerName string, filter Filter) []GraphPoint {
+	sorted := make([]*history.HandHistory, 0)
+	for _, h := range histories {
+		if filter.Matches(h) && h.HasPlayer(playerName) {
+			sorted = append(sorted, h)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].StartedAt.Before(sorted[j].StartedAt)
+	})
+
+	points := make([]GraphPoint, 0)
+	winnings := 0
+	allInEV := 0.0
+	for _, h := range sorted {
+		seat := h.GetSeatByName(playerName)
+		net := h.GetNetWinnings(seat.PlayerID)
+		winnings += net
+
+		expected, isAllIn := getAllInEV(h, seat.PlayerID)
+		if isAllIn {
+			allInEV += expected - float64(h.GetChipsPutIn(seat.PlayerID))
+		} else {
+			allInEV += float64(net)
+		}
+
+		points = append(points, GraphPoint{
+			AllInEV:   round(allInEV),
+			HandID:    h.ID,
+			IsAllIn:   isAllIn,
+			Stack:     seat.Chips + net,
+			StartedAt: h.StartedAt,
+			TableName: h.TableName,
+			Winnings:  winnings,
+		})
+	}
+	return points
+}
+
+// getAllInEV gets the chips a player was expected to win when all the money went in before the river.
+//
+// False is returned if the hand was not all in before the river, if the player folded, or if
+// the hole cards of a player in the showdown are not known.
+func getAllInEV(h *history.HandHistory, playerID string) (float64, bool) {
+	// Find the street where the betting ended
+	isAllIn := false
+	lastStreet := history.StreetPreflop
+	for _, a := range h.Actions {
+		switch a.Type {
+		case history.ActionPostSmallBlind, history.ActionPostBigBlind, history.ActionPostAnte, history.ActionFold,
+			history.ActionCheck, history.ActionCall, history.ActionBet, history.ActionRaise:
+			lastStreet = a.Street
+			isAllIn = isAllIn || a.IsAllIn
+		}
+	}
+	if isAllIn == false || lastStreet == history.StreetRiver || hasFolded(h, playerID) {
+		return 0, false
+	}
+
+	board := poker.Board{}
+	if lastStreet == history.StreetFlop || lastStreet == history.StreetTurn {
+		board.Flop = h.Board.Flop
+	}
+	if lastStreet == history.StreetTurn {
+		board.Turn = h.Board.Turn
+	}
+
+	// Chips put in by every player, including the players who folded
+	contributions := make(map[string]int)
+	showdownPlayers := make([]string, 0)
+	for _, seat := range h.Seats {
+		contributions[seat.PlayerID] = h.GetChipsPutIn(seat.PlayerID)
+		if hasFolded(h, seat.PlayerID) == false {
+			if seat.HoleCards[0] == nil || seat.HoleCards[1] == nil {
+				return 0, false
+			}
+			showdownPlayers = append(showdownPlayers, seat.PlayerID)
+		}
+	}
+	if len(showdownPlayers) < 2 {
+		return 0, false
+	}
+
+	// The rake is taken from the expected winnings in the same proportion as the real pot
+	totalContributed := 0
+	for _, amount := range contributions {
+		totalContributed += amount
+	}
+	totalAwarded := 0
+	for _, pot := range h.Pots {
+		for _, w := range pot.Winners {
+			totalAwarded += w.Amount
+		}
+	}
+	if totalContributed == 0 {
+		return 0, false
+	}
+	rakeFactor := float64(totalAwarded) / float64(totalContributed)
+
+	// Split the chips into a main pot and side pots by the amount each player in the showdown put in
+	levels := make([]int, 0)
+	for _, id := range showdownPlayers {
+		levels = append(levels, contributions[id])
+	}
+	sort.Ints(levels)
+
+	r := rand.New(rand.NewSource(h.Seed))
+	expected := 0.0
+	prevLevel := 0
+	for _, level := range levels {
+		if level == prevLevel {
+			continue
+		}
+		potAmount := 0
+		for _, amount := range contributions {
+			potAmount += minInt(amount, level) - minInt(amount, prevLevel)
+		}
+		prevLevel = level
+
+		eligible := make([]string, 0)
+		holeCards := make([][2]*poker.Card, 0)
+		for _, id := range showdownPlayers {
+			if contributions[id] >= level {
+				eligible = append(eligible, id)
+				holeCards = append(holeCards, h.GetSeat(id).HoleCards)
+			}
+		}
+
+		equities := []float64{1}
+		if len(eligible) > 1 {
+			equities = poker.CalculateEquity(holeCards, board, r)
+		}
+		for i, id := range eligible {
+			if id == playerID {
+				expected += float64(potAmount) * rakeFactor * equities[i]
+			}
+		}
+	}
+	return expected, true
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
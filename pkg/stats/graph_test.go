@@ -0,0 +1,71 @@
+package stats_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/history"
+	"github.com/richard-to/go-poker/pkg/stats"
+)
+
+const allInHand = `PokerStars Hand #5003: Hold'em No Limit (1/2) - 2021/02/03 12:00:00 UTC
+Table 'Main' 6-max Seat #1 is the button
+Seat 1: alice (100 in chips)
+Seat 2: bob (200 in chips)
+alice: posts small blind 1
+bob: posts big blind 2
+*** HOLE CARDS ***
+alice: raises 98 to 100 and is all-in
+bob: calls 98
+*** FLOP *** [Kd 8s 3c]
+*** TURN *** [Kd 8s 3c] [2h]
+*** RIVER *** [Kd 8s 3c 2h] [Ks]
+*** SHOW DOWN ***
+alice: shows [Ac Ah] (two pair, Aces and Kings)
+bob: shows [Kh Qc] (three of a kind, Kings)
+bob collected 200 from pot
+*** SUMMARY ***
+Total pot 200 | Rake 0
+Board [Kd 8s 3c 2h Ks]
+`
+
+var _ = Describe("Graph", func() {
+	var histories []*history.HandHistory
+
+	BeforeEach(func() {
+		var err error
+		histories, err = history.ParsePokerStars(threeBetHand + "\n\n" + allInHand + "\n\n" + cBetHand)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("orders the hands by when they started", func() {
+		points := stats.Graph(histories, "alice", stats.Filter{})
+		Expect(points).To(HaveLen(3))
+		Expect(points[0].HandID).To(Equal("5001"))
+		Expect(points[1].HandID).To(Equal("5002"))
+		Expect(points[2].HandID).To(Equal("5003"))
+	})
+
+	It("adds up the player's winnings and stack", func() {
+		points := stats.Graph(histories, "bob", stats.Filter{})
+		Expect(points[0].Winnings).To(Equal(22))
+		Expect(points[0].Stack).To(Equal(122))
+		Expect(points[1].Winnings).To(Equal(16))
+		Expect(points[2].Winnings).To(Equal(116))
+		Expect(points[2].Stack).To(Equal(300))
+	})
+
+	It("uses the expected winnings of hands that were all in before the river", func() {
+		points := stats.Graph(histories, "alice", stats.Filter{})
+		Expect(points[1].IsAllIn).To(BeFalse())
+		Expect(points[1].AllInEV).To(BeNumerically("==", points[1].Winnings))
+
+		// Aces are about an 87% favourite over king queen
+		Expect(points[2].IsAllIn).To(BeTrue())
+		Expect(points[2].Winnings).To(Equal(-16 - 2 - 100))
+		Expect(points[2].AllInEV - float64(points[1].Winnings)).To(BeNumerically("~", 200*0.87-100, 5))
+
+		bob := stats.Graph(histories, "bob", stats.Filter{})
+		Expect(bob[2].AllInEV - float64(bob[1].Winnings)).To(BeNumerically("~", 200*0.13-100, 5))
+	})
+})
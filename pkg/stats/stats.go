@@ -0,0 +1,243 @@
+package stats
+
+import (
+	"math"
+	"time"
+
+	"github.com/richard-to/go-poker/pkg/history"
+)
+
+// Counters are the running totals that a player's stats are calculated from.
+type Counters struct {
+	Hands                   int     `json:"hands"`
+	VPIP                    int     `json:"vpip"` // Hands where chips were voluntarily put in preflop
+	PFR                     int     `json:"pfr"`  // Hands raised preflop
+	ThreeBets               int     `json:"threeBets"`
+	ThreeBetOpportunities   int     `json:"threeBetOpportunities"` // Hands where the player faced a single raise preflop
+	FoldToCBet              int     `json:"foldToCBet"`
+	FoldToCBetOpportunities int     `json:"foldToCBetOpportunities"` // Hands where the player faced a flop c-bet
+	SawFlop                 int     `json:"sawFlop"`
+	WentToShowdown          int     `json:"wentToShowdown"`
+	WonAtShowdown           int     `json:"wonAtShowdown"`
+	PostflopAggressive      int     `json:"postflopAggressive"` // Bets and raises after the flop
+	PostflopCalls           int     `json:"postflopCalls"`
+	BigBlindsWon            float64 `json:"bigBlindsWon"`
+}
+
+// Stats are a player's stats. Percentages are between 0 and 100.
+type Stats struct {
+	AggressionFactor float64  `json:"aggressionFactor"` // Postflop (bets + raises) / calls
+	BigBlindsPer100  float64  `json:"bbPer100"`
+	FoldToCBet       float64  `json:"foldToCBet"`
+	Hands            int      `json:"hands"`
+	PFR              float64  `json:"pfr"`
+	ThreeBet         float64  `json:"threeBet"`
+	VPIP             float64  `json:"vpip"`
+	WentToShowdown   float64  `json:"wtsd"` // Percentage of the hands where the player saw the flop that went to showdown
+	WonAtShowdown    float64  `json:"wsd"`  // Percentage of showdowns won
+	Counters         Counters `json:"counters"`
+}
+
+// Filter limits the hands that stats are calculated from.
+//
+// Zero values are not filtered on. A session can be filtered using the time the player sat down.
+type Filter struct {
+	Since     time.Time
+	TableName string
+	Until     time.Time
+}
+
+// Matches checks if the hand passes the filter.
+func (f Filter) Matches(h *history.HandHistory) bool {
+	if f.TableName != "" && h.TableName != f.TableName {
+		return false
+	}
+	if f.Since.IsZero() == false && h.StartedAt.Before(f.Since) {
+		return false
+	}
+	if f.Until.IsZero() == false && h.StartedAt.Before(f.Until) == false {
+		return false
+	}
+	return true
+}
+
+// Compute calculates a player's stats from the hands that pass the filter.
+func Compute(histories []*history.HandHistory, playerName string, filter Filter) Stats {
+	var c Counters
+	for _, h := range histories {
+		if filter.Matches(h) {
+			c.Add(h, playerName)
+		}
+	}
+	return c.Stats()
+}
+
+// Add adds a hand to the counters. Hands the player was not dealt into are ignored.
+func (c *Counters) Add(h *history.HandHistory, playerName string) {
+	seat := h.GetSeatByName(playerName)
+	if seat == nil {
+		return
+	}
+	playerID := seat.PlayerID
+
+	c.Hands++
+
+	// Preflop
+	numRaises := 0
+	preflopRaiser := ""
+	isVPIP := false
+	isPFR := false
+	facedThreeBet := false
+	foldedPreflop := false
+	for _, a := range h.GetActionsByStreet(history.StreetPreflop) {
+		if a.IsForced() {
+			continue
+		}
+		if a.PlayerID == playerID {
+			// The first time the player acts facing a single raise is a chance to 3-bet
+			if numRaises == 1 && preflopRaiser != playerID && facedThreeBet == false {
+				facedThreeBet = true
+				c.ThreeBetOpportunities++
+				if isAggressive(a) {
+					c.ThreeBets++
+				}
+			}
+			if a.Type == history.ActionCall || isAggressive(a) {
+				isVPIP = true
+			}
+			if isAggressive(a) {
+				isPFR = true
+			}
+			if a.Type == history.ActionFold {
+				foldedPreflop = true
+			}
+		}
+		if isAggressive(a) {
+			numRaises++
+			preflopRaiser = a.PlayerID
+		}
+	}
+	if isVPIP {
+		c.VPIP++
+	}
+	if isPFR {
+		c.PFR++
+	}
+
+	// Flop c-bets. A c-bet is the first bet on the flop made by the last preflop raiser.
+	cBetMade := false
+	respondedToCBet := false
+	for _, a := range h.GetActionsByStreet(history.StreetFlop) {
+		if cBetMade == false {
+			if a.Type == history.ActionBet {
+				if a.PlayerID != preflopRaiser {
+					break
+				}
+				cBetMade = true
+			}
+			continue
+		}
+		if isAggressive(a) && a.PlayerID != playerID {
+			// Players acting after a raise are no longer facing just the c-bet
+			break
+		}
+		if a.PlayerID == playerID && respondedToCBet == false {
+			respondedToCBet = true
+			c.FoldToCBetOpportunities++
+			if a.Type == history.ActionFold {
+				c.FoldToCBet++
+			}
+			break
+		}
+	}
+
+	// Postflop aggression
+	for _, street := range []history.Street{history.StreetFlop, history.StreetTurn, history.StreetRiver} {
+		for _, a := range h.GetActionsByStreet(street) {
+			if a.PlayerID != playerID {
+				continue
+			}
+			if isAggressive(a) {
+				c.PostflopAggressive++
+			} else if a.Type == history.ActionCall {
+				c.PostflopCalls++
+			}
+		}
+	}
+
+	// Showdowns
+	winnings := h.GetWinnings(playerID)
+	if foldedPreflop == false && h.Board.Flop[0] != nil {
+		c.SawFlop++
+		if hasFolded(h, playerID) == false && countPlayersNotFolded(h) > 1 {
+			c.WentToShowdown++
+			if winnings > 0 {
+				c.WonAtShowdown++
+			}
+		}
+	}
+
+	// Net winnings
+	if h.BigBlind > 0 {
+		c.BigBlindsWon += float64(h.GetNetWinnings(playerID)) / float64(h.BigBlind)
+	}
+}
+
+// Stats calculates the stats from the counters.
+func (c Counters) Stats() Stats {
+	s := Stats{
+		Counters:       c,
+		FoldToCBet:     percent(c.FoldToCBet, c.FoldToCBetOpportunities),
+		Hands:          c.Hands,
+		PFR:            percent(c.PFR, c.Hands),
+		ThreeBet:       percent(c.ThreeBets, c.ThreeBetOpportunities),
+		VPIP:           percent(c.VPIP, c.Hands),
+		WentToShowdown: percent(c.WentToShowdown, c.SawFlop),
+		WonAtShowdown:  percent(c.WonAtShowdown, c.WentToShowdown),
+	}
+	if c.PostflopCalls > 0 {
+		s.AggressionFactor = round(float64(c.PostflopAggressive) / float64(c.PostflopCalls))
+	} else {
+		// Count no calls as one call so that the factor is not infinite
+		s.AggressionFactor = float64(c.PostflopAggressive)
+	}
+	if c.Hands > 0 {
+		s.BigBlindsPer100 = round(c.BigBlindsWon / float64(c.Hands) * 100)
+	}
+	return s
+}
+
+func isAggressive(a history.Action) bool {
+	return a.Type == history.ActionBet || a.Type == history.ActionRaise
+}
+
+func hasFolded(h *history.HandHistory, playerID string) bool {
+	for _, a := range h.Actions {
+		if a.PlayerID == playerID && a.Type == history.ActionFold {
+			return true
+		}
+	}
+	return false
+}
+
+func countPlayersNotFolded(h *history.HandHistory) int {
+	count := 0
+	for _, seat := range h.Seats {
+		if hasFolded(h, seat.PlayerID) == false {
+			count++
+		}
+	}
+	return count
+}
+
+func percent(n int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return round(float64(n) / float64(total) * 100)
+}
+
+// round rounds to one decimal place
+func round(f float64) float64 {
+	return math.Round(f*10) / 10
+}
@@ -0,0 +1,174 @@
+package stats_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/history"
+	"github.com/richard-to/go-poker/pkg/stats"
+)
+
+const cBetHand = `PokerStars Hand #5001: Hold'em No Limit (1/2) - 2021/02/03 10:00:00 UTC
+Table 'Main' 6-max Seat #1 is the button
+Seat 1: alice (100 in chips)
+Seat 2: bob (100 in chips)
+Seat 3: carol (100 in chips)
+bob: posts small blind 1
+carol: posts big blind 2
+*** HOLE CARDS ***
+alice: raises 4 to 6
+bob: calls 5
+carol: calls 4
+*** FLOP *** [Kd 8s 3c]
+bob: checks
+carol: checks
+alice: bets 10
+bob: calls 10
+carol: folds
+*** TURN *** [Kd 8s 3c] [2h]
+bob: checks
+alice: checks
+*** RIVER *** [Kd 8s 3c 2h] [7d]
+bob: checks
+alice: checks
+*** SHOW DOWN ***
+bob: shows [Kh Qc] (a pair of Kings)
+alice: mucks hand
+bob collected 38 from pot
+*** SUMMARY ***
+Total pot 38 | Rake 0
+Board [Kd 8s 3c 2h 7d]
+`
+
+const threeBetHand = `PokerStars Hand #5002: Hold'em No Limit (1/2) - 2021/02/03 11:00:00 UTC
+Table 'Other' 6-max Seat #2 is the button
+Seat 1: alice (100 in chips)
+Seat 2: bob (100 in chips)
+Seat 3: carol (100 in chips)
+carol: posts small blind 1
+alice: posts big blind 2
+*** HOLE CARDS ***
+bob: raises 4 to 6
+carol: raises 12 to 18
+alice: folds
+bob: folds
+Uncalled bet (12) returned to carol
+carol collected 14 from pot
+*** SUMMARY ***
+Total pot 14 | Rake 0
+`
+
+func parseHands() []*history.HandHistory {
+	histories, err := history.ParsePokerStars(cBetHand + "\n\n" + threeBetHand)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(histories).To(HaveLen(2))
+	return histories
+}
+
+var _ = Describe("Compute", func() {
+	It("calculates preflop stats", func() {
+		histories := parseHands()
+
+		alice := stats.Compute(histories, "alice", stats.Filter{})
+		Expect(alice.Hands).To(Equal(2))
+		Expect(alice.VPIP).To(Equal(50.0))
+		Expect(alice.PFR).To(Equal(50.0))
+		// The only raise alice faced had already been 3-bet
+		Expect(alice.Counters.ThreeBetOpportunities).To(Equal(0))
+
+		carol := stats.Compute(histories, "carol", stats.Filter{})
+		Expect(carol.VPIP).To(Equal(100.0))
+		Expect(carol.PFR).To(Equal(50.0))
+		Expect(carol.ThreeBet).To(Equal(50.0))
+		Expect(carol.Counters.ThreeBetOpportunities).To(Equal(2))
+	})
+
+	It("calculates postflop stats", func() {
+		histories := parseHands()
+
+		alice := stats.Compute(histories, "alice", stats.Filter{})
+		Expect(alice.AggressionFactor).To(Equal(1.0))
+		Expect(alice.WentToShowdown).To(Equal(100.0))
+		Expect(alice.WonAtShowdown).To(Equal(0.0))
+
+		bob := stats.Compute(histories, "bob", stats.Filter{})
+		Expect(bob.AggressionFactor).To(Equal(0.0))
+		Expect(bob.Counters.FoldToCBetOpportunities).To(Equal(1))
+		Expect(bob.FoldToCBet).To(Equal(0.0))
+		Expect(bob.WentToShowdown).To(Equal(100.0))
+		Expect(bob.WonAtShowdown).To(Equal(100.0))
+
+		carol := stats.Compute(histories, "carol", stats.Filter{})
+		Expect(carol.FoldToCBet).To(Equal(100.0))
+		Expect(carol.WentToShowdown).To(Equal(0.0))
+	})
+
+	It("calculates big blinds won per 100 hands", func() {
+		histories := parseHands()
+
+		// Bob wins 22 chips in the first hand and loses 6 chips in the second hand
+		bob := stats.Compute(histories, "bob", stats.Filter{})
+		Expect(bob.Counters.BigBlindsWon).To(Equal(8.0))
+		Expect(bob.BigBlindsPer100).To(Equal(400.0))
+
+		// The uncalled part of carol's raise is not counted
+		carol := stats.Compute(histories, "carol", stats.Filter{})
+		Expect(carol.Counters.BigBlindsWon).To(Equal(-3.0 + 4.0))
+	})
+
+	It("filters hands by table and time", func() {
+		histories := parseHands()
+
+		s := stats.Compute(histories, "alice", stats.Filter{TableName: "Other"})
+		Expect(s.Hands).To(Equal(1))
+
+		since := time.Date(2021, 2, 3, 10, 30, 0, 0, time.UTC)
+		s = stats.Compute(histories, "alice", stats.Filter{Since: since})
+		Expect(s.Hands).To(Equal(1))
+		s = stats.Compute(histories, "alice", stats.Filter{Until: since})
+		Expect(s.Hands).To(Equal(1))
+	})
+})
+
+var _ = Describe("Tracker", func() {
+	It("only adds hands for players that have been loaded", func() {
+		histories := parseHands()
+		tracker := stats.NewTracker()
+		Expect(tracker.GetHUD("alice")).To(BeNil())
+
+		tracker.Load("alice", histories[:1])
+		tracker.Add(histories[1])
+		Expect(tracker.Has("bob")).To(BeFalse())
+
+		hud := tracker.GetHUD("alice")
+		Expect(hud.Hands).To(Equal(2))
+		Expect(hud.VPIP).To(Equal(50.0))
+		Expect(hud.PFR).To(Equal(50.0))
+	})
+
+	It("counts the hands that finish while a player is loading once", func() {
+		histories := parseHands()
+		tracker := stats.NewTracker()
+		Expect(tracker.StartLoading("alice")).To(BeTrue())
+		Expect(tracker.StartLoading("alice")).To(BeFalse())
+
+		// The first hand was saved before the past hands were listed
+		tracker.Add(histories[0])
+		tracker.Add(histories[1])
+		Expect(tracker.GetHUD("alice")).To(BeNil())
+
+		tracker.Load("alice", histories[:1])
+		Expect(tracker.GetHUD("alice").Hands).To(Equal(2))
+		Expect(tracker.StartLoading("alice")).To(BeFalse())
+	})
+
+	It("can load a player again after loading is cancelled", func() {
+		tracker := stats.NewTracker()
+		Expect(tracker.StartLoading("alice")).To(BeTrue())
+		tracker.CancelLoading("alice")
+		Expect(tracker.Has("alice")).To(BeFalse())
+		Expect(tracker.StartLoading("alice")).To(BeTrue())
+	})
+})
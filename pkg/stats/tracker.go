@@ -0,0 +1,127 @@
+package stats
+
+import (
+	"sync"
+
+	"github.com/richard-to/go-poker/pkg/history"
+)
+
+// HUD is a compact summary of a player's stats that is shown next to their seat.
+type HUD struct {
+	AggressionFactor float64 `json:"af"`
+	Hands            int     `json:"hands"`
+	PFR              float64 `json:"pfr"`
+	ThreeBet         float64 `json:"3b"`
+	VPIP             float64 `json:"vpip"`
+	WentToShowdown   float64 `json:"wtsd"`
+}
+
+// Tracker keeps the all time counters of the players at a table up to date as hands finish.
+//
+// Players need to be loaded from their hand histories before hands are added for them.
+// Loading can take a while, so hands that finish while a player is loading are kept until
+// their past hands have been counted.
+type Tracker struct {
+	counters map[string]*Counters
+	loading  map[string][]*history.HandHistory // Hands that finished while the player was loading
+	mu       sync.RWMutex
+}
+
+// NewTracker creates a new tracker
+func NewTracker() *Tracker {
+	return &Tracker{
+		counters: make(map[string]*Counters),
+		loading:  make(map[string][]*history.HandHistory),
+	}
+}
+
+// StartLoading marks a player as loading. False is returned if the player has already been
+// loaded or is loading.
+func (t *Tracker) StartLoading(playerName string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.counters[playerName]; ok {
+		return false
+	}
+	if _, ok := t.loading[playerName]; ok {
+		return false
+	}
+	t.loading[playerName] = make([]*history.HandHistory, 0)
+	return true
+}
+
+// CancelLoading stops waiting for a player's past hands, so the player can be loaded again.
+func (t *Tracker) CancelLoading(playerName string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.loading, playerName)
+}
+
+// Load calculates a player's counters from their past hands.
+//
+// Hands that finished while the player was loading are counted too, unless they were already
+// in the past hands.
+func (t *Tracker) Load(playerName string, histories []*history.HandHistory) {
+	c := &Counters{}
+	handIDs := make(map[string]bool)
+	for _, h := range histories {
+		c.Add(h, playerName)
+		handIDs[h.ID] = true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, h := range t.loading[playerName] {
+		if handIDs[h.ID] == false {
+			c.Add(h, playerName)
+		}
+	}
+	delete(t.loading, playerName)
+	t.counters[playerName] = c
+}
+
+// Has checks if a player has been loaded.
+func (t *Tracker) Has(playerName string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	_, ok := t.counters[playerName]
+	return ok
+}
+
+// Add adds a finished hand to the counters of the players in the hand who have been loaded.
+func (t *Tracker) Add(h *history.HandHistory) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, seat := range h.Seats {
+		if c, ok := t.counters[seat.Name]; ok {
+			c.Add(h, seat.Name)
+		} else if hands, ok := t.loading[seat.Name]; ok {
+			t.loading[seat.Name] = append(hands, h)
+		}
+	}
+}
+
+// Get gets a player's all time stats.
+func (t *Tracker) Get(playerName string) Stats {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if c, ok := t.counters[playerName]; ok {
+		return c.Stats()
+	}
+	return Stats{}
+}
+
+// GetHUD gets the HUD for a player. Nil is returned if the player has not been loaded.
+func (t *Tracker) GetHUD(playerName string) *HUD {
+	if t.Has(playerName) == false {
+		return nil
+	}
+	s := t.Get(playerName)
+	return &HUD{
+		AggressionFactor: s.AggressionFactor,
+		Hands:            s.Hands,
+		PFR:              s.PFR,
+		ThreeBet:         s.ThreeBet,
+		VPIP:             s.VPIP,
+		WentToShowdown:   s.WentToShowdown,
+	}
+}
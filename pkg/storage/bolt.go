@@ -0,0 +1,701 @@
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/history"
+	"github.com/richard-to/go-poker/pkg/rating"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Bucket names
+var (
+	bankrollsBucket     = []byte("bankrolls")
+	bansBucket          = []byte("bans")
+	handsBucket         = []byte("hands")
+	handsByPlayerBucket = []byte("hands-by-player") // Nested bucket per player
+	journalsBucket      = []byte("journals")        // Nested bucket per table
+	ratingChangesBucket = []byte("rating-changes")  // Nested bucket per player
+	ratingsBucket       = []byte("ratings")
+	scheduledBucket     = []byte("scheduled-tournaments")
+	snapshotsBucket     = []byte("snapshots")
+	stacksBucket        = []byte("stacks") // Nested bucket per table
+	tablesBucket        = []byte("tables")
+	transactionsBucket  = []byte("transactions")
+	usersBucket         = []byte("users")
+)
+
+// BoltStore stores everything in a bbolt database file.
+//
+// Every chip movement happens in a single bbolt transaction, so it is either saved in full
+// or not at all.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens the database file, creating it if it does not exist.
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		buckets := [][]byte{
+			bankrollsBucket,
+			bansBucket,
+			handsBucket,
+			handsByPlayerBucket,
+			journalsBucket,
+			ratingChangesBucket,
+			ratingsBucket,
+			scheduledBucket,
+			snapshotsBucket,
+			stacksBucket,
+			tablesBucket,
+			transactionsBucket,
+			usersBucket,
+		}
+		for _, name := range buckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// HandHistories gets the hand history store.
+func (s *BoltStore) HandHistories() history.Store {
+	return &boltHandStore{db: s.db}
+}
+
+// Ratings gets the rating store.
+func (s *BoltStore) Ratings() rating.Store {
+	return &boltRatingStore{db: s.db}
+}
+
+// Users gets the user store.
+func (s *BoltStore) Users() auth.UserStore {
+	return &boltUserStore{db: s.db}
+}
+
+// OpenBankroll creates a bankroll with the starting amount if the user does not have one yet.
+func (s *BoltStore) OpenBankroll(username string, amount int) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bankrolls := tx.Bucket(bankrollsBucket)
+		if bankrolls.Get([]byte(username)) != nil {
+			return nil
+		}
+		if err := putInt(bankrolls, username, amount); err != nil {
+			return err
+		}
+		return putTransaction(tx, newTransaction(username, amount, ReasonDeposit, ""))
+	})
+}
+
+// GetBankroll gets the chips in a user's bankroll.
+func (s *BoltStore) GetBankroll(username string) (int, error) {
+	amount := 0
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		amount, err = getInt(tx.Bucket(bankrollsBucket), username)
+		return err
+	})
+	return amount, err
+}
+
+// AdjustBankroll adds chips to or removes chips from a bankroll. The note records why.
+func (s *BoltStore) AdjustBankroll(username string, amount int, note string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bankrolls := tx.Bucket(bankrollsBucket)
+		bankroll, err := getInt(bankrolls, username)
+		if err != nil {
+			return err
+		}
+		if bankroll+amount < 0 {
+			return ErrInsufficientChips
+		}
+		if err := putInt(bankrolls, username, bankroll+amount); err != nil {
+			return err
+		}
+		t := newTransaction(username, amount, ReasonAdjustment, "")
+		t.Note = note
+		return putTransaction(tx, t)
+	})
+}
+
+// BuyIn moves chips from a bankroll to a stack at a table.
+func (s *BoltStore) BuyIn(tableName string, username string, amount int) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bankrolls := tx.Bucket(bankrollsBucket)
+		bankroll, err := getInt(bankrolls, username)
+		if err != nil {
+			return err
+		}
+		if bankroll < amount {
+			return ErrInsufficientChips
+		}
+		stacks, err := tx.Bucket(stacksBucket).CreateBucketIfNotExists([]byte(tableName))
+		if err != nil {
+			return err
+		}
+		stack, err := getInt(stacks, username)
+		if err != nil {
+			return err
+		}
+		if err := putInt(bankrolls, username, bankroll-amount); err != nil {
+			return err
+		}
+		if err := putInt(stacks, username, stack+amount); err != nil {
+			return err
+		}
+		return putTransaction(tx, newTransaction(username, -amount, ReasonBuyIn, tableName))
+	})
+}
+
+// CashOut moves a stack at a table back to the bankroll and returns the amount.
+func (s *BoltStore) CashOut(tableName string, username string) (int, error) {
+	amount := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		amount, err = cashOut(tx, tableName, username)
+		return err
+	})
+	return amount, err
+}
+
+// CashOutTable cashes out every stack at a table.
+func (s *BoltStore) CashOutTable(tableName string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		stacks := tx.Bucket(stacksBucket).Bucket([]byte(tableName))
+		if stacks == nil {
+			return nil
+		}
+		usernames := make([]string, 0)
+		stacks.ForEach(func(k, v []byte) error {
+			usernames = append(usernames, string(k))
+			return nil
+		})
+		for _, username := range usernames {
+			if _, err := cashOut(tx, tableName, username); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetStacks gets the stacks at a table keyed by username.
+func (s *BoltStore) GetStacks(tableName string) (map[string]int, error) {
+	stacks := make(map[string]int)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		stacks, err = getStacks(tx, tableName)
+		return err
+	})
+	return stacks, err
+}
+
+// SettleHand saves the stacks at the end of a hand.
+func (s *BoltStore) SettleHand(tableName string, stacks map[string]int, rake int) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		before, err := getStacks(tx, tableName)
+		if err != nil {
+			return err
+		}
+		if err := checkSettlement(tableName, before, stacks, rake); err != nil {
+			return err
+		}
+		bucket := tx.Bucket(stacksBucket).Bucket([]byte(tableName))
+		for username, chips := range stacks {
+			if err := putInt(bucket, username, chips); err != nil {
+				return err
+			}
+		}
+		if rake == 0 {
+			return nil
+		}
+		bankrolls := tx.Bucket(bankrollsBucket)
+		house, err := getInt(bankrolls, HouseAccount)
+		if err != nil {
+			return err
+		}
+		if err := putInt(bankrolls, HouseAccount, house+rake); err != nil {
+			return err
+		}
+		return putTransaction(tx, newTransaction(HouseAccount, rake, ReasonRake, tableName))
+	})
+}
+
+// ListTransactions lists the transactions for a user, oldest first.
+func (s *BoltStore) ListTransactions(username string) ([]Transaction, error) {
+	transactions := make([]Transaction, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(transactionsBucket).ForEach(func(k, v []byte) error {
+			var t Transaction
+			if err := json.Unmarshal(v, &t); err != nil {
+				return err
+			}
+			if t.Account == username {
+				transactions = append(transactions, t)
+			}
+			return nil
+		})
+	})
+	return transactions, err
+}
+
+// SaveSnapshot replaces the snapshot of a table's game state and clears its journal.
+func (s *BoltStore) SaveSnapshot(tableName string, snapshot []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(snapshotsBucket).Put([]byte(tableName), snapshot); err != nil {
+			return err
+		}
+		journals := tx.Bucket(journalsBucket)
+		if journals.Bucket([]byte(tableName)) == nil {
+			return nil
+		}
+		return journals.DeleteBucket([]byte(tableName))
+	})
+}
+
+// AppendJournal adds an action to the table's journal.
+func (s *BoltStore) AppendJournal(tableName string, entry []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		journal, err := tx.Bucket(journalsBucket).CreateBucketIfNotExists([]byte(tableName))
+		if err != nil {
+			return err
+		}
+		seq, err := journal.NextSequence()
+		if err != nil {
+			return err
+		}
+		return journal.Put(encodeSequence(seq), entry)
+	})
+}
+
+// LoadSnapshot gets the table's snapshot and the journal entries added since.
+func (s *BoltStore) LoadSnapshot(tableName string) ([]byte, [][]byte, error) {
+	var snapshot []byte
+	journal := make([][]byte, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		// Values are only valid during the transaction, so they need to be copied
+		if data := tx.Bucket(snapshotsBucket).Get([]byte(tableName)); data != nil {
+			snapshot = append([]byte{}, data...)
+		}
+		bucket := tx.Bucket(journalsBucket).Bucket([]byte(tableName))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			journal = append(journal, append([]byte{}, v...))
+			return nil
+		})
+	})
+	return snapshot, journal, err
+}
+
+// SaveTableConfig saves a table config as JSON.
+func (s *BoltStore) SaveTableConfig(tableName string, config interface{}) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(tablesBucket).Put([]byte(tableName), data)
+	})
+}
+
+// GetTableConfig loads a table config into the given value.
+func (s *BoltStore) GetTableConfig(tableName string, config interface{}) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(tablesBucket).Get([]byte(tableName))
+		if data == nil {
+			return ErrTableConfigNotFound
+		}
+		return json.Unmarshal(data, config)
+	})
+}
+
+// ListTableConfigs gets the table configs as JSON, sorted by table name.
+func (s *BoltStore) ListTableConfigs() ([][]byte, error) {
+	configs := make([][]byte, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(tablesBucket).ForEach(func(k, v []byte) error {
+			configs = append(configs, append([]byte{}, v...))
+			return nil
+		})
+	})
+	return configs, err
+}
+
+// DeleteTableConfig removes a table config. Does nothing if it was not saved.
+func (s *BoltStore) DeleteTableConfig(tableName string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(tablesBucket).Delete([]byte(tableName))
+	})
+}
+
+// SaveScheduledTournament saves a scheduled tournament as JSON.
+func (s *BoltStore) SaveScheduledTournament(name string, tournament interface{}) error {
+	data, err := json.Marshal(tournament)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(scheduledBucket).Put([]byte(name), data)
+	})
+}
+
+// ListScheduledTournaments gets the scheduled tournaments as JSON, sorted by name.
+func (s *BoltStore) ListScheduledTournaments() ([][]byte, error) {
+	tournaments := make([][]byte, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(scheduledBucket).ForEach(func(k, v []byte) error {
+			tournaments = append(tournaments, append([]byte{}, v...))
+			return nil
+		})
+	})
+	return tournaments, err
+}
+
+// DeleteScheduledTournament removes a scheduled tournament. Does nothing if it was not saved.
+func (s *BoltStore) DeleteScheduledTournament(name string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(scheduledBucket).Delete([]byte(name))
+	})
+}
+
+// SaveBan saves a ban as JSON.
+func (s *BoltStore) SaveBan(key string, ban interface{}) error {
+	data, err := json.Marshal(ban)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bansBucket).Put([]byte(key), data)
+	})
+}
+
+// ListBans gets the bans as JSON, sorted by key.
+func (s *BoltStore) ListBans() ([][]byte, error) {
+	bans := make([][]byte, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bansBucket).ForEach(func(k, v []byte) error {
+			bans = append(bans, append([]byte{}, v...))
+			return nil
+		})
+	})
+	return bans, err
+}
+
+// DeleteBan removes a ban. Does nothing if it was not saved.
+func (s *BoltStore) DeleteBan(key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bansBucket).Delete([]byte(key))
+	})
+}
+
+// Close closes the database file.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// boltUserStore stores player accounts in the users bucket keyed by lower case username.
+type boltUserStore struct {
+	db *bolt.DB
+}
+
+// Create adds a new user. Usernames are unique regardless of case.
+func (s *boltUserStore) Create(u *auth.User) error {
+	data, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		users := tx.Bucket(usersBucket)
+		key := []byte(strings.ToLower(u.Username))
+		if users.Get(key) != nil {
+			return auth.ErrUsernameTaken
+		}
+		return users.Put(key, data)
+	})
+}
+
+// Get gets a user by username.
+func (s *boltUserStore) Get(username string) (*auth.User, error) {
+	var u *auth.User
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(usersBucket).Get([]byte(strings.ToLower(username)))
+		if data == nil {
+			return auth.ErrUserNotFound
+		}
+		u = &auth.User{}
+		return json.Unmarshal(data, u)
+	})
+	return u, err
+}
+
+// boltHandStore stores hand histories in the hands bucket keyed by hand ID.
+//
+// Each player has an index of the hands they played in the order they were saved.
+type boltHandStore struct {
+	db *bolt.DB
+}
+
+// Save saves a hand history.
+func (s *boltHandStore) Save(h *history.HandHistory) error {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		hands := tx.Bucket(handsBucket)
+		if err := hands.Put([]byte(h.ID), data); err != nil {
+			return err
+		}
+		seq, err := hands.NextSequence()
+		if err != nil {
+			return err
+		}
+		for _, seat := range h.Seats {
+			index, err := tx.Bucket(handsByPlayerBucket).CreateBucketIfNotExists([]byte(seat.Name))
+			if err != nil {
+				return err
+			}
+			if err := index.Put(encodeSequence(seq), []byte(h.ID)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AddAction records an action taken after the hand history was saved, such as cards shown
+// once the hand is over.
+func (s *boltHandStore) AddAction(id string, a history.Action) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		h, err := getHandHistory(tx, []byte(id))
+		if err != nil {
+			return err
+		}
+		h.AddAction(a)
+		data, err := json.Marshal(h)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(handsBucket).Put([]byte(id), data)
+	})
+}
+
+// Get gets a hand history by ID.
+func (s *boltHandStore) Get(id string) (*history.HandHistory, error) {
+	var h *history.HandHistory
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		h, err = getHandHistory(tx, []byte(id))
+		return err
+	})
+	return h, err
+}
+
+// ListByPlayer gets the hand histories of the hands a player was dealt into, oldest first.
+func (s *boltHandStore) ListByPlayer(name string) ([]*history.HandHistory, error) {
+	histories := make([]*history.HandHistory, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		index := tx.Bucket(handsByPlayerBucket).Bucket([]byte(name))
+		if index == nil {
+			return nil
+		}
+		return index.ForEach(func(k, v []byte) error {
+			h, err := getHandHistory(tx, v)
+			if err != nil {
+				return err
+			}
+			histories = append(histories, h)
+			return nil
+		})
+	})
+	return histories, err
+}
+
+// boltRatingStore stores ratings in the ratings bucket keyed by username.
+//
+// Each player has a log of their rating changes in the order they were saved.
+type boltRatingStore struct {
+	db *bolt.DB
+}
+
+// Get gets a player's rating. A new rating is returned if the player has not been rated.
+func (s *boltRatingStore) Get(username string) (rating.Rating, error) {
+	r := rating.NewRating(username)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(ratingsBucket).Get([]byte(username))
+		if data == nil {
+			return nil
+		}
+		return json.Unmarshal(data, &r)
+	})
+	return r, err
+}
+
+// List lists every rating.
+func (s *boltRatingStore) List() ([]rating.Rating, error) {
+	ratings := make([]rating.Rating, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(ratingsBucket).ForEach(func(k, v []byte) error {
+			var r rating.Rating
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+			ratings = append(ratings, r)
+			return nil
+		})
+	})
+	return ratings, err
+}
+
+// ListChanges lists the changes to a player's rating, oldest first.
+func (s *boltRatingStore) ListChanges(username string) ([]rating.Change, error) {
+	changes := make([]rating.Change, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(ratingChangesBucket).Bucket([]byte(username))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var c rating.Change
+			if err := json.Unmarshal(v, &c); err != nil {
+				return err
+			}
+			changes = append(changes, c)
+			return nil
+		})
+	})
+	return changes, err
+}
+
+// Save saves the new ratings and the changes that produced them.
+func (s *boltRatingStore) Save(ratings []rating.Rating, changes []rating.Change) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, r := range ratings {
+			data, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			if err := tx.Bucket(ratingsBucket).Put([]byte(r.Username), data); err != nil {
+				return err
+			}
+		}
+		for _, c := range changes {
+			bucket, err := tx.Bucket(ratingChangesBucket).CreateBucketIfNotExists([]byte(c.Username))
+			if err != nil {
+				return err
+			}
+			seq, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+			data, err := json.Marshal(c)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(encodeSequence(seq), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func getHandHistory(tx *bolt.Tx, id []byte) (*history.HandHistory, error) {
+	data := tx.Bucket(handsBucket).Get(id)
+	if data == nil {
+		return nil, history.ErrNotFound
+	}
+	h := &history.HandHistory{}
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func cashOut(tx *bolt.Tx, tableName string, username string) (int, error) {
+	stacks := tx.Bucket(stacksBucket).Bucket([]byte(tableName))
+	if stacks == nil || stacks.Get([]byte(username)) == nil {
+		return 0, nil
+	}
+	amount, err := getInt(stacks, username)
+	if err != nil {
+		return 0, err
+	}
+	bankrolls := tx.Bucket(bankrollsBucket)
+	bankroll, err := getInt(bankrolls, username)
+	if err != nil {
+		return 0, err
+	}
+	if err := stacks.Delete([]byte(username)); err != nil {
+		return 0, err
+	}
+	if err := putInt(bankrolls, username, bankroll+amount); err != nil {
+		return 0, err
+	}
+	return amount, putTransaction(tx, newTransaction(username, amount, ReasonCashOut, tableName))
+}
+
+func getStacks(tx *bolt.Tx, tableName string) (map[string]int, error) {
+	stacks := make(map[string]int)
+	bucket := tx.Bucket(stacksBucket).Bucket([]byte(tableName))
+	if bucket == nil {
+		return stacks, nil
+	}
+	err := bucket.ForEach(func(k, v []byte) error {
+		chips, err := strconv.Atoi(string(v))
+		stacks[string(k)] = chips
+		return err
+	})
+	return stacks, err
+}
+
+func putTransaction(tx *bolt.Tx, t Transaction) error {
+	transactions := tx.Bucket(transactionsBucket)
+	seq, err := transactions.NextSequence()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return transactions.Put(encodeSequence(seq), data)
+}
+
+// getInt gets an integer value. Missing keys are treated as 0.
+func getInt(b *bolt.Bucket, key string) (int, error) {
+	v := b.Get([]byte(key))
+	if v == nil {
+		return 0, nil
+	}
+	return strconv.Atoi(string(v))
+}
+
+func putInt(b *bolt.Bucket, key string, value int) error {
+	return b.Put([]byte(key), []byte(strconv.Itoa(value)))
+}
+
+// encodeSequence encodes a sequence number so that keys are sorted in the order they were added
+func encodeSequence(seq uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, seq)
+	return b
+}
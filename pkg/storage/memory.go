@@ -0,0 +1,329 @@
+package storage
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/history"
+	"github.com/richard-to/go-poker/pkg/rating"
+)
+
+// MemoryStore keeps everything in memory.
+//
+// Everything is lost when the server restarts. Useful for tests and local development.
+type MemoryStore struct {
+	bankrolls            map[string]int
+	bans                 map[string][]byte
+	hands                *history.MemoryStore
+	journals             map[string][][]byte
+	mu                   sync.Mutex
+	ratings              *rating.MemoryStore
+	scheduledTournaments map[string][]byte
+	snapshots            map[string][]byte
+	stacks               map[string]map[string]int // Keyed by table name, then username
+	tableConfigs         map[string][]byte
+	transactions         []Transaction
+	users                *auth.MemoryUserStore
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		bankrolls:            make(map[string]int),
+		bans:                 make(map[string][]byte),
+		hands:                history.NewMemoryStore(),
+		journals:             make(map[string][][]byte),
+		ratings:              rating.NewMemoryStore(),
+		scheduledTournaments: make(map[string][]byte),
+		snapshots:            make(map[string][]byte),
+		stacks:               make(map[string]map[string]int),
+		tableConfigs:         make(map[string][]byte),
+		transactions:         make([]Transaction, 0),
+		users:                auth.NewMemoryUserStore(),
+	}
+}
+
+// HandHistories gets the hand history store.
+func (s *MemoryStore) HandHistories() history.Store {
+	return s.hands
+}
+
+// Ratings gets the rating store.
+func (s *MemoryStore) Ratings() rating.Store {
+	return s.ratings
+}
+
+// Users gets the user store.
+func (s *MemoryStore) Users() auth.UserStore {
+	return s.users
+}
+
+// OpenBankroll creates a bankroll with the starting amount if the user does not have one yet.
+func (s *MemoryStore) OpenBankroll(username string, amount int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.bankrolls[username]; ok {
+		return nil
+	}
+	s.bankrolls[username] = amount
+	s.transactions = append(s.transactions, newTransaction(username, amount, ReasonDeposit, ""))
+	return nil
+}
+
+// GetBankroll gets the chips in a user's bankroll.
+func (s *MemoryStore) GetBankroll(username string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bankrolls[username], nil
+}
+
+// AdjustBankroll adds chips to or removes chips from a bankroll. The note records why.
+func (s *MemoryStore) AdjustBankroll(username string, amount int, note string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bankrolls[username]+amount < 0 {
+		return ErrInsufficientChips
+	}
+	s.bankrolls[username] += amount
+	t := newTransaction(username, amount, ReasonAdjustment, "")
+	t.Note = note
+	s.transactions = append(s.transactions, t)
+	return nil
+}
+
+// BuyIn moves chips from a bankroll to a stack at a table.
+func (s *MemoryStore) BuyIn(tableName string, username string, amount int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bankrolls[username] < amount {
+		return ErrInsufficientChips
+	}
+	if _, ok := s.stacks[tableName]; ok == false {
+		s.stacks[tableName] = make(map[string]int)
+	}
+	s.bankrolls[username] -= amount
+	s.stacks[tableName][username] += amount
+	s.transactions = append(s.transactions, newTransaction(username, -amount, ReasonBuyIn, tableName))
+	return nil
+}
+
+// CashOut moves a stack at a table back to the bankroll and returns the amount.
+func (s *MemoryStore) CashOut(tableName string, username string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cashOut(tableName, username), nil
+}
+
+// CashOutTable cashes out every stack at a table.
+func (s *MemoryStore) CashOutTable(tableName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for username := range s.stacks[tableName] {
+		s.cashOut(tableName, username)
+	}
+	return nil
+}
+
+func (s *MemoryStore) cashOut(tableName string, username string) int {
+	amount, ok := s.stacks[tableName][username]
+	if ok == false {
+		return 0
+	}
+	delete(s.stacks[tableName], username)
+	s.bankrolls[username] += amount
+	s.transactions = append(s.transactions, newTransaction(username, amount, ReasonCashOut, tableName))
+	return amount
+}
+
+// GetStacks gets the stacks at a table keyed by username.
+func (s *MemoryStore) GetStacks(tableName string) (map[string]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stacks := make(map[string]int)
+	for username, chips := range s.stacks[tableName] {
+		stacks[username] = chips
+	}
+	return stacks, nil
+}
+
+// SettleHand saves the stacks at the end of a hand.
+func (s *MemoryStore) SettleHand(tableName string, stacks map[string]int, rake int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := checkSettlement(tableName, s.stacks[tableName], stacks, rake); err != nil {
+		return err
+	}
+	for username, chips := range stacks {
+		s.stacks[tableName][username] = chips
+	}
+	if rake > 0 {
+		s.bankrolls[HouseAccount] += rake
+		s.transactions = append(s.transactions, newTransaction(HouseAccount, rake, ReasonRake, tableName))
+	}
+	return nil
+}
+
+// ListTransactions lists the transactions for a user, oldest first.
+func (s *MemoryStore) ListTransactions(username string) ([]Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	transactions := make([]Transaction, 0)
+	for _, t := range s.transactions {
+		if t.Account == username {
+			transactions = append(transactions, t)
+		}
+	}
+	return transactions, nil
+}
+
+// SaveSnapshot replaces the snapshot of a table's game state and clears its journal.
+func (s *MemoryStore) SaveSnapshot(tableName string, snapshot []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots[tableName] = snapshot
+	s.journals[tableName] = nil
+	return nil
+}
+
+// AppendJournal adds an action to the table's journal.
+func (s *MemoryStore) AppendJournal(tableName string, entry []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.journals[tableName] = append(s.journals[tableName], entry)
+	return nil
+}
+
+// LoadSnapshot gets the table's snapshot and the journal entries added since.
+func (s *MemoryStore) LoadSnapshot(tableName string) ([]byte, [][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	journal := make([][]byte, len(s.journals[tableName]))
+	copy(journal, s.journals[tableName])
+	return s.snapshots[tableName], journal, nil
+}
+
+// SaveTableConfig saves a table config as JSON.
+func (s *MemoryStore) SaveTableConfig(tableName string, config interface{}) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tableConfigs[tableName] = data
+	return nil
+}
+
+// GetTableConfig loads a table config into the given value.
+func (s *MemoryStore) GetTableConfig(tableName string, config interface{}) error {
+	s.mu.Lock()
+	data, ok := s.tableConfigs[tableName]
+	s.mu.Unlock()
+	if ok == false {
+		return ErrTableConfigNotFound
+	}
+	return json.Unmarshal(data, config)
+}
+
+// ListTableConfigs gets the table configs as JSON, sorted by table name.
+func (s *MemoryStore) ListTableConfigs() ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.tableConfigs))
+	for name := range s.tableConfigs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	configs := make([][]byte, 0, len(names))
+	for _, name := range names {
+		configs = append(configs, s.tableConfigs[name])
+	}
+	return configs, nil
+}
+
+// DeleteTableConfig removes a table config. Does nothing if it was not saved.
+func (s *MemoryStore) DeleteTableConfig(tableName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tableConfigs, tableName)
+	return nil
+}
+
+// SaveScheduledTournament saves a scheduled tournament as JSON.
+func (s *MemoryStore) SaveScheduledTournament(name string, tournament interface{}) error {
+	data, err := json.Marshal(tournament)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scheduledTournaments[name] = data
+	return nil
+}
+
+// ListScheduledTournaments gets the scheduled tournaments as JSON, sorted by name.
+func (s *MemoryStore) ListScheduledTournaments() ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.scheduledTournaments))
+	for name := range s.scheduledTournaments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tournaments := make([][]byte, 0, len(names))
+	for _, name := range names {
+		tournaments = append(tournaments, s.scheduledTournaments[name])
+	}
+	return tournaments, nil
+}
+
+// DeleteScheduledTournament removes a scheduled tournament. Does nothing if it was not saved.
+func (s *MemoryStore) DeleteScheduledTournament(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.scheduledTournaments, name)
+	return nil
+}
+
+// SaveBan saves a ban as JSON.
+func (s *MemoryStore) SaveBan(key string, ban interface{}) error {
+	data, err := json.Marshal(ban)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bans[key] = data
+	return nil
+}
+
+// ListBans gets the bans as JSON, sorted by key.
+func (s *MemoryStore) ListBans() ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.bans))
+	for key := range s.bans {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	bans := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		bans = append(bans, s.bans[key])
+	}
+	return bans, nil
+}
+
+// DeleteBan removes a ban. Does nothing if it was not saved.
+func (s *MemoryStore) DeleteBan(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.bans, key)
+	return nil
+}
+
+// Close does nothing since there is nothing to close.
+func (s *MemoryStore) Close() error {
+	return nil
+}
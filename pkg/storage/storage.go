@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/history"
+	"github.com/richard-to/go-poker/pkg/rating"
+)
+
+// Account that rake is paid into. Usernames can't contain #, so no player can register it.
+const HouseAccount string = "#house"
+
+// Transaction reasons
+const (
+	ReasonAdjustment string = "adjustment"
+	ReasonBuyIn      string = "buy-in"
+	ReasonCashOut    string = "cash-out"
+	ReasonDeposit    string = "deposit"
+	ReasonRake       string = "rake"
+)
+
+// Store persists the data that needs to survive a restart.
+//
+// Chips are either in a player's bankroll or in their stack at a table. Chips only move
+// between the two in a single transaction, so chips can't be created or destroyed if the
+// server stops part way through.
+type Store interface {
+	HandHistories() history.Store
+	Ratings() rating.Store
+	Users() auth.UserStore
+
+	// OpenBankroll creates a bankroll with the starting amount if the user does not have one yet.
+	OpenBankroll(username string, amount int) error
+	GetBankroll(username string) (int, error)
+	// AdjustBankroll adds chips to or removes chips from a bankroll. The note records why.
+	AdjustBankroll(username string, amount int, note string) error
+	// BuyIn moves chips from a bankroll to a stack at a table.
+	BuyIn(tableName string, username string, amount int) error
+	// CashOut moves a stack at a table back to the bankroll and returns the amount.
+	CashOut(tableName string, username string) (int, error)
+	// CashOutTable cashes out every stack at a table.
+	CashOutTable(tableName string) error
+	GetStacks(tableName string) (map[string]int, error)
+	// SettleHand saves the stacks at the end of a hand. The stacks must add up to the stacks
+	// at the start of the hand minus the rake.
+	SettleHand(tableName string, stacks map[string]int, rake int) error
+	ListTransactions(username string) ([]Transaction, error)
+
+	// SaveSnapshot replaces the snapshot of a table's game state and clears its journal.
+	SaveSnapshot(tableName string, snapshot []byte) error
+	// AppendJournal adds an action to the table's journal.
+	AppendJournal(tableName string, entry []byte) error
+	// LoadSnapshot gets the table's snapshot and the journal entries added since, oldest first.
+	// The snapshot is nil if one has not been saved.
+	LoadSnapshot(tableName string) ([]byte, [][]byte, error)
+
+	// SaveTableConfig saves a table config as JSON.
+	SaveTableConfig(tableName string, config interface{}) error
+	// GetTableConfig loads a table config into the given value.
+	GetTableConfig(tableName string, config interface{}) error
+	// ListTableConfigs gets the table configs as JSON, sorted by table name.
+	ListTableConfigs() ([][]byte, error)
+	// DeleteTableConfig removes a table config. Does nothing if it was not saved.
+	DeleteTableConfig(tableName string) error
+
+	// SaveScheduledTournament saves a scheduled tournament as JSON.
+	SaveScheduledTournament(name string, tournament interface{}) error
+	// ListScheduledTournaments gets the scheduled tournaments as JSON, sorted by name.
+	ListScheduledTournaments() ([][]byte, error)
+	// DeleteScheduledTournament removes a scheduled tournament. Does nothing if it was not saved.
+	DeleteScheduledTournament(name string) error
+
+	// SaveBan saves a ban as JSON.
+	SaveBan(key string, ban interface{}) error
+	// ListBans gets the bans as JSON, sorted by key.
+	ListBans() ([][]byte, error)
+	// DeleteBan removes a ban. Does nothing if it was not saved.
+	DeleteBan(key string) error
+
+	Close() error
+}
+
+// Transaction is a record of chips moving in or out of a bankroll.
+type Transaction struct {
+	Account   string    `json:"account"`
+	Amount    int       `json:"amount"` // Positive amounts are added to the bankroll
+	CreatedAt time.Time `json:"createdAt"`
+	Note      string    `json:"note,omitempty"` // Why an admin adjusted the bankroll
+	Reason    string    `json:"reason"`
+	TableName string    `json:"tableName,omitempty"`
+}
+
+// ErrInsufficientChips is returned when a bankroll does not have enough chips for a buy-in.
+var ErrInsufficientChips = fmt.Errorf("You do not have enough chips in your bankroll")
+
+// ErrTableConfigNotFound is returned when a table config has not been saved.
+var ErrTableConfigNotFound = fmt.Errorf("Table config not found")
+
+// checkSettlement checks that no chips were created or destroyed during a hand
+func checkSettlement(tableName string, before map[string]int, after map[string]int, rake int) error {
+	if rake < 0 {
+		return fmt.Errorf("Rake cannot be negative")
+	}
+	totalBefore := 0
+	totalAfter := 0
+	for username, chips := range after {
+		stack, ok := before[username]
+		if ok == false {
+			return fmt.Errorf("%s does not have a stack at table %s", username, tableName)
+		}
+		if chips < 0 {
+			return fmt.Errorf("%s cannot have a negative stack", username)
+		}
+		totalBefore += stack
+		totalAfter += chips
+	}
+	if totalBefore != totalAfter+rake {
+		return fmt.Errorf(
+			"Stacks at table %s do not add up: %d chips before the hand, %d after and %d rake",
+			tableName, totalBefore, totalAfter, rake,
+		)
+	}
+	return nil
+}
+
+func newTransaction(account string, amount int, reason string, tableName string) Transaction {
+	return Transaction{
+		Account:   account,
+		Amount:    amount,
+		CreatedAt: time.Now().UTC(),
+		Reason:    reason,
+		TableName: tableName,
+	}
+}
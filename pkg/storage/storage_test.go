@@ -0,0 +1,272 @@
+package storage_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/richard-to/go-poker/pkg/auth"
+	"github.com/richard-to/go-poker/pkg/history"
+	"github.com/richard-to/go-poker/pkg/rating"
+	"github.com/richard-to/go-poker/pkg/storage"
+)
+
+// describeStore runs the same specs against each store implementation
+func describeStore(name string, newStore func() (storage.Store, func())) bool {
+	return Describe(name, func() {
+		var store storage.Store
+		var cleanup func()
+
+		BeforeEach(func() {
+			store, cleanup = newStore()
+			Expect(store.OpenBankroll("alice", 1000)).To(Succeed())
+			Expect(store.OpenBankroll("bob", 1000)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			cleanup()
+		})
+
+		It("only opens a bankroll once", func() {
+			Expect(store.OpenBankroll("alice", 5000)).To(Succeed())
+			Expect(store.GetBankroll("alice")).To(Equal(1000))
+		})
+
+		It("moves chips between bankrolls and tables", func() {
+			Expect(store.BuyIn("Main", "alice", 100)).To(Succeed())
+			Expect(store.GetBankroll("alice")).To(Equal(900))
+			Expect(store.GetStacks("Main")).To(Equal(map[string]int{"alice": 100}))
+
+			Expect(store.BuyIn("Main", "alice", 2000)).To(Equal(storage.ErrInsufficientChips))
+
+			Expect(store.CashOut("Main", "alice")).To(Equal(100))
+			Expect(store.GetBankroll("alice")).To(Equal(1000))
+			Expect(store.GetStacks("Main")).To(BeEmpty())
+
+			transactions, err := store.ListTransactions("alice")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(transactions).To(HaveLen(3))
+			Expect(transactions[0].Reason).To(Equal(storage.ReasonDeposit))
+			Expect(transactions[1].Reason).To(Equal(storage.ReasonBuyIn))
+			Expect(transactions[1].Amount).To(Equal(-100))
+			Expect(transactions[2].Reason).To(Equal(storage.ReasonCashOut))
+		})
+
+		It("adjusts bankrolls with a note", func() {
+			Expect(store.AdjustBankroll("alice", 250, "Refund for a server crash")).To(Succeed())
+			Expect(store.AdjustBankroll("alice", -1500, "Too much")).To(Equal(storage.ErrInsufficientChips))
+			Expect(store.AdjustBankroll("alice", -50, "Chip dumping")).To(Succeed())
+			Expect(store.GetBankroll("alice")).To(Equal(1200))
+
+			transactions, err := store.ListTransactions("alice")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(transactions).To(HaveLen(3))
+			Expect(transactions[1].Reason).To(Equal(storage.ReasonAdjustment))
+			Expect(transactions[1].Note).To(Equal("Refund for a server crash"))
+			Expect(transactions[2].Amount).To(Equal(-50))
+		})
+
+		It("settles hands without creating or destroying chips", func() {
+			Expect(store.BuyIn("Main", "alice", 100)).To(Succeed())
+			Expect(store.BuyIn("Main", "bob", 100)).To(Succeed())
+
+			Expect(store.SettleHand("Main", map[string]int{"alice": 150, "bob": 48}, 2)).To(Succeed())
+			Expect(store.GetStacks("Main")).To(Equal(map[string]int{"alice": 150, "bob": 48}))
+			Expect(store.GetBankroll(storage.HouseAccount)).To(Equal(2))
+			Expect(store.GetBankroll("house")).To(Equal(0))
+
+			Expect(store.SettleHand("Main", map[string]int{"alice": 200, "bob": 48}, 0)).NotTo(Succeed())
+			Expect(store.SettleHand("Main", map[string]int{"carol": 0}, 0)).NotTo(Succeed())
+			Expect(store.GetStacks("Main")).To(Equal(map[string]int{"alice": 150, "bob": 48}))
+		})
+
+		It("cashes out everyone at a table", func() {
+			Expect(store.BuyIn("Main", "alice", 100)).To(Succeed())
+			Expect(store.BuyIn("Main", "bob", 100)).To(Succeed())
+			Expect(store.SettleHand("Main", map[string]int{"alice": 150, "bob": 50}, 0)).To(Succeed())
+
+			Expect(store.CashOutTable("Main")).To(Succeed())
+			Expect(store.GetStacks("Main")).To(BeEmpty())
+			Expect(store.GetBankroll("alice")).To(Equal(1050))
+			Expect(store.GetBankroll("bob")).To(Equal(950))
+		})
+
+		It("saves table configs", func() {
+			type config struct {
+				Name string
+				Rake int
+			}
+			Expect(store.SaveTableConfig("Main", config{Name: "Main", Rake: 5})).To(Succeed())
+
+			var c config
+			Expect(store.GetTableConfig("Main", &c)).To(Succeed())
+			Expect(c).To(Equal(config{Name: "Main", Rake: 5}))
+			Expect(store.GetTableConfig("Other", &c)).To(Equal(storage.ErrTableConfigNotFound))
+
+			Expect(store.SaveTableConfig("Friday Game", config{Name: "Friday Game"})).To(Succeed())
+			configs, err := store.ListTableConfigs()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(configs).To(HaveLen(2))
+			Expect(configs[0]).To(MatchJSON(`{"Name": "Friday Game", "Rake": 0}`))
+
+			Expect(store.DeleteTableConfig("Friday Game")).To(Succeed())
+			Expect(store.GetTableConfig("Friday Game", &c)).To(Equal(storage.ErrTableConfigNotFound))
+			Expect(store.ListTableConfigs()).To(HaveLen(1))
+		})
+
+		It("saves scheduled tournaments", func() {
+			Expect(store.SaveScheduledTournament("Sunday Event", map[string]int{"buyIn": 100})).To(Succeed())
+			Expect(store.SaveScheduledTournament("Monday Event", map[string]int{"buyIn": 50})).To(Succeed())
+			Expect(store.SaveScheduledTournament("Sunday Event", map[string]int{"buyIn": 200})).To(Succeed())
+
+			tournaments, err := store.ListScheduledTournaments()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tournaments).To(HaveLen(2))
+			Expect(tournaments[0]).To(MatchJSON(`{"buyIn": 50}`))
+			Expect(tournaments[1]).To(MatchJSON(`{"buyIn": 200}`))
+
+			Expect(store.DeleteScheduledTournament("Monday Event")).To(Succeed())
+			Expect(store.DeleteScheduledTournament("Other")).To(Succeed())
+			tournaments, err = store.ListScheduledTournaments()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tournaments).To(HaveLen(1))
+		})
+
+		It("saves bans", func() {
+			Expect(store.SaveBan("ip:10.0.0.1", map[string]string{"value": "10.0.0.1"})).To(Succeed())
+			Expect(store.SaveBan("account:bob", map[string]string{"value": "bob"})).To(Succeed())
+
+			bans, err := store.ListBans()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bans).To(HaveLen(2))
+			Expect(bans[0]).To(MatchJSON(`{"value": "bob"}`))
+
+			Expect(store.DeleteBan("account:bob")).To(Succeed())
+			Expect(store.DeleteBan("account:carol")).To(Succeed())
+			Expect(store.ListBans()).To(HaveLen(1))
+		})
+
+		It("saves snapshots and the journal since the last snapshot", func() {
+			snapshot, journal, err := store.LoadSnapshot("Main")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(snapshot).To(BeNil())
+			Expect(journal).To(BeEmpty())
+
+			Expect(store.SaveSnapshot("Main", []byte("snapshot-1"))).To(Succeed())
+			Expect(store.AppendJournal("Main", []byte("fold"))).To(Succeed())
+			Expect(store.AppendJournal("Main", []byte("call"))).To(Succeed())
+			snapshot, journal, err = store.LoadSnapshot("Main")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(snapshot)).To(Equal("snapshot-1"))
+			Expect(journal).To(Equal([][]byte{[]byte("fold"), []byte("call")}))
+
+			Expect(store.SaveSnapshot("Main", []byte("snapshot-2"))).To(Succeed())
+			snapshot, journal, err = store.LoadSnapshot("Main")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(snapshot)).To(Equal("snapshot-2"))
+			Expect(journal).To(BeEmpty())
+		})
+
+		It("saves users", func() {
+			Expect(store.Users().Create(&auth.User{Username: "Alice"})).To(Succeed())
+			Expect(store.Users().Create(&auth.User{Username: "alice"})).To(Equal(auth.ErrUsernameTaken))
+			u, err := store.Users().Get("ALICE")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(u.Username).To(Equal("Alice"))
+			_, err = store.Users().Get("bob")
+			Expect(err).To(Equal(auth.ErrUserNotFound))
+		})
+
+		It("saves hand histories", func() {
+			h1 := &history.HandHistory{ID: "1", Seats: []history.Seat{{Name: "alice"}, {Name: "bob"}}}
+			h2 := &history.HandHistory{ID: "2", Seats: []history.Seat{{Name: "alice"}}}
+			Expect(store.HandHistories().Save(h1)).To(Succeed())
+			Expect(store.HandHistories().Save(h2)).To(Succeed())
+
+			h, err := store.HandHistories().Get("2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(h.ID).To(Equal("2"))
+			_, err = store.HandHistories().Get("3")
+			Expect(err).To(Equal(history.ErrNotFound))
+
+			histories, err := store.HandHistories().ListByPlayer("alice")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(histories).To(HaveLen(2))
+			Expect(histories[0].ID).To(Equal("1"))
+			Expect(histories[1].ID).To(Equal("2"))
+			Expect(store.HandHistories().ListByPlayer("bob")).To(HaveLen(1))
+
+			// Actions can be added after the hand was saved
+			show := history.Action{Street: history.StreetShowdown, PlayerID: "bob", Type: history.ActionShow}
+			Expect(store.HandHistories().AddAction("1", show)).To(Succeed())
+			h, err = store.HandHistories().Get("1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(h.Actions).To(Equal([]history.Action{show}))
+			Expect(store.HandHistories().ListByPlayer("bob")).To(HaveLen(1))
+			Expect(store.HandHistories().AddAction("3", show)).To(Equal(history.ErrNotFound))
+		})
+
+		It("saves ratings and their history", func() {
+			r, err := store.Ratings().Get("alice")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(r.Rating).To(Equal(rating.DefaultRating))
+
+			r.Rating = 1516
+			changes := []rating.Change{{Username: "alice", Before: 1500, After: 1516}}
+			Expect(store.Ratings().Save([]rating.Rating{r}, changes)).To(Succeed())
+			r.Rating = 1520
+			changes = []rating.Change{{Username: "alice", Before: 1516, After: 1520}}
+			Expect(store.Ratings().Save([]rating.Rating{r}, changes)).To(Succeed())
+
+			r, err = store.Ratings().Get("alice")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(r.Rating).To(Equal(1520.0))
+			Expect(store.Ratings().List()).To(HaveLen(1))
+
+			changes, err = store.Ratings().ListChanges("alice")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changes).To(HaveLen(2))
+			Expect(changes[1].After).To(Equal(1520.0))
+			Expect(store.Ratings().ListChanges("bob")).To(BeEmpty())
+		})
+	})
+}
+
+var _ = describeStore("MemoryStore", func() (storage.Store, func()) {
+	return storage.NewMemoryStore(), func() {}
+})
+
+var _ = describeStore("BoltStore", func() (storage.Store, func()) {
+	dir, err := ioutil.TempDir("", "poker")
+	Expect(err).NotTo(HaveOccurred())
+	store, err := storage.OpenBoltStore(filepath.Join(dir, "poker.db"))
+	Expect(err).NotTo(HaveOccurred())
+	return store, func() {
+		store.Close()
+		os.RemoveAll(dir)
+	}
+})
+
+var _ = Describe("BoltStore", func() {
+	It("keeps chips after the database is reopened", func() {
+		dir, err := ioutil.TempDir("", "poker")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "poker.db")
+
+		store, err := storage.OpenBoltStore(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.OpenBankroll("alice", 1000)).To(Succeed())
+		Expect(store.BuyIn("Main", "alice", 100)).To(Succeed())
+		Expect(store.Close()).To(Succeed())
+
+		store, err = storage.OpenBoltStore(path)
+		Expect(err).NotTo(HaveOccurred())
+		defer store.Close()
+		Expect(store.GetBankroll("alice")).To(Equal(900))
+		Expect(store.GetStacks("Main")).To(Equal(map[string]int{"alice": 100}))
+	})
+})